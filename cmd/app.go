@@ -16,66 +16,63 @@ import (
 	"github.com/AlexJudin/DocumentCacheServer/config"
 	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
 	"github.com/AlexJudin/DocumentCacheServer/internal/controller/api"
-	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
-	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/cache"
-	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/client"
-	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
 	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/saga"
+	"github.com/AlexJudin/DocumentCacheServer/internal/service"
 )
 
-func startApp(cfg *config.Config) {
-	connStr := cfg.GetDataSourceName()
-	db, err := client.NewDatabase(connStr)
+func startApp(cfg *config.Config, storageType string) {
+	store, err := newStorage(cfg, storageType)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
+	defer store.Close()
 
-	err = db.Migrate()
-	if err != nil {
-		log.Fatal(err)
-	}
+	// init metrics
+	appMetrics := metric.NewAppMetrics()
+	_ = appMetrics
 
-	connMgDbStr := cfg.GetMongoDBSourse()
-	mgDb, err := client.NewMongoDBClient(connMgDbStr)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer mgDb.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	cacheManager, err := client.ConnectToRedis(cfg)
-	if err != nil {
-		log.Error("error connecting to redis")
-	}
-	defer cacheManager.Close()
+	r := newRouter(ctx, cfg, store)
 
-	fileClient, err := client.NewFileStorageClient(cfg)
-	if err != nil {
-		log.Fatal(err)
-	}
+	startPprofServer()
 
-	// init cacheClient
-	cacheRepo := cache.NewDocumentRepo(cfg, cacheManager)
+	startHTTPServer(cfg, r)
+}
 
-	// init metrics
-	appMetrics := metric.NewAppMetrics()
-	_ = appMetrics
+// newRouter запускает фоновые службы сервера, которые работают до отмены ctx,
+// и создает маршрутизатор API поверх хранилища store
+func newRouter(ctx context.Context, cfg *config.Config, store *storage) *chi.Mux {
+	sagaOrchestrator := saga.NewDocumentOrchestrator(cfg, store.documents, store.sagaLog, store.keyring)
 
-	repoMetrics := metric.NewDatabaseMetrics()
+	// восстановление саг, прерванных остановкой сервиса
+	go sagaOrchestrator.Run(ctx)
 
-	// init repository
-	documentRepo := repository.NewDocumentRepository(db.DB, mgDb.Client, fileClient, repoMetrics)
-	userRepo := postgres.NewUserRepo(db.DB)
-	tokenRepo := postgres.NewTokenStorageRepo(db.DB)
+	outboxRelay := service.NewOutboxRelay(cfg, store.outbox, store.sender)
 
-	sagaOrchestrator := saga.NewDocumentOrchestrator(documentRepo)
+	go outboxRelay.Run(ctx)
 
-	r := chi.NewRouter()
-	api.AddRoutes(cfg, documentRepo, userRepo, tokenRepo, cacheRepo, sagaOrchestrator, r)
+	webhookDispatcher := service.NewWebhookDispatcher(cfg, store.webhooks)
 
-	startPprofServer()
+	go webhookDispatcher.Run(ctx)
 
-	startHTTPServer(cfg, r)
+	changeFeed := service.NewChangeFeed(store.changeStream)
+
+	go changeFeed.Run(ctx)
+
+	consistencyChecker := service.NewConsistencyChecker(cfg, store.consistency, store.documents, metric.NewConsistencyMetrics())
+
+	go consistencyChecker.Run(ctx)
+
+	uploadCleaner := service.NewUploadCleaner(cfg, store.uploads, store.uploadFiles)
+
+	go uploadCleaner.Run(ctx)
+
+	r := chi.NewRouter()
+	api.AddRoutes(cfg, store.documents, store.users, store.tokens, store.cache, store.idempotency, store.sagaLog, store.webhooks, store.uploads, store.uploadFiles, store.presigner, store.shares, sagaOrchestrator, webhookDispatcher, changeFeed, uploadCleaner, r)
+
+	return r
 }
 
 func startPprofServer() {
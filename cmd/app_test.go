@@ -0,0 +1,869 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// Тесты обработчиков API работают с сервером в режиме --storage=memory. Ожидаемые результаты
+// описывают поведение запросов к Postgres и MongoDB, которое хранилище в памяти должно повторять
+
+const (
+	testAdminToken = "admin-token"
+	testPassword   = "Password123"
+
+	// searchWait время ожидания асинхронного обновления полнотекстового индекса
+	searchWait = 5 * time.Second
+)
+
+const testEnv = `PORT="7540"
+LOGLEVEL="error"
+ADMIN_TOKEN="` + testAdminToken + `"
+PASSWORD_SALT="password_secret"
+TOKEN_SALT="token_secret"
+ACCESS_TOKEN_TTL=60
+REFRESH_TOKEN_TTL=120
+CACHE_MAX_FILE_SIZE=1048576
+BROKER_TYPE="memory"
+`
+
+var (
+	testServer *httptest.Server
+	testStore  *storage
+)
+
+// TestMain запускает один сервер на все тесты: метрики сервера регистрируются глобально
+// и не могут быть созданы повторно в том же процессе
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	cfg, err := testConfig()
+	if err != nil {
+		log.Errorf("failed to load test config: %+v", err)
+		return 1
+	}
+
+	log.SetLevel(cfg.LogLevel)
+
+	testStore, err = newStorage(cfg, storageMemory)
+	if err != nil {
+		log.Errorf("failed to create memory storage: %+v", err)
+		return 1
+	}
+	defer testStore.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testServer = httptest.NewServer(newRouter(ctx, cfg, testStore))
+	defer testServer.Close()
+
+	return m.Run()
+}
+
+// testConfig читает конфигурацию из файла .env во временном каталоге
+func testConfig() (*config.Config, error) {
+	dir, err := os.MkdirTemp("", "document-server-test-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.WriteFile(dir+"/.env", []byte(testEnv), 0o600); err != nil {
+		return nil, err
+	}
+
+	if err = os.Chdir(dir); err != nil {
+		return nil, err
+	}
+	defer os.Chdir(wd)
+
+	return config.New()
+}
+
+func TestDocumentListFilters(t *testing.T) {
+	owner := newTestClient(t, "filterowner")
+	viewer := newTestClient(t, "filterviewer")
+
+	owner.saveFile(t, documentMeta{Name: "report-2024.txt", Mime: "text/plain"}, []byte("annual report"))
+	owner.saveFile(t, documentMeta{Name: "report-2025.csv", Mime: "text/csv", Public: true}, []byte("a,b\n1,2\n"))
+	owner.saveFile(t, documentMeta{Name: "100%_done.txt", Mime: "text/plain", Grant: []string{viewer.login}}, []byte("done"))
+	owner.saveJSON(t, documentMeta{Name: "settings"}, map[string]interface{}{"theme": "dark"})
+
+	tests := []struct {
+		name    string
+		client  *testClient
+		query   url.Values
+		want    []string
+		wantErr int
+	}{
+		{
+			name:   "all own documents sorted by name",
+			client: owner,
+			query:  url.Values{},
+			want:   []string{"100%_done.txt", "report-2024.txt", "report-2025.csv", "settings"},
+		},
+		{
+			name:   "prefix",
+			client: owner,
+			query:  url.Values{"filter": {"name:prefix:report-"}},
+			want:   []string{"report-2024.txt", "report-2025.csv"},
+		},
+		{
+			name:   "prefix matches LIKE wildcards literally",
+			client: owner,
+			query:  url.Values{"filter": {"name:prefix:100%_"}},
+			want:   []string{"100%_done.txt"},
+		},
+		{
+			name:   "LIKE wildcards do not match other characters",
+			client: owner,
+			query:  url.Values{"filter": {"name:prefix:re_ort"}},
+			want:   []string{},
+		},
+		{
+			name:   "in and ne are combined with AND",
+			client: owner,
+			query:  url.Values{"filter": {"mime:in:text/plain,text/csv", "name:ne:100%_done.txt"}},
+			want:   []string{"report-2024.txt", "report-2025.csv"},
+		},
+		{
+			name:   "boolean fields",
+			client: owner,
+			query:  url.Values{"filter": {"file:eq:false"}},
+			want:   []string{"settings"},
+		},
+		{
+			name:   "simple key and value filter",
+			client: owner,
+			query:  url.Values{"key": {"public"}, "value": {"true"}},
+			want:   []string{"report-2025.csv"},
+		},
+		{
+			name:   "created_at range",
+			client: owner,
+			query:  url.Values{"filter": {"created_at:range:" + time.Now().Add(-time.Hour).Format(time.RFC3339) + ".."}},
+			want:   []string{"100%_done.txt", "report-2024.txt", "report-2025.csv", "settings"},
+		},
+		{
+			name:   "documents of another user visible to viewer",
+			client: viewer,
+			query:  url.Values{"login": {owner.login}},
+			want:   []string{"100%_done.txt", "report-2025.csv"},
+		},
+		{
+			name:   "own documents include granted ones",
+			client: viewer,
+			query:  url.Values{},
+			want:   []string{"100%_done.txt"},
+		},
+		{
+			name:    "unknown field",
+			client:  owner,
+			query:   url.Values{"filter": {"owner:eq:" + owner.login}},
+			wantErr: http.StatusBadRequest,
+		},
+		{
+			name:    "unknown operator",
+			client:  owner,
+			query:   url.Values{"filter": {"file:prefix:t"}},
+			wantErr: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, list := tt.client.listDocuments(t, tt.query)
+			if tt.wantErr != 0 {
+				if status != tt.wantErr {
+					t.Fatalf("status = %d, want %d", status, tt.wantErr)
+				}
+
+				return
+			}
+
+			if status != http.StatusOK {
+				t.Fatalf("status = %d, want %d", status, http.StatusOK)
+			}
+
+			if got := documentNames(list.Docs); !slices.Equal(got, tt.want) {
+				t.Errorf("documents = %q, want %q", got, tt.want)
+			}
+
+			if list.Total != int64(len(tt.want)) {
+				t.Errorf("total = %d, want %d", list.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestDocumentListPagination(t *testing.T) {
+	client := newTestClient(t, "pageowner")
+
+	// одинаковые имена упорядочиваются по идентификатору, то есть по времени создания
+	names := []string{"delta", "alpha", "echo", "bravo", "alpha", "charlie", "foxtrot"}
+	ids := make(map[string][]string)
+
+	for _, name := range names {
+		id := client.saveJSON(t, documentMeta{Name: name}, map[string]interface{}{"name": name})
+		ids[name] = append(ids[name], id)
+	}
+
+	ascending := []string{"alpha", "alpha", "bravo", "charlie", "delta", "echo", "foxtrot"}
+	descending := slices.Clone(ascending)
+	slices.Reverse(descending)
+
+	tests := []struct {
+		name  string
+		query url.Values
+		want  []string
+	}{
+		{
+			name:  "ascending",
+			query: url.Values{"sort": {"name"}, "order": {"asc"}, "limit": {"3"}},
+			want:  ascending,
+		},
+		{
+			name:  "descending",
+			query: url.Values{"sort": {"name"}, "order": {"desc"}, "limit": {"2"}},
+			want:  descending,
+		},
+		{
+			name:  "by creation time",
+			query: url.Values{"sort": {"created_at"}, "limit": {"4"}},
+			want:  names,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got   []model.MetaDocument
+				pages int
+			)
+
+			query := tt.query
+
+			for {
+				status, list := client.listDocuments(t, query)
+				if status != http.StatusOK {
+					t.Fatalf("status = %d, want %d", status, http.StatusOK)
+				}
+
+				if list.Total != int64(len(names)) {
+					t.Errorf("total = %d, want %d", list.Total, len(names))
+				}
+
+				got = append(got, list.Docs...)
+				pages++
+
+				if list.NextCursor == "" {
+					break
+				}
+
+				if pages > len(names) {
+					t.Fatal("pagination does not stop")
+				}
+
+				query = url.Values{"cursor": {list.NextCursor}, "sort": tt.query["sort"], "order": tt.query["order"], "limit": tt.query["limit"]}
+			}
+
+			if names := documentNames(got); !slices.Equal(names, tt.want) {
+				t.Fatalf("documents = %q, want %q", names, tt.want)
+			}
+		})
+	}
+
+	t.Run("same names are ordered by id", func(t *testing.T) {
+		_, list := client.listDocuments(t, url.Values{"filter": {"name:eq:alpha"}, "order": {"desc"}})
+
+		want := slices.Clone(ids["alpha"])
+		slices.Reverse(want)
+
+		if got := documentIDs(list.Docs); !slices.Equal(got, want) {
+			t.Errorf("documents = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("offset", func(t *testing.T) {
+		_, list := client.listDocuments(t, url.Values{"limit": {"2"}, "offset": {"5"}})
+
+		if got := documentNames(list.Docs); !slices.Equal(got, ascending[5:]) {
+			t.Errorf("documents = %q, want %q", got, ascending[5:])
+		}
+
+		if list.NextCursor != "" {
+			t.Errorf("next cursor = %q on the last page", list.NextCursor)
+		}
+	})
+
+	t.Run("head returns total count", func(t *testing.T) {
+		resp := client.do(t, http.MethodHead, "/api/docs?filter=name:prefix:a", nil, "")
+		defer resp.Body.Close()
+
+		if got := resp.Header.Get("X-Total-Count"); got != "2" {
+			t.Errorf("X-Total-Count = %q, want %q", got, "2")
+		}
+	})
+
+	t.Run("cursor of another sort is rejected", func(t *testing.T) {
+		_, list := client.listDocuments(t, url.Values{"sort": {"name"}, "limit": {"1"}})
+
+		status, _ := client.listDocuments(t, url.Values{"cursor": {list.NextCursor}, "sort": {"mime"}})
+		if status != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", status, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestFullTextSearch(t *testing.T) {
+	owner := newTestClient(t, "searchowner")
+	viewer := newTestClient(t, "searchviewer")
+
+	budgetName := owner.saveJSON(t, documentMeta{Name: "budget plan"}, map[string]interface{}{"text": "spending limits"})
+	budgetBody := owner.saveJSON(t, documentMeta{Name: "minutes"}, map[string]interface{}{"text": "the quarterly budget report was approved"})
+	budgetFinance := owner.saveFile(t, documentMeta{Name: "finance.txt", Mime: "text/plain", Public: true}, []byte("finance report: budget <b>approved</b> & signed"))
+	reportOnly := owner.saveJSON(t, documentMeta{Name: "weekly"}, map[string]interface{}{"text": "report budget"})
+
+	tests := []struct {
+		name   string
+		client *testClient
+		query  string
+		want   []string
+	}{
+		{
+			name:   "matches in name rank above matches in text",
+			client: owner,
+			query:  "budget",
+			want:   []string{budgetName, budgetBody, budgetFinance, reportOnly},
+		},
+		{
+			name:   "words are combined with AND",
+			client: owner,
+			query:  "budget approved",
+			want:   []string{budgetBody, budgetFinance},
+		},
+		{
+			name:   "excluded word",
+			client: owner,
+			query:  "budget -finance",
+			want:   []string{budgetName, budgetBody, reportOnly},
+		},
+		{
+			name:   "phrase",
+			client: owner,
+			query:  `"budget report"`,
+			want:   []string{budgetBody},
+		},
+		{
+			name:   "or",
+			client: owner,
+			query:  "limits or signed",
+			want:   []string{budgetName, budgetFinance},
+		},
+		{
+			name:   "case insensitive",
+			client: owner,
+			query:  "QUARTERLY",
+			want:   []string{budgetBody},
+		},
+		{
+			name:   "other users find only public documents",
+			client: viewer,
+			query:  "budget",
+			want:   []string{budgetFinance},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := tt.client.searchUntil(t, tt.query, len(tt.want))
+
+			got := make([]string, 0, len(hits))
+			for _, hit := range hits {
+				got = append(got, hit.Meta.UUID)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("documents = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		owner.searchUntil(t, "budget", 4)
+
+		hits := owner.search(t, url.Values{"q": {"budget"}, "limit": {"2"}, "offset": {"1"}})
+
+		got := []string{}
+		for _, hit := range hits {
+			got = append(got, hit.Meta.UUID)
+		}
+
+		if want := []string{budgetBody, budgetFinance}; !slices.Equal(got, want) {
+			t.Errorf("documents = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("snippet is escaped and highlighted", func(t *testing.T) {
+		hits := owner.searchUntil(t, "signed", 1)
+
+		snippet := hits[0].Snippet
+		if !strings.Contains(snippet, "<b>signed</b>") {
+			t.Errorf("snippet %q does not highlight the found word", snippet)
+		}
+
+		if strings.Contains(snippet, "<b>approved</b>") || !strings.Contains(snippet, "&amp;") {
+			t.Errorf("snippet %q is not escaped for HTML", snippet)
+		}
+	})
+
+	t.Run("deleted documents are not found", func(t *testing.T) {
+		owner.deleteDocument(t, budgetName)
+
+		hits := owner.searchUntil(t, "limits", 0)
+		if len(hits) != 0 {
+			t.Errorf("found %d documents, want none", len(hits))
+		}
+	})
+}
+
+func TestFileDeduplication(t *testing.T) {
+	client := newTestClient(t, "dedupowner")
+
+	content := []byte("the same file content stored once")
+	digest := sha256Hex(content)
+
+	first := client.saveFile(t, documentMeta{Name: "first.txt", Mime: "text/plain"}, content)
+	second := client.saveFile(t, documentMeta{Name: "second.txt", Mime: "text/plain"}, content)
+	other := client.saveFile(t, documentMeta{Name: "other.txt", Mime: "text/plain"}, []byte("other content"))
+
+	_, list := client.listDocuments(t, url.Values{"filter": {"name:in:first.txt,second.txt"}})
+	for _, document := range list.Docs {
+		if document.Hash != digest {
+			t.Fatalf("document [%s] digest = %s, want %s", document.UUID, document.Hash, digest)
+		}
+	}
+
+	if got := countFileKeys(t, digest); got != 1 {
+		t.Fatalf("file is stored %d times, want once", got)
+	}
+
+	client.deleteDocument(t, first)
+
+	if got := countFileKeys(t, digest); got != 1 {
+		t.Fatalf("file referenced by another document is removed")
+	}
+
+	if status, body := client.getDocument(t, second); status != http.StatusOK || !bytes.Equal(body, content) {
+		t.Fatalf("document [%s] = %d %q, want %d %q", second, status, body, http.StatusOK, content)
+	}
+
+	client.deleteDocument(t, second)
+
+	if got := countFileKeys(t, digest); got != 0 {
+		t.Fatalf("file without references is kept")
+	}
+
+	if got := countFileKeys(t, sha256Hex([]byte("other content"))); got != 1 {
+		t.Fatalf("file of document [%s] is removed", other)
+	}
+
+	// после удаления последней ссылки то же содержимое снова записывается в хранилище
+	third := client.saveFile(t, documentMeta{Name: "third.txt", Mime: "text/plain"}, content)
+
+	if status, body := client.getDocument(t, third); status != http.StatusOK || !bytes.Equal(body, content) {
+		t.Fatalf("document [%s] = %d %q, want %d %q", third, status, body, http.StatusOK, content)
+	}
+}
+
+func TestPatchDocumentBodyLimit(t *testing.T) {
+	client := newTestClient(t, "patchowner")
+
+	id := client.saveJSON(t, documentMeta{Name: "settings"}, map[string]interface{}{"theme": "dark"})
+
+	client.expect(t, http.MethodPatch, "/api/docs/"+id, strings.NewReader(`{"theme":"light"}`), "application/merge-patch+json", http.StatusOK)
+
+	patch := `{"theme":"` + strings.Repeat("a", 1<<20) + `"}`
+	client.expect(t, http.MethodPatch, "/api/docs/"+id, strings.NewReader(patch), "application/merge-patch+json", http.StatusRequestEntityTooLarge)
+
+	status, body := client.getDocument(t, id)
+	if status != http.StatusOK || !strings.Contains(string(body), `"theme":"light"`) {
+		t.Errorf("document = %d %s, want the first patch applied", status, body)
+	}
+}
+
+func TestShareDownloadGrant(t *testing.T) {
+	owner := newTestClient(t, "shareowner")
+
+	content := []byte("shared file content")
+	id := owner.saveFile(t, documentMeta{Name: "shared.txt", Mime: "text/plain"}, content)
+
+	data := owner.expect(t, http.MethodPost, "/api/docs/"+id+"/shares", strings.NewReader(`{"max_downloads":1}`), "application/json", http.StatusCreated)
+
+	var share struct {
+		Data struct {
+			URL string `json:"url"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(data, &share); err != nil {
+		t.Fatal(err)
+	}
+
+	first := newAnonymousClient(t)
+	second := newAnonymousClient(t)
+
+	openShare := func(c *testClient, rangeHeader string) (int, []byte) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, testServer.URL+share.Data.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("User-Agent", "share-test")
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return resp.StatusCode, body
+	}
+
+	if status, body := openShare(first, ""); status != http.StatusOK || !bytes.Equal(body, content) {
+		t.Fatalf("first download = %d %q, want %d %q", status, body, http.StatusOK, content)
+	}
+
+	// другой клиент с тем же адресом и User-Agent не получает документ без разрешения
+	if status, _ := openShare(second, "bytes=0-5"); status != http.StatusGone {
+		t.Errorf("range request without grant status = %d, want %d", status, http.StatusGone)
+	}
+
+	if status, body := openShare(first, "bytes=7-10"); status != http.StatusPartialContent || string(body) != "file" {
+		t.Errorf("range request with grant = %d %q, want %d %q", status, body, http.StatusPartialContent, "file")
+	}
+
+	// полный повтор скачивания засчитывается как новое скачивание
+	if status, _ := openShare(first, ""); status != http.StatusGone {
+		t.Errorf("repeated download status = %d, want %d", status, http.StatusGone)
+	}
+}
+
+// documentMeta метаданные сохраняемого документа
+type documentMeta struct {
+	Name   string   `json:"name"`
+	File   bool     `json:"file"`
+	Mime   string   `json:"mime,omitempty"`
+	Public bool     `json:"public"`
+	Grant  []string `json:"grant,omitempty"`
+}
+
+// testClient пользователь API с токенами в cookie
+type testClient struct {
+	client *http.Client
+	login  string
+}
+
+// newTestClient регистрирует пользователя и авторизует его
+func newTestClient(t *testing.T, login string) *testClient {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &testClient{client: &http.Client{Jar: jar}, login: login}
+
+	register := fmt.Sprintf(`{"token":%q,"login":%q,"pswd":%q}`, testAdminToken, login, testPassword)
+	c.expect(t, http.MethodPost, "/api/register", strings.NewReader(register), "application/json", http.StatusCreated)
+
+	auth := fmt.Sprintf(`{"login":%q,"pswd":%q}`, login, testPassword)
+	c.expect(t, http.MethodPost, "/api/auth", strings.NewReader(auth), "application/json", http.StatusOK)
+
+	return c
+}
+
+// newAnonymousClient создает клиента без учетной записи
+func newAnonymousClient(t *testing.T) *testClient {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testClient{client: &http.Client{Jar: jar}}
+}
+
+func (c *testClient) do(t *testing.T, method, path string, body io.Reader, contentType string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, testServer.URL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp
+}
+
+// expect выполняет запрос, проверяет код ответа и возвращает тело ответа
+func (c *testClient) expect(t *testing.T, method, path string, body io.Reader, contentType string, status int) []byte {
+	t.Helper()
+
+	resp := c.do(t, method, path, body, contentType)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != status {
+		t.Fatalf("%s %s: status = %d, want %d: %s", method, path, resp.StatusCode, status, data)
+	}
+
+	return data
+}
+
+func (c *testClient) saveJSON(t *testing.T, meta documentMeta, content map[string]interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta.File = false
+
+	return c.saveDocument(t, meta, func(form *multipart.Writer) error {
+		return form.WriteField("json", string(data))
+	})
+}
+
+func (c *testClient) saveFile(t *testing.T, meta documentMeta, content []byte) string {
+	t.Helper()
+
+	meta.File = true
+
+	return c.saveDocument(t, meta, func(form *multipart.Writer) error {
+		part, err := form.CreateFormFile("file", meta.Name)
+		if err != nil {
+			return err
+		}
+
+		_, err = part.Write(content)
+
+		return err
+	})
+}
+
+// saveDocument сохраняет документ и возвращает его идентификатор. Часть с содержимым пишет writeContent
+func (c *testClient) saveDocument(t *testing.T, meta documentMeta, writeContent func(form *multipart.Writer) error) string {
+	t.Helper()
+
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+
+	form := multipart.NewWriter(&body)
+
+	if err = form.WriteField("meta", string(metaData)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = writeContent(form); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = form.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := c.expect(t, http.MethodPost, "/api/docs", &body, form.FormDataContentType(), http.StatusCreated)
+
+	var resp struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+
+	if err = json.Unmarshal(data, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.Data.ID
+}
+
+func (c *testClient) getDocument(t *testing.T, id string) (int, []byte) {
+	t.Helper()
+
+	resp := c.do(t, http.MethodGet, "/api/docs/?id="+url.QueryEscape(id), nil, "")
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, data
+}
+
+func (c *testClient) deleteDocument(t *testing.T, id string) {
+	t.Helper()
+
+	c.expect(t, http.MethodDelete, "/api/docs/?id="+url.QueryEscape(id), nil, "", http.StatusOK)
+}
+
+// listDocuments возвращает код ответа и страницу списка документов
+func (c *testClient) listDocuments(t *testing.T, query url.Values) (int, entity.DocumentList) {
+	t.Helper()
+
+	resp := c.do(t, http.MethodGet, "/api/docs?"+query.Encode(), nil, "")
+	defer resp.Body.Close()
+
+	var body struct {
+		Data entity.DocumentList `json:"data"`
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if header := resp.Header.Get("X-Total-Count"); header != strconv.FormatInt(body.Data.Total, 10) {
+			t.Errorf("X-Total-Count = %q, total = %d", header, body.Data.Total)
+		}
+	}
+
+	return resp.StatusCode, body.Data
+}
+
+func (c *testClient) search(t *testing.T, query url.Values) []entity.SearchHit {
+	t.Helper()
+
+	data := c.expect(t, http.MethodGet, "/api/docs/search?"+query.Encode(), nil, "", http.StatusOK)
+
+	var body struct {
+		Data struct {
+			Docs []entity.SearchHit `json:"docs"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatal(err)
+	}
+
+	return body.Data.Docs
+}
+
+// searchUntil повторяет поиск, пока индекс не вернет count документов: индекс обновляется асинхронно
+func (c *testClient) searchUntil(t *testing.T, query string, count int) []entity.SearchHit {
+	t.Helper()
+
+	deadline := time.Now().Add(searchWait)
+
+	for {
+		hits := c.search(t, url.Values{"q": {query}})
+		if len(hits) == count || time.Now().After(deadline) {
+			return hits
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// countFileKeys возвращает количество файлов хранилища с ключом key
+func countFileKeys(t *testing.T, key string) int {
+	t.Helper()
+
+	keys, err := testStore.documents.FileKeys(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	for _, k := range keys {
+		if k == key {
+			count++
+		}
+	}
+
+	return count
+}
+
+func documentNames(documents []model.MetaDocument) []string {
+	names := make([]string, 0, len(documents))
+	for _, document := range documents {
+		names = append(names, document.Name)
+	}
+
+	return names
+}
+
+func documentIDs(documents []model.MetaDocument) []string {
+	ids := make([]string, 0, len(documents))
+	for _, document := range documents {
+		ids = append(ids, document.UUID)
+	}
+
+	return ids
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
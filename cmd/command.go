@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/client"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/encryption"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/mongodb"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/service"
+)
+
+const (
+	commandConsistency    = "consistency"
+	commandMigrateContent = "migrate-content"
+	commandRotateKeys     = "rotate-keys"
+)
+
+func runCommand(cfg *config.Config, storageType, name string, args []string) {
+	// хранилище в памяти пусто при запуске, поэтому служебным командам в нем нечего обрабатывать
+	if storageType == storageMemory {
+		log.Fatalf("command [%s] is not available with %s storage", name, storageMemory)
+	}
+
+	switch name {
+	case commandConsistency:
+		runConsistencyCheck(cfg, args)
+	case commandMigrateContent:
+		runContentMigration(cfg)
+	case commandRotateKeys:
+		runKeyRotation(cfg)
+	default:
+		log.Fatalf("unknown command [%s], available commands: %s, %s, %s", name, commandConsistency, commandMigrateContent, commandRotateKeys)
+	}
+}
+
+// runConsistencyCheck сверяет Postgres, MongoDB и MinIO и выводит отчет в формате JSON.
+// Без флага -repair расхождения только выводятся
+func runConsistencyCheck(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet(commandConsistency, flag.ExitOnError)
+	repair := flags.Bool("repair", false, "delete orphan contents and files, mark documents without content as broken")
+	_ = flags.Parse(args)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	db, err := client.NewDatabase(cfg.GetDataSourceName())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = db.Migrate(); err != nil {
+		log.Fatal(err)
+	}
+
+	repoMetrics := metric.NewDatabaseMetrics()
+
+	contentStorage, closeContentStorage, err := repository.NewContentStorage(cfg, db.DB, repoMetrics)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeContentStorage()
+
+	fileStorage, err := repository.NewFileStorage(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keyring, err := encryption.NewKeyring(cfg.ConfigEncryption)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	documentRepo := repository.NewDocumentRepository(db.DB, contentStorage, fileStorage, keyring, repoMetrics)
+	consistencyRepo := postgres.NewConsistencyRepo(db.DB, repoMetrics)
+	checker := service.NewConsistencyChecker(cfg, consistencyRepo, documentRepo, metric.NewConsistencyMetrics())
+
+	report, err := checker.Check(ctx, *repair)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	printReport(report)
+}
+
+// runContentMigration копирует содержимое JSON документов из MongoDB в Postgres и сверяет копию.
+// Команда выполняется до переключения CONTENT_STORAGE_TYPE на postgres и завершается ошибкой,
+// если хотя бы одно содержимое не перенесено или отличается от исходного
+func runContentMigration(cfg *config.Config) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	db, err := client.NewDatabase(cfg.GetDataSourceName())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = db.Migrate(); err != nil {
+		log.Fatal(err)
+	}
+
+	mgDb, err := client.NewMongoDBClient(cfg.GetMongoDBSourse())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer mgDb.Close()
+
+	repoMetrics := metric.NewDatabaseMetrics()
+
+	migrator := service.NewContentMigrator(
+		mongodb.NewContentRepository(mgDb.Client, cfg.ConfigCompression, repoMetrics),
+		postgres.NewContentRepository(db.DB, repoMetrics),
+	)
+
+	report, err := migrator.Migrate(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	printReport(report)
+
+	if !report.Complete() {
+		log.Fatalf("content migration is incomplete: %d of %d contents verified", report.Verified, report.Total)
+	}
+}
+
+// runKeyRotation перешифровывает ключи данных документов мастер-ключом ENCRYPTION_KEY_ID.
+// Содержимое документов при этом не перезаписывается. Команда завершается ошибкой,
+// если хотя бы один ключ данных не перешифрован: его мастер-ключ нельзя удалять из конфигурации
+func runKeyRotation(cfg *config.Config) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	keyring, err := encryption.NewKeyring(cfg.ConfigEncryption)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := client.NewDatabase(cfg.GetDataSourceName())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = db.Migrate(); err != nil {
+		log.Fatal(err)
+	}
+
+	rotator := service.NewKeyRotator(postgres.NewEncryptionKeyRepo(db.DB, metric.NewDatabaseMetrics()), keyring)
+
+	report, err := rotator.Rotate(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	printReport(report)
+
+	if !report.Complete() {
+		log.Fatalf("key rotation is incomplete: %d data keys were not rewrapped", report.Failed)
+	}
+}
+
+// printReport выводит отчет команды в формате JSON
+func printReport(report interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"flag"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/AlexJudin/DocumentCacheServer/config"
@@ -35,5 +37,14 @@ func main() {
 
 	log.SetLevel(cfg.LogLevel)
 
-	startApp(cfg)
+	storageType := flag.String("storage", storagePersistent, "storage type: persistent (Postgres, MongoDB, Redis and file storage) or memory")
+	flag.Parse()
+
+	// подкоманды выполняют служебные задачи и завершаются, без подкоманды запускается сервер
+	if flag.NArg() > 0 {
+		runCommand(cfg, *storageType, flag.Arg(0), flag.Args()[1:])
+		return
+	}
+
+	startApp(cfg, *storageType)
 }
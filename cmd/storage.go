@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/broker"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/cache"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/client"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/encryption"
+	filestorage "github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/file_storage"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/memory"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+)
+
+const (
+	// storagePersistent данные хранятся в Postgres, MongoDB, Redis и файловом хранилище
+	storagePersistent = "persistent"
+	// storageMemory все данные хранятся в памяти процесса и теряются при остановке.
+	// Режим для демонстраций и интеграционных тестов без внешних сервисов
+	storageMemory = "memory"
+)
+
+// storage репозитории и брокер, с которыми работает сервер
+type storage struct {
+	documents    repository.DocumentRepository
+	users        postgres.User
+	tokens       postgres.TokenStorage
+	sagaLog      postgres.SagaLog
+	outbox       postgres.Outbox
+	webhooks     postgres.Webhook
+	shares       postgres.Share
+	consistency  postgres.Consistency
+	cache        cache.Document
+	idempotency  cache.Idempotency
+	changeStream cache.ChangeStream
+	sender       broker.Sender
+	keyring      *encryption.Keyring
+
+	uploads postgres.Upload
+	// uploadFiles хранилище, в котором собираются файлы загрузок.
+	// Пустое, если файловое хранилище не поддерживает загрузку частями
+	uploadFiles filestorage.MultipartRepository
+	// presigner выдает подписанные ссылки для прямой передачи файлов.
+	// Пустой, если файловое хранилище их не поддерживает
+	presigner filestorage.Presigner
+
+	closers []func() error
+}
+
+func newStorage(cfg *config.Config, storageType string) (*storage, error) {
+	keyring, err := encryption.NewKeyring(cfg.ConfigEncryption)
+	if err != nil {
+		return nil, err
+	}
+
+	switch storageType {
+	case storagePersistent:
+		return newPersistentStorage(cfg, keyring)
+	case storageMemory:
+		return newMemoryStorage(cfg, keyring), nil
+	default:
+		return nil, fmt.Errorf("unknown storage type [%s], available types: %s, %s", storageType, storagePersistent, storageMemory)
+	}
+}
+
+func newPersistentStorage(cfg *config.Config, keyring *encryption.Keyring) (*storage, error) {
+	s := &storage{keyring: keyring}
+
+	db, err := client.NewDatabase(cfg.GetDataSourceName())
+	if err != nil {
+		return nil, err
+	}
+	s.closers = append(s.closers, db.Close)
+
+	if err = db.Migrate(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	repoMetrics := metric.NewDatabaseMetrics()
+
+	contentStorage, closeContentStorage, err := repository.NewContentStorage(cfg, db.DB, repoMetrics)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.closers = append(s.closers, closeContentStorage)
+
+	cacheManager, err := client.ConnectToRedis(cfg)
+	if err != nil {
+		log.Error("error connecting to redis")
+	} else {
+		s.closers = append(s.closers, cacheManager.Close)
+	}
+
+	fileStorage, err := repository.NewFileStorage(cfg)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	sender, err := broker.NewSender(cfg)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	s.closers = append(s.closers, sender.Close)
+
+	s.documents = repository.NewDocumentRepository(db.DB, contentStorage, fileStorage, keyring, repoMetrics)
+	s.users = postgres.NewUserRepo(db.DB)
+	s.tokens = postgres.NewTokenStorageRepo(db.DB)
+	s.sagaLog = postgres.NewSagaLogRepo(db.DB, repoMetrics)
+	s.outbox = postgres.NewOutboxRepo(db.DB, repoMetrics)
+	s.webhooks = postgres.NewWebhookRepo(db.DB, repoMetrics)
+	s.shares = postgres.NewShareRepo(db.DB, repoMetrics)
+	s.consistency = postgres.NewConsistencyRepo(db.DB, repoMetrics)
+	s.uploads = postgres.NewUploadRepo(db.DB, repoMetrics)
+	// файлы загрузок собираются без шифрования и сжатия: документ получает их при создании через сагу
+	s.uploadFiles, _ = fileStorage.(filestorage.MultipartRepository)
+	s.presigner, _ = fileStorage.(filestorage.Presigner)
+	if cacheManager != nil {
+		s.cache = cache.NewDocumentRepo(cfg, cacheManager, keyring)
+		s.idempotency = cache.NewIdempotencyRepo(cacheManager)
+		s.changeStream = cache.NewChangeStreamRepo(cfg, cacheManager)
+	} else {
+		// без Redis кэш и ключи идемпотентности хранятся в памяти экземпляра сервиса и не видны другим экземплярам,
+		// а лента изменений отключается: событие должно доходить до клиентов всех экземпляров
+		log.Warn("redis is unavailable: cache and idempotency keys are kept in memory, change feed is disabled")
+
+		s.cache = memory.NewDocumentCacheRepo(cfg)
+		s.idempotency = memory.NewIdempotencyRepo()
+	}
+	s.sender = sender
+
+	return s, nil
+}
+
+// newMemoryStorage создает хранилище в памяти. События документов передаются через брокер в памяти,
+// поэтому серверу не нужен ни один внешний сервис
+func newMemoryStorage(cfg *config.Config, keyring *encryption.Keyring) *storage {
+	log.Warn("memory storage is used, all data will be lost when the server stops")
+
+	db := memory.NewDatabase()
+	files := memory.NewFileRepository()
+
+	return &storage{
+		documents:    repository.NewMemoryDocumentRepository(db, files, keyring),
+		users:        memory.NewUserRepo(db),
+		tokens:       memory.NewTokenStorageRepo(db),
+		sagaLog:      memory.NewSagaLogRepo(db),
+		outbox:       memory.NewOutboxRepo(db),
+		webhooks:     memory.NewWebhookRepo(db),
+		shares:       memory.NewShareRepo(db),
+		consistency:  memory.NewConsistencyRepo(db),
+		uploads:      memory.NewUploadRepo(db),
+		uploadFiles:  files,
+		cache:        memory.NewDocumentCacheRepo(cfg),
+		idempotency:  memory.NewIdempotencyRepo(),
+		changeStream: memory.NewChangeStreamRepo(cfg),
+		sender:       broker.NewMemorySender(),
+		keyring:      keyring,
+	}
+}
+
+// Close закрывает соединения в порядке, обратном открытию
+func (s *storage) Close() {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i](); err != nil {
+			log.Errorf("failed to close storage connection: %+v", err)
+		}
+	}
+}
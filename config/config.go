@@ -1,8 +1,12 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -14,6 +18,54 @@ const (
 	refreshTokenTTLDefault = 60
 
 	cacheTTLDefault = 15
+
+	cacheMaxFileSizeDefault = 1 << 20
+
+	sagaStepAttemptsDefault     = 3
+	sagaRetryBackoffDefault     = 200
+	sagaRecoveryAttemptsDefault = 5
+	sagaRecoveryIntervalDefault = 30
+	sagaStaleAfterDefault       = 60
+
+	brokerTypeDefault           = "file"
+	natsURLDefault              = "nats://localhost:4222"
+	brokerFilePathDefault       = "data/events.jsonl"
+	brokerSubjectPrefixDefault  = "documents"
+	natsStreamDefault           = "DOCUMENTS"
+	brokerPublishTimeoutDefault = 5
+	outboxPollIntervalDefault   = 1000
+	outboxBatchSizeDefault      = 100
+	outboxRetentionDefault      = 24
+
+	webhookMaxAttemptsDefault  = 8
+	webhookRetryBackoffDefault = 10
+	webhookMaxBackoffDefault   = 3600
+	webhookTimeoutDefault      = 10
+	webhookPollIntervalDefault = 1000
+	webhookWorkersDefault      = 4
+
+	feedMaxLenDefault    = 10000
+	feedHeartbeatDefault = 15
+
+	idempotencyTTLDefault     = 24
+	idempotencyLockTTLDefault = 300
+
+	consistencyIntervalDefault = 60
+
+	fileStorageTypeDefault = "minio"
+
+	contentStorageTypeDefault = "mongodb"
+
+	compressionMinSizeDefault = 4096
+
+	uploadMaxSizeDefault         = 10 << 30
+	uploadPartSizeDefault        = 8 << 20
+	uploadTTLDefault             = 24
+	uploadCleanupIntervalDefault = 10
+
+	presignURLTTLDefault = 900
+
+	shareGrantTTLDefault = 3600
 )
 
 type Config struct {
@@ -25,7 +77,21 @@ type Config struct {
 	*ConfigAuth
 	*ConfigRedis
 	*ConfigFileStorage
+	*ConfigContentStorage
 	*ConfigMinio
+	*ConfigVersions
+	*ConfigSaga
+	*ConfigBroker
+	*ConfigWebhook
+	*ConfigFeed
+	*ConfigIdempotency
+	*ConfigConsistency
+	*ConfigEncryption
+	*ConfigCompression
+	*ConfigUpload
+	*ConfigPresign
+	*ConfigShare
+	*ConfigProxy
 }
 
 type ConfigDB struct {
@@ -55,14 +121,170 @@ type ConfigRedis struct {
 	Port     string
 	Password string
 	CacheTTL time.Duration
+	// MaxFileSize максимальный размер файла в байтах, который помещается в кэш
+	MaxFileSize int64
 }
 
 type ConfigFileStorage struct {
+	// Type хранилище файлов: minio или local
+	Type string
+	// MainDir каталог файлов для хранилища local
 	MainDir string
 }
 
+// ConfigContentStorage параметры хранилища содержимого JSON документов
+type ConfigContentStorage struct {
+	// Type хранилище содержимого: mongodb или postgres (колонка JSONB)
+	Type string
+}
+
+// ConfigVersions политика хранения версий документов.
+// Нулевые значения означают отсутствие ограничения
+type ConfigVersions struct {
+	KeepLast int
+	KeepFor  time.Duration
+}
+
+// ConfigSaga параметры повторов и восстановления саг
+type ConfigSaga struct {
+	// StepAttempts количество попыток выполнить шаг саги
+	StepAttempts int
+	// RetryBackoff задержка перед первым повтором шага, далее удваивается
+	RetryBackoff time.Duration
+	// RecoveryAttempts количество попыток восстановления саги, после которых она считается зависшей
+	RecoveryAttempts int
+	// RecoveryInterval период проверки незавершенных саг
+	RecoveryInterval time.Duration
+	// StaleAfter время без обновлений, после которого незавершенная сага считается прерванной
+	StaleAfter time.Duration
+}
+
+// ConfigBroker параметры публикации событий документов
+type ConfigBroker struct {
+	// Type брокер сообщений: nats, file или memory
+	Type       string
+	NatsURL    string
+	NatsStream string
+	// SubjectPrefix префикс темы, к которому добавляется тип события
+	SubjectPrefix string
+	// FilePath файл сообщений для брокера file
+	FilePath       string
+	PublishTimeout time.Duration
+	// OutboxPollInterval период проверки неопубликованных событий
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
+	// OutboxRetention срок хранения опубликованных событий
+	OutboxRetention time.Duration
+}
+
+// ConfigWebhook параметры доставки webhook
+type ConfigWebhook struct {
+	// MaxAttempts количество попыток доставки, после которых доставка переходит в состояние dead
+	MaxAttempts int
+	// RetryBackoff задержка перед первым повтором, далее удваивается до MaxBackoff
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	// Timeout время ожидания ответа получателя
+	Timeout      time.Duration
+	PollInterval time.Duration
+	// Workers количество одновременных доставок
+	Workers int
+}
+
+// ConfigFeed параметры ленты изменений документов
+type ConfigFeed struct {
+	// MaxLen примерное количество последних событий, которые хранятся в ленте для продолжения чтения
+	MaxLen int64
+	// Heartbeat период служебных сообщений, которые не дают прокси закрыть соединение
+	Heartbeat time.Duration
+}
+
+// ConfigIdempotency параметры хранения результатов запросов с ключом идемпотентности
+type ConfigIdempotency struct {
+	// TTL время, в течение которого повтор запроса получает сохраненный ответ
+	TTL time.Duration
+	// LockTTL время, на которое ключ занимается выполняющимся запросом.
+	// Если экземпляр сервиса остановился, не сохранив результат, ключ освобождается по его истечении
+	LockTTL time.Duration
+}
+
+const (
+	// ConsistencyModeOff проверка согласованности хранилищ по расписанию не выполняется
+	ConsistencyModeOff = "off"
+	// ConsistencyModeDryRun расхождения только выводятся в отчет и метрики
+	ConsistencyModeDryRun = "dry-run"
+	// ConsistencyModeRepair осиротевшее содержимое удаляется, документы без содержимого помечаются
+	ConsistencyModeRepair = "repair"
+)
+
+// ConfigConsistency параметры проверки согласованности Postgres, MongoDB и MinIO
+type ConfigConsistency struct {
+	// Mode режим проверки по расписанию: off, dry-run или repair
+	Mode     string
+	Interval time.Duration
+}
+
+// ConfigEncryption параметры шифрования содержимого документов
+type ConfigEncryption struct {
+	// KeyID мастер-ключ, которым шифруются ключи данных новых документов.
+	// Пустое значение отключает шифрование новых документов
+	KeyID string
+	// MasterKeys мастер-ключи по идентификатору. Старые ключи нужны для чтения
+	// документов, ключи данных которых еще не перешифрованы активным ключом
+	MasterKeys map[string][]byte
+}
+
+const (
+	// CompressionGzip содержимое сжимается gzip
+	CompressionGzip = "gzip"
+	// CompressionZstd содержимое сжимается zstd
+	CompressionZstd = "zstd"
+)
+
+// ConfigCompression параметры сжатия содержимого документов
+type ConfigCompression struct {
+	// Codec алгоритм сжатия нового содержимого: gzip или zstd. Пустое значение отключает сжатие
+	Codec string
+	// MinSize размер содержимого в байтах, начиная с которого оно сжимается
+	MinSize int64
+}
+
+// ConfigUpload параметры возобновляемых загрузок файлов
+type ConfigUpload struct {
+	// MaxSize максимальный размер загружаемого файла в байтах
+	MaxSize int64
+	// PartSize размер части multipart загрузки в байтах, не меньше 5 МиБ
+	PartSize int64
+	// TTL время, за которое загрузка должна быть завершена
+	TTL time.Duration
+	// CleanupInterval период удаления загрузок с истекшим сроком
+	CleanupInterval time.Duration
+}
+
+// ConfigPresign параметры прямой передачи файлов между клиентом и MinIO по подписанным ссылкам
+type ConfigPresign struct {
+	// TTL срок действия подписанной ссылки
+	TTL time.Duration
+}
+
+// ConfigShare параметры выдачи документов по ссылкам
+type ConfigShare struct {
+	// GrantTTL срок действия разрешения продолжить скачивание по ссылке, которое выдается клиенту
+	// вместе с засчитанным скачиванием
+	GrantTTL time.Duration
+}
+
+// ConfigProxy параметры обратных прокси перед сервисом
+type ConfigProxy struct {
+	// TrustedProxies адреса прокси, которым разрешено передавать адрес клиента
+	// в заголовках X-Forwarded-For и X-Real-IP. Заголовки остальных клиентов не учитываются
+	TrustedProxies []netip.Prefix
+}
+
 type ConfigMinio struct {
-	Endpoint        string
+	Endpoint string
+	// PublicEndpoint адрес MinIO, доступный клиентам, для подписанных ссылок. Пустое значение - Endpoint
+	PublicEndpoint  string
 	AccessKeyID     string
 	SecretAccessKey string
 	UseSSL          bool
@@ -125,29 +347,151 @@ func New() (*Config, error) {
 		cacheTTL = cacheTTLDefault
 	}
 
+	cacheMaxFileSize, err := strconv.ParseInt(os.Getenv("CACHE_MAX_FILE_SIZE"), 10, 64)
+	if err != nil {
+		cacheMaxFileSize = cacheMaxFileSizeDefault
+	}
+
 	redisCfg := ConfigRedis{
-		Host:     os.Getenv("REDIS_HOST"),
-		Port:     os.Getenv("REDIS_PORT"),
-		Password: os.Getenv("REDIS_PASSWORD"),
-		CacheTTL: cacheTTL * time.Minute,
+		Host:        os.Getenv("REDIS_HOST"),
+		Port:        os.Getenv("REDIS_PORT"),
+		Password:    os.Getenv("REDIS_PASSWORD"),
+		CacheTTL:    cacheTTL * time.Minute,
+		MaxFileSize: cacheMaxFileSize,
 	}
 	cfg.ConfigRedis = &redisCfg
 
 	fileCfg := ConfigFileStorage{
+		Type:    getEnvString("FILE_STORAGE_TYPE", fileStorageTypeDefault),
 		MainDir: os.Getenv("FILE_MAIN_DIR"),
 	}
 	cfg.ConfigFileStorage = &fileCfg
 
+	cfg.ConfigContentStorage = &ConfigContentStorage{
+		Type: getEnvString("CONTENT_STORAGE_TYPE", contentStorageTypeDefault),
+	}
+
 	cfg.ConfigMinio = &ConfigMinio{
 		Endpoint:        getMinioEndpoint(),
+		PublicEndpoint:  os.Getenv("MINIO_PUBLIC_ENDPOINT"),
 		AccessKeyID:     os.Getenv("MINIO_ROOT_USER"),
 		SecretAccessKey: os.Getenv("MINIO_ROOT_PASSWORD"),
 		UseSSL:          false,
 	}
 
+	versionsKeepLast, err := strconv.Atoi(os.Getenv("VERSIONS_KEEP_LAST"))
+	if err != nil {
+		versionsKeepLast = 0
+	}
+
+	versionsKeepDays, err := strconv.Atoi(os.Getenv("VERSIONS_KEEP_DAYS"))
+	if err != nil {
+		versionsKeepDays = 0
+	}
+
+	cfg.ConfigVersions = &ConfigVersions{
+		KeepLast: versionsKeepLast,
+		KeepFor:  time.Duration(versionsKeepDays) * 24 * time.Hour,
+	}
+
+	cfg.ConfigSaga = &ConfigSaga{
+		StepAttempts:     getEnvInt("SAGA_STEP_ATTEMPTS", sagaStepAttemptsDefault),
+		RetryBackoff:     time.Duration(getEnvInt("SAGA_RETRY_BACKOFF_MS", sagaRetryBackoffDefault)) * time.Millisecond,
+		RecoveryAttempts: getEnvInt("SAGA_RECOVERY_ATTEMPTS", sagaRecoveryAttemptsDefault),
+		RecoveryInterval: time.Duration(getEnvInt("SAGA_RECOVERY_INTERVAL", sagaRecoveryIntervalDefault)) * time.Second,
+		StaleAfter:       time.Duration(getEnvInt("SAGA_STALE_AFTER", sagaStaleAfterDefault)) * time.Second,
+	}
+
+	cfg.ConfigBroker = &ConfigBroker{
+		Type:               getEnvString("BROKER_TYPE", brokerTypeDefault),
+		NatsURL:            getEnvString("NATS_URL", natsURLDefault),
+		NatsStream:         getEnvString("NATS_STREAM", natsStreamDefault),
+		SubjectPrefix:      getEnvString("BROKER_SUBJECT_PREFIX", brokerSubjectPrefixDefault),
+		FilePath:           getEnvString("BROKER_FILE_PATH", brokerFilePathDefault),
+		PublishTimeout:     time.Duration(getEnvInt("BROKER_PUBLISH_TIMEOUT", brokerPublishTimeoutDefault)) * time.Second,
+		OutboxPollInterval: time.Duration(getEnvInt("OUTBOX_POLL_INTERVAL_MS", outboxPollIntervalDefault)) * time.Millisecond,
+		OutboxBatchSize:    getEnvInt("OUTBOX_BATCH_SIZE", outboxBatchSizeDefault),
+		OutboxRetention:    time.Duration(getEnvInt("OUTBOX_RETENTION_HOURS", outboxRetentionDefault)) * time.Hour,
+	}
+
+	cfg.ConfigWebhook = &ConfigWebhook{
+		MaxAttempts:  getEnvInt("WEBHOOK_MAX_ATTEMPTS", webhookMaxAttemptsDefault),
+		RetryBackoff: time.Duration(getEnvInt("WEBHOOK_RETRY_BACKOFF", webhookRetryBackoffDefault)) * time.Second,
+		MaxBackoff:   time.Duration(getEnvInt("WEBHOOK_MAX_BACKOFF", webhookMaxBackoffDefault)) * time.Second,
+		Timeout:      time.Duration(getEnvInt("WEBHOOK_TIMEOUT", webhookTimeoutDefault)) * time.Second,
+		PollInterval: time.Duration(getEnvInt("WEBHOOK_POLL_INTERVAL_MS", webhookPollIntervalDefault)) * time.Millisecond,
+		Workers:      getEnvInt("WEBHOOK_WORKERS", webhookWorkersDefault),
+	}
+
+	cfg.ConfigFeed = &ConfigFeed{
+		MaxLen:    int64(getEnvInt("FEED_MAX_LEN", feedMaxLenDefault)),
+		Heartbeat: time.Duration(getEnvInt("FEED_HEARTBEAT", feedHeartbeatDefault)) * time.Second,
+	}
+
+	cfg.ConfigIdempotency = &ConfigIdempotency{
+		TTL:     time.Duration(getEnvInt("IDEMPOTENCY_TTL", idempotencyTTLDefault)) * time.Hour,
+		LockTTL: time.Duration(getEnvInt("IDEMPOTENCY_LOCK_TTL", idempotencyLockTTLDefault)) * time.Second,
+	}
+
+	cfg.ConfigConsistency = &ConfigConsistency{
+		Mode:     getEnvString("CONSISTENCY_MODE", ConsistencyModeDryRun),
+		Interval: time.Duration(getEnvInt("CONSISTENCY_INTERVAL", consistencyIntervalDefault)) * time.Minute,
+	}
+
+	masterKeys, err := parseMasterKeys(os.Getenv("ENCRYPTION_MASTER_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ConfigEncryption = &ConfigEncryption{
+		KeyID:      os.Getenv("ENCRYPTION_KEY_ID"),
+		MasterKeys: masterKeys,
+	}
+
+	compressionCodec := os.Getenv("COMPRESSION_CODEC")
+	switch compressionCodec {
+	case "", CompressionGzip, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("unknown compression codec [%s]", compressionCodec)
+	}
+
+	cfg.ConfigCompression = &ConfigCompression{
+		Codec:   compressionCodec,
+		MinSize: int64(getEnvInt("COMPRESSION_MIN_SIZE", compressionMinSizeDefault)),
+	}
+
+	cfg.ConfigUpload = &ConfigUpload{
+		MaxSize:         int64(getEnvInt("UPLOAD_MAX_SIZE", uploadMaxSizeDefault)),
+		PartSize:        int64(getEnvInt("UPLOAD_PART_SIZE", uploadPartSizeDefault)),
+		TTL:             time.Duration(getEnvInt("UPLOAD_TTL", uploadTTLDefault)) * time.Hour,
+		CleanupInterval: time.Duration(getEnvInt("UPLOAD_CLEANUP_INTERVAL", uploadCleanupIntervalDefault)) * time.Minute,
+	}
+
+	cfg.ConfigPresign = &ConfigPresign{
+		TTL: time.Duration(getEnvInt("PRESIGN_URL_TTL", presignURLTTLDefault)) * time.Second,
+	}
+
+	cfg.ConfigShare = &ConfigShare{
+		GrantTTL: time.Duration(getEnvInt("SHARE_GRANT_TTL", shareGrantTTLDefault)) * time.Second,
+	}
+
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ConfigProxy = &ConfigProxy{
+		TrustedProxies: trustedProxies,
+	}
+
 	return &cfg, nil
 }
 
+// Subject возвращает тему брокера для события
+func (c *ConfigBroker) Subject(eventType string) string {
+	return c.SubjectPrefix + "." + eventType
+}
+
 func (c *Config) GetDataSourceName() string {
 	str := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		c.ConfigDB.Host, c.ConfigDB.Port, c.ConfigDB.User, c.ConfigDB.Password, c.ConfigDB.DBName)
@@ -162,3 +506,81 @@ func (c *Config) GetMongoDBSourse() string {
 func getMinioEndpoint() string {
 	return fmt.Sprintf("%s:%s", os.Getenv("MINIO_ROOT_HOST"), os.Getenv("MINIO_ROOT_PORT"))
 }
+
+// getEnvInt читает положительное целое из переменной окружения, при ошибке возвращает значение по умолчанию
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvString читает переменную окружения, для пустого значения возвращает значение по умолчанию
+func getEnvString(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+// parseMasterKeys разбирает список мастер-ключей вида <id>:<ключ в base64>, разделенных запятой
+func parseMasterKeys(value string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+
+	for i, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		// в тексте ошибки ключ указывается номером, чтобы значение ключа не попало в лог
+		id, encoded, found := strings.Cut(item, ":")
+		if !found || id == "" {
+			return nil, fmt.Errorf("invalid encryption master key #%d, expected <id>:<base64 key>", i+1)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption master key [%s]: %w", id, err)
+		}
+
+		keys[id] = key
+	}
+
+	return keys, nil
+}
+
+// parseTrustedProxies разбирает список адресов и подсетей прокси, разделенных запятой.
+// Адрес без маски означает подсеть из одного адреса
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy [%s]: %w", item, err)
+			}
+
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy [%s]: %w", item, err)
+		}
+
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
@@ -9,51 +9,304 @@ const docTemplate = `{
     "info": {
         "description": "{{escape .Description}}",
         "title": "{{.Title}}",
-        "termsOfService": "api://swagger.io/terms/",
+        "termsOfService": "http://swagger.io/terms/",
         "contact": {
             "name": "Alexey Yudin",
-            "url": "api://www.swagger.io/support",
+            "url": "http://www.swagger.io/support",
             "email": "spdante@mail.ru"
         },
         "license": {
             "name": "Apache 2.0",
-            "url": "api://www.apache.org/licenses/LICENSE-2.0.html"
+            "url": "http://www.apache.org/licenses/LICENSE-2.0.html"
         },
         "version": "{{.Version}}"
     },
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/docs": {
+        "/admin/sagas": {
             "get": {
-                "description": "Возвращает список документов с возможностью фильтрации по пользователю",
-                "consumes": [
+                "description": "Возвращает саги, которые не удалось восстановить автоматически (статус failed),\nи незавершенные саги, которые не обновлялись дольше SAGA_STALE_AFTER.\nДоступно только с административным токеном в заголовке X-Admin-Token",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Получить зависшие саги",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Административный токен",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список саг успешно получен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный административный токен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/sagas/{id}": {
+            "get": {
+                "description": "Возвращает сагу вместе с журналом ее шагов.\nДоступно только с административным токеном в заголовке X-Admin-Token",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "documents"
+                    "admin"
                 ],
-                "summary": "Получить список документов",
+                "summary": "Получить сагу",
                 "parameters": [
                     {
-                        "description": "Параметры запроса списка документов",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Административный токен",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор саги",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сага успешно получена",
                         "schema": {
-                            "$ref": "#/definitions/entity.DocumentListRequest"
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный административный токен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Сага не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/sagas/{id}/retry": {
+            "post": {
+                "description": "Повторно запускает восстановление саги с новым запасом попыток и возвращает ее состояние после восстановления.\nСага, которая еще выполняется, не перезапускается.\nДоступно только с административным токеном в заголовке X-Admin-Token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Повторить восстановление саги",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Административный токен",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор саги",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Восстановление саги выполнено",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный административный токен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Сага не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Сага уже завершена или еще выполняется",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
                         }
                     }
+                }
+            }
+        },
+        "/docs": {
+            "get": {
+                "description": "Возвращает список документов с возможностью фильтрации по пользователю.\nПараметры передаются в строке запроса, фильтры объединяются условием И.\nФильтр задается параметром filter вида поле:оператор:значение, значения оператора in\nперечисляются через запятую, границы range - через \"..\".\nПоля: name, mime (eq, ne, in, prefix), file, public (eq, ne), created_at (range с границами в формате RFC 3339).\nСортировка задается параметрами sort (name, mime, created_at, updated_at) и order (asc, desc).\nСледующая страница запрашивается по курсору next_cursor из ответа, общее количество документов\nвозвращается в поле total и заголовке X-Total-Count. HEAD запрос возвращает только количество.\nТело запроса в формате JSON поддерживается для совместимости, сложные запросы следует отправлять в POST /docs/search",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Получить список документов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Логин владельца документов",
+                        "name": "login",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Поле простого фильтра на равенство",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Значение простого фильтра на равенство",
+                        "name": "value",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Фильтры вида поле:оператор:значение",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "name",
+                            "mime",
+                            "created_at",
+                            "updated_at"
+                        ],
+                        "type": "string",
+                        "description": "Поле сортировки",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Направление сортировки",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Для HEAD запроса - только проверка доступности"
+                        "description": "Для HEAD запроса - только заголовок X-Total-Count",
+                        "headers": {
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Общее количество документов"
+                            }
+                        }
                     },
                     "400": {
-                        "description": "Некорректные параметры запроса",
+                        "description": "Некорректные параметры запроса или фильтр",
                         "schema": {
                             "$ref": "#/definitions/entity.ApiError"
                         }
@@ -73,7 +326,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Загружает и сохраняет документ с метаданными и опциональным JSON содержимым",
+                "description": "Загружает и сохраняет документ с метаданными и опциональным JSON содержимым.\nФайл передается потоком, поэтому часть file должна идти в запросе последней, после meta и json.\nС заголовком Idempotency-Key повтор запроса с тем же ключом и содержимым возвращает ответ первого запроса\nс заголовком Idempotent-Replayed и не создает новый документ",
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -85,6 +338,12 @@ const docTemplate = `{
                 ],
                 "summary": "Сохранить документ",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
                     {
                         "type": "string",
                         "description": "Метаданные документа в формате JSON",
@@ -118,6 +377,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/entity.ApiError"
                         }
                     },
+                    "409": {
+                        "description": "Запрос с этим ключом идемпотентности еще выполняется",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "422": {
+                        "description": "Ключ идемпотентности использован для запроса с другим содержимым",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
@@ -133,10 +404,7 @@ const docTemplate = `{
                 }
             },
             "head": {
-                "description": "Возвращает список документов с возможностью фильтрации по пользователю",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает список документов с возможностью фильтрации по пользователю.\nПараметры передаются в строке запроса, фильтры объединяются условием И.\nФильтр задается параметром filter вида поле:оператор:значение, значения оператора in\nперечисляются через запятую, границы range - через \"..\".\nПоля: name, mime (eq, ne, in, prefix), file, public (eq, ne), created_at (range с границами в формате RFC 3339).\nСортировка задается параметрами sort (name, mime, created_at, updated_at) и order (asc, desc).\nСледующая страница запрашивается по курсору next_cursor из ответа, общее количество документов\nвозвращается в поле total и заголовке X-Total-Count. HEAD запрос возвращает только количество.\nТело запроса в формате JSON поддерживается для совместимости, сложные запросы следует отправлять в POST /docs/search",
                 "produces": [
                     "application/json"
                 ],
@@ -146,21 +414,86 @@ const docTemplate = `{
                 "summary": "Получить список документов",
                 "parameters": [
                     {
-                        "description": "Параметры запроса списка документов",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/entity.DocumentListRequest"
-                        }
+                        "type": "string",
+                        "description": "Логин владельца документов",
+                        "name": "login",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Поле простого фильтра на равенство",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Значение простого фильтра на равенство",
+                        "name": "value",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Фильтры вида поле:оператор:значение",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "name",
+                            "mime",
+                            "created_at",
+                            "updated_at"
+                        ],
+                        "type": "string",
+                        "description": "Поле сортировки",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Направление сортировки",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Для HEAD запроса - только проверка доступности"
+                        "description": "Для HEAD запроса - только заголовок X-Total-Count",
+                        "headers": {
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Общее количество документов"
+                            }
+                        }
                     },
                     "400": {
-                        "description": "Некорректные параметры запроса",
+                        "description": "Некорректные параметры запроса или фильтр",
                         "schema": {
                             "$ref": "#/definitions/entity.ApiError"
                         }
@@ -182,7 +515,7 @@ const docTemplate = `{
         },
         "/docs/": {
             "get": {
-                "description": "Возвращает документ по его идентификатору",
+                "description": "Возвращает документ по его идентификатору.\nПоддерживает запросы диапазонов (Range) и условные запросы по ETag и Last-Modified.\nПубличные документы доступны без авторизации",
                 "consumes": [
                     "application/json"
                 ],
@@ -201,11 +534,47 @@ const docTemplate = `{
                         "name": "id",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Запрашиваемые диапазоны байт, например bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag закэшированной клиентом версии",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Время модификации закэшированной клиентом версии",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag или дата, при совпадении которых выполняется запрос диапазона",
+                        "name": "If-Range",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Для HEAD запроса - только проверка доступности"
+                        "description": "Документ успешно получен",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Часть документа",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Документ не изменился"
                     },
                     "400": {
                         "description": "Документ не найден или ошибка сервера",
@@ -213,6 +582,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/entity.ApiError"
                         }
                     },
+                    "416": {
+                        "description": "Запрошенный диапазон недоступен",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "503": {
                         "description": "Сервер недоступен",
                         "schema": {
@@ -255,48 +630,1812 @@ const docTemplate = `{
                             "$ref": "#/definitions/entity.ApiError"
                         }
                     },
-                    "500": {
-                        "description": "Внутренняя ошибка сервера",
+                    "403": {
+                        "description": "Недостаточно прав для удаления документа",
                         "schema": {
                             "$ref": "#/definitions/entity.ApiError"
                         }
                     },
-                    "503": {
-                        "description": "Сервер недоступен",
+                    "404": {
+                        "description": "Документ не найден",
                         "schema": {
                             "$ref": "#/definitions/entity.ApiError"
                         }
-                    }
-                }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Возвращает документ по его идентификатору.\nПоддерживает запросы диапазонов (Range) и условные запросы по ETag и Last-Modified.\nПубличные документы доступны без авторизации",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/octet-stream",
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Получить документ по ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор документа",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Запрашиваемые диапазоны байт, например bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag закэшированной клиентом версии",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Время модификации закэшированной клиентом версии",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag или дата, при совпадении которых выполняется запрос диапазона",
+                        "name": "If-Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Документ успешно получен",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Часть документа",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Документ не изменился"
+                    },
+                    "400": {
+                        "description": "Документ не найден или ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "416": {
+                        "description": "Запрошенный диапазон недоступен",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/events": {
+            "get": {
+                "description": "Отправляет события создания, изменения и удаления документов, доступных пользователю для чтения.\nКаждое событие содержит поле id. Чтобы продолжить чтение после переподключения,\nпередайте id последнего полученного события в заголовке Last-Event-ID или параметре last_event_id.\nДля поддержания соединения сервер периодически отправляет комментарии",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "feed"
+                ],
+                "summary": "Лента изменений документов (Server-Sent Events)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор последнего полученного события",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор последнего полученного события",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поток событий",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ChangeEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный идентификатор события",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Лента изменений недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/events/ws": {
+            "get": {
+                "description": "Отправляет события создания, изменения и удаления документов, доступных пользователю для чтения,\nтекстовыми сообщениями WebSocket в формате JSON. Чтобы продолжить чтение после переподключения,\nпередайте id последнего полученного события в заголовке Last-Event-ID или параметре last_event_id.\nДля поддержания соединения сервер периодически отправляет ping",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "feed"
+                ],
+                "summary": "Лента изменений документов (WebSocket)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор последнего полученного события",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор последнего полученного события",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Соединение переключено на WebSocket",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ChangeEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный идентификатор события",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Лента изменений недоступна",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/query": {
+            "post": {
+                "description": "Ищет JSON документы, доступные пользователю, по условиям на поля содержимого.\nУсловия where объединяются условием И. Путь к вложенному полю задается через точку (address.city).\nОператоры: eq, ne, gt, gte, lt, lte (диапазоны), exists (true или false), contains (значение в массиве).\nЗначения условий - строки, числа, логические значения или null.\nПоле fields ограничивает возвращаемые поля содержимого, следующая страница запрашивается по курсору next_cursor.\nСтраница может содержать меньше документов, чем limit, даже если курсор не пуст",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Поиск по содержимому JSON документов",
+                "parameters": [
+                    {
+                        "description": "Запрос поиска по содержимому",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.ContentQuery"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Документы успешно найдены",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/search": {
+            "get": {
+                "description": "Ищет документы, доступные пользователю, по словам в имени, строковых значениях JSON документов\nи тексте текстовых файлов (text/*, JSON, CSV, Markdown). Результаты упорядочены по релевантности.\nЗапрос поддерживает фразы в кавычках, OR и исключение слов через минус.\nТекст фрагмента snippet экранирован для HTML, найденные слова в нем выделены тегами \u003cb\u003e и \u003c/b\u003e.\nИндекс обновляется асинхронно, поэтому только что сохраненный документ может появиться в выдаче с задержкой",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Полнотекстовый поиск документов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Поисковый запрос",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Документы успешно найдены",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный поисковый запрос",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Возвращает список документов по параметрам из тела запроса.\nПредназначен для сложных запросов, которые не помещаются в строку запроса GET /docs.\nФильтры задаются списком filters и объединяются условием И, формат ответа совпадает с GET /docs",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Поиск документов",
+                "parameters": [
+                    {
+                        "description": "Параметры запроса списка документов",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.DocumentListRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список документов успешно получен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        },
+                        "headers": {
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Общее количество документов"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса или фильтр",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/{id}": {
+            "put": {
+                "description": "Полностью заменяет метаданные и содержимое (файл или JSON) документа.\nФайл передается потоком, поэтому часть file должна идти в запросе последней, после meta и json",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Заменить документ",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор документа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Метаданные документа в формате JSON",
+                        "name": "meta",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON содержимое документа",
+                        "name": "json",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Файл документа (если meta.file = true)",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Документ успешно обновлен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав для изменения документа",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Документ не найден",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Применяет к JSON содержимому документа JSON Patch (RFC 6902) или JSON Merge Patch (RFC 7396).\nФормат изменений определяется заголовком Content-Type",
+                "consumes": [
+                    "application/json-patch+json",
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Частично изменить JSON документ",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор документа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Изменения документа",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Документ успешно изменен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный формат изменений",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав для изменения документа",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Документ не найден",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Документ не является JSON документом",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "413": {
+                        "description": "Размер изменений превышает допустимый",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "415": {
+                        "description": "Неподдерживаемый формат изменений",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "422": {
+                        "description": "Изменения не могут быть применены к документу",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/{id}/diff": {
+            "get": {
+                "description": "Возвращает JSON Merge Patch (RFC 7396), который превращает версию from в версию to",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "versions"
+                ],
+                "summary": "Сравнить версии JSON документа",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор документа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер исходной версии",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер итоговой версии",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Разница версий успешно получена",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные номера версий",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Версия не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Версия не является JSON документом",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/{id}/download-url": {
+            "get": {
+                "description": "Возвращает подписанную ссылку с коротким сроком действия, по которой файл документа скачивается\nнапрямую из хранилища. Зашифрованные файлы доступны только через сервер. Сжатый файл отдается\nхранилищем с заголовком Content-Encoding, поэтому клиент должен принимать его алгоритм сжатия по Accept-Encoding",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "documents"
+                ],
+                "summary": "Получить ссылку для скачивания файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор документа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Алгоритмы сжатия, которые принимает клиент",
+                        "name": "Accept-Encoding",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ссылка для скачивания в поле link",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Документ не найден",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Документ нельзя скачать напрямую из хранилища",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Файловое хранилище не поддерживает подписанные ссылки",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/{id}/shares": {
+            "post": {
+                "description": "Создает ссылку, по которой документ может получить любой, кто знает ее токен, без учетной записи.\nСсылку можно ограничить сроком действия, количеством скачиваний и паролем.\nТокен и адрес ссылки возвращаются только в ответе на создание",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Создать ссылку на документ",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор документа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры ссылки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.ShareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Ссылка успешно создана",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры ссылки",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав для создания ссылки на документ",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Документ не найден",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/{id}/versions": {
+            "get": {
+                "description": "Возвращает список версий документа от новых к старым",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "versions"
+                ],
+                "summary": "Получить историю версий документа",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор документа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список версий успешно получен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Документ не найден",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/{id}/versions/{version}": {
+            "get": {
+                "description": "Возвращает содержимое указанной версии документа.\nПоддерживает запросы диапазонов (Range) и условные запросы по ETag и Last-Modified",
+                "produces": [
+                    "application/octet-stream",
+                    "application/json"
+                ],
+                "tags": [
+                    "versions"
+                ],
+                "summary": "Получить версию документа",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор документа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер версии",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Версия документа успешно получена",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Часть версии документа",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Версия не изменилась"
+                    },
+                    "400": {
+                        "description": "Некорректный номер версии",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Версия не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Возвращает содержимое указанной версии документа.\nПоддерживает запросы диапазонов (Range) и условные запросы по ETag и Last-Modified",
+                "produces": [
+                    "application/octet-stream",
+                    "application/json"
+                ],
+                "tags": [
+                    "versions"
+                ],
+                "summary": "Получить версию документа",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор документа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер версии",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Версия документа успешно получена",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Часть версии документа",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Версия не изменилась"
+                    },
+                    "400": {
+                        "description": "Некорректный номер версии",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Версия не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/{id}/versions/{version}/restore": {
+            "post": {
+                "description": "Делает указанную версию текущей. Восстановление создает новую версию, история не переписывается",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "versions"
+                ],
+                "summary": "Восстановить версию документа",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор документа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Номер восстанавливаемой версии",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Версия успешно восстановлена",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный номер версии",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав для изменения документа",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Документ или версия не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/register": {
+            "post": {
+                "description": "Регистрирует нового пользователя в системе с использованием административного токена",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Регистрация нового пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя для регистрации",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Пользователь успешно зарегистрирован",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный административный токен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Пользователь уже существует",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/share/{token}": {
+            "get": {
+                "description": "Возвращает содержимое документа по токену ссылки без авторизации. Вместе с засчитанным скачиванием\nклиент получает cookie shareGrant: запросы диапазонов с этой cookie продолжают скачивание и не расходуют\nскачивания ссылки, остальные запросы засчитываются как новые скачивания.\nПароль ссылки передается в заголовке X-Share-Password",
+                "produces": [
+                    "application/octet-stream",
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Получить документ по ссылке",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен ссылки",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пароль ссылки",
+                        "name": "X-Share-Password",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Документ успешно получен",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Пароль ссылки не передан или неверен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка или документ не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "410": {
+                        "description": "Ссылка отозвана, истекла или исчерпала количество скачиваний",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/shares": {
+            "get": {
+                "description": "Возвращает ссылки на документы текущего пользователя, в том числе отозванные и истекшие",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Получить ссылки пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор документа",
+                        "name": "document_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список ссылок успешно получен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/shares/{id}": {
+            "delete": {
+                "description": "Отзывает ссылку на документ. Отозванная ссылка остается в списке ссылок вместе с журналом обращений",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Отозвать ссылку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор ссылки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ссылка успешно отозвана",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/shares/{id}/accesses": {
+            "get": {
+                "description": "Возвращает обращения к документу по ссылке от новых к старым: результат, IP-адрес и User-Agent клиента.\nРезультат обращения: granted, wrong_password, expired, revoked, exhausted или not_found",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Получить журнал обращений по ссылке",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор ссылки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Журнал обращений успешно получен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads": {
+            "post": {
+                "description": "Создает возобновляемую загрузку файла по протоколу tus (расширение creation).\nПараметры документа передаются в Upload-Metadata: filename, filetype, public (true/false)\nи grant (логины через запятую), значения кодируются в base64.\nАдрес загрузки возвращается в заголовке Location, срок ее завершения - в Upload-Expires",
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Создать загрузку файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Версия протокола tus: 1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер файла в байтах",
+                        "name": "Upload-Length",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Параметры документа",
+                        "name": "Upload-Metadata",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Загрузка создана"
+                    },
+                    "400": {
+                        "description": "Некорректные параметры загрузки",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия протокола tus не поддерживается",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "413": {
+                        "description": "Размер файла превышает допустимый",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Файловое хранилище не поддерживает загрузку частями",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            },
+            "options": {
+                "description": "Возвращает версию протокола tus, поддерживаемые расширения и максимальный размер файла",
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Параметры загрузки файлов по протоколу tus",
+                "responses": {
+                    "204": {
+                        "description": "Параметры загрузки в заголовках Tus-Version, Tus-Extension и Tus-Max-Size"
+                    }
+                }
+            }
+        },
+        "/uploads/direct": {
+            "post": {
+                "description": "Создает загрузку, файл которой клиент записывает в хранилище сам запросом PUT по подписанной ссылке.\nЕсли указан document_id, файл станет новой версией документа, иначе будет создан новый документ.\nПосле загрузки файла ее нужно подтвердить запросом POST /uploads/direct/{id}/complete",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Создать прямую загрузку файла",
+                "parameters": [
+                    {
+                        "description": "Параметры загрузки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.DirectUploadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Загрузка создана, ссылка для записи файла в поле link",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры загрузки",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав для изменения документа",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Документ не найден",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "413": {
+                        "description": "Размер файла превышает допустимый",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "501": {
+                        "description": "Файловое хранилище не поддерживает подписанные ссылки",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/direct/{id}/complete": {
+            "post": {
+                "description": "Проверяет, что файл записан в хранилище, сверяет его размер и SHA-256\nи создает из него документ или новую версию документа.\nПовторное подтверждение завершенной загрузки возвращает тот же документ",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Подтвердить прямую загрузку файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Хеш загруженного файла",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.DirectUploadCompletion"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Документ создан",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный хеш файла",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав для изменения документа",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Загрузка не найдена или ее срок истек",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Файл еще не загружен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "422": {
+                        "description": "Файл не совпадает с объявленным размером или хешем",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "423": {
+                        "description": "Загрузка обрабатывается другим запросом",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/{id}": {
+            "delete": {
+                "description": "Отменяет загрузку и удаляет полученные части файла (расширение termination).\nДокумент, уже созданный из загрузки, не удаляется",
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Отменить загрузку",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Версия протокола tus: 1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Загрузка отменена"
+                    },
+                    "404": {
+                        "description": "Загрузка не найдена или ее срок истек",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия протокола tus не поддерживается",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "423": {
+                        "description": "Загрузка занята другим запросом",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
             },
             "head": {
-                "description": "Возвращает документ по его идентификатору",
+                "description": "Возвращает количество полученных байт в заголовке Upload-Offset.\nКогда получен весь файл и из него создан документ, его идентификатор возвращается в заголовке X-Document-Id",
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Получить смещение загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Версия протокола tus: 1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Состояние загрузки в заголовках"
+                    },
+                    "404": {
+                        "description": "Загрузка не найдена или ее срок истек",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия протокола tus не поддерживается",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Записывает тело запроса со смещения Upload-Offset, которое должно совпадать с количеством полученных байт.\nЕсли соединение оборвалось, полученные байты сохраняются, и загрузку можно продолжить со смещения из HEAD.\nКогда получен весь файл, из него создается документ, идентификатор которого возвращается в заголовке X-Document-Id.\nЕсли создать документ не удалось, запрос можно повторить с пустым телом",
+                "consumes": [
+                    "application/offset+octet-stream"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Записать часть файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Версия протокола tus: 1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение, с которого записывается тело запроса",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Часть файла записана, новое смещение в заголовке Upload-Offset"
+                    },
+                    "400": {
+                        "description": "Некорректное смещение",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "404": {
+                        "description": "Загрузка не найдена или ее срок истек",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "409": {
+                        "description": "Смещение не совпадает с количеством полученных байт",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "412": {
+                        "description": "Версия протокола tus не поддерживается",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "413": {
+                        "description": "Тело запроса выходит за размер файла",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "415": {
+                        "description": "Некорректный тип тела запроса",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "423": {
+                        "description": "Загрузка занята другим запросом",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Возвращает зарегистрированные webhook текущего пользователя",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Получить webhook пользователя",
+                "responses": {
+                    "200": {
+                        "description": "Список webhook успешно получен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Регистрирует адрес, на который отправляются события документов пользователя\nи документов, к которым ему выдан доступ. Пустой список events подписывает на все события.\nЕсли секрет не передан, он генерируется сервером. Секрет возвращается только в ответе на регистрацию",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/octet-stream",
                     "application/json"
                 ],
                 "tags": [
-                    "documents"
+                    "webhooks"
                 ],
-                "summary": "Получить документ по ID",
+                "summary": "Зарегистрировать webhook",
+                "parameters": [
+                    {
+                        "description": "Параметры webhook",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/entity.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Webhook успешно зарегистрирован",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры webhook",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "delete": {
+                "description": "Удаляет webhook вместе с журналом доставок. Неотправленные доставки отменяются",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Удалить webhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Идентификатор документа",
+                        "description": "Идентификатор webhook",
                         "name": "id",
-                        "in": "query",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Для HEAD запроса - только проверка доступности"
+                        "description": "Webhook успешно удален",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
                     },
-                    "400": {
-                        "description": "Документ не найден или ошибка сервера",
+                    "404": {
+                        "description": "Webhook не найден",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
                         "schema": {
                             "$ref": "#/definitions/entity.ApiError"
                         }
@@ -310,51 +2449,118 @@ const docTemplate = `{
                 }
             }
         },
-        "/register": {
-            "post": {
-                "description": "Регистрирует нового пользователя в системе с использованием административного токена",
-                "consumes": [
-                    "application/json"
-                ],
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Возвращает доставки событий webhook от новых к старым.\nДоставки в состоянии dead исчерпали все попытки и могут быть отправлены повторно",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "webhooks"
                 ],
-                "summary": "Регистрация нового пользователя",
+                "summary": "Получить журнал доставок webhook",
                 "parameters": [
                     {
-                        "description": "Данные пользователя для регистрации",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.User"
-                        }
+                        "type": "string",
+                        "description": "Идентификатор webhook",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Состояние доставки: pending, retrying, delivered, dead",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Пользователь успешно зарегистрирован",
+                    "200": {
+                        "description": "Журнал доставок успешно получен",
                         "schema": {
                             "$ref": "#/definitions/entity.ApiResponse"
                         }
                     },
                     "400": {
-                        "description": "Некорректные данные запроса",
+                        "description": "Некорректные параметры запроса",
                         "schema": {
                             "$ref": "#/definitions/entity.ApiError"
                         }
                     },
-                    "403": {
-                        "description": "Неверный административный токен",
+                    "404": {
+                        "description": "Webhook не найден",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервер недоступен",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiError"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{delivery}/redeliver": {
+            "post": {
+                "description": "Возвращает в очередь доставку в состоянии dead с новым запасом попыток",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Повторить доставку webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор webhook",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор доставки",
+                        "name": "delivery",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Доставка поставлена в очередь",
+                        "schema": {
+                            "$ref": "#/definitions/entity.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook или доставка не найдены",
                         "schema": {
                             "$ref": "#/definitions/entity.ApiError"
                         }
                     },
                     "409": {
-                        "description": "Пользователь уже существует",
+                        "description": "Доставка не находится в состоянии dead",
                         "schema": {
                             "$ref": "#/definitions/entity.ApiError"
                         }
@@ -403,10 +2609,142 @@ const docTemplate = `{
                 }
             }
         },
+        "entity.ChangeEvent": {
+            "type": "object",
+            "properties": {
+                "document": {
+                    "$ref": "#/definitions/model.MetaDocument"
+                },
+                "document_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.ContentCondition": {
+            "type": "object",
+            "properties": {
+                "op": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "value": {}
+            }
+        },
+        "entity.ContentQuery": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "where": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.ContentCondition"
+                    }
+                }
+            }
+        },
+        "entity.DirectUploadCompletion": {
+            "type": "object",
+            "properties": {
+                "sha256": {
+                    "description": "SHA256 хеш загруженного файла в hex, с которым сверяется файл в хранилище",
+                    "type": "string"
+                }
+            }
+        },
+        "entity.DirectUploadRequest": {
+            "type": "object",
+            "properties": {
+                "document_id": {
+                    "type": "string"
+                },
+                "grant": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "mime": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "public": {
+                    "type": "boolean"
+                },
+                "sha256": {
+                    "description": "SHA256 хеш файла в hex. Если он указан, клиент передает его вместе с файлом в заголовках из link.headers,\nхранилище само проверяет файл при записи, и при подтверждении загрузки файл не перечитывается сервером",
+                    "type": "string"
+                },
+                "size": {
+                    "description": "Size размер файла в байтах, который клиент загрузит по ссылке",
+                    "type": "integer"
+                }
+            }
+        },
+        "entity.DocumentFilter": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                },
+                "values": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "entity.DocumentListRequest": {
             "type": "object",
             "properties": {
+                "cursor": {
+                    "description": "Cursor значение next_cursor из предыдущего ответа, нельзя использовать вместе с Offset",
+                    "type": "string"
+                },
+                "filters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.DocumentFilter"
+                    }
+                },
                 "key": {
+                    "description": "Key и Value задают простой фильтр на равенство, аналогичный фильтру с оператором eq",
                     "type": "string"
                 },
                 "limit": {
@@ -415,11 +2753,89 @@ const docTemplate = `{
                 "login": {
                     "type": "string"
                 },
+                "offset": {
+                    "type": "integer"
+                },
+                "order": {
+                    "type": "string"
+                },
+                "sort": {
+                    "description": "Sort поле сортировки, Order - направление (asc или desc)",
+                    "type": "string"
+                },
                 "value": {
                     "type": "string"
                 }
             }
         },
+        "entity.ShareRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_downloads": {
+                    "type": "integer"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "entity.WebhookRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MetaDocument": {
+            "type": "object",
+            "properties": {
+                "digest": {
+                    "description": "Hash SHA-256 содержимого документа в hex, используется как ETag",
+                    "type": "string"
+                },
+                "file": {
+                    "type": "boolean"
+                },
+                "grant": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "mime": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "public": {
+                    "type": "boolean"
+                },
+                "version": {
+                    "description": "Version номер текущей версии документа",
+                    "type": "integer"
+                }
+            }
+        },
         "model.User": {
             "type": "object",
             "properties": {
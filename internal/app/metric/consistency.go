@@ -0,0 +1,88 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+const (
+	consistencyDiscrepancies   = "consistency_discrepancies"
+	consistencyRepairedTotal   = "consistency_repaired_total"
+	consistencyRunsTotal       = "consistency_runs_total"
+	consistencyLastRunSeconds  = "consistency_last_run_timestamp_seconds"
+	consistencyDurationSeconds = "consistency_duration_seconds"
+
+	DiscrepancyOrphanContent  = "orphan_content"
+	DiscrepancyOrphanFile     = "orphan_file"
+	DiscrepancyBrokenDocument = "broken_document"
+	DiscrepancyMissingVersion = "missing_version"
+)
+
+// ConsistencyMetrics метрики проверки согласованности хранилищ
+type ConsistencyMetrics struct {
+	discrepancies *prometheus.GaugeVec
+	repaired      *prometheus.CounterVec
+	runs          *prometheus.CounterVec
+	lastRun       prometheus.Gauge
+	duration      prometheus.Gauge
+}
+
+func NewConsistencyMetrics() *ConsistencyMetrics {
+	return &ConsistencyMetrics{
+		discrepancies: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: consistencyDiscrepancies,
+				Help: "Number of discrepancies between Postgres, MongoDB and MinIO found by the last consistency check",
+			},
+			[]string{"kind"},
+		),
+		repaired: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: consistencyRepairedTotal,
+				Help: "Total number of repaired discrepancies",
+			},
+			[]string{"status"},
+		),
+		runs: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: consistencyRunsTotal,
+				Help: "Total number of consistency checks",
+			},
+			[]string{"mode", "status"},
+		),
+		lastRun: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: consistencyLastRunSeconds,
+				Help: "Unix time of the last successful consistency check",
+			},
+		),
+		duration: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: consistencyDurationSeconds,
+				Help: "Duration of the last successful consistency check in seconds",
+			},
+		),
+	}
+}
+
+// Observe сохраняет результат успешной проверки
+func (m *ConsistencyMetrics) Observe(mode string, report entity.ConsistencyReport) {
+	m.discrepancies.WithLabelValues(DiscrepancyOrphanContent).Set(float64(len(report.OrphanContents)))
+	m.discrepancies.WithLabelValues(DiscrepancyOrphanFile).Set(float64(len(report.OrphanFiles)))
+	m.discrepancies.WithLabelValues(DiscrepancyBrokenDocument).Set(float64(len(report.BrokenDocuments)))
+	m.discrepancies.WithLabelValues(DiscrepancyMissingVersion).Set(float64(len(report.MissingVersions)))
+
+	m.repaired.WithLabelValues(StatusSuccess).Add(float64(report.Repaired))
+	m.repaired.WithLabelValues(StatusFailed).Add(float64(report.RepairFailed))
+
+	m.runs.WithLabelValues(mode, StatusSuccess).Inc()
+	m.lastRun.Set(float64(report.FinishedAt.Unix()))
+	m.duration.Set(report.FinishedAt.Sub(report.StartedAt).Seconds())
+}
+
+// Failed учитывает проверку, завершившуюся ошибкой
+func (m *ConsistencyMetrics) Failed(mode string) {
+	m.runs.WithLabelValues(mode, StatusFailed).Inc()
+}
@@ -0,0 +1,219 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/usecases"
+)
+
+var messageError string
+
+type AdminHandler struct {
+	uc usecases.Admin
+}
+
+func NewAdminHandler(uc usecases.Admin) AdminHandler {
+	return AdminHandler{uc: uc}
+}
+
+// GetStuckSagas godoc
+// @Summary Получить зависшие саги
+// @Description Возвращает саги, которые не удалось восстановить автоматически (статус failed),
+// @Description и незавершенные саги, которые не обновлялись дольше SAGA_STALE_AFTER.
+// @Description Доступно только с административным токеном в заголовке X-Admin-Token
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Административный токен"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} entity.ApiResponse "Список саг успешно получен"
+// @Failure 400 {object} entity.ApiError "Некорректные параметры запроса"
+// @Failure 403 {object} entity.ApiError "Неверный административный токен"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /admin/sagas [get]
+func (h *AdminHandler) GetStuckSagas(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var (
+		req entity.SagaListRequest
+		err error
+	)
+
+	if query.Has("limit") {
+		if req.Limit, err = strconv.Atoi(query.Get("limit")); err != nil {
+			log.Errorf("get stuck sagas error: %+v", err)
+			messageError = "Параметр [limit] должен быть целым числом."
+
+			common.ApiError(http.StatusBadRequest, messageError, w)
+			return
+		}
+	}
+
+	if query.Has("offset") {
+		if req.Offset, err = strconv.Atoi(query.Get("offset")); err != nil {
+			log.Errorf("get stuck sagas error: %+v", err)
+			messageError = "Параметр [offset] должен быть целым числом."
+
+			common.ApiError(http.StatusBadRequest, messageError, w)
+			return
+		}
+	}
+
+	var paramErr *entity.ParamError
+
+	sagas, err := h.uc.GetStuckSagas(req)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("get stuck sagas error: %+v", err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("get stuck sagas error: %+v", err)
+		messageError = "Ошибка сервера, не удалось получить список саг. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"sagas": sagas.Sagas,
+			"total": sagas.Total,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "get stuck sagas")
+}
+
+// GetSaga godoc
+// @Summary Получить сагу
+// @Description Возвращает сагу вместе с журналом ее шагов.
+// @Description Доступно только с административным токеном в заголовке X-Admin-Token
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Административный токен"
+// @Param id path string true "Идентификатор саги"
+// @Success 200 {object} entity.ApiResponse "Сага успешно получена"
+// @Failure 403 {object} entity.ApiError "Неверный административный токен"
+// @Failure 404 {object} entity.ApiError "Сага не найдена"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /admin/sagas/{id} [get]
+func (h *AdminHandler) GetSaga(w http.ResponseWriter, r *http.Request) {
+	idSaga := chi.URLParam(r, "id")
+
+	details, err := h.uc.GetSaga(idSaga)
+	switch {
+	case errors.Is(err, custom_error.ErrSagaNotFound):
+		log.Errorf("get saga error: %+v", err)
+		messageError = fmt.Sprintf("Сага [%s] не найдена.", idSaga)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("get saga error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось получить сагу [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idSaga)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"saga":  details.Saga,
+			"steps": details.Steps,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "get saga")
+}
+
+// RetrySaga godoc
+// @Summary Повторить восстановление саги
+// @Description Повторно запускает восстановление саги с новым запасом попыток и возвращает ее состояние после восстановления.
+// @Description Сага, которая еще выполняется, не перезапускается.
+// @Description Доступно только с административным токеном в заголовке X-Admin-Token
+// @Tags admin
+// @Produce json
+// @Param X-Admin-Token header string true "Административный токен"
+// @Param id path string true "Идентификатор саги"
+// @Success 200 {object} entity.ApiResponse "Восстановление саги выполнено"
+// @Failure 403 {object} entity.ApiError "Неверный административный токен"
+// @Failure 404 {object} entity.ApiError "Сага не найдена"
+// @Failure 409 {object} entity.ApiError "Сага уже завершена или еще выполняется"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /admin/sagas/{id}/retry [post]
+func (h *AdminHandler) RetrySaga(w http.ResponseWriter, r *http.Request) {
+	idSaga := chi.URLParam(r, "id")
+
+	saga, err := h.uc.RetrySaga(idSaga)
+	switch {
+	case errors.Is(err, custom_error.ErrSagaNotFound):
+		log.Errorf("retry saga error: %+v", err)
+		messageError = fmt.Sprintf("Сага [%s] не найдена.", idSaga)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrSagaFinished):
+		log.Errorf("retry saga error: %+v", err)
+		messageError = fmt.Sprintf("Сага [%s] уже завершена.", idSaga)
+
+		common.ApiError(http.StatusConflict, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrSagaInProgress):
+		log.Errorf("retry saga error: %+v", err)
+		messageError = fmt.Sprintf("Сага [%s] еще выполняется.", idSaga)
+
+		common.ApiError(http.StatusConflict, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("retry saga error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось восстановить сагу [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idSaga)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"saga": saga,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "retry saga")
+}
+
+func writeJson(w http.ResponseWriter, status int, response entity.ApiResponse, operation string) {
+	resp, err := json.Marshal(response)
+	if err != nil {
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Ошибка сервера. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(resp)
+	if err != nil {
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Сервер недоступен. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusServiceUnavailable, messageError, w)
+	}
+}
@@ -0,0 +1,92 @@
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// SearchDocumentsContent godoc
+// @Summary Поиск по содержимому JSON документов
+// @Description Ищет JSON документы, доступные пользователю, по условиям на поля содержимого.
+// @Description Условия where объединяются условием И. Путь к вложенному полю задается через точку (address.city).
+// @Description Операторы: eq, ne, gt, gte, lt, lte (диапазоны), exists (true или false), contains (значение в массиве).
+// @Description Значения условий - строки, числа, логические значения или null.
+// @Description Поле fields ограничивает возвращаемые поля содержимого, следующая страница запрашивается по курсору next_cursor.
+// @Description Страница может содержать меньше документов, чем limit, даже если курсор не пуст
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param request body entity.ContentQuery true "Запрос поиска по содержимому"
+// @Success 200 {object} entity.ApiResponse "Документы успешно найдены"
+// @Failure 400 {object} entity.ApiError "Некорректный запрос"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /docs/query [post]
+func (h *DocumentHandler) SearchDocumentsContent(w http.ResponseWriter, r *http.Request) {
+	var (
+		query entity.ContentQuery
+		buf   bytes.Buffer
+	)
+
+	_, err := buf.ReadFrom(io.LimitReader(r.Body, maxListBodySize))
+	if err != nil {
+		log.Errorf("search documents content error: %+v", err)
+		messageError = "Переданы некорректные параметры для поиска по содержимому документов."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	if err = json.Unmarshal(buf.Bytes(), &query); err != nil {
+		log.Errorf("search documents content error: %+v", err)
+		messageError = "Не удалось прочитать параметры для поиска по содержимому документов."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	query.Viewer, err = getCurrentUser(r)
+	if err != nil {
+		log.Errorf("search documents content error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	var paramErr *entity.ParamError
+
+	result, err := h.uc.SearchContent(r.Context(), query)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("search documents content error: %+v", err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("search documents content error: %+v", err)
+		messageError = "Ошибка сервера, не удалось выполнить поиск по содержимому документов. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"docs":        result.Docs,
+			"next_cursor": result.NextCursor,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "search documents content")
+}
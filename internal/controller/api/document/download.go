@@ -0,0 +1,72 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// GetDownloadURL godoc
+// @Summary Получить ссылку для скачивания файла
+// @Description Возвращает подписанную ссылку с коротким сроком действия, по которой файл документа скачивается
+// @Description напрямую из хранилища. Зашифрованные файлы доступны только через сервер. Сжатый файл отдается
+// @Description хранилищем с заголовком Content-Encoding, поэтому клиент должен принимать его алгоритм сжатия по Accept-Encoding
+// @Tags documents
+// @Produce json
+// @Param id path string true "Идентификатор документа"
+// @Param Accept-Encoding header string false "Алгоритмы сжатия, которые принимает клиент"
+// @Success 200 {object} entity.ApiResponse "Ссылка для скачивания в поле link"
+// @Failure 404 {object} entity.ApiError "Документ не найден"
+// @Failure 409 {object} entity.ApiError "Документ нельзя скачать напрямую из хранилища"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 501 {object} entity.ApiError "Файловое хранилище не поддерживает подписанные ссылки"
+// @Router /docs/{id}/download-url [get]
+func (h *DocumentHandler) GetDownloadURL(w http.ResponseWriter, r *http.Request) {
+	idDoc := chi.URLParam(r, "id")
+
+	// публичные документы доступны без авторизации, поэтому логин может отсутствовать
+	login, _ := getCurrentUser(r)
+
+	link, err := h.uc.GetDownloadURL(r.Context(), login, idDoc, r.Header.Get("Accept-Encoding"))
+	switch {
+	case errors.Is(err, custom_error.ErrDocumentNotFound):
+		log.Errorf("get download url error: %+v", err)
+		messageError = fmt.Sprintf("Документ [%s] не найден.", idDoc)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrDirectTransferUnavailable):
+		log.Errorf("get download url error: %+v", err)
+		messageError = fmt.Sprintf("Документ [%s] можно получить только через сервер.", idDoc)
+
+		common.ApiError(http.StatusConflict, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrDirectTransferUnsupported):
+		log.Errorf("get download url error: %+v", err)
+		messageError = "Файловое хранилище сервера не поддерживает скачивание файлов по подписанным ссылкам."
+
+		common.ApiError(http.StatusNotImplemented, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("get download url error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось получить ссылку на документ [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDoc)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"link": link,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "get download url")
+}
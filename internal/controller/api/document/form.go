@@ -0,0 +1,68 @@
+package document
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// readDocumentForm читает части multipart формы meta, json и file.
+// Файл не буферизуется: чтение формы останавливается на части file, и ее содержимое
+// передается в хранилище потоком прямо из тела запроса, поэтому file должна идти последней.
+// Вместе с ошибкой возвращается сообщение для клиента
+func readDocumentForm(r *http.Request) (entity.Document, string, error) {
+	document := entity.Document{
+		Json: make(map[string]interface{}),
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return document, "Переданы некорректные параметры запроса.", err
+	}
+
+	for document.File == nil {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return document, "Переданы некорректные параметры запроса.", err
+		}
+
+		switch part.FormName() {
+		case "meta":
+			if err = json.NewDecoder(part).Decode(&document.Meta); err != nil {
+				return document, "Не удалось прочитать параметры документа.", err
+			}
+		case "json":
+			if err = json.NewDecoder(part).Decode(&document.Json); err != nil {
+				return document, "Не удалось загрузить json документа.", err
+			}
+		case "file":
+			if document.Meta == nil {
+				err = fmt.Errorf("file part received before meta")
+				return document, "Параметры документа (meta) должны передаваться до файла.", err
+			}
+
+			document.File = &entity.DocumentFile{
+				Name:    part.FileName(),
+				Content: part,
+				Size:    -1,
+			}
+		}
+	}
+
+	if document.Meta == nil {
+		return document, "Не удалось прочитать параметры документа.", fmt.Errorf("meta part is missing")
+	}
+
+	if document.Meta.File && document.File == nil {
+		return document, "Не удалось загрузить файл документа.", fmt.Errorf("file part is missing")
+	}
+
+	return document, "", nil
+}
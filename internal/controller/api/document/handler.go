@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
@@ -16,72 +18,122 @@ import (
 	"github.com/AlexJudin/DocumentCacheServer/internal/usecases"
 )
 
+const (
+	// totalCountHeader заголовок с общим количеством документов в списке
+	totalCountHeader = "X-Total-Count"
+
+	// maxPatchBodySize ограничивает размер тела запроса с изменениями JSON документа
+	maxPatchBodySize = 1 << 20
+)
+
 var messageError string
 
 type DocumentHandler struct {
-	uc usecases.Document
+	uc          usecases.Document
+	idempotency usecases.Idempotency
 }
 
-func NewDocumentHandler(uc usecases.Document) DocumentHandler {
+func NewDocumentHandler(uc usecases.Document, idempotency usecases.Idempotency) DocumentHandler {
 	return DocumentHandler{
-		uc: uc,
+		uc:          uc,
+		idempotency: idempotency,
 	}
 }
 
 // SaveDocument godoc
 // @Summary Сохранить документ
-// @Description Загружает и сохраняет документ с метаданными и опциональным JSON содержимым
+// @Description Загружает и сохраняет документ с метаданными и опциональным JSON содержимым.
+// @Description Файл передается потоком, поэтому часть file должна идти в запросе последней, после meta и json.
+// @Description С заголовком Idempotency-Key повтор запроса с тем же ключом и содержимым возвращает ответ первого запроса
+// @Description с заголовком Idempotent-Replayed и не создает новый документ
 // @Tags documents
 // @Accept multipart/form-data
 // @Produce json
+// @Param Idempotency-Key header string false "Ключ идемпотентности"
 // @Param meta formData string true "Метаданные документа в формате JSON"
 // @Param json formData string false "JSON содержимое документа"
 // @Param file formData file false "Файл документа (если meta.file = true)"
 // @Success 201 {object} entity.ApiResponse "Документ успешно сохранен"
 // @Failure 400 {object} entity.ApiError "Некорректные параметры запроса"
+// @Failure 409 {object} entity.ApiError "Запрос с этим ключом идемпотентности еще выполняется"
+// @Failure 422 {object} entity.ApiError "Ключ идемпотентности использован для запроса с другим содержимым"
 // @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
 // @Failure 503 {object} entity.ApiError "Сервер недоступен"
 // @Router /docs [post]
 func (h *DocumentHandler) SaveDocument(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(32 << 20)
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("save saga error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	document, messageError, err := readDocumentForm(r)
 	if err != nil {
 		log.Errorf("save saga error: %+v", err)
-		messageError = "Переданы некорректные параметры запроса на загрузку нового документа."
 
 		common.ApiError(http.StatusBadRequest, messageError, w)
 		return
 	}
 
-	metaDoc := r.FormValue("meta")
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key != "" {
+		h.saveDocumentOnce(w, r, login, key, &document)
+		return
+	}
 
-	var document entity.Document
+	err = h.uc.SaveDocument(r.Context(), login, &document)
+	if err != nil {
+		log.Errorf("save saga: error save saga [%s]: service is not allowed", document.Meta.Name)
+		messageError = "Ошибка сервера, не удалось сохранить документ. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	writeJson(w, http.StatusCreated, savedDocumentResponse(&document), "save saga")
+}
 
-	if err = json.Unmarshal([]byte(metaDoc), &document.Meta); err != nil {
+// saveDocumentOnce сохраняет документ с ключом идемпотентности.
+// Повтор запроса с тем же ключом и содержимым получает ответ первого запроса без повторного сохранения
+func (h *DocumentHandler) saveDocumentOnce(w http.ResponseWriter, r *http.Request, login, key string, document *entity.Document) {
+	fingerprint, err := newDocumentFingerprint(document)
+	if err != nil {
 		log.Errorf("save saga error: %+v", err)
-		messageError = "Не удалось прочитать параметры документа."
+		messageError = "Переданы некорректные параметры запроса."
 
 		common.ApiError(http.StatusBadRequest, messageError, w)
 		return
 	}
 
-	jsonDoc := r.FormValue("json")
+	var paramErr *entity.ParamError
 
-	jsonDocMap := make(map[string]interface{})
-	if jsonDoc != "" {
-		err = json.Unmarshal([]byte(jsonDoc), &jsonDocMap)
-		if err != nil {
-			log.Errorf("save saga error: %+v", err)
-			messageError = "Не удалось загрузить json документа."
+	record, found, err := h.idempotency.Begin(login, key)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("save saga error: %+v", err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
 
-			common.ApiError(http.StatusBadRequest, messageError, w)
-			return
-		}
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrIdempotencyKeyInProgress):
+		log.Errorf("save saga error: %+v", err)
+		messageError = "Запрос с этим ключом идемпотентности еще выполняется. Повторите запрос позже."
 
-		document.Json = jsonDocMap
+		common.ApiError(http.StatusConflict, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("save saga error: %+v", err)
+		messageError = "Ошибка сервера, не удалось проверить ключ идемпотентности. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
 	}
 
-	if document.Meta.File {
-		file, header, err := r.FormFile("file")
+	if found {
+		sum, err := fingerprint.Sum()
 		if err != nil {
 			log.Errorf("save saga error: %+v", err)
 			messageError = "Не удалось загрузить файл документа."
@@ -89,40 +141,42 @@ func (h *DocumentHandler) SaveDocument(w http.ResponseWriter, r *http.Request) {
 			common.ApiError(http.StatusBadRequest, messageError, w)
 			return
 		}
-		defer file.Close()
 
-		data, err := io.ReadAll(file)
-		if err != nil {
-			log.Errorf("save saga error: %+v", err)
-			messageError = "Не удалось прочитать файл документа."
+		if sum != record.Fingerprint {
+			log.Errorf("save saga error: %+v", custom_error.ErrIdempotencyKeyReused)
+			messageError = "Ключ идемпотентности уже использован для запроса с другим содержимым."
 
-			common.ApiError(http.StatusBadRequest, messageError, w)
+			common.ApiError(http.StatusUnprocessableEntity, messageError, w)
 			return
 		}
 
-		document.File = &entity.DocumentFile{
-			Name:    header.Filename,
-			Content: data,
+		log.Infof("replaying saved response for document [%s]", record.DocumentUUID)
+
+		w.Header().Set(idempotentReplayedHeader, "true")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(record.Status)
+		if _, err = w.Write(record.Body); err != nil {
+			log.Errorf("save saga error: %+v", err)
 		}
+		return
 	}
 
-	err = h.uc.SaveDocument(&document)
+	err = h.uc.SaveDocument(r.Context(), login, document)
 	if err != nil {
 		log.Errorf("save saga: error save saga [%s]: service is not allowed", document.Meta.Name)
+
+		// документ не сохранен, поэтому повтор с тем же ключом должен выполнить запрос заново
+		if err = h.idempotency.Abort(login, key); err != nil {
+			log.Errorf("failed to release idempotency key: %+v", err)
+		}
+
 		messageError = "Ошибка сервера, не удалось сохранить документ. Попробуйте позже или обратитесь в тех. поддержку."
 
 		common.ApiError(http.StatusInternalServerError, messageError, w)
 		return
 	}
 
-	respMap := entity.ApiResponse{
-		Data: map[string]interface{}{
-			"json": jsonDocMap,
-			"file": document.Meta.Name,
-		},
-	}
-
-	resp, err := json.Marshal(respMap)
+	resp, err := json.Marshal(savedDocumentResponse(document))
 	if err != nil {
 		log.Errorf("save saga error: %+v", err)
 		messageError = "Ошибка сервера. Попробуйте позже или обратитесь в тех. поддержку."
@@ -131,89 +185,206 @@ func (h *DocumentHandler) SaveDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sum, err := fingerprint.Sum()
+	if err == nil {
+		err = h.idempotency.Complete(login, key, entity.IdempotencyRecord{
+			Fingerprint:  sum,
+			Status:       http.StatusCreated,
+			Body:         resp,
+			DocumentUUID: document.Meta.UUID,
+		})
+	}
+	if err != nil {
+		// документ уже сохранен, поэтому клиент получает успешный ответ, а ключ освобождается по LockTTL
+		log.Errorf("failed to save idempotency record of document [%s]: %+v", document.Meta.UUID, err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	_, err = w.Write(resp)
-	if err != nil {
+	if _, err = w.Write(resp); err != nil {
 		log.Errorf("save saga error: %+v", err)
-		messageError = "Сервер недоступен. Попробуйте позже или обратитесь в тех. поддержку."
+	}
+}
 
-		common.ApiError(http.StatusServiceUnavailable, messageError, w)
+func savedDocumentResponse(document *entity.Document) entity.ApiResponse {
+	return entity.ApiResponse{
+		Data: map[string]interface{}{
+			"id":   document.Meta.UUID,
+			"json": document.Json,
+			"file": document.Meta.Name,
+		},
 	}
 }
 
 // GetDocumentsList godoc
 // @Summary Получить список документов
-// @Description Возвращает список документов с возможностью фильтрации по пользователю
+// @Description Возвращает список документов с возможностью фильтрации по пользователю.
+// @Description Параметры передаются в строке запроса, фильтры объединяются условием И.
+// @Description Фильтр задается параметром filter вида поле:оператор:значение, значения оператора in
+// @Description перечисляются через запятую, границы range - через "..".
+// @Description Поля: name, mime (eq, ne, in, prefix), file, public (eq, ne), created_at (range с границами в формате RFC 3339).
+// @Description Сортировка задается параметрами sort (name, mime, created_at, updated_at) и order (asc, desc).
+// @Description Следующая страница запрашивается по курсору next_cursor из ответа, общее количество документов
+// @Description возвращается в поле total и заголовке X-Total-Count. HEAD запрос возвращает только количество.
+// @Description Тело запроса в формате JSON поддерживается для совместимости, сложные запросы следует отправлять в POST /docs/search
 // @Tags documents
-// @Accept json
 // @Produce json
-// @Param request body entity.DocumentListRequest true "Параметры запроса списка документов"
+// @Param login query string false "Логин владельца документов"
+// @Param key query string false "Поле простого фильтра на равенство"
+// @Param value query string false "Значение простого фильтра на равенство"
+// @Param filter query []string false "Фильтры вида поле:оператор:значение" collectionFormat(multi)
+// @Param sort query string false "Поле сортировки" Enums(name, mime, created_at, updated_at)
+// @Param order query string false "Направление сортировки" Enums(asc, desc)
+// @Param cursor query string false "Курсор следующей страницы"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение"
 // @Success 200 {object} entity.ApiResponse "Список документов успешно получен"
-// @Success 200 {object} nil "Для HEAD запроса - только проверка доступности"
-// @Failure 400 {object} entity.ApiError "Некорректные параметры запроса"
+// @Success 200 {object} nil "Для HEAD запроса - только заголовок X-Total-Count"
+// @Header 200 {integer} X-Total-Count "Общее количество документов"
+// @Failure 400 {object} entity.ApiError "Некорректные параметры запроса или фильтр"
 // @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
 // @Failure 503 {object} entity.ApiError "Сервер недоступен"
 // @Router /docs [get]
 // @Router /docs [head]
 func (h *DocumentHandler) GetDocumentsList(w http.ResponseWriter, r *http.Request) {
-	var (
-		req entity.DocumentListRequest
-		buf bytes.Buffer
-	)
-
-	_, err := buf.ReadFrom(r.Body)
+	req, messageError, err := readListRequest(r)
 	if err != nil {
 		log.Errorf("get documents list error: %+v", err)
-		messageError = "Переданы некорректные параметры для получения списка документов."
 
 		common.ApiError(http.StatusBadRequest, messageError, w)
 		return
 	}
 
-	if err = json.Unmarshal(buf.Bytes(), &req); err != nil {
-		log.Errorf("get documents list error: %+v", err)
-		messageError = "Не удалось прочитать параметры для получения списка документов."
+	h.writeDocumentsList(w, r, req)
+}
+
+// GetDocumentById godoc
+// @Summary Получить документ по ID
+// @Description Возвращает документ по его идентификатору.
+// @Description Поддерживает запросы диапазонов (Range) и условные запросы по ETag и Last-Modified.
+// @Description Публичные документы доступны без авторизации
+// @Tags documents
+// @Accept json
+// @Produce octet-stream
+// @Produce json
+// @Param id query string true "Идентификатор документа"
+// @Param Range header string false "Запрашиваемые диапазоны байт, например bytes=0-1023"
+// @Param If-None-Match header string false "ETag закэшированной клиентом версии"
+// @Param If-Modified-Since header string false "Время модификации закэшированной клиентом версии"
+// @Param If-Range header string false "ETag или дата, при совпадении которых выполняется запрос диапазона"
+// @Success 200 {file} byte "Документ успешно получен"
+// @Success 206 {file} byte "Часть документа"
+// @Success 304 {object} nil "Документ не изменился"
+// @Failure 416 {string} string "Запрошенный диапазон недоступен"
+// @Failure 400 {object} entity.ApiError "Не передан идентификатор документа"
+// @Failure 400 {object} entity.ApiError "Документ не найден или ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /docs/ [get]
+// @Router /docs/ [head]
+func (h *DocumentHandler) GetDocumentById(w http.ResponseWriter, r *http.Request) {
+	idDoc := r.FormValue("id")
+	if idDoc == "" {
+		err := fmt.Errorf("saga id is empty")
+		log.Errorf("get saga by id error: %+v", err)
+		messageError = "Не передан идентификатор документа."
 
 		common.ApiError(http.StatusBadRequest, messageError, w)
 		return
 	}
 
-	if req.LoginIsEmpty() {
-		login, err := getCurrentUser(r)
-		if err != nil {
-			log.Errorf("get documents list error: %+v", err)
-			messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+	// публичные документы доступны без авторизации, поэтому логин может отсутствовать
+	login, _ := getCurrentUser(r)
 
-			common.ApiError(http.StatusInternalServerError, messageError, w)
-			return
-		}
+	content, err := h.uc.GetDocumentById(r.Context(), login, idDoc, r.Header.Get("Accept-Encoding"))
+	switch {
+	case errors.Is(err, custom_error.ErrDocumentNotFound):
+		log.Errorf("get saga by id error: %+v", err)
+		messageError = fmt.Sprintf("Документ [%s] не найден.", idDoc)
 
-		req.Login = login
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("get saga by id error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось получить документ [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDoc)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
 	}
 
-	documentList, err := h.uc.GetDocumentsList(req)
+	common.ServeContent(w, r, content)
+}
+
+// UpdateDocument godoc
+// @Summary Заменить документ
+// @Description Полностью заменяет метаданные и содержимое (файл или JSON) документа.
+// @Description Файл передается потоком, поэтому часть file должна идти в запросе последней, после meta и json
+// @Tags documents
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Идентификатор документа"
+// @Param meta formData string true "Метаданные документа в формате JSON"
+// @Param json formData string false "JSON содержимое документа"
+// @Param file formData file false "Файл документа (если meta.file = true)"
+// @Success 200 {object} entity.ApiResponse "Документ успешно обновлен"
+// @Failure 400 {object} entity.ApiError "Некорректные параметры запроса"
+// @Failure 403 {object} entity.ApiError "Недостаточно прав для изменения документа"
+// @Failure 404 {object} entity.ApiError "Документ не найден"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /docs/{id} [put]
+func (h *DocumentHandler) UpdateDocument(w http.ResponseWriter, r *http.Request) {
+	idDoc := chi.URLParam(r, "id")
+
+	login, err := getCurrentUser(r)
 	if err != nil {
-		log.Error("get documents list error: service is not allowed")
-		messageError = "Ошибка сервера, не удалось получить список документов. Попробуйте позже или обратитесь в тех. поддержку."
+		log.Errorf("update saga error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
 
 		common.ApiError(http.StatusInternalServerError, messageError, w)
 		return
 	}
 
-	if r.Method == http.MethodHead {
-		w.WriteHeader(http.StatusOK)
+	document, messageError, err := readDocumentForm(r)
+	if err != nil {
+		log.Errorf("update saga error: %+v", err)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	err = h.uc.UpdateDocument(r.Context(), login, idDoc, &document)
+	switch {
+	case errors.Is(err, custom_error.ErrDocumentNotFound):
+		log.Errorf("update saga error: %+v", err)
+		messageError = fmt.Sprintf("Документ [%s] не найден.", idDoc)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrAccessDenied):
+		log.Errorf("update saga error: %+v", err)
+		messageError = fmt.Sprintf("Недостаточно прав для изменения документа [%s].", idDoc)
+
+		common.ApiError(http.StatusForbidden, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("update saga error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось обновить документ [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDoc)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
 	}
 
 	respMap := entity.ApiResponse{
 		Data: map[string]interface{}{
-			"docs": documentList,
+			"id":   idDoc,
+			"json": document.Json,
+			"file": document.Meta.Name,
 		},
 	}
 
 	resp, err := json.Marshal(respMap)
 	if err != nil {
-		log.Errorf("get documents list error: %+v", err)
+		log.Errorf("update saga error: %+v", err)
 		messageError = "Ошибка сервера. Попробуйте позже или обратитесь в тех. поддержку."
 
 		common.ApiError(http.StatusInternalServerError, messageError, w)
@@ -224,64 +395,135 @@ func (h *DocumentHandler) GetDocumentsList(w http.ResponseWriter, r *http.Reques
 	w.WriteHeader(http.StatusOK)
 	_, err = w.Write(resp)
 	if err != nil {
-		log.Errorf("get documents list error: %+v", err)
+		log.Errorf("update saga error: %+v", err)
 		messageError = "Сервер недоступен. Попробуйте позже или обратитесь в тех. поддержку."
 
 		common.ApiError(http.StatusServiceUnavailable, messageError, w)
 	}
 }
 
-// GetDocumentById godoc
-// @Summary Получить документ по ID
-// @Description Возвращает документ по его идентификатору
+// PatchDocument godoc
+// @Summary Частично изменить JSON документ
+// @Description Применяет к JSON содержимому документа JSON Patch (RFC 6902) или JSON Merge Patch (RFC 7396).
+// @Description Формат изменений определяется заголовком Content-Type
 // @Tags documents
-// @Accept json
-// @Produce octet-stream
+// @Accept application/json-patch+json
+// @Accept application/merge-patch+json
 // @Produce json
-// @Param id query string true "Идентификатор документа"
-// @Success 200 {file} byte "Документ успешно получен"
-// @Success 200 {object} nil "Для HEAD запроса - только проверка доступности"
-// @Failure 400 {object} entity.ApiError "Не передан идентификатор документа"
-// @Failure 400 {object} entity.ApiError "Документ не найден или ошибка сервера"
+// @Param id path string true "Идентификатор документа"
+// @Param patch body object true "Изменения документа"
+// @Success 200 {object} entity.ApiResponse "Документ успешно изменен"
+// @Failure 400 {object} entity.ApiError "Некорректный формат изменений"
+// @Failure 403 {object} entity.ApiError "Недостаточно прав для изменения документа"
+// @Failure 404 {object} entity.ApiError "Документ не найден"
+// @Failure 409 {object} entity.ApiError "Документ не является JSON документом"
+// @Failure 413 {object} entity.ApiError "Размер изменений превышает допустимый"
+// @Failure 415 {object} entity.ApiError "Неподдерживаемый формат изменений"
+// @Failure 422 {object} entity.ApiError "Изменения не могут быть применены к документу"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
 // @Failure 503 {object} entity.ApiError "Сервер недоступен"
-// @Router /docs/ [get]
-// @Router /docs/ [head]
-func (h *DocumentHandler) GetDocumentById(w http.ResponseWriter, r *http.Request) {
-	idDoc := r.FormValue("id")
-	if idDoc == "" {
-		err := fmt.Errorf("saga id is empty")
-		log.Errorf("get saga by id error: %+v", err)
-		messageError = "Не передан идентификатор документа."
+// @Router /docs/{id} [patch]
+func (h *DocumentHandler) PatchDocument(w http.ResponseWriter, r *http.Request) {
+	idDoc := chi.URLParam(r, "id")
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("patch saga error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	patchType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (patchType != entity.JsonPatchMimeType && patchType != entity.MergePatchMimeType) {
+		log.Errorf("patch saga error: unsupported content type %q", r.Header.Get("Content-Type"))
+		messageError = fmt.Sprintf("Поддерживаются только форматы %s и %s.", entity.JsonPatchMimeType, entity.MergePatchMimeType)
+
+		common.ApiError(http.StatusUnsupportedMediaType, messageError, w)
+		return
+	}
+
+	var buf bytes.Buffer
+
+	// лишний байт сверх лимита показывает, что тело запроса длиннее допустимого
+	_, err = buf.ReadFrom(io.LimitReader(r.Body, maxPatchBodySize+1))
+	if err != nil {
+		log.Errorf("patch saga error: %+v", err)
+		messageError = "Переданы некорректные изменения документа."
 
 		common.ApiError(http.StatusBadRequest, messageError, w)
 		return
 	}
 
-	resp, mime, err := h.uc.GetDocumentById(idDoc)
+	if buf.Len() > maxPatchBodySize {
+		log.Errorf("patch saga error: patch body exceeds %d bytes", maxPatchBodySize)
+		messageError = fmt.Sprintf("Размер изменений документа превышает %d байт.", maxPatchBodySize)
+
+		common.ApiError(http.StatusRequestEntityTooLarge, messageError, w)
+		return
+	}
+
+	err = h.uc.PatchDocument(r.Context(), login, idDoc, patchType, buf.Bytes())
 	switch {
 	case errors.Is(err, custom_error.ErrDocumentNotFound):
-		log.Errorf("get saga by id error: %+v", err)
+		log.Errorf("patch saga error: %+v", err)
 		messageError = fmt.Sprintf("Документ [%s] не найден.", idDoc)
 
 		common.ApiError(http.StatusNotFound, messageError, w)
 		return
-	case err != nil:
-		log.Errorf("get saga by id error: %+v", err)
-		messageError = fmt.Sprintf("Ошибка сервера, не удалось получить документ [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDoc)
+	case errors.Is(err, custom_error.ErrAccessDenied):
+		log.Errorf("patch saga error: %+v", err)
+		messageError = fmt.Sprintf("Недостаточно прав для изменения документа [%s].", idDoc)
+
+		common.ApiError(http.StatusForbidden, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrDocumentNotJson):
+		log.Errorf("patch saga error: %+v", err)
+		messageError = fmt.Sprintf("Документ [%s] не является JSON документом.", idDoc)
+
+		common.ApiError(http.StatusConflict, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrInvalidPatch):
+		log.Errorf("patch saga error: %+v", err)
+		messageError = "Переданы некорректные изменения документа."
 
 		common.ApiError(http.StatusBadRequest, messageError, w)
 		return
+	case errors.Is(err, custom_error.ErrPatchFailed):
+		log.Errorf("patch saga error: %+v", err)
+		messageError = fmt.Sprintf("Не удалось применить изменения к документу [%s].", idDoc)
+
+		common.ApiError(http.StatusUnprocessableEntity, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("patch saga error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось изменить документ [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDoc)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
 	}
 
-	if r.Method == http.MethodHead {
-		w.WriteHeader(http.StatusOK)
+	respMap := entity.ApiResponse{
+		Response: map[string]interface{}{
+			idDoc: true,
+		},
+	}
+
+	resp, err := json.Marshal(respMap)
+	if err != nil {
+		log.Errorf("patch saga error: %+v", err)
+		messageError = "Ошибка сервера. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
 	}
 
-	w.Header().Set("Content-Type", mime)
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_, err = w.Write(resp)
 	if err != nil {
-		log.Errorf("get saga by id error: %+v", err)
+		log.Errorf("patch saga error: %+v", err)
 		messageError = "Сервер недоступен. Попробуйте позже или обратитесь в тех. поддержку."
 
 		common.ApiError(http.StatusServiceUnavailable, messageError, w)
@@ -298,6 +540,8 @@ func (h *DocumentHandler) GetDocumentById(w http.ResponseWriter, r *http.Request
 // @Success 200 {object} entity.ApiResponse "Документ успешно удален"
 // @Failure 400 {object} entity.ApiError "Не передан идентификатор документа"
 // @Failure 400 {object} entity.ApiError "Ошибка при удалении документа"
+// @Failure 403 {object} entity.ApiError "Недостаточно прав для удаления документа"
+// @Failure 404 {object} entity.ApiError "Документ не найден"
 // @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
 // @Failure 503 {object} entity.ApiError "Сервер недоступен"
 // @Router /docs/ [delete]
@@ -312,7 +556,16 @@ func (h *DocumentHandler) DeleteDocumentById(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err := h.uc.DeleteDocumentById(idDoc)
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("delete saga by id error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	err = h.uc.DeleteDocumentById(r.Context(), login, idDoc)
 	switch {
 	case errors.Is(err, custom_error.ErrDocumentNotFound):
 		log.Errorf("delete saga by id error: %+v", err)
@@ -320,6 +573,12 @@ func (h *DocumentHandler) DeleteDocumentById(w http.ResponseWriter, r *http.Requ
 
 		common.ApiError(http.StatusNotFound, messageError, w)
 		return
+	case errors.Is(err, custom_error.ErrAccessDenied):
+		log.Errorf("delete saga by id error: %+v", err)
+		messageError = fmt.Sprintf("Недостаточно прав для удаления документа [%s].", idDoc)
+
+		common.ApiError(http.StatusForbidden, messageError, w)
+		return
 	case err != nil:
 		log.Errorf("delete saga by id error: %+v", err)
 		messageError = fmt.Sprintf("Ошибка сервера, не удалось удалить документ [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDoc)
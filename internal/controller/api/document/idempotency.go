@@ -0,0 +1,60 @@
+package document
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// documentFingerprint отпечаток содержимого запроса создания документа.
+// Граница multipart формы меняется от запроса к запросу, поэтому отпечаток считается
+// по разобранным частям: meta и json сериализуются заново, а файл хешируется по мере чтения
+type documentFingerprint struct {
+	hash     hash.Hash
+	document *entity.Document
+}
+
+// newDocumentFingerprint должен вызываться до сохранения документа, пока файл еще не прочитан
+func newDocumentFingerprint(document *entity.Document) (*documentFingerprint, error) {
+	f := &documentFingerprint{
+		hash:     sha256.New(),
+		document: document,
+	}
+
+	encoder := json.NewEncoder(f.hash)
+
+	if err := encoder.Encode(document.Meta); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Encode(document.Json); err != nil {
+		return nil, err
+	}
+
+	if document.File != nil {
+		_, _ = io.WriteString(f.hash, document.File.Name+"\n")
+		document.File.Content = io.TeeReader(document.File.Content, f.hash)
+	}
+
+	return f, nil
+}
+
+// Sum дочитывает файл, если он прочитан не полностью, и возвращает отпечаток запроса
+func (f *documentFingerprint) Sum() (string, error) {
+	if f.document.File != nil {
+		if _, err := io.Copy(io.Discard, f.document.File.Content); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(f.hash.Sum(nil)), nil
+}
@@ -0,0 +1,293 @@
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// maxListBodySize ограничивает размер JSON тела запроса списка документов
+const maxListBodySize = 1 << 20
+
+// listQueryParams допустимые параметры строки запроса списка документов
+var listQueryParams = map[string]bool{
+	"login":  true,
+	"key":    true,
+	"value":  true,
+	"filter": true,
+	"sort":   true,
+	"order":  true,
+	"cursor": true,
+	"limit":  true,
+	"offset": true,
+}
+
+// SearchDocuments godoc
+// @Summary Поиск документов
+// @Description Возвращает список документов по параметрам из тела запроса.
+// @Description Предназначен для сложных запросов, которые не помещаются в строку запроса GET /docs.
+// @Description Фильтры задаются списком filters и объединяются условием И, формат ответа совпадает с GET /docs
+// @Tags documents
+// @Accept json
+// @Produce json
+// @Param request body entity.DocumentListRequest true "Параметры запроса списка документов"
+// @Success 200 {object} entity.ApiResponse "Список документов успешно получен"
+// @Header 200 {integer} X-Total-Count "Общее количество документов"
+// @Failure 400 {object} entity.ApiError "Некорректные параметры запроса или фильтр"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /docs/search [post]
+func (h *DocumentHandler) SearchDocuments(w http.ResponseWriter, r *http.Request) {
+	var (
+		req entity.DocumentListRequest
+		buf bytes.Buffer
+	)
+
+	_, err := buf.ReadFrom(io.LimitReader(r.Body, maxListBodySize))
+	if err != nil {
+		log.Errorf("search documents error: %+v", err)
+		messageError = "Переданы некорректные параметры для поиска документов."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	if err = json.Unmarshal(buf.Bytes(), &req); err != nil {
+		log.Errorf("search documents error: %+v", err)
+		messageError = "Не удалось прочитать параметры для поиска документов."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	h.writeDocumentsList(w, r, req)
+}
+
+// writeDocumentsList выполняет запрос списка документов от имени текущего пользователя и отправляет ответ.
+// На HEAD запрос отправляется только количество документов
+func (h *DocumentHandler) writeDocumentsList(w http.ResponseWriter, r *http.Request, req entity.DocumentListRequest) {
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("get documents list error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	req.Viewer = login
+
+	if req.LoginIsEmpty() {
+		req.Login = login
+	}
+
+	var paramErr *entity.ParamError
+
+	if r.Method == http.MethodHead {
+		total, err := h.uc.CountDocuments(req)
+		switch {
+		case errors.As(err, &paramErr):
+			log.Errorf("count documents error: %+v", err)
+			messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+			common.ApiError(http.StatusBadRequest, messageError, w)
+			return
+		case err != nil:
+			log.Errorf("count documents error: %+v", err)
+			messageError = "Ошибка сервера, не удалось получить количество документов. Попробуйте позже или обратитесь в тех. поддержку."
+
+			common.ApiError(http.StatusInternalServerError, messageError, w)
+			return
+		}
+
+		w.Header().Set(totalCountHeader, strconv.FormatInt(total, 10))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	documentList, err := h.uc.GetDocumentsList(req)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("get documents list error: %+v", err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	case err != nil:
+		log.Error("get documents list error: service is not allowed")
+		messageError = "Ошибка сервера, не удалось получить список документов. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"docs":        documentList.Docs,
+			"total":       documentList.Total,
+			"next_cursor": documentList.NextCursor,
+		},
+	}
+
+	w.Header().Set(totalCountHeader, strconv.FormatInt(documentList.Total, 10))
+
+	resp, err := json.Marshal(respMap)
+	if err != nil {
+		log.Errorf("get documents list error: %+v", err)
+		messageError = "Ошибка сервера. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(resp)
+	if err != nil {
+		log.Errorf("get documents list error: %+v", err)
+		messageError = "Сервер недоступен. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusServiceUnavailable, messageError, w)
+	}
+}
+
+// readListRequest читает параметры списка документов из строки запроса.
+// Если строка запроса пуста, параметры читаются из JSON тела запроса для совместимости
+// со старыми клиентами. Вместе с ошибкой возвращается сообщение для клиента
+func readListRequest(r *http.Request) (entity.DocumentListRequest, string, error) {
+	var (
+		req entity.DocumentListRequest
+		buf bytes.Buffer
+	)
+
+	if r.URL.RawQuery != "" {
+		return parseListQuery(r.URL.Query())
+	}
+
+	_, err := buf.ReadFrom(io.LimitReader(r.Body, maxListBodySize))
+	if err != nil {
+		return req, "Переданы некорректные параметры для получения списка документов.", err
+	}
+
+	if buf.Len() == 0 {
+		return req, "", nil
+	}
+
+	if err = json.Unmarshal(buf.Bytes(), &req); err != nil {
+		return req, "Не удалось прочитать параметры для получения списка документов.", err
+	}
+
+	return req, "", nil
+}
+
+// parseListQuery разбирает и проверяет типы параметров строки запроса.
+// Допустимость полей и операторов фильтров проверяется при выполнении запроса
+func parseListQuery(query url.Values) (entity.DocumentListRequest, string, error) {
+	var req entity.DocumentListRequest
+
+	for param, values := range query {
+		if !listQueryParams[param] {
+			return req, fmt.Sprintf("Неизвестный параметр [%s].", param), fmt.Errorf("unknown query parameter [%s]", param)
+		}
+
+		if param != "filter" && len(values) > 1 {
+			return req, fmt.Sprintf("Параметр [%s] указан несколько раз.", param), fmt.Errorf("duplicated query parameter [%s]", param)
+		}
+	}
+
+	req.Login = query.Get("login")
+	req.Key = query.Get("key")
+	req.Value = query.Get("value")
+	req.Sort = query.Get("sort")
+	req.Order = query.Get("order")
+	req.Cursor = query.Get("cursor")
+
+	var err error
+
+	if query.Has("limit") {
+		if req.Limit, err = strconv.Atoi(query.Get("limit")); err != nil {
+			return req, "Параметр [limit] должен быть целым числом.", err
+		}
+	}
+
+	if query.Has("offset") {
+		if req.Offset, err = strconv.Atoi(query.Get("offset")); err != nil {
+			return req, "Параметр [offset] должен быть целым числом.", err
+		}
+	}
+
+	for _, raw := range query["filter"] {
+		filter, messageError, err := parseFilter(raw)
+		if err != nil {
+			return req, messageError, err
+		}
+
+		req.Filters = append(req.Filters, filter)
+	}
+
+	return req, "", nil
+}
+
+// parseFilter разбирает фильтр вида поле:оператор:значение.
+// Значение может содержать двоеточия, например дату в формате RFC 3339
+func parseFilter(raw string) (entity.DocumentFilter, string, error) {
+	var filter entity.DocumentFilter
+
+	parts := strings.SplitN(raw, ":", 3)
+	if len(parts) != 3 {
+		return filter, fmt.Sprintf("Фильтр [%s] должен иметь вид поле:оператор:значение.", raw), fmt.Errorf("malformed filter [%s]", raw)
+	}
+
+	filter.Field = parts[0]
+	filter.Op = parts[1]
+
+	switch filter.Op {
+	case entity.FilterOpIn:
+		filter.Values = strings.Split(parts[2], ",")
+	case entity.FilterOpRange:
+		bounds := strings.Split(parts[2], "..")
+		if len(bounds) != 2 {
+			return filter, fmt.Sprintf("Границы диапазона фильтра [%s] должны быть разделены \"..\".", raw), fmt.Errorf("malformed range filter [%s]", raw)
+		}
+
+		var err error
+
+		if filter.From, err = parseBound(bounds[0]); err != nil {
+			return filter, fmt.Sprintf("Граница диапазона [%s] должна быть в формате RFC 3339.", bounds[0]), err
+		}
+
+		if filter.To, err = parseBound(bounds[1]); err != nil {
+			return filter, fmt.Sprintf("Граница диапазона [%s] должна быть в формате RFC 3339.", bounds[1]), err
+		}
+	default:
+		filter.Value = parts[2]
+	}
+
+	return filter, "", nil
+}
+
+// parseBound разбирает границу диапазона, пустая граница означает ее отсутствие
+func parseBound(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	bound, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bound, nil
+}
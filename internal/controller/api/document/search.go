@@ -0,0 +1,95 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// FullTextSearch godoc
+// @Summary Полнотекстовый поиск документов
+// @Description Ищет документы, доступные пользователю, по словам в имени, строковых значениях JSON документов
+// @Description и тексте текстовых файлов (text/*, JSON, CSV, Markdown). Результаты упорядочены по релевантности.
+// @Description Запрос поддерживает фразы в кавычках, OR и исключение слов через минус.
+// @Description Текст фрагмента snippet экранирован для HTML, найденные слова в нем выделены тегами <b> и </b>.
+// @Description Индекс обновляется асинхронно, поэтому только что сохраненный документ может появиться в выдаче с задержкой
+// @Tags documents
+// @Produce json
+// @Param q query string true "Поисковый запрос"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} entity.ApiResponse "Документы успешно найдены"
+// @Failure 400 {object} entity.ApiError "Некорректный поисковый запрос"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /docs/search [get]
+func (h *DocumentHandler) FullTextSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	req := entity.SearchRequest{
+		Query: query.Get("q"),
+	}
+
+	var err error
+
+	if query.Has("limit") {
+		if req.Limit, err = strconv.Atoi(query.Get("limit")); err != nil {
+			log.Errorf("full text search error: %+v", err)
+			messageError = "Параметр [limit] должен быть целым числом."
+
+			common.ApiError(http.StatusBadRequest, messageError, w)
+			return
+		}
+	}
+
+	if query.Has("offset") {
+		if req.Offset, err = strconv.Atoi(query.Get("offset")); err != nil {
+			log.Errorf("full text search error: %+v", err)
+			messageError = "Параметр [offset] должен быть целым числом."
+
+			common.ApiError(http.StatusBadRequest, messageError, w)
+			return
+		}
+	}
+
+	req.Viewer, err = getCurrentUser(r)
+	if err != nil {
+		log.Errorf("full text search error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	var paramErr *entity.ParamError
+
+	hits, err := h.uc.SearchDocuments(req)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("full text search error: %+v", err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("full text search error: %+v", err)
+		messageError = "Ошибка сервера, не удалось выполнить поиск документов. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"docs": hits,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "full text search")
+}
@@ -0,0 +1,279 @@
+package document
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// GetDocumentVersions godoc
+// @Summary Получить историю версий документа
+// @Description Возвращает список версий документа от новых к старым
+// @Tags versions
+// @Produce json
+// @Param id path string true "Идентификатор документа"
+// @Success 200 {object} entity.ApiResponse "Список версий успешно получен"
+// @Failure 404 {object} entity.ApiError "Документ не найден"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /docs/{id}/versions [get]
+func (h *DocumentHandler) GetDocumentVersions(w http.ResponseWriter, r *http.Request) {
+	idDoc := chi.URLParam(r, "id")
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("get document versions error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	versions, err := h.uc.GetDocumentVersions(login, idDoc)
+	switch {
+	case errors.Is(err, custom_error.ErrDocumentNotFound):
+		log.Errorf("get document versions error: %+v", err)
+		messageError = fmt.Sprintf("Документ [%s] не найден.", idDoc)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("get document versions error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось получить версии документа [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDoc)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"versions": versions,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "get document versions")
+}
+
+// GetDocumentVersion godoc
+// @Summary Получить версию документа
+// @Description Возвращает содержимое указанной версии документа.
+// @Description Поддерживает запросы диапазонов (Range) и условные запросы по ETag и Last-Modified
+// @Tags versions
+// @Produce octet-stream
+// @Produce json
+// @Param id path string true "Идентификатор документа"
+// @Param version path int true "Номер версии"
+// @Success 200 {file} byte "Версия документа успешно получена"
+// @Success 206 {file} byte "Часть версии документа"
+// @Success 304 {object} nil "Версия не изменилась"
+// @Failure 400 {object} entity.ApiError "Некорректный номер версии"
+// @Failure 404 {object} entity.ApiError "Версия не найдена"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Router /docs/{id}/versions/{version} [get]
+// @Router /docs/{id}/versions/{version} [head]
+func (h *DocumentHandler) GetDocumentVersion(w http.ResponseWriter, r *http.Request) {
+	idDoc := chi.URLParam(r, "id")
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("get document version error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		log.Errorf("get document version error: %+v", err)
+		messageError = "Передан некорректный номер версии."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	content, err := h.uc.GetDocumentVersion(r.Context(), login, idDoc, version, r.Header.Get("Accept-Encoding"))
+	switch {
+	case errors.Is(err, custom_error.ErrVersionNotFound), errors.Is(err, custom_error.ErrDocumentNotFound):
+		log.Errorf("get document version error: %+v", err)
+		messageError = fmt.Sprintf("Версия [%d] документа [%s] не найдена.", version, idDoc)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("get document version error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось получить версию документа [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDoc)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	common.ServeContent(w, r, content)
+}
+
+// DiffDocumentVersions godoc
+// @Summary Сравнить версии JSON документа
+// @Description Возвращает JSON Merge Patch (RFC 7396), который превращает версию from в версию to
+// @Tags versions
+// @Produce json
+// @Param id path string true "Идентификатор документа"
+// @Param from query int true "Номер исходной версии"
+// @Param to query int true "Номер итоговой версии"
+// @Success 200 {object} entity.ApiResponse "Разница версий успешно получена"
+// @Failure 400 {object} entity.ApiError "Некорректные номера версий"
+// @Failure 404 {object} entity.ApiError "Версия не найдена"
+// @Failure 409 {object} entity.ApiError "Версия не является JSON документом"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /docs/{id}/diff [get]
+func (h *DocumentHandler) DiffDocumentVersions(w http.ResponseWriter, r *http.Request) {
+	idDoc := chi.URLParam(r, "id")
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("diff document versions error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	from, errFrom := strconv.Atoi(r.URL.Query().Get("from"))
+	to, errTo := strconv.Atoi(r.URL.Query().Get("to"))
+	if err = errors.Join(errFrom, errTo); err != nil {
+		log.Errorf("diff document versions error: %+v", err)
+		messageError = "Переданы некорректные номера версий."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	diff, err := h.uc.DiffDocumentVersions(r.Context(), login, idDoc, from, to)
+	switch {
+	case errors.Is(err, custom_error.ErrVersionNotFound), errors.Is(err, custom_error.ErrDocumentNotFound):
+		log.Errorf("diff document versions error: %+v", err)
+		messageError = fmt.Sprintf("Версия документа [%s] не найдена.", idDoc)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrDocumentNotJson):
+		log.Errorf("diff document versions error: %+v", err)
+		messageError = "Сравнивать можно только версии JSON документа."
+
+		common.ApiError(http.StatusConflict, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("diff document versions error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось сравнить версии документа [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDoc)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"from":  from,
+			"to":    to,
+			"patch": diff,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "diff document versions")
+}
+
+// RestoreDocumentVersion godoc
+// @Summary Восстановить версию документа
+// @Description Делает указанную версию текущей. Восстановление создает новую версию, история не переписывается
+// @Tags versions
+// @Produce json
+// @Param id path string true "Идентификатор документа"
+// @Param version path int true "Номер восстанавливаемой версии"
+// @Success 200 {object} entity.ApiResponse "Версия успешно восстановлена"
+// @Failure 400 {object} entity.ApiError "Некорректный номер версии"
+// @Failure 403 {object} entity.ApiError "Недостаточно прав для изменения документа"
+// @Failure 404 {object} entity.ApiError "Документ или версия не найдены"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /docs/{id}/versions/{version}/restore [post]
+func (h *DocumentHandler) RestoreDocumentVersion(w http.ResponseWriter, r *http.Request) {
+	idDoc := chi.URLParam(r, "id")
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("restore document version error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		log.Errorf("restore document version error: %+v", err)
+		messageError = "Передан некорректный номер версии."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	err = h.uc.RestoreDocumentVersion(r.Context(), login, idDoc, version)
+	switch {
+	case errors.Is(err, custom_error.ErrDocumentNotFound), errors.Is(err, custom_error.ErrVersionNotFound):
+		log.Errorf("restore document version error: %+v", err)
+		messageError = fmt.Sprintf("Версия [%d] документа [%s] не найдена.", version, idDoc)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrAccessDenied):
+		log.Errorf("restore document version error: %+v", err)
+		messageError = fmt.Sprintf("Недостаточно прав для изменения документа [%s].", idDoc)
+
+		common.ApiError(http.StatusForbidden, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("restore document version error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось восстановить версию документа [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDoc)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Response: map[string]interface{}{
+			idDoc: true,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "restore document version")
+}
+
+// writeJson отправляет ответ в формате JSON, operation используется в логах
+func writeJson(w http.ResponseWriter, status int, response entity.ApiResponse, operation string) {
+	resp, err := json.Marshal(response)
+	if err != nil {
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Ошибка сервера. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(resp)
+	if err != nil {
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Сервер недоступен. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusServiceUnavailable, messageError, w)
+	}
+}
@@ -0,0 +1,249 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/usecases"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+	lastEventIDParam  = "last_event_id"
+
+	// websocketWriteTimeout время отправки одного сообщения WebSocket
+	websocketWriteTimeout = 10 * time.Second
+)
+
+var messageError string
+
+// upgrader по умолчанию принимает соединения только с того же origin,
+// что не позволяет чужим страницам подключаться к ленте с cookie пользователя
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+type FeedHandler struct {
+	heartbeat time.Duration
+	uc        usecases.Feed
+}
+
+func NewFeedHandler(cfg *config.Config, uc usecases.Feed) FeedHandler {
+	return FeedHandler{
+		heartbeat: cfg.ConfigFeed.Heartbeat,
+		uc:        uc,
+	}
+}
+
+// StreamEvents godoc
+// @Summary Лента изменений документов (Server-Sent Events)
+// @Description Отправляет события создания, изменения и удаления документов, доступных пользователю для чтения.
+// @Description Каждое событие содержит поле id. Чтобы продолжить чтение после переподключения,
+// @Description передайте id последнего полученного события в заголовке Last-Event-ID или параметре last_event_id.
+// @Description Для поддержания соединения сервер периодически отправляет комментарии
+// @Tags feed
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "Идентификатор последнего полученного события"
+// @Param last_event_id query string false "Идентификатор последнего полученного события"
+// @Success 200 {object} entity.ChangeEvent "Поток событий"
+// @Failure 400 {object} entity.ApiError "Некорректный идентификатор события"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Лента изменений недоступна"
+// @Router /docs/events [get]
+func (h *FeedHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	events, ok := h.subscribe(ctx, w, r, "stream events")
+	if !ok {
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// отключает буферизацию ответа в nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		log.Errorf("stream events error: %+v", err)
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		var err error
+
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			err = writeEvent(w, event)
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case <-ctx.Done():
+			return
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+
+		if err != nil {
+			log.Debugf("stream events closed: %+v", err)
+			return
+		}
+	}
+}
+
+// StreamEventsWebSocket godoc
+// @Summary Лента изменений документов (WebSocket)
+// @Description Отправляет события создания, изменения и удаления документов, доступных пользователю для чтения,
+// @Description текстовыми сообщениями WebSocket в формате JSON. Чтобы продолжить чтение после переподключения,
+// @Description передайте id последнего полученного события в заголовке Last-Event-ID или параметре last_event_id.
+// @Description Для поддержания соединения сервер периодически отправляет ping
+// @Tags feed
+// @Produce json
+// @Param Last-Event-ID header string false "Идентификатор последнего полученного события"
+// @Param last_event_id query string false "Идентификатор последнего полученного события"
+// @Success 101 {object} entity.ChangeEvent "Соединение переключено на WebSocket"
+// @Failure 400 {object} entity.ApiError "Некорректный идентификатор события"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Лента изменений недоступна"
+// @Router /docs/events/ws [get]
+func (h *FeedHandler) StreamEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	events, ok := h.subscribe(ctx, w, r, "stream events websocket")
+	if !ok {
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// ответ с ошибкой уже отправлен upgrader
+		log.Errorf("stream events websocket error: %+v", err)
+		return
+	}
+	defer conn.Close()
+
+	// сообщения клиента не ожидаются, чтение нужно для обработки pong и закрытия соединения
+	go func() {
+		defer cancel()
+
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				closeMessage := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscription closed")
+				_ = conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(websocketWriteTimeout))
+				return
+			}
+
+			_ = conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+			err = conn.WriteJSON(event)
+		case <-ticker.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(websocketWriteTimeout))
+		case <-ctx.Done():
+			return
+		}
+
+		if err != nil {
+			log.Debugf("stream events websocket closed: %+v", err)
+			return
+		}
+	}
+}
+
+// subscribe подписывает текущего пользователя на ленту и отвечает ошибкой, если это не удалось
+func (h *FeedHandler) subscribe(ctx context.Context, w http.ResponseWriter, r *http.Request, operation string) (<-chan entity.ChangeEvent, bool) {
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return nil, false
+	}
+
+	lastEventID := r.Header.Get(lastEventIDHeader)
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get(lastEventIDParam)
+	}
+
+	var paramErr *entity.ParamError
+
+	events, err := h.uc.Subscribe(ctx, login, lastEventID)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return nil, false
+	case errors.Is(err, custom_error.ErrFeedUnavailable):
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Лента изменений недоступна. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusServiceUnavailable, messageError, w)
+		return nil, false
+	case err != nil:
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Ошибка сервера, не удалось подписаться на ленту изменений. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return nil, false
+	}
+
+	return events, true
+}
+
+func writeEvent(w http.ResponseWriter, event entity.ChangeEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+
+	return err
+}
+
+func getCurrentUser(r *http.Request) (string, error) {
+	login, ok := r.Context().Value(entity.CurrentUserKey).(string)
+	if !ok {
+		return "", fmt.Errorf("current user not found")
+	}
+
+	return login, nil
+}
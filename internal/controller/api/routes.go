@@ -8,12 +8,18 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/api/admin"
 	"github.com/AlexJudin/DocumentCacheServer/internal/controller/api/auth"
 	"github.com/AlexJudin/DocumentCacheServer/internal/controller/api/document"
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/api/feed"
 	"github.com/AlexJudin/DocumentCacheServer/internal/controller/api/register"
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/api/share"
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/api/upload"
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/api/webhook"
 	"github.com/AlexJudin/DocumentCacheServer/internal/controller/middleware"
 	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
 	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/cache"
+	filestorage "github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/file_storage"
 	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
 	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/saga"
 	"github.com/AlexJudin/DocumentCacheServer/internal/service"
@@ -21,18 +27,30 @@ import (
 )
 
 func AddRoutes(cfg *config.Config,
-	documentRepo *repository.DocumentRepo,
-	userRepo *postgres.UserRepo,
-	tokenRepo *postgres.TokenStorageRepo,
-	cacheRepo *cache.DocumentRepo,
-	sagaOrchestrator *saga.DocumentOrchestrator,
+	documentRepo repository.DocumentRepository,
+	userRepo postgres.User,
+	tokenRepo postgres.TokenStorage,
+	cacheRepo cache.Document,
+	idempotencyRepo cache.Idempotency,
+	sagaLogRepo postgres.SagaLog,
+	webhookRepo postgres.Webhook,
+	uploadRepo postgres.Upload,
+	uploadStorage filestorage.MultipartRepository,
+	presigner filestorage.Presigner,
+	shareRepo postgres.Share,
+	sagaOrchestrator saga.Orchestrator,
+	webhookDispatcher *service.WebhookDispatcher,
+	changeFeed *service.ChangeFeed,
+	uploadCleaner *service.UploadCleaner,
 	r *chi.Mux) {
 	// init services
 	authService := service.NewAuthService(cfg, tokenRepo)
+	searchIndexer := service.NewSearchIndexer(documentRepo)
 
 	// init usecases
-	docsUC := usecases.NewDocumentUsecase(documentRepo, cacheRepo, sagaOrchestrator)
-	docsHandler := document.NewDocumentHandler(docsUC)
+	docsUC := usecases.NewDocumentUsecase(cfg, documentRepo, presigner, cacheRepo, sagaOrchestrator, searchIndexer, webhookDispatcher, changeFeed)
+	idempotencyUC := usecases.NewIdempotencyUsecase(cfg, idempotencyRepo)
+	docsHandler := document.NewDocumentHandler(docsUC, idempotencyUC)
 
 	registerUC := usecases.NewRegisterUsecase(userRepo, authService)
 	registerHandler := register.NewRegisterHandler(registerUC)
@@ -40,15 +58,37 @@ func AddRoutes(cfg *config.Config,
 	authUC := usecases.NewAuthUsecase(userRepo, authService)
 	authHandler := auth.NewAuthHandler(authUC)
 
+	webhookUC := usecases.NewWebhookUsecase(webhookRepo, webhookDispatcher)
+	webhookHandler := webhook.NewWebhookHandler(webhookUC)
+
+	uploadUC := usecases.NewUploadUsecase(cfg, uploadRepo, uploadStorage, presigner, documentRepo, docsUC, uploadCleaner)
+	uploadHandler := upload.NewUploadHandler(uploadUC)
+
+	shareUC := usecases.NewShareUsecase(cfg, shareRepo, documentRepo, docsUC, authService)
+	shareHandler := share.NewShareHandler(shareUC)
+
+	feedUC := usecases.NewFeedUsecase(changeFeed)
+	feedHandler := feed.NewFeedHandler(cfg, feedUC)
+
+	adminUC := usecases.NewAdminUsecase(cfg, sagaLogRepo, sagaOrchestrator)
+	adminHandler := admin.NewAdminHandler(adminUC)
+
 	// init auth middleware
 	authMiddleware := middleware.NewAuthMiddleware(authService)
 
+	// init admin middleware
+	adminMiddleware := middleware.NewAdminMiddleware(cfg.AdminToken)
+
 	// init timeout middleware
 	timeoutMiddleware := middleware.NewTimeoutMiddleware(time.Second)
 
 	// init metrics middleware
 	metricsMiddleware := middleware.NewHTTPMetrics()
 
+	// init real ip middleware
+	realIPMiddleware := middleware.NewRealIPMiddleware(cfg.TrustedProxies)
+
+	r.Use(realIPMiddleware.RealIP)
 	r.Use(metricsMiddleware.HTTPMetricsMiddleware)
 	r.Post("/api/register", registerHandler.RegisterUser)
 	r.Post("/api/auth", authHandler.AuthorizationUser)
@@ -61,15 +101,98 @@ func AddRoutes(cfg *config.Config,
 			authMiddleware.CheckToken,
 			timeoutMiddleware.WithTimeout,
 		)
-		r.Post("/api/docs", docsHandler.SaveDocument)
-
 		r.Get("/api/docs", docsHandler.GetDocumentsList)
 		r.Head("/api/docs", docsHandler.GetDocumentsList)
+		r.Get("/api/docs/search", docsHandler.FullTextSearch)
+		r.Post("/api/docs/search", docsHandler.SearchDocuments)
+		r.Post("/api/docs/query", docsHandler.SearchDocumentsContent)
+
+		r.Patch("/api/docs/{id}", docsHandler.PatchDocument)
+
+		r.Get("/api/docs/{id}/versions", docsHandler.GetDocumentVersions)
+		r.Post("/api/docs/{id}/versions/{version}/restore", docsHandler.RestoreDocumentVersion)
+		r.Get("/api/docs/{id}/diff", docsHandler.DiffDocumentVersions)
 
+		r.Delete("/api/docs/", docsHandler.DeleteDocumentById)
+
+		r.Post("/api/webhooks", webhookHandler.CreateWebhook)
+		r.Get("/api/webhooks", webhookHandler.GetWebhooks)
+		r.Delete("/api/webhooks/{id}", webhookHandler.DeleteWebhook)
+		r.Get("/api/webhooks/{id}/deliveries", webhookHandler.GetDeliveries)
+		r.Post("/api/webhooks/{id}/deliveries/{delivery}/redeliver", webhookHandler.Redeliver)
+
+		r.Post("/api/docs/{id}/shares", shareHandler.CreateShare)
+		r.Get("/api/shares", shareHandler.GetShares)
+		r.Delete("/api/shares/{id}", shareHandler.RevokeShare)
+		r.Get("/api/shares/{id}/accesses", shareHandler.GetShareAccesses)
+	})
+
+	// документ по ссылке выдается без авторизации, поэтому лимит запросов ниже,
+	// чтобы затруднить подбор токенов и паролей ссылок. Файл передается потоком без таймаута
+	r.Group(func(r chi.Router) {
+		r.Use(httprate.LimitByIP(100, time.Second))
+		r.Get("/api/share/{token}", shareHandler.OpenShare)
+	})
+
+	// файлы документов передаются потоком и могут идти дольше таймаута обычных запросов.
+	// Запрос отменяется при отключении клиента через контекст запроса
+	r.Group(func(r chi.Router) {
+		r.Use(httprate.LimitByIP(5000, time.Second))
+		r.Use(authMiddleware.CheckToken)
+		r.Post("/api/docs", docsHandler.SaveDocument)
+		r.Put("/api/docs/{id}", docsHandler.UpdateDocument)
+		r.Get("/api/docs/{id}/versions/{version}", docsHandler.GetDocumentVersion)
+		r.Head("/api/docs/{id}/versions/{version}", docsHandler.GetDocumentVersion)
+	})
+
+	// лента изменений держит соединение открытым, поэтому таймаут запросов к ней не применяется
+	r.Group(func(r chi.Router) {
+		r.Use(httprate.LimitByIP(100, time.Second))
+		r.Use(authMiddleware.CheckToken)
+		r.Get("/api/docs/events", feedHandler.StreamEvents)
+		r.Get("/api/docs/events/ws", feedHandler.StreamEventsWebSocket)
+	})
+
+	// части файла передаются потоком и могут идти дольше таймаута обычных запросов,
+	// подтверждение прямой загрузки копирует файл через сагу.
+	// Параметры протокола tus запрашиваются без авторизации
+	r.Options("/api/uploads", uploadHandler.GetOptions)
+	r.Group(func(r chi.Router) {
+		r.Use(httprate.LimitByIP(5000, time.Second))
+		r.Use(authMiddleware.CheckToken)
+		r.Post("/api/uploads", uploadHandler.CreateUpload)
+		r.Head("/api/uploads/{id}", uploadHandler.GetUploadOffset)
+		r.Patch("/api/uploads/{id}", uploadHandler.WriteUpload)
+		r.Delete("/api/uploads/{id}", uploadHandler.DeleteUpload)
+		r.Post("/api/uploads/direct", uploadHandler.CreateDirectUpload)
+		r.Post("/api/uploads/direct/{id}/complete", uploadHandler.CompleteDirectUpload)
+	})
+
+	// публичные документы можно получить без авторизации
+	r.Group(func(r chi.Router) {
+		r.Use(httprate.LimitByIP(5000, time.Second))
+		r.Use(
+			authMiddleware.OptionalToken,
+			timeoutMiddleware.WithTimeout,
+		)
+		r.Get("/api/docs/{id}/download-url", docsHandler.GetDownloadURL)
+	})
+
+	// содержимое документа передается потоком без таймаута обычных запросов
+	r.Group(func(r chi.Router) {
+		r.Use(httprate.LimitByIP(5000, time.Second))
+		r.Use(authMiddleware.OptionalToken)
 		r.Get("/api/docs/", docsHandler.GetDocumentById)
 		r.Head("/api/docs/", docsHandler.GetDocumentById)
+	})
 
-		r.Delete("/api/docs/", docsHandler.DeleteDocumentById)
+	// восстановление саги выполняется синхронно и может занять больше таймаута обычных запросов
+	r.Group(func(r chi.Router) {
+		r.Use(httprate.LimitByIP(100, time.Second))
+		r.Use(adminMiddleware.CheckAdminToken)
+		r.Get("/api/admin/sagas", adminHandler.GetStuckSagas)
+		r.Get("/api/admin/sagas/{id}", adminHandler.GetSaga)
+		r.Post("/api/admin/sagas/{id}/retry", adminHandler.RetrySaga)
 	})
 
 	r.Handle("/api/metrics", promhttp.Handler())
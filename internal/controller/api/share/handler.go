@@ -0,0 +1,445 @@
+package share
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/usecases"
+)
+
+const (
+	// maxShareBodySize ограничивает размер тела запроса создания ссылки
+	maxShareBodySize = 64 << 10
+
+	// passwordHeader заголовок с паролем ссылки
+	passwordHeader = "X-Share-Password"
+
+	// grantCookie cookie с разрешением продолжить скачивание по ссылке
+	grantCookie = "shareGrant"
+)
+
+var messageError string
+
+type ShareHandler struct {
+	uc usecases.Share
+}
+
+func NewShareHandler(uc usecases.Share) ShareHandler {
+	return ShareHandler{uc: uc}
+}
+
+// CreateShare godoc
+// @Summary Создать ссылку на документ
+// @Description Создает ссылку, по которой документ может получить любой, кто знает ее токен, без учетной записи.
+// @Description Ссылку можно ограничить сроком действия, количеством скачиваний и паролем.
+// @Description Токен и адрес ссылки возвращаются только в ответе на создание
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param id path string true "Идентификатор документа"
+// @Param request body entity.ShareRequest true "Параметры ссылки"
+// @Success 201 {object} entity.ApiResponse "Ссылка успешно создана"
+// @Failure 400 {object} entity.ApiError "Некорректные параметры ссылки"
+// @Failure 403 {object} entity.ApiError "Недостаточно прав для создания ссылки на документ"
+// @Failure 404 {object} entity.ApiError "Документ не найден"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /docs/{id}/shares [post]
+func (h *ShareHandler) CreateShare(w http.ResponseWriter, r *http.Request) {
+	var (
+		req entity.ShareRequest
+		buf bytes.Buffer
+	)
+
+	idDoc := chi.URLParam(r, "id")
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("create share error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	_, err = buf.ReadFrom(io.LimitReader(r.Body, maxShareBodySize))
+	if err != nil {
+		log.Errorf("create share error: %+v", err)
+		messageError = "Переданы некорректные параметры ссылки."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	// пустое тело создает ссылку без ограничений
+	if buf.Len() > 0 {
+		if err = json.Unmarshal(buf.Bytes(), &req); err != nil {
+			log.Errorf("create share error: %+v", err)
+			messageError = "Не удалось прочитать параметры ссылки."
+
+			common.ApiError(http.StatusBadRequest, messageError, w)
+			return
+		}
+	}
+
+	var paramErr *entity.ParamError
+
+	share, err := h.uc.CreateShare(login, idDoc, req)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("create share error: %+v", err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrDocumentNotFound):
+		log.Errorf("create share error: %+v", err)
+		messageError = fmt.Sprintf("Документ [%s] не найден.", idDoc)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrAccessDenied):
+		log.Errorf("create share error: %+v", err)
+		messageError = fmt.Sprintf("Недостаточно прав для создания ссылки на документ [%s].", idDoc)
+
+		common.ApiError(http.StatusForbidden, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("create share error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось создать ссылку на документ [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDoc)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"share": share.Share,
+			"token": share.Token,
+			"url":   share.URL,
+		},
+	}
+
+	writeJson(w, http.StatusCreated, respMap, "create share")
+}
+
+// GetShares godoc
+// @Summary Получить ссылки пользователя
+// @Description Возвращает ссылки на документы текущего пользователя, в том числе отозванные и истекшие
+// @Tags shares
+// @Produce json
+// @Param document_id query string false "Идентификатор документа"
+// @Success 200 {object} entity.ApiResponse "Список ссылок успешно получен"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /shares [get]
+func (h *ShareHandler) GetShares(w http.ResponseWriter, r *http.Request) {
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("get shares error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	shares, err := h.uc.GetShares(login, r.URL.Query().Get("document_id"))
+	if err != nil {
+		log.Errorf("get shares error: %+v", err)
+		messageError = "Ошибка сервера, не удалось получить список ссылок. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"shares": shares,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "get shares")
+}
+
+// RevokeShare godoc
+// @Summary Отозвать ссылку
+// @Description Отзывает ссылку на документ. Отозванная ссылка остается в списке ссылок вместе с журналом обращений
+// @Tags shares
+// @Produce json
+// @Param id path string true "Идентификатор ссылки"
+// @Success 200 {object} entity.ApiResponse "Ссылка успешно отозвана"
+// @Failure 404 {object} entity.ApiError "Ссылка не найдена"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /shares/{id} [delete]
+func (h *ShareHandler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	idShare := chi.URLParam(r, "id")
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("revoke share error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	err = h.uc.RevokeShare(login, idShare)
+	switch {
+	case errors.Is(err, custom_error.ErrShareNotFound):
+		log.Errorf("revoke share error: %+v", err)
+		messageError = fmt.Sprintf("Ссылка [%s] не найдена.", idShare)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("revoke share error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось отозвать ссылку [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idShare)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Response: map[string]interface{}{
+			idShare: true,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "revoke share")
+}
+
+// GetShareAccesses godoc
+// @Summary Получить журнал обращений по ссылке
+// @Description Возвращает обращения к документу по ссылке от новых к старым: результат, IP-адрес и User-Agent клиента.
+// @Description Результат обращения: granted, wrong_password, expired, revoked, exhausted или not_found
+// @Tags shares
+// @Produce json
+// @Param id path string true "Идентификатор ссылки"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} entity.ApiResponse "Журнал обращений успешно получен"
+// @Failure 400 {object} entity.ApiError "Некорректные параметры запроса"
+// @Failure 404 {object} entity.ApiError "Ссылка не найдена"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /shares/{id}/accesses [get]
+func (h *ShareHandler) GetShareAccesses(w http.ResponseWriter, r *http.Request) {
+	idShare := chi.URLParam(r, "id")
+	query := r.URL.Query()
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("get share accesses error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	var req entity.ShareAccessListRequest
+
+	if query.Has("limit") {
+		if req.Limit, err = strconv.Atoi(query.Get("limit")); err != nil {
+			log.Errorf("get share accesses error: %+v", err)
+			messageError = "Параметр [limit] должен быть целым числом."
+
+			common.ApiError(http.StatusBadRequest, messageError, w)
+			return
+		}
+	}
+
+	if query.Has("offset") {
+		if req.Offset, err = strconv.Atoi(query.Get("offset")); err != nil {
+			log.Errorf("get share accesses error: %+v", err)
+			messageError = "Параметр [offset] должен быть целым числом."
+
+			common.ApiError(http.StatusBadRequest, messageError, w)
+			return
+		}
+	}
+
+	var paramErr *entity.ParamError
+
+	accesses, err := h.uc.GetShareAccesses(login, idShare, req)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("get share accesses error: %+v", err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrShareNotFound):
+		log.Errorf("get share accesses error: %+v", err)
+		messageError = fmt.Sprintf("Ссылка [%s] не найдена.", idShare)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("get share accesses error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось получить журнал обращений по ссылке [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idShare)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"accesses": accesses.Accesses,
+			"total":    accesses.Total,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "get share accesses")
+}
+
+// OpenShare godoc
+// @Summary Получить документ по ссылке
+// @Description Возвращает содержимое документа по токену ссылки без авторизации. Вместе с засчитанным скачиванием
+// @Description клиент получает cookie shareGrant: запросы диапазонов с этой cookie продолжают скачивание и не расходуют
+// @Description скачивания ссылки, остальные запросы засчитываются как новые скачивания.
+// @Description Пароль ссылки передается в заголовке X-Share-Password
+// @Tags shares
+// @Produce octet-stream
+// @Produce json
+// @Param token path string true "Токен ссылки"
+// @Param X-Share-Password header string false "Пароль ссылки"
+// @Success 200 {file} byte "Документ успешно получен"
+// @Failure 401 {object} entity.ApiError "Пароль ссылки не передан или неверен"
+// @Failure 404 {object} entity.ApiError "Ссылка или документ не найдены"
+// @Failure 410 {object} entity.ApiError "Ссылка отозвана, истекла или исчерпала количество скачиваний"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Router /share/{token} [get]
+func (h *ShareHandler) OpenShare(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	req := entity.OpenShareRequest{
+		Token:          token,
+		Password:       r.Header.Get(passwordHeader),
+		AcceptEncoding: r.Header.Get("Accept-Encoding"),
+		Continuation:   r.Header.Get("Range") != "",
+		Client: entity.ShareClient{
+			IP:        clientIP(r),
+			UserAgent: r.UserAgent(),
+		},
+	}
+
+	if grant, err := r.Cookie(grantCookie); err == nil {
+		req.Grant = grant.Value
+	}
+
+	shared, err := h.uc.OpenShare(r.Context(), req)
+	switch {
+	case errors.Is(err, custom_error.ErrShareNotFound):
+		log.Errorf("open share error: %+v", err)
+		messageError = "Ссылка не найдена."
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrSharePasswordWrong):
+		log.Errorf("open share error: %+v", err)
+		messageError = "Неверный пароль ссылки."
+
+		common.ApiError(http.StatusUnauthorized, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrShareRevoked):
+		log.Errorf("open share error: %+v", err)
+		messageError = "Ссылка отозвана владельцем."
+
+		common.ApiError(http.StatusGone, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrShareExpired):
+		log.Errorf("open share error: %+v", err)
+		messageError = "Срок действия ссылки истек."
+
+		common.ApiError(http.StatusGone, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrShareExhausted):
+		log.Errorf("open share error: %+v", err)
+		messageError = "Количество скачиваний по ссылке исчерпано."
+
+		common.ApiError(http.StatusGone, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrDocumentNotFound):
+		log.Errorf("open share error: %+v", err)
+		messageError = "Документ ссылки не найден."
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("open share error: %+v", err)
+		messageError = "Ошибка сервера, не удалось получить документ по ссылке. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	if shared.Grant != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     grantCookie,
+			Value:    shared.Grant,
+			Path:     entity.SharePath + token,
+			Expires:  shared.GrantExpiresAt,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	// ответ зависит от пароля в заголовке запроса, поэтому не должен попадать в общие кэши
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	common.ServeContent(w, r, shared.Content)
+}
+
+// clientIP возвращает IP-адрес клиента без порта. Адрес клиента за доверенным прокси
+// подставляет RealIPMiddleware
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func getCurrentUser(r *http.Request) (string, error) {
+	login, ok := r.Context().Value(entity.CurrentUserKey).(string)
+	if !ok {
+		return "", fmt.Errorf("current user not found")
+	}
+
+	return login, nil
+}
+
+func writeJson(w http.ResponseWriter, status int, response entity.ApiResponse, operation string) {
+	resp, err := json.Marshal(response)
+	if err != nil {
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Ошибка сервера. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(resp)
+	if err != nil {
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Сервер недоступен. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusServiceUnavailable, messageError, w)
+	}
+}
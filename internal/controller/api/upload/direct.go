@@ -0,0 +1,245 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// maxDirectUploadBodySize максимальный размер тела запросов прямой загрузки
+const maxDirectUploadBodySize = 64 << 10
+
+// CreateDirectUpload godoc
+// @Summary Создать прямую загрузку файла
+// @Description Создает загрузку, файл которой клиент записывает в хранилище сам запросом PUT по подписанной ссылке.
+// @Description Если указан document_id, файл станет новой версией документа, иначе будет создан новый документ.
+// @Description После загрузки файла ее нужно подтвердить запросом POST /uploads/direct/{id}/complete
+// @Tags uploads
+// @Accept json
+// @Produce json
+// @Param request body entity.DirectUploadRequest true "Параметры загрузки"
+// @Success 201 {object} entity.ApiResponse "Загрузка создана, ссылка для записи файла в поле link"
+// @Failure 400 {object} entity.ApiError "Некорректные параметры загрузки"
+// @Failure 403 {object} entity.ApiError "Недостаточно прав для изменения документа"
+// @Failure 404 {object} entity.ApiError "Документ не найден"
+// @Failure 413 {object} entity.ApiError "Размер файла превышает допустимый"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 501 {object} entity.ApiError "Файловое хранилище не поддерживает подписанные ссылки"
+// @Router /uploads/direct [post]
+func (h *UploadHandler) CreateDirectUpload(w http.ResponseWriter, r *http.Request) {
+	var req entity.DirectUploadRequest
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("create direct upload error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	if err = readJson(r, &req); err != nil {
+		log.Errorf("create direct upload error: %+v", err)
+		messageError = "Не удалось прочитать параметры загрузки."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	var paramErr *entity.ParamError
+
+	directUpload, err := h.uc.CreateDirectUpload(login, req)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("create direct upload error: %+v", err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrDocumentNotFound):
+		log.Errorf("create direct upload error: %+v", err)
+		messageError = fmt.Sprintf("Документ [%s] не найден.", req.DocumentID)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrAccessDenied):
+		log.Errorf("create direct upload error: %+v", err)
+		messageError = fmt.Sprintf("Недостаточно прав для изменения документа [%s].", req.DocumentID)
+
+		common.ApiError(http.StatusForbidden, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrUploadTooLarge):
+		log.Errorf("create direct upload error: %+v", err)
+		messageError = "Размер файла превышает допустимый для загрузки одним запросом."
+
+		common.ApiError(http.StatusRequestEntityTooLarge, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrDirectTransferUnsupported):
+		log.Errorf("create direct upload error: %+v", err)
+		messageError = "Файловое хранилище сервера не поддерживает загрузку файлов по подписанным ссылкам."
+
+		common.ApiError(http.StatusNotImplemented, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("create direct upload error: %+v", err)
+		messageError = "Ошибка сервера, не удалось создать загрузку. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"upload": directUpload.Upload,
+			"link":   directUpload.Link,
+		},
+	}
+
+	writeJson(w, http.StatusCreated, respMap, "create direct upload")
+}
+
+// CompleteDirectUpload godoc
+// @Summary Подтвердить прямую загрузку файла
+// @Description Проверяет, что файл записан в хранилище, сверяет его размер и SHA-256
+// @Description и создает из него документ или новую версию документа.
+// @Description Повторное подтверждение завершенной загрузки возвращает тот же документ
+// @Tags uploads
+// @Accept json
+// @Produce json
+// @Param id path string true "Идентификатор загрузки"
+// @Param request body entity.DirectUploadCompletion true "Хеш загруженного файла"
+// @Success 200 {object} entity.ApiResponse "Документ создан"
+// @Failure 400 {object} entity.ApiError "Некорректный хеш файла"
+// @Failure 403 {object} entity.ApiError "Недостаточно прав для изменения документа"
+// @Failure 404 {object} entity.ApiError "Загрузка не найдена или ее срок истек"
+// @Failure 409 {object} entity.ApiError "Файл еще не загружен"
+// @Failure 422 {object} entity.ApiError "Файл не совпадает с объявленным размером или хешем"
+// @Failure 423 {object} entity.ApiError "Загрузка обрабатывается другим запросом"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Router /uploads/direct/{id}/complete [post]
+func (h *UploadHandler) CompleteDirectUpload(w http.ResponseWriter, r *http.Request) {
+	var req entity.DirectUploadCompletion
+
+	idUpload := chi.URLParam(r, "id")
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("complete direct upload error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	if err = readJson(r, &req); err != nil {
+		log.Errorf("complete direct upload error: %+v", err)
+		messageError = "Не удалось прочитать параметры подтверждения загрузки."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	var paramErr *entity.ParamError
+
+	result, err := h.uc.CompleteDirectUpload(login, idUpload, req)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("complete direct upload error: %+v", err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrUploadNotFound):
+		log.Errorf("complete direct upload error: %+v", err)
+		messageError = fmt.Sprintf("Загрузка [%s] не найдена.", idUpload)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrDocumentNotFound):
+		log.Errorf("complete direct upload error: %+v", err)
+		messageError = "Документ, новой версией которого должен стать файл, не найден."
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrAccessDenied):
+		log.Errorf("complete direct upload error: %+v", err)
+		messageError = "Недостаточно прав для изменения документа."
+
+		common.ApiError(http.StatusForbidden, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrUploadIncomplete):
+		log.Errorf("complete direct upload error: %+v", err)
+		messageError = fmt.Sprintf("Файл загрузки [%s] еще не записан в хранилище.", idUpload)
+
+		common.ApiError(http.StatusConflict, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrChecksumMismatch):
+		log.Errorf("complete direct upload error: %+v", err)
+		messageError = fmt.Sprintf("Файл загрузки [%s] не совпадает с объявленным размером или хешем.", idUpload)
+
+		common.ApiError(http.StatusUnprocessableEntity, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrUploadLocked):
+		log.Errorf("complete direct upload error: %+v", err)
+		messageError = fmt.Sprintf("Загрузка [%s] обрабатывается другим запросом.", idUpload)
+
+		common.ApiError(http.StatusLocked, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("complete direct upload error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось завершить загрузку [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idUpload)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"document": result,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "complete direct upload")
+}
+
+// readJson читает из тела запроса параметры в формате JSON
+func readJson(r *http.Request, v interface{}) error {
+	var buf bytes.Buffer
+
+	if _, err := buf.ReadFrom(io.LimitReader(r.Body, maxDirectUploadBodySize)); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(buf.Bytes(), v)
+}
+
+func writeJson(w http.ResponseWriter, status int, response entity.ApiResponse, operation string) {
+	resp, err := json.Marshal(response)
+	if err != nil {
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Ошибка сервера. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(resp)
+	if err != nil {
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Сервер недоступен. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusServiceUnavailable, messageError, w)
+	}
+}
@@ -0,0 +1,412 @@
+package upload
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+	"github.com/AlexJudin/DocumentCacheServer/internal/usecases"
+)
+
+const (
+	// tusVersion версия протокола tus, которую поддерживает сервер
+	tusVersion = "1.0.0"
+	// tusExtensions поддерживаемые расширения протокола tus
+	tusExtensions = "creation,termination,expiration"
+	// offsetContentType тип тела запроса с частью файла
+	offsetContentType = "application/offset+octet-stream"
+
+	// documentIDHeader заголовок с идентификатором документа, созданного из загрузки
+	documentIDHeader = "X-Document-Id"
+
+	uploadsPath = "/api/uploads/"
+)
+
+var messageError string
+
+type UploadHandler struct {
+	uc usecases.Upload
+}
+
+func NewUploadHandler(uc usecases.Upload) UploadHandler {
+	return UploadHandler{uc: uc}
+}
+
+// GetOptions godoc
+// @Summary Параметры загрузки файлов по протоколу tus
+// @Description Возвращает версию протокола tus, поддерживаемые расширения и максимальный размер файла
+// @Tags uploads
+// @Success 204 "Параметры загрузки в заголовках Tus-Version, Tus-Extension и Tus-Max-Size"
+// @Router /uploads [options]
+func (h *UploadHandler) GetOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.uc.MaxSize(), 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateUpload godoc
+// @Summary Создать загрузку файла
+// @Description Создает возобновляемую загрузку файла по протоколу tus (расширение creation).
+// @Description Параметры документа передаются в Upload-Metadata: filename, filetype, public (true/false)
+// @Description и grant (логины через запятую), значения кодируются в base64.
+// @Description Адрес загрузки возвращается в заголовке Location, срок ее завершения - в Upload-Expires
+// @Tags uploads
+// @Param Tus-Resumable header string true "Версия протокола tus: 1.0.0"
+// @Param Upload-Length header int true "Размер файла в байтах"
+// @Param Upload-Metadata header string false "Параметры документа"
+// @Success 201 "Загрузка создана"
+// @Failure 400 {object} entity.ApiError "Некорректные параметры загрузки"
+// @Failure 412 {object} entity.ApiError "Версия протокола tus не поддерживается"
+// @Failure 413 {object} entity.ApiError "Размер файла превышает допустимый"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 501 {object} entity.ApiError "Файловое хранилище не поддерживает загрузку частями"
+// @Router /uploads [post]
+func (h *UploadHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusResumable(w, r) {
+		return
+	}
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("create upload error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		log.Errorf("create upload error: invalid Upload-Length [%s]", r.Header.Get("Upload-Length"))
+		messageError = "Заголовок Upload-Length должен содержать размер файла в байтах."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	req, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		log.Errorf("create upload error: %+v", err)
+		messageError = "Некорректный заголовок Upload-Metadata."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	req.Length = length
+
+	upload, err := h.uc.CreateUpload(login, req)
+	switch {
+	case errors.Is(err, custom_error.ErrUploadTooLarge):
+		log.Errorf("create upload error: %+v", err)
+		messageError = fmt.Sprintf("Размер файла не может превышать %d байт.", h.uc.MaxSize())
+
+		common.ApiError(http.StatusRequestEntityTooLarge, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrUploadsUnsupported):
+		log.Errorf("create upload error: %+v", err)
+		messageError = "Файловое хранилище сервера не поддерживает загрузку файлов частями."
+
+		common.ApiError(http.StatusNotImplemented, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("create upload error: %+v", err)
+		messageError = "Ошибка сервера, не удалось создать загрузку. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set("Location", uploadsPath+upload.UUID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// GetUploadOffset godoc
+// @Summary Получить смещение загрузки
+// @Description Возвращает количество полученных байт в заголовке Upload-Offset.
+// @Description Когда получен весь файл и из него создан документ, его идентификатор возвращается в заголовке X-Document-Id
+// @Tags uploads
+// @Param Tus-Resumable header string true "Версия протокола tus: 1.0.0"
+// @Param id path string true "Идентификатор загрузки"
+// @Success 200 "Состояние загрузки в заголовках"
+// @Failure 404 {object} entity.ApiError "Загрузка не найдена или ее срок истек"
+// @Failure 412 {object} entity.ApiError "Версия протокола tus не поддерживается"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Router /uploads/{id} [head]
+func (h *UploadHandler) GetUploadOffset(w http.ResponseWriter, r *http.Request) {
+	idUpload := chi.URLParam(r, "id")
+
+	if !checkTusResumable(w, r) {
+		return
+	}
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("get upload offset error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	upload, err := h.uc.GetUpload(login, idUpload)
+	switch {
+	case errors.Is(err, custom_error.ErrUploadNotFound):
+		log.Errorf("get upload offset error: %+v", err)
+		messageError = fmt.Sprintf("Загрузка [%s] не найдена.", idUpload)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("get upload offset error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось получить загрузку [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idUpload)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// WriteUpload godoc
+// @Summary Записать часть файла
+// @Description Записывает тело запроса со смещения Upload-Offset, которое должно совпадать с количеством полученных байт.
+// @Description Если соединение оборвалось, полученные байты сохраняются, и загрузку можно продолжить со смещения из HEAD.
+// @Description Когда получен весь файл, из него создается документ, идентификатор которого возвращается в заголовке X-Document-Id.
+// @Description Если создать документ не удалось, запрос можно повторить с пустым телом
+// @Tags uploads
+// @Accept application/offset+octet-stream
+// @Param Tus-Resumable header string true "Версия протокола tus: 1.0.0"
+// @Param Upload-Offset header int true "Смещение, с которого записывается тело запроса"
+// @Param id path string true "Идентификатор загрузки"
+// @Success 204 "Часть файла записана, новое смещение в заголовке Upload-Offset"
+// @Failure 400 {object} entity.ApiError "Некорректное смещение"
+// @Failure 404 {object} entity.ApiError "Загрузка не найдена или ее срок истек"
+// @Failure 409 {object} entity.ApiError "Смещение не совпадает с количеством полученных байт"
+// @Failure 412 {object} entity.ApiError "Версия протокола tus не поддерживается"
+// @Failure 413 {object} entity.ApiError "Тело запроса выходит за размер файла"
+// @Failure 415 {object} entity.ApiError "Некорректный тип тела запроса"
+// @Failure 423 {object} entity.ApiError "Загрузка занята другим запросом"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Router /uploads/{id} [patch]
+func (h *UploadHandler) WriteUpload(w http.ResponseWriter, r *http.Request) {
+	idUpload := chi.URLParam(r, "id")
+
+	if !checkTusResumable(w, r) {
+		return
+	}
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("write upload error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != offsetContentType {
+		log.Errorf("write upload error: unsupported content type [%s]", r.Header.Get("Content-Type"))
+		messageError = fmt.Sprintf("Тело запроса должно иметь тип %s.", offsetContentType)
+
+		common.ApiError(http.StatusUnsupportedMediaType, messageError, w)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		log.Errorf("write upload error: invalid Upload-Offset [%s]", r.Header.Get("Upload-Offset"))
+		messageError = "Заголовок Upload-Offset должен содержать смещение в байтах."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	upload, err := h.uc.GetUpload(login, idUpload)
+	if err == nil && r.ContentLength > upload.Length-offset {
+		err = custom_error.ErrUploadTooLarge
+	}
+	if err == nil {
+		upload, err = h.uc.WriteUpload(login, idUpload, offset, r.Body)
+	}
+
+	switch {
+	case errors.Is(err, custom_error.ErrUploadNotFound):
+		log.Errorf("write upload error: %+v", err)
+		messageError = fmt.Sprintf("Загрузка [%s] не найдена.", idUpload)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrUploadOffsetMismatch):
+		log.Errorf("write upload error: %+v", err)
+		messageError = fmt.Sprintf("Смещение %d не совпадает с количеством полученных байт %d.", offset, upload.Offset)
+
+		common.ApiError(http.StatusConflict, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrUploadTooLarge):
+		log.Errorf("write upload error: %+v", err)
+		messageError = fmt.Sprintf("Тело запроса выходит за размер файла %d байт.", upload.Length)
+
+		common.ApiError(http.StatusRequestEntityTooLarge, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrUploadLocked):
+		log.Errorf("write upload error: %+v", err)
+		messageError = fmt.Sprintf("Загрузка [%s] занята другим запросом.", idUpload)
+
+		common.ApiError(http.StatusLocked, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("write upload error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось записать загрузку [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idUpload)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	setUploadHeaders(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteUpload godoc
+// @Summary Отменить загрузку
+// @Description Отменяет загрузку и удаляет полученные части файла (расширение termination).
+// @Description Документ, уже созданный из загрузки, не удаляется
+// @Tags uploads
+// @Param Tus-Resumable header string true "Версия протокола tus: 1.0.0"
+// @Param id path string true "Идентификатор загрузки"
+// @Success 204 "Загрузка отменена"
+// @Failure 404 {object} entity.ApiError "Загрузка не найдена или ее срок истек"
+// @Failure 412 {object} entity.ApiError "Версия протокола tus не поддерживается"
+// @Failure 423 {object} entity.ApiError "Загрузка занята другим запросом"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Router /uploads/{id} [delete]
+func (h *UploadHandler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	idUpload := chi.URLParam(r, "id")
+
+	if !checkTusResumable(w, r) {
+		return
+	}
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("delete upload error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	err = h.uc.DeleteUpload(login, idUpload)
+	switch {
+	case errors.Is(err, custom_error.ErrUploadNotFound):
+		log.Errorf("delete upload error: %+v", err)
+		messageError = fmt.Sprintf("Загрузка [%s] не найдена.", idUpload)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrUploadLocked):
+		log.Errorf("delete upload error: %+v", err)
+		messageError = fmt.Sprintf("Загрузка [%s] занята другим запросом.", idUpload)
+
+		common.ApiError(http.StatusLocked, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("delete upload error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось отменить загрузку [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idUpload)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkTusResumable проверяет версию протокола tus в запросе. Ответ на любой запрос содержит версию сервера
+func checkTusResumable(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	if r.Header.Get("Tus-Resumable") == tusVersion {
+		return true
+	}
+
+	log.Errorf("upload error: unsupported tus version [%s]", r.Header.Get("Tus-Resumable"))
+	messageError = fmt.Sprintf("Поддерживается версия протокола tus %s.", tusVersion)
+
+	w.Header().Set("Tus-Version", tusVersion)
+	common.ApiError(http.StatusPreconditionFailed, messageError, w)
+
+	return false
+}
+
+// setUploadHeaders добавляет в ответ смещение и срок загрузки, а для завершенной загрузки - идентификатор документа
+func setUploadHeaders(w http.ResponseWriter, upload model.Upload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+
+	if upload.Completed() {
+		w.Header().Set(documentIDHeader, upload.DocumentUUID)
+	}
+}
+
+// parseMetadata разбирает заголовок Upload-Metadata: пары ключа и значения в base64 через запятую
+func parseMetadata(header string) (entity.UploadRequest, error) {
+	var req entity.UploadRequest
+
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(item, " ")
+
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return req, fmt.Errorf("invalid value of metadata key [%s]: %w", key, err)
+		}
+
+		switch key {
+		case "filename", "name":
+			req.Name = string(value)
+		case "filetype", "mime":
+			req.Mime = string(value)
+		case "public":
+			if req.Public, err = strconv.ParseBool(string(value)); err != nil {
+				return req, fmt.Errorf("invalid value of metadata key [%s]: %w", key, err)
+			}
+		case "grant":
+			for _, login := range strings.Split(string(value), ",") {
+				if login = strings.TrimSpace(login); login != "" {
+					req.Grant = append(req.Grant, login)
+				}
+			}
+		}
+	}
+
+	return req, nil
+}
+
+func getCurrentUser(r *http.Request) (string, error) {
+	login, ok := r.Context().Value(entity.CurrentUserKey).(string)
+	if !ok {
+		return "", fmt.Errorf("current user not found")
+	}
+
+	return login, nil
+}
@@ -0,0 +1,371 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/usecases"
+)
+
+// maxWebhookBodySize ограничивает размер тела запроса регистрации webhook
+const maxWebhookBodySize = 64 << 10
+
+var messageError string
+
+type WebhookHandler struct {
+	uc usecases.Webhook
+}
+
+func NewWebhookHandler(uc usecases.Webhook) WebhookHandler {
+	return WebhookHandler{uc: uc}
+}
+
+// CreateWebhook godoc
+// @Summary Зарегистрировать webhook
+// @Description Регистрирует адрес, на который отправляются события документов пользователя
+// @Description и документов, к которым ему выдан доступ. Пустой список events подписывает на все события.
+// @Description Если секрет не передан, он генерируется сервером. Секрет возвращается только в ответе на регистрацию
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param request body entity.WebhookRequest true "Параметры webhook"
+// @Success 201 {object} entity.ApiResponse "Webhook успешно зарегистрирован"
+// @Failure 400 {object} entity.ApiError "Некорректные параметры webhook"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var (
+		req entity.WebhookRequest
+		buf bytes.Buffer
+	)
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("create webhook error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	_, err = buf.ReadFrom(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		log.Errorf("create webhook error: %+v", err)
+		messageError = "Переданы некорректные параметры webhook."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	if err = json.Unmarshal(buf.Bytes(), &req); err != nil {
+		log.Errorf("create webhook error: %+v", err)
+		messageError = "Не удалось прочитать параметры webhook."
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	}
+
+	var paramErr *entity.ParamError
+
+	webhook, err := h.uc.CreateWebhook(login, req)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("create webhook error: %+v", err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("create webhook error: %+v", err)
+		messageError = "Ошибка сервера, не удалось зарегистрировать webhook. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"webhook": webhook.Webhook,
+			"secret":  webhook.Secret,
+		},
+	}
+
+	writeJson(w, http.StatusCreated, respMap, "create webhook")
+}
+
+// GetWebhooks godoc
+// @Summary Получить webhook пользователя
+// @Description Возвращает зарегистрированные webhook текущего пользователя
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} entity.ApiResponse "Список webhook успешно получен"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /webhooks [get]
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("get webhooks error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	webhooks, err := h.uc.GetWebhooks(login)
+	if err != nil {
+		log.Errorf("get webhooks error: %+v", err)
+		messageError = "Ошибка сервера, не удалось получить список webhook. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"webhooks": webhooks,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "get webhooks")
+}
+
+// DeleteWebhook godoc
+// @Summary Удалить webhook
+// @Description Удаляет webhook вместе с журналом доставок. Неотправленные доставки отменяются
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Идентификатор webhook"
+// @Success 200 {object} entity.ApiResponse "Webhook успешно удален"
+// @Failure 404 {object} entity.ApiError "Webhook не найден"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	idWebhook := chi.URLParam(r, "id")
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("delete webhook error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	err = h.uc.DeleteWebhook(login, idWebhook)
+	switch {
+	case errors.Is(err, custom_error.ErrWebhookNotFound):
+		log.Errorf("delete webhook error: %+v", err)
+		messageError = fmt.Sprintf("Webhook [%s] не найден.", idWebhook)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("delete webhook error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось удалить webhook [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idWebhook)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Response: map[string]interface{}{
+			idWebhook: true,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "delete webhook")
+}
+
+// GetDeliveries godoc
+// @Summary Получить журнал доставок webhook
+// @Description Возвращает доставки событий webhook от новых к старым.
+// @Description Доставки в состоянии dead исчерпали все попытки и могут быть отправлены повторно
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Идентификатор webhook"
+// @Param status query string false "Состояние доставки: pending, retrying, delivered, dead"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} entity.ApiResponse "Журнал доставок успешно получен"
+// @Failure 400 {object} entity.ApiError "Некорректные параметры запроса"
+// @Failure 404 {object} entity.ApiError "Webhook не найден"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	idWebhook := chi.URLParam(r, "id")
+	query := r.URL.Query()
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("get webhook deliveries error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	req := entity.DeliveryListRequest{
+		Status: query.Get("status"),
+	}
+
+	if query.Has("limit") {
+		if req.Limit, err = strconv.Atoi(query.Get("limit")); err != nil {
+			log.Errorf("get webhook deliveries error: %+v", err)
+			messageError = "Параметр [limit] должен быть целым числом."
+
+			common.ApiError(http.StatusBadRequest, messageError, w)
+			return
+		}
+	}
+
+	if query.Has("offset") {
+		if req.Offset, err = strconv.Atoi(query.Get("offset")); err != nil {
+			log.Errorf("get webhook deliveries error: %+v", err)
+			messageError = "Параметр [offset] должен быть целым числом."
+
+			common.ApiError(http.StatusBadRequest, messageError, w)
+			return
+		}
+	}
+
+	var paramErr *entity.ParamError
+
+	deliveries, err := h.uc.GetDeliveries(login, idWebhook, req)
+	switch {
+	case errors.As(err, &paramErr):
+		log.Errorf("get webhook deliveries error: %+v", err)
+		messageError = fmt.Sprintf("Некорректный параметр [%s]: %s.", paramErr.Param, paramErr.Reason)
+
+		common.ApiError(http.StatusBadRequest, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrWebhookNotFound):
+		log.Errorf("get webhook deliveries error: %+v", err)
+		messageError = fmt.Sprintf("Webhook [%s] не найден.", idWebhook)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("get webhook deliveries error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось получить журнал доставок webhook [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idWebhook)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Data: map[string]interface{}{
+			"deliveries": deliveries.Deliveries,
+			"total":      deliveries.Total,
+		},
+	}
+
+	writeJson(w, http.StatusOK, respMap, "get webhook deliveries")
+}
+
+// Redeliver godoc
+// @Summary Повторить доставку webhook
+// @Description Возвращает в очередь доставку в состоянии dead с новым запасом попыток
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Идентификатор webhook"
+// @Param delivery path string true "Идентификатор доставки"
+// @Success 202 {object} entity.ApiResponse "Доставка поставлена в очередь"
+// @Failure 404 {object} entity.ApiError "Webhook или доставка не найдены"
+// @Failure 409 {object} entity.ApiError "Доставка не находится в состоянии dead"
+// @Failure 500 {object} entity.ApiError "Внутренняя ошибка сервера"
+// @Failure 503 {object} entity.ApiError "Сервер недоступен"
+// @Router /webhooks/{id}/deliveries/{delivery}/redeliver [post]
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	idWebhook := chi.URLParam(r, "id")
+	idDelivery := chi.URLParam(r, "delivery")
+
+	login, err := getCurrentUser(r)
+	if err != nil {
+		log.Errorf("redeliver webhook error: %+v", err)
+		messageError = "Внутренняя ошибка сервера. Не удалось получить логин текущего пользователя."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	err = h.uc.Redeliver(login, idWebhook, idDelivery)
+	switch {
+	case errors.Is(err, custom_error.ErrWebhookNotFound):
+		log.Errorf("redeliver webhook error: %+v", err)
+		messageError = fmt.Sprintf("Webhook [%s] не найден.", idWebhook)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrDeliveryNotFound):
+		log.Errorf("redeliver webhook error: %+v", err)
+		messageError = fmt.Sprintf("Доставка [%s] не найдена.", idDelivery)
+
+		common.ApiError(http.StatusNotFound, messageError, w)
+		return
+	case errors.Is(err, custom_error.ErrDeliveryNotDead):
+		log.Errorf("redeliver webhook error: %+v", err)
+		messageError = fmt.Sprintf("Доставка [%s] еще не исчерпала попытки.", idDelivery)
+
+		common.ApiError(http.StatusConflict, messageError, w)
+		return
+	case err != nil:
+		log.Errorf("redeliver webhook error: %+v", err)
+		messageError = fmt.Sprintf("Ошибка сервера, не удалось повторить доставку [%s]. Попробуйте позже или обратитесь в тех. поддержку.", idDelivery)
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	respMap := entity.ApiResponse{
+		Response: map[string]interface{}{
+			idDelivery: true,
+		},
+	}
+
+	writeJson(w, http.StatusAccepted, respMap, "redeliver webhook")
+}
+
+func getCurrentUser(r *http.Request) (string, error) {
+	login, ok := r.Context().Value(entity.CurrentUserKey).(string)
+	if !ok {
+		return "", fmt.Errorf("current user not found")
+	}
+
+	return login, nil
+}
+
+func writeJson(w http.ResponseWriter, status int, response entity.ApiResponse, operation string) {
+	resp, err := json.Marshal(response)
+	if err != nil {
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Ошибка сервера. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusInternalServerError, messageError, w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(resp)
+	if err != nil {
+		log.Errorf("%s error: %+v", operation, err)
+		messageError = "Сервер недоступен. Попробуйте позже или обратитесь в тех. поддержку."
+
+		common.ApiError(http.StatusServiceUnavailable, messageError, w)
+	}
+}
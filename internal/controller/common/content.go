@@ -0,0 +1,41 @@
+package common
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// ServeContent отдает содержимое документа и закрывает его.
+// Сжатое содержимое отдается с заголовком Content-Encoding, диапазоны в этом случае относятся к сжатым данным.
+// Для содержимого, распакованного при отдаче, запрос нескольких диапазонов игнорируется и содержимое
+// отдается целиком: диапазоны могут идти в любом порядке, и каждый переход назад распаковывал бы его сначала.
+// Один диапазон требует не больше одной распаковки до его конца
+func ServeContent(w http.ResponseWriter, r *http.Request, content entity.DocumentContent) {
+	defer content.Body.Close()
+
+	w.Header().Set("Content-Type", content.Mime)
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	etag := content.Hash
+	if content.Encoding != "" {
+		w.Header().Set("Content-Encoding", content.Encoding)
+
+		// у сжатого и несжатого представлений разные байты, поэтому и ETag разный
+		etag += "-" + content.Encoding
+	}
+
+	if content.Hash != "" {
+		w.Header().Set("ETag", strconv.Quote(etag))
+	}
+
+	if content.Decoded && strings.Contains(r.Header.Get("Range"), ",") {
+		r.Header.Del("Range")
+	}
+
+	// ServeContent обрабатывает HEAD, Range (в том числе несколько диапазонов)
+	// и условные заголовки If-None-Match, If-Modified-Since, If-Range
+	http.ServeContent(w, r, "", content.ModTime, content.Body)
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error {
+	return nil
+}
+
+func TestServeContentRanges(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+
+	tests := []struct {
+		name        string
+		decoded     bool
+		rangeHeader string
+		wantStatus  int
+		wantBody    string
+	}{
+		{name: "single range", rangeHeader: "bytes=2-5", wantStatus: http.StatusPartialContent, wantBody: "2345"},
+		{name: "decoded single range", decoded: true, rangeHeader: "bytes=2-5", wantStatus: http.StatusPartialContent, wantBody: "2345"},
+		{name: "multiple ranges", rangeHeader: "bytes=10-12,0-1", wantStatus: http.StatusPartialContent},
+		{name: "decoded multiple ranges", decoded: true, rangeHeader: "bytes=10-12,0-1", wantStatus: http.StatusOK, wantBody: string(data)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/docs/doc-1", nil)
+			r.Header.Set("Range", tt.rangeHeader)
+
+			w := httptest.NewRecorder()
+
+			ServeContent(w, r, entity.DocumentContent{
+				Mime:    "text/plain",
+				Size:    int64(len(data)),
+				Hash:    "hash",
+				ModTime: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+				Body:    nopSeekCloser{bytes.NewReader(data)},
+				Decoded: tt.decoded,
+			})
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+
+			if tt.wantBody == "" && !strings.HasPrefix(w.Header().Get("Content-Type"), "multipart/byteranges") {
+				t.Errorf("content type = %s, want multipart/byteranges", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
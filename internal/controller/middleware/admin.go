@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/controller/common"
+)
+
+const adminTokenHeader = "X-Admin-Token"
+
+type AdminMiddleware struct {
+	adminToken string
+}
+
+func NewAdminMiddleware(adminToken string) *AdminMiddleware {
+	return &AdminMiddleware{
+		adminToken: adminToken,
+	}
+}
+
+// CheckAdminToken пропускает только запросы с административным токеном в заголовке X-Admin-Token.
+// Если токен не задан в конфигурации, административные методы недоступны
+func (a *AdminMiddleware) CheckAdminToken(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(adminTokenHeader)
+
+		if a.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) != 1 {
+			log.Errorf("admin request %s rejected: invalid admin token", r.URL.Path)
+			common.ApiError(http.StatusForbidden, "admin token invalid", w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
@@ -43,3 +43,28 @@ func (a *AuthMiddleware) CheckToken(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(fn)
 }
+
+// OptionalToken пропускает анонимные запросы без токена,
+// но отклоняет запросы с недействительным токеном
+func (a *AuthMiddleware) OptionalToken(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := r.Cookie("accessToken")
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		userLogin, err := a.authService.VerifyUser(accessToken.Value)
+		if err != nil {
+			common.ApiError(http.StatusUnauthorized, err.Error(), w)
+			return
+		}
+
+		log.Infof("Пользователь %s сделал запрос %s", userLogin, r.URL.Path)
+
+		ctx := context.WithValue(r.Context(), entity.CurrentUserKey, userLogin)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+
+	return http.HandlerFunc(fn)
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIPMiddleware заменяет адрес запроса адресом клиента, который передал доверенный прокси.
+// Заголовки X-Forwarded-For и X-Real-IP учитываются, только если запрос пришел от доверенного прокси,
+// поэтому клиент не может подменить свой адрес для ограничения частоты запросов и журнала обращений
+type RealIPMiddleware struct {
+	trustedProxies []netip.Prefix
+}
+
+func NewRealIPMiddleware(trustedProxies []netip.Prefix) *RealIPMiddleware {
+	return &RealIPMiddleware{
+		trustedProxies: trustedProxies,
+	}
+}
+
+func (m *RealIPMiddleware) RealIP(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if ip, ok := m.clientIP(r); ok {
+			r.RemoteAddr = ip.String()
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// clientIP возвращает адрес клиента из заголовков доверенного прокси.
+// В X-Forwarded-For каждый прокси дописывает адрес справа, поэтому адресом клиента считается
+// крайний справа адрес, не принадлежащий доверенным прокси
+func (m *RealIPMiddleware) clientIP(r *http.Request) (netip.Addr, bool) {
+	remote, ok := parseIP(r.RemoteAddr)
+	if !ok || !m.trusted(remote) {
+		return netip.Addr{}, false
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip, ok := parseIP(strings.TrimSpace(hops[i]))
+			if !ok {
+				return netip.Addr{}, false
+			}
+
+			if !m.trusted(ip) {
+				return ip, true
+			}
+		}
+	}
+
+	return parseIP(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+}
+
+func (m *RealIPMiddleware) trusted(ip netip.Addr) bool {
+	for _, prefix := range m.trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseIP разбирает адрес с портом или без него
+func parseIP(value string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	ip, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return ip.Unmap(), true
+}
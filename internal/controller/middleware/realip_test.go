@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	m := NewRealIPMiddleware([]netip.Prefix{
+		netip.MustParsePrefix("172.28.0.10/32"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{
+			name:       "headers of untrusted client are ignored",
+			remoteAddr: "203.0.113.7:51000",
+			forwarded:  []string{"198.51.100.1"},
+			realIP:     "198.51.100.2",
+			want:       "203.0.113.7:51000",
+		},
+		{
+			name:       "real ip from trusted proxy",
+			remoteAddr: "172.28.0.10:40000",
+			realIP:     "198.51.100.2",
+			want:       "198.51.100.2",
+		},
+		{
+			name:       "rightmost untrusted forwarded address",
+			remoteAddr: "172.28.0.10:40000",
+			forwarded:  []string{"192.0.2.1, 198.51.100.1, 10.1.2.3"},
+			realIP:     "10.1.2.3",
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "forwarded header repeated",
+			remoteAddr: "172.28.0.10:40000",
+			forwarded:  []string{"192.0.2.1", "198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "invalid forwarded address",
+			remoteAddr: "172.28.0.10:40000",
+			forwarded:  []string{"192.0.2.1, unknown"},
+			realIP:     "198.51.100.2",
+			want:       "172.28.0.10:40000",
+		},
+		{
+			name:       "ipv4 mapped proxy address",
+			remoteAddr: "[::ffff:172.28.0.10]:40000",
+			realIP:     "2001:db8::1",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "trusted proxy without headers",
+			remoteAddr: "172.28.0.10:40000",
+			want:       "172.28.0.10:40000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+
+			handler := m.RealIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/share/token", nil)
+			r.RemoteAddr = tt.remoteAddr
+
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tt.want {
+				t.Errorf("remote address = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
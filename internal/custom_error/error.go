@@ -12,5 +12,50 @@ var (
 	ErrInvalidLogin      = errors.New("invalid login")
 	ErrInvalidPassword   = errors.New("invalid password")
 
-	ErrDocumentNotFound = errors.New("document not found")
+	ErrDocumentNotFound    = errors.New("document not found")
+	ErrAccessDenied        = errors.New("access denied")
+	ErrDocumentNotJson     = errors.New("document is not json")
+	ErrInvalidPatch        = errors.New("invalid patch")
+	ErrPatchFailed         = errors.New("patch cannot be applied")
+	ErrVersionNotFound     = errors.New("document version not found")
+	ErrInvalidFilter       = errors.New("invalid filter")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrInvalidListParams   = errors.New("invalid list parameters")
+	ErrInvalidContentQuery = errors.New("invalid content query")
+	ErrInvalidSearchQuery  = errors.New("invalid search query")
+	ErrSagaNotFound        = errors.New("saga not found")
+	ErrSagaFinished        = errors.New("saga already finished")
+	ErrSagaInProgress      = errors.New("saga in progress")
+	ErrWebhookNotFound     = errors.New("webhook not found")
+	ErrDeliveryNotFound    = errors.New("webhook delivery not found")
+	ErrDeliveryNotDead     = errors.New("webhook delivery is not dead")
+	ErrInvalidWebhook      = errors.New("invalid webhook")
+	ErrInvalidLastEventID  = errors.New("invalid last event id")
+	ErrFeedUnavailable     = errors.New("change feed is unavailable")
+
+	ErrInvalidIdempotencyKey    = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyNotFound   = errors.New("idempotency key not found")
+	ErrIdempotencyKeyInProgress = errors.New("request with idempotency key is in progress")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key reused with different payload")
+
+	ErrConsistencyCheckRunning = errors.New("consistency check is already running")
+
+	ErrUploadNotFound       = errors.New("upload not found")
+	ErrUploadOffsetMismatch = errors.New("upload offset mismatch")
+	ErrUploadTooLarge       = errors.New("upload too large")
+	ErrUploadLocked         = errors.New("upload is locked")
+	ErrUploadsUnsupported   = errors.New("uploads are not supported by file storage")
+	ErrInvalidUpload        = errors.New("invalid upload")
+	ErrUploadIncomplete     = errors.New("upload is not complete")
+	ErrChecksumMismatch     = errors.New("checksum mismatch")
+
+	ErrDirectTransferUnsupported = errors.New("direct transfer is not supported by file storage")
+	ErrDirectTransferUnavailable = errors.New("direct transfer is not available for document")
+
+	ErrShareNotFound      = errors.New("share link not found")
+	ErrShareExpired       = errors.New("share link expired")
+	ErrShareRevoked       = errors.New("share link revoked")
+	ErrShareExhausted     = errors.New("share link download limit reached")
+	ErrSharePasswordWrong = errors.New("share link password is wrong")
+	ErrInvalidShare       = errors.New("invalid share link")
 )
@@ -1,10 +1,22 @@
 package entity
 
 import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/AlexJudin/DocumentCacheServer/internal/model"
 )
 
-const DefaultMimeType = "application/json"
+const (
+	DefaultMimeType = "application/json"
+
+	// JsonPatchMimeType тип тела запроса с JSON Patch (RFC 6902)
+	JsonPatchMimeType = "application/json-patch+json"
+	// MergePatchMimeType тип тела запроса с JSON Merge Patch (RFC 7396)
+	MergePatchMimeType = "application/merge-patch+json"
+)
 
 type ApiError struct {
 	Code int    `json:"code"`
@@ -17,25 +29,72 @@ type ApiResponse struct {
 	Data     map[string]interface{} `json:"data,omitempty"`
 }
 
-type DocumentListRequest struct {
-	Login  string `json:"login"`
-	Key    string `json:"key"`
-	Value  string `json:"value"`
-	Limit  int    `json:"limit"`
-	Offset int    `json:"offset"`
-}
-
-func (d *DocumentListRequest) LoginIsEmpty() bool {
-	return d.Login == ""
-}
-
+// DocumentFile описывает файл документа, содержимое которого читается потоком.
+// Size равен -1, если размер файла заранее неизвестен
 type DocumentFile struct {
 	Name    string
-	Content []byte
+	Content io.Reader
+	Size    int64
+	// Digest ожидаемый SHA-256 содержимого в hex. Пустое значение - содержимое не сверяется
+	Digest string
+	// StagedKey ключ файла, уже записанного в файловое хранилище, хеш которого Digest проверен заранее.
+	// Content при этом не задается: файл без шифрования и сжатия копируется под ключ хеша средствами хранилища
+	StagedKey string
 }
 
 type Document struct {
 	Meta *model.MetaDocument
 	Json map[string]interface{}
 	File *DocumentFile
+	// ReservedUUID идентификатор нового документа, выбранный сервером заранее.
+	// Пустое значение - идентификатор создается при сохранении
+	ReservedUUID string
+}
+
+// DocumentContent содержимое документа для отдачи клиенту.
+// Body должен быть закрыт вызывающей стороной
+type DocumentContent struct {
+	Mime    string
+	Size    int64
+	Hash    string
+	ModTime time.Time
+	Body    io.ReadSeekCloser
+	// Encoding алгоритм, которым сжат Body, пустая строка - Body не сжат.
+	// Size в этом случае - размер сжатого содержимого
+	Encoding string
+	// Decoded сообщает, что Body распаковывается при чтении. Переход назад по такому Body
+	// начинает распаковку сначала, поэтому несколько диапазонов в одном запросе не отдаются
+	Decoded bool
+}
+
+// AcceptsEncoding проверяет, принимает ли клиент содержимое, сжатое алгоритмом codec,
+// по значению заголовка Accept-Encoding. Явно указанный алгоритм важнее "*"
+func AcceptsEncoding(header, codec string) bool {
+	wildcard := false
+
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(item, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		switch {
+		case name == codec, codec == "gzip" && name == "x-gzip":
+			return acceptable(params)
+		case name == "*":
+			wildcard = acceptable(params)
+		}
+	}
+
+	return wildcard
+}
+
+// acceptable проверяет вес кодировки в заголовке Accept-Encoding: кодировка с q=0 не принимается
+func acceptable(params string) bool {
+	weight, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+	if !found {
+		return true
+	}
+
+	q, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+
+	return err == nil && q > 0
 }
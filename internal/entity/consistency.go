@@ -0,0 +1,55 @@
+package entity
+
+import "time"
+
+const (
+	// ContentRefDocument ключ текущего содержимого документа
+	ContentRefDocument = "document"
+	// ContentRefVersion ключ содержимого версии документа
+	ContentRefVersion = "version"
+	// ContentRefSaga ключ содержимого, с которым работает незавершенная сага
+	ContentRefSaga = "saga"
+	// ContentRefUpload ключ файла, который собирается незавершенной загрузкой
+	ContentRefUpload = "upload"
+)
+
+// ContentRef ссылка из Postgres на содержимое в MongoDB (File = false) или MinIO (File = true)
+type ContentRef struct {
+	Source       string
+	DocumentUUID string
+	Version      int
+	ContentKey   string
+	File         bool
+	Broken       bool
+}
+
+// MissingVersion версия документа, содержимое которой не найдено
+type MissingVersion struct {
+	DocumentUUID string `json:"document_id"`
+	Version      int    `json:"version"`
+	ContentKey   string `json:"content_key"`
+}
+
+// ConsistencyReport результат проверки согласованности Postgres, MongoDB и MinIO
+type ConsistencyReport struct {
+	Repair     bool      `json:"repair"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// OrphanContents содержимое в MongoDB, на которое не ссылаются метаданные
+	OrphanContents []string `json:"orphan_contents"`
+	// OrphanFiles файлы в MinIO, на которые не ссылаются метаданные
+	OrphanFiles []string `json:"orphan_files"`
+	// BrokenDocuments документы, текущее содержимое которых не найдено
+	BrokenDocuments []string `json:"broken_documents"`
+	// RestoredDocuments документы, помеченные ранее, содержимое которых снова найдено
+	RestoredDocuments []string         `json:"restored_documents"`
+	MissingVersions   []MissingVersion `json:"missing_versions"`
+	// Repaired количество исправленных расхождений, RepairFailed - расхождений, исправить которые не удалось
+	Repaired     int `json:"repaired"`
+	RepairFailed int `json:"repair_failed"`
+}
+
+// Discrepancies возвращает общее количество найденных расхождений
+func (r ConsistencyReport) Discrepancies() int {
+	return len(r.OrphanContents) + len(r.OrphanFiles) + len(r.BrokenDocuments) + len(r.MissingVersions)
+}
@@ -0,0 +1,230 @@
+package entity
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	ContentOpEq       = "eq"
+	ContentOpNe       = "ne"
+	ContentOpGt       = "gt"
+	ContentOpGte      = "gte"
+	ContentOpLt       = "lt"
+	ContentOpLte      = "lte"
+	ContentOpExists   = "exists"
+	ContentOpContains = "contains"
+
+	// ContentIDField поле, в котором хранилище возвращает ключ содержимого
+	ContentIDField = "_id"
+
+	DefaultContentLimit = 20
+	MaxContentLimit     = 100
+
+	maxContentConditions = 20
+	maxContentFields     = 50
+	maxContentPathDepth  = 16
+)
+
+var contentOps = []string{
+	ContentOpEq, ContentOpNe, ContentOpGt, ContentOpGte, ContentOpLt, ContentOpLte, ContentOpExists, ContentOpContains,
+}
+
+// contentPathSegment допустимый сегмент пути к полю JSON документа.
+// Запрет символов $ и точек внутри сегмента не позволяет передать в запрос операторы MongoDB
+var contentPathSegment = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ContentQuery запрос поиска по содержимому JSON документов.
+// Условия Where объединяются условием И, Fields задает поля, которые вернутся в ответе
+// (по умолчанию документ возвращается целиком)
+type ContentQuery struct {
+	// Viewer логин пользователя, выполняющего запрос
+	Viewer string             `json:"-"`
+	Where  []ContentCondition `json:"where"`
+	Fields []string           `json:"fields"`
+	Cursor string             `json:"cursor"`
+	Limit  int                `json:"limit"`
+}
+
+// ContentCondition условие на поле JSON документа.
+// Path - путь к полю через точку (например, address.city).
+// Value - скалярное значение: строка, число, логическое значение или null.
+// Оператор exists принимает true или false, contains ищет значение в массиве
+type ContentCondition struct {
+	Path  string      `json:"path"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// ContentSearchItem найденный документ вместе с выбранными полями содержимого
+type ContentSearchItem struct {
+	Meta model.MetaDocument     `json:"meta"`
+	Json map[string]interface{} `json:"json"`
+}
+
+// ContentSearchResult страница результатов поиска по содержимому.
+// NextCursor пуст, если документов больше нет
+type ContentSearchResult struct {
+	Docs       []ContentSearchItem `json:"docs"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// Validate проверяет запрос и заполняет значения по умолчанию
+func (q *ContentQuery) Validate() error {
+	if len(q.Where) > maxContentConditions {
+		return contentError("where", fmt.Sprintf("допускается не более %d условий", maxContentConditions))
+	}
+
+	for _, condition := range q.Where {
+		if err := condition.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if len(q.Fields) > maxContentFields {
+		return contentError("fields", fmt.Sprintf("допускается не более %d полей", maxContentFields))
+	}
+
+	for _, field := range q.Fields {
+		if err := validateContentPath(field); err != nil {
+			return err
+		}
+	}
+
+	if q.Limit == 0 {
+		q.Limit = DefaultContentLimit
+	}
+
+	if q.Limit < 0 || q.Limit > MaxContentLimit {
+		return contentError("limit", fmt.Sprintf("допустимые значения от 1 до %d", MaxContentLimit))
+	}
+
+	if _, err := q.AfterKey(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AfterKey возвращает ключ содержимого, после которого продолжается поиск
+func (q *ContentQuery) AfterKey() (string, error) {
+	if q.Cursor == "" {
+		return "", nil
+	}
+
+	key, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil || len(key) == 0 {
+		return "", &ParamError{Param: "cursor", Reason: "курсор поврежден", Err: custom_error.ErrInvalidCursor}
+	}
+
+	return string(key), nil
+}
+
+// NewContentCursor кодирует ключ последнего просмотренного содержимого в курсор
+func NewContentCursor(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// Validate проверяет путь, оператор и значение условия
+func (c ContentCondition) Validate() error {
+	if err := validateContentPath(c.Path); err != nil {
+		return err
+	}
+
+	switch c.Op {
+	case ContentOpExists:
+		if _, ok := c.Value.(bool); !ok {
+			return contentError(c.Path, "оператор exists принимает значение true или false")
+		}
+	case ContentOpGt, ContentOpGte, ContentOpLt, ContentOpLte:
+		switch c.Value.(type) {
+		case float64, string:
+		default:
+			return contentError(c.Path, fmt.Sprintf("оператор %s принимает число или строку", c.Op))
+		}
+	case ContentOpEq, ContentOpNe, ContentOpContains:
+		switch c.Value.(type) {
+		case nil, bool, float64, string:
+		default:
+			return contentError(c.Path, "значение должно быть строкой, числом, логическим значением или null")
+		}
+	default:
+		return contentError(c.Path, fmt.Sprintf("оператор [%s] не поддерживается, допустимые операторы: %s", c.Op, strings.Join(contentOps, ", ")))
+	}
+
+	return nil
+}
+
+func validateContentPath(path string) error {
+	segments := strings.Split(path, ".")
+	if len(segments) > maxContentPathDepth {
+		return contentError(path, fmt.Sprintf("допускается не более %d уровней вложенности", maxContentPathDepth))
+	}
+
+	for _, segment := range segments {
+		if !contentPathSegment.MatchString(segment) {
+			return contentError(path, "путь должен состоять из латинских букв, цифр, символов _ и -, разделенных точкой")
+		}
+	}
+
+	if segments[0] == ContentIDField {
+		return contentError(path, "поле недоступно для поиска")
+	}
+
+	return nil
+}
+
+// contentError ошибка проверки запроса поиска по содержимому
+func contentError(param, reason string) *ParamError {
+	return &ParamError{Param: param, Reason: reason, Err: custom_error.ErrInvalidContentQuery}
+}
+
+// ProjectContent оставляет в содержимом только поля fields и ключ _id
+func ProjectContent(content map[string]interface{}, fields []string) map[string]interface{} {
+	if len(fields) == 0 {
+		return content
+	}
+
+	result := map[string]interface{}{ContentIDField: content[ContentIDField]}
+
+	for _, field := range fields {
+		projectPath(result, content, strings.Split(field, "."))
+	}
+
+	return result
+}
+
+// projectPath копирует значение по пути из source в target, создавая промежуточные объекты
+func projectPath(target, source map[string]interface{}, segments []string) {
+	value, ok := source[segments[0]]
+	if !ok {
+		return
+	}
+
+	if len(segments) == 1 {
+		target[segments[0]] = value
+		return
+	}
+
+	child, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	next, ok := target[segments[0]].(map[string]interface{})
+	if !ok {
+		next = make(map[string]interface{})
+		target[segments[0]] = next
+	}
+
+	projectPath(next, child, segments[1:])
+
+	if len(next) == 0 {
+		delete(target, segments[0])
+	}
+}
@@ -0,0 +1,176 @@
+package entity
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MatchContent проверяет содержимое на все условия так же, как их проверяет MongoDB:
+// путь проходит внутрь массивов, а условие на массив выполняется, если ему подходит любой элемент
+func MatchContent(content map[string]interface{}, conditions []ContentCondition) bool {
+	for _, condition := range conditions {
+		if !matchCondition(content, condition) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchCondition(content map[string]interface{}, condition ContentCondition) bool {
+	values := lookupPath(content, strings.Split(condition.Path, "."))
+
+	switch condition.Op {
+	case ContentOpExists:
+		exists, _ := condition.Value.(bool)
+
+		return (len(values) > 0) == exists
+	case ContentOpContains:
+		for _, value := range values {
+			if items, ok := value.([]interface{}); ok && anyEqual(items, condition.Value) {
+				return true
+			}
+		}
+
+		return false
+	case ContentOpEq:
+		return matchEqual(values, condition.Value)
+	case ContentOpNe:
+		return !matchEqual(values, condition.Value)
+	}
+
+	for _, value := range expandArrays(values) {
+		result, ok := compareContent(value, condition.Value)
+		if !ok {
+			continue
+		}
+
+		switch condition.Op {
+		case ContentOpGt:
+			ok = result > 0
+		case ContentOpGte:
+			ok = result >= 0
+		case ContentOpLt:
+			ok = result < 0
+		case ContentOpLte:
+			ok = result <= 0
+		}
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookupPath возвращает все значения, найденные по пути. Сегмент пути после массива
+// применяется к каждому его элементу, а числовой сегмент также выбирает элемент по индексу
+func lookupPath(value interface{}, segments []string) []interface{} {
+	if len(segments) == 0 {
+		return []interface{}{value}
+	}
+
+	segment, rest := segments[0], segments[1:]
+
+	switch node := value.(type) {
+	case map[string]interface{}:
+		child, ok := node[segment]
+		if !ok {
+			return nil
+		}
+
+		return lookupPath(child, rest)
+	case []interface{}:
+		var values []interface{}
+
+		if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(node) {
+			values = append(values, lookupPath(node[i], rest)...)
+		}
+
+		for _, item := range node {
+			if _, ok := item.(map[string]interface{}); ok {
+				values = append(values, lookupPath(item, segments)...)
+			}
+		}
+
+		return values
+	default:
+		return nil
+	}
+}
+
+// matchEqual сравнивает найденные значения со скалярным значением условия.
+// Условие на null выполняется и для отсутствующего поля
+func matchEqual(values []interface{}, expected interface{}) bool {
+	if expected == nil && len(values) == 0 {
+		return true
+	}
+
+	return anyEqual(expandArrays(values), expected)
+}
+
+// expandArrays добавляет к значениям элементы найденных массивов
+func expandArrays(values []interface{}) []interface{} {
+	expanded := make([]interface{}, 0, len(values))
+
+	for _, value := range values {
+		expanded = append(expanded, value)
+
+		if items, ok := value.([]interface{}); ok {
+			expanded = append(expanded, items...)
+		}
+	}
+
+	return expanded
+}
+
+func anyEqual(values []interface{}, expected interface{}) bool {
+	for _, value := range values {
+		if result, ok := compareContent(value, expected); ok && result == 0 {
+			return true
+		}
+
+		if value == nil && expected == nil {
+			return true
+		}
+
+		if b, ok := value.(bool); ok {
+			if e, ok := expected.(bool); ok && b == e {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compareContent сравнивает числа с числами и строки со строками.
+// Значения разных типов несравнимы, как в MongoDB
+func compareContent(value, expected interface{}) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		e, ok := expected.(float64)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case v < e:
+			return -1, true
+		case v > e:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case string:
+		e, ok := expected.(string)
+		if !ok {
+			return 0, false
+		}
+
+		return strings.Compare(v, e), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,157 @@
+package entity
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+)
+
+func TestContentConditionValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition ContentCondition
+		wantErr   bool
+	}{
+		{name: "nested path", condition: ContentCondition{Path: "address.city", Op: ContentOpEq, Value: "Moscow"}},
+		{name: "array index", condition: ContentCondition{Path: "items.0.sku", Op: ContentOpNe, Value: nil}},
+		{name: "number comparison", condition: ContentCondition{Path: "age", Op: ContentOpGt, Value: 18.0}},
+		{name: "string comparison", condition: ContentCondition{Path: "date", Op: ContentOpLte, Value: "2026-10-18"}},
+		{name: "exists", condition: ContentCondition{Path: "deleted", Op: ContentOpExists, Value: true}},
+		{name: "contains", condition: ContentCondition{Path: "tags", Op: ContentOpContains, Value: "urgent"}},
+		{name: "operator in path", condition: ContentCondition{Path: "$where", Op: ContentOpEq, Value: "1"}, wantErr: true},
+		{name: "operator in nested path", condition: ContentCondition{Path: "a.$gt", Op: ContentOpEq, Value: "1"}, wantErr: true},
+		{name: "empty path", condition: ContentCondition{Path: "", Op: ContentOpEq, Value: "1"}, wantErr: true},
+		{name: "empty segment", condition: ContentCondition{Path: "a..b", Op: ContentOpEq, Value: "1"}, wantErr: true},
+		{name: "content key", condition: ContentCondition{Path: "_id", Op: ContentOpEq, Value: "1"}, wantErr: true},
+		{name: "path too deep", condition: ContentCondition{Path: strings.Repeat("a.", maxContentPathDepth) + "a", Op: ContentOpEq, Value: "1"}, wantErr: true},
+		{name: "unknown operator", condition: ContentCondition{Path: "name", Op: "regex", Value: ".*"}, wantErr: true},
+		{name: "mongodb operator", condition: ContentCondition{Path: "name", Op: "$ne", Value: "a"}, wantErr: true},
+		{name: "object value", condition: ContentCondition{Path: "name", Op: ContentOpEq, Value: map[string]interface{}{"$ne": "a"}}, wantErr: true},
+		{name: "array value", condition: ContentCondition{Path: "tags", Op: ContentOpContains, Value: []interface{}{"a"}}, wantErr: true},
+		{name: "exists without bool", condition: ContentCondition{Path: "name", Op: ContentOpExists, Value: "yes"}, wantErr: true},
+		{name: "comparison with bool", condition: ContentCondition{Path: "age", Op: ContentOpLt, Value: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.condition.Validate()
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, custom_error.ErrInvalidContentQuery) {
+				t.Errorf("error = %v, want %v", err, custom_error.ErrInvalidContentQuery)
+			}
+		})
+	}
+}
+
+func TestContentQueryValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   ContentQuery
+		wantErr error
+	}{
+		{name: "defaults", query: ContentQuery{}},
+		{name: "invalid field", query: ContentQuery{Fields: []string{"name", "$expr"}}, wantErr: custom_error.ErrInvalidContentQuery},
+		{name: "too many conditions", query: ContentQuery{Where: make([]ContentCondition, maxContentConditions+1)}, wantErr: custom_error.ErrInvalidContentQuery},
+		{name: "limit too large", query: ContentQuery{Limit: MaxContentLimit + 1}, wantErr: custom_error.ErrInvalidContentQuery},
+		{name: "corrupted cursor", query: ContentQuery{Cursor: "%%%"}, wantErr: custom_error.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query.Validate()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if tt.query.Limit != DefaultContentLimit {
+					t.Errorf("limit = %d, want %d", tt.query.Limit, DefaultContentLimit)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	query := ContentQuery{Cursor: NewContentCursor("key-1")}
+	if key, err := query.AfterKey(); err != nil || key != "key-1" {
+		t.Errorf("AfterKey() = %q, %v, want key-1", key, err)
+	}
+}
+
+func TestMatchContent(t *testing.T) {
+	content := map[string]interface{}{
+		"name":    "report",
+		"pages":   12.0,
+		"draft":   false,
+		"tags":    []interface{}{"urgent", "finance"},
+		"address": map[string]interface{}{"city": "Moscow"},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a-1", "qty": 2.0},
+			map[string]interface{}{"sku": "b-2", "qty": 5.0},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		condition ContentCondition
+		want      bool
+	}{
+		{name: "eq string", condition: ContentCondition{Path: "name", Op: ContentOpEq, Value: "report"}, want: true},
+		{name: "eq bool", condition: ContentCondition{Path: "draft", Op: ContentOpEq, Value: false}, want: true},
+		{name: "eq nested", condition: ContentCondition{Path: "address.city", Op: ContentOpEq, Value: "Moscow"}, want: true},
+		{name: "eq array element", condition: ContentCondition{Path: "tags", Op: ContentOpEq, Value: "finance"}, want: true},
+		{name: "eq null for missing field", condition: ContentCondition{Path: "owner", Op: ContentOpEq, Value: nil}, want: true},
+		{name: "ne", condition: ContentCondition{Path: "name", Op: ContentOpNe, Value: "report"}, want: false},
+		{name: "gt number", condition: ContentCondition{Path: "pages", Op: ContentOpGt, Value: 10.0}, want: true},
+		{name: "lt number", condition: ContentCondition{Path: "pages", Op: ContentOpLt, Value: 12.0}, want: false},
+		{name: "string not comparable with number", condition: ContentCondition{Path: "pages", Op: ContentOpGte, Value: "1"}, want: false},
+		{name: "path through array", condition: ContentCondition{Path: "items.qty", Op: ContentOpGte, Value: 5.0}, want: true},
+		{name: "array index", condition: ContentCondition{Path: "items.0.sku", Op: ContentOpEq, Value: "b-2"}, want: false},
+		{name: "exists", condition: ContentCondition{Path: "address.city", Op: ContentOpExists, Value: true}, want: true},
+		{name: "not exists", condition: ContentCondition{Path: "address.zip", Op: ContentOpExists, Value: false}, want: true},
+		{name: "contains", condition: ContentCondition{Path: "tags", Op: ContentOpContains, Value: "urgent"}, want: true},
+		{name: "contains on scalar", condition: ContentCondition{Path: "name", Op: ContentOpContains, Value: "report"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchContent(content, []ContentCondition{tt.condition}); got != tt.want {
+				t.Errorf("MatchContent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectContent(t *testing.T) {
+	content := map[string]interface{}{
+		"_id":     "key-1",
+		"name":    "report",
+		"address": map[string]interface{}{"city": "Moscow", "zip": "101000"},
+		"tags":    []interface{}{"urgent"},
+	}
+
+	got := ProjectContent(content, []string{"address.city", "tags.0", "missing"})
+	want := map[string]interface{}{
+		"_id":     "key-1",
+		"address": map[string]interface{}{"city": "Moscow"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ProjectContent() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// KeyRotationReport результат перешифрования ключей данных активным мастер-ключом
+type KeyRotationReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// KeyID активный мастер-ключ, которым перешифрованы ключи данных
+	KeyID string `json:"key_id"`
+	// Rewrapped количество перешифрованных ключей данных
+	Rewrapped int `json:"rewrapped"`
+	// Failed количество ключей данных, которые не удалось перешифровать,
+	// например, потому что их мастер-ключ не задан в конфигурации
+	Failed int `json:"failed"`
+}
+
+// Complete проверяет, что все ключи данных перешифрованы
+func (r KeyRotationReport) Complete() bool {
+	return r.Failed == 0
+}
@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// changeEventID формат идентификатора события ленты: идентификатор записи Redis Stream
+var changeEventID = regexp.MustCompile(`^[0-9]+-[0-9]+$`)
+
+// ChangeEvent событие ленты изменений документов.
+// ID - позиция события в ленте, по ней клиент продолжает чтение после переподключения
+type ChangeEvent struct {
+	ID         string             `json:"id"`
+	Type       string             `json:"type"`
+	DocumentID string             `json:"document_id"`
+	Document   model.MetaDocument `json:"document"`
+	OccurredAt time.Time          `json:"occurred_at"`
+}
+
+// ValidateLastEventID проверяет позицию, с которой клиент продолжает чтение ленты
+func ValidateLastEventID(id string) error {
+	if id == "" || changeEventID.MatchString(id) {
+		return nil
+	}
+
+	return &ParamError{
+		Param:  "Last-Event-ID",
+		Reason: "ожидается идентификатор события из ленты",
+		Err:    custom_error.ErrInvalidLastEventID,
+	}
+}
+
+// ChangeEventAfter сообщает, что событие с идентификатором id следует в ленте после события after
+func ChangeEventAfter(id, after string) bool {
+	idMs, idSeq := splitChangeEventID(id)
+	afterMs, afterSeq := splitChangeEventID(after)
+
+	if idMs != afterMs {
+		return idMs > afterMs
+	}
+
+	return idSeq > afterSeq
+}
+
+func splitChangeEventID(id string) (uint64, uint64) {
+	ms, seq, _ := strings.Cut(id, "-")
+
+	msValue, _ := strconv.ParseUint(ms, 10, 64)
+	seqValue, _ := strconv.ParseUint(seq, 10, 64)
+
+	return msValue, seqValue
+}
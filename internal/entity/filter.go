@@ -0,0 +1,114 @@
+package entity
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+)
+
+const (
+	FilterOpEq     = "eq"
+	FilterOpNe     = "ne"
+	FilterOpIn     = "in"
+	FilterOpPrefix = "prefix"
+	FilterOpRange  = "range"
+
+	// maxFilters ограничивает количество фильтров в одном запросе
+	maxFilters = 20
+)
+
+// FilterFields допустимые для фильтрации поля и операторы для каждого из них
+var FilterFields = map[string][]string{
+	"name":       {FilterOpEq, FilterOpNe, FilterOpIn, FilterOpPrefix},
+	"mime":       {FilterOpEq, FilterOpNe, FilterOpIn, FilterOpPrefix},
+	"file":       {FilterOpEq, FilterOpNe},
+	"public":     {FilterOpEq, FilterOpNe},
+	"created_at": {FilterOpRange},
+}
+
+// filterFieldNames порядок полей для сообщений об ошибках
+var filterFieldNames = []string{"name", "mime", "file", "public", "created_at"}
+
+// DocumentFilter условие фильтрации списка документов.
+// Value используется операторами eq, ne и prefix, Values - оператором in,
+// From и To - оператором range (границы включительно, любая из них может отсутствовать)
+type DocumentFilter struct {
+	Field  string     `json:"field"`
+	Op     string     `json:"op"`
+	Value  string     `json:"value,omitempty"`
+	Values []string   `json:"values,omitempty"`
+	From   *time.Time `json:"from,omitempty"`
+	To     *time.Time `json:"to,omitempty"`
+}
+
+// ParamError ошибка проверки параметров запроса списка документов.
+// Reason содержит описание для клиента, Err - причину из custom_error
+type ParamError struct {
+	Param  string
+	Reason string
+	Err    error
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("%s: parameter [%s]", e.Err, e.Param)
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// filterError ошибка проверки фильтра по полю field
+func filterError(field, reason string) *ParamError {
+	return &ParamError{Param: field, Reason: reason, Err: custom_error.ErrInvalidFilter}
+}
+
+// Validate проверяет поле, оператор и значения фильтра
+func (f DocumentFilter) Validate() error {
+	ops, ok := FilterFields[f.Field]
+	if !ok {
+		return filterError(f.Field, fmt.Sprintf("поле недоступно для фильтрации, допустимые поля: %s", strings.Join(filterFieldNames, ", ")))
+	}
+
+	if !slices.Contains(ops, f.Op) {
+		return filterError(f.Field, fmt.Sprintf("оператор [%s] не поддерживается, допустимые операторы: %s", f.Op, strings.Join(ops, ", ")))
+	}
+
+	switch f.Op {
+	case FilterOpIn:
+		if len(f.Values) == 0 {
+			return filterError(f.Field, "оператор in требует непустой список values")
+		}
+	case FilterOpRange:
+		if f.From == nil && f.To == nil {
+			return filterError(f.Field, "оператор range требует хотя бы одну из границ from или to")
+		}
+
+		if f.From != nil && f.To != nil && f.From.After(*f.To) {
+			return filterError(f.Field, "граница from позже границы to")
+		}
+	}
+
+	if f.IsBool() {
+		if _, err := strconv.ParseBool(f.Value); err != nil {
+			return filterError(f.Field, "ожидается значение true или false")
+		}
+	}
+
+	return nil
+}
+
+// IsBool сообщает, что фильтр относится к логическому полю
+func (f DocumentFilter) IsBool() bool {
+	return f.Field == "file" || f.Field == "public"
+}
+
+// BoolValue значение фильтра логического поля, фильтр должен быть проверен Validate
+func (f DocumentFilter) BoolValue() bool {
+	value, _ := strconv.ParseBool(f.Value)
+
+	return value
+}
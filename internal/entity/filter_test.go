@@ -0,0 +1,83 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+)
+
+func TestDocumentFilterValidate(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		filter  DocumentFilter
+		wantErr bool
+	}{
+		{name: "eq", filter: DocumentFilter{Field: "name", Op: FilterOpEq, Value: "report"}},
+		{name: "prefix", filter: DocumentFilter{Field: "mime", Op: FilterOpPrefix, Value: "image/"}},
+		{name: "in", filter: DocumentFilter{Field: "mime", Op: FilterOpIn, Values: []string{"text/plain", "image/png"}}},
+		{name: "bool", filter: DocumentFilter{Field: "public", Op: FilterOpEq, Value: "true"}},
+		{name: "range with both bounds", filter: DocumentFilter{Field: "created_at", Op: FilterOpRange, From: &from, To: &to}},
+		{name: "range with one bound", filter: DocumentFilter{Field: "created_at", Op: FilterOpRange, To: &to}},
+		{name: "unknown field", filter: DocumentFilter{Field: "owner", Op: FilterOpEq, Value: "alice"}, wantErr: true},
+		{name: "json field", filter: DocumentFilter{Field: "json", Op: FilterOpEq, Value: "{}"}, wantErr: true},
+		{name: "operator not allowed for field", filter: DocumentFilter{Field: "file", Op: FilterOpPrefix, Value: "t"}, wantErr: true},
+		{name: "unknown operator", filter: DocumentFilter{Field: "name", Op: "like", Value: "%"}, wantErr: true},
+		{name: "in without values", filter: DocumentFilter{Field: "name", Op: FilterOpIn}, wantErr: true},
+		{name: "range without bounds", filter: DocumentFilter{Field: "created_at", Op: FilterOpRange}, wantErr: true},
+		{name: "range with reversed bounds", filter: DocumentFilter{Field: "created_at", Op: FilterOpRange, From: &to, To: &from}, wantErr: true},
+		{name: "invalid bool", filter: DocumentFilter{Field: "file", Op: FilterOpEq, Value: "yes"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, custom_error.ErrInvalidFilter) {
+				t.Fatalf("error = %v, want %v", err, custom_error.ErrInvalidFilter)
+			}
+
+			var paramErr *ParamError
+			if !errors.As(err, &paramErr) || paramErr.Param != tt.filter.Field || paramErr.Reason == "" {
+				t.Errorf("error = %#v, want parameter error for field [%s]", err, tt.filter.Field)
+			}
+		})
+	}
+}
+
+func TestDocumentListRequestFilters(t *testing.T) {
+	req := DocumentListRequest{
+		Key:     "name",
+		Value:   "report",
+		Filters: []DocumentFilter{{Field: "public", Op: FilterOpEq, Value: "false"}},
+	}
+
+	filters := req.AllFilters()
+	if len(filters) != 2 || filters[0].Field != "name" || filters[0].Op != FilterOpEq || filters[0].Value != "report" {
+		t.Fatalf("filters = %+v, want simple filter followed by request filters", filters)
+	}
+
+	if err := req.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req.Filters = make([]DocumentFilter, maxFilters)
+	for i := range req.Filters {
+		req.Filters[i] = DocumentFilter{Field: "name", Op: FilterOpNe, Value: "draft"}
+	}
+
+	if err := req.Validate(); !errors.Is(err, custom_error.ErrInvalidFilter) {
+		t.Errorf("error = %v, want %v for %d filters", err, custom_error.ErrInvalidFilter, maxFilters+1)
+	}
+}
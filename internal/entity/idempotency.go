@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"time"
+	"unicode"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+)
+
+// maxIdempotencyKeyLength максимальная длина ключа идемпотентности
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyRecord результат первого запроса с ключом идемпотентности.
+// Повтор запроса с тем же ключом и отпечатком содержимого получает сохраненный ответ
+type IdempotencyRecord struct {
+	Fingerprint  string    `json:"fingerprint"`
+	Status       int       `json:"status"`
+	Body         []byte    `json:"body"`
+	DocumentUUID string    `json:"document_uuid"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ValidateIdempotencyKey проверяет ключ идемпотентности из заголовка Idempotency-Key
+func ValidateIdempotencyKey(key string) error {
+	if len(key) > maxIdempotencyKeyLength {
+		return &ParamError{
+			Param:  "Idempotency-Key",
+			Reason: "длина ключа не должна превышать 255 символов",
+			Err:    custom_error.ErrInvalidIdempotencyKey,
+		}
+	}
+
+	for _, r := range key {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return &ParamError{
+				Param:  "Idempotency-Key",
+				Reason: "ключ должен состоять из печатных ASCII символов",
+				Err:    custom_error.ErrInvalidIdempotencyKey,
+			}
+		}
+	}
+
+	return nil
+}
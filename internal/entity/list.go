@@ -0,0 +1,198 @@
+package entity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+
+	DefaultListSort  = "name"
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// SortFields допустимые поля сортировки, значение сообщает, что поле содержит дату
+var SortFields = map[string]bool{
+	"name":       false,
+	"mime":       false,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// sortFieldNames порядок полей сортировки для сообщений об ошибках
+var sortFieldNames = []string{"name", "mime", "created_at", "updated_at"}
+
+type DocumentListRequest struct {
+	// Viewer логин пользователя, выполняющего запрос
+	Viewer string `json:"-"`
+	Login  string `json:"login"`
+	// Key и Value задают простой фильтр на равенство, аналогичный фильтру с оператором eq
+	Key     string           `json:"key"`
+	Value   string           `json:"value"`
+	Filters []DocumentFilter `json:"filters"`
+	// Sort поле сортировки, Order - направление (asc или desc)
+	Sort  string `json:"sort"`
+	Order string `json:"order"`
+	// Cursor значение next_cursor из предыдущего ответа, нельзя использовать вместе с Offset
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// DocumentList страница списка документов.
+// Total - количество документов, подходящих под фильтры, без учета постраничной выборки.
+// NextCursor пуст на последней странице
+type DocumentList struct {
+	Docs       []model.MetaDocument `json:"docs"`
+	Total      int64                `json:"total"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// ListCursor позиция последнего документа страницы.
+// Курсор привязан к сортировке, с которой он был получен
+type ListCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    uint   `json:"i"`
+}
+
+func (d *DocumentListRequest) LoginIsEmpty() bool {
+	return d.Login == ""
+}
+
+// AllFilters возвращает фильтры запроса вместе с простым фильтром Key и Value.
+// Все фильтры объединяются условием И
+func (d *DocumentListRequest) AllFilters() []DocumentFilter {
+	if d.Key == "" {
+		return d.Filters
+	}
+
+	filters := make([]DocumentFilter, 0, len(d.Filters)+1)
+	filters = append(filters, DocumentFilter{Field: d.Key, Op: FilterOpEq, Value: d.Value})
+
+	return append(filters, d.Filters...)
+}
+
+// Validate проверяет параметры запроса и заполняет значения по умолчанию
+func (d *DocumentListRequest) Validate() error {
+	filters := d.AllFilters()
+	if len(filters) > maxFilters {
+		return filterError("filters", fmt.Sprintf("допускается не более %d фильтров", maxFilters))
+	}
+
+	for _, filter := range filters {
+		if err := filter.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if d.Sort == "" {
+		d.Sort = DefaultListSort
+	}
+
+	if _, ok := SortFields[d.Sort]; !ok {
+		return paramError("sort", fmt.Sprintf("сортировка недоступна, допустимые поля: %s", strings.Join(sortFieldNames, ", ")))
+	}
+
+	d.Order = strings.ToLower(d.Order)
+	if d.Order == "" {
+		d.Order = SortOrderAsc
+	}
+
+	if d.Order != SortOrderAsc && d.Order != SortOrderDesc {
+		return paramError("order", "допустимые значения: asc, desc")
+	}
+
+	if d.Limit == 0 {
+		d.Limit = DefaultListLimit
+	}
+
+	if d.Limit < 0 || d.Limit > MaxListLimit {
+		return paramError("limit", fmt.Sprintf("допустимые значения от 1 до %d", MaxListLimit))
+	}
+
+	if d.Offset < 0 {
+		return paramError("offset", "значение не может быть отрицательным")
+	}
+
+	if d.Cursor == "" {
+		return nil
+	}
+
+	if d.Offset > 0 {
+		return paramError("cursor", "курсор нельзя использовать вместе с offset")
+	}
+
+	_, err := d.DecodeCursor()
+
+	return err
+}
+
+// DecodeCursor разбирает курсор запроса и проверяет, что он получен с той же сортировкой
+func (d *DocumentListRequest) DecodeCursor() (ListCursor, error) {
+	var cursor ListCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(d.Cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+
+	if err != nil {
+		return cursor, &ParamError{Param: "cursor", Reason: "курсор поврежден", Err: custom_error.ErrInvalidCursor}
+	}
+
+	if cursor.Sort != d.Sort || cursor.Order != d.Order {
+		return cursor, &ParamError{
+			Param:  "cursor",
+			Reason: "курсор получен с другой сортировкой",
+			Err:    custom_error.ErrInvalidCursor,
+		}
+	}
+
+	if SortFields[cursor.Sort] {
+		if _, err = time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return cursor, &ParamError{Param: "cursor", Reason: "курсор поврежден", Err: custom_error.ErrInvalidCursor}
+		}
+	}
+
+	return cursor, nil
+}
+
+// NewListCursor кодирует позицию документа в курсор для сортировки запроса
+func (d *DocumentListRequest) NewListCursor(document model.MetaDocument) string {
+	cursor := ListCursor{
+		Sort:  d.Sort,
+		Order: d.Order,
+		ID:    document.ID,
+	}
+
+	switch d.Sort {
+	case "name":
+		cursor.Value = document.Name
+	case "mime":
+		cursor.Value = document.Mime
+	case "created_at":
+		cursor.Value = document.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		cursor.Value = document.UpdatedAt.Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// paramError ошибка проверки параметра постраничной выборки или сортировки
+func paramError(param, reason string) *ParamError {
+	return &ParamError{Param: param, Reason: reason, Err: custom_error.ErrInvalidListParams}
+}
@@ -0,0 +1,141 @@
+package entity
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+func TestDocumentListRequestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       DocumentListRequest
+		wantSort  string
+		wantOrder string
+		wantLimit int
+		wantErr   error
+	}{
+		{name: "defaults", req: DocumentListRequest{}, wantSort: DefaultListSort, wantOrder: SortOrderAsc, wantLimit: DefaultListLimit},
+		{name: "order is case insensitive", req: DocumentListRequest{Sort: "created_at", Order: "DESC", Limit: 5}, wantSort: "created_at", wantOrder: SortOrderDesc, wantLimit: 5},
+		{name: "unknown sort field", req: DocumentListRequest{Sort: "json"}, wantErr: custom_error.ErrInvalidListParams},
+		{name: "unknown order", req: DocumentListRequest{Order: "up"}, wantErr: custom_error.ErrInvalidListParams},
+		{name: "limit too large", req: DocumentListRequest{Limit: MaxListLimit + 1}, wantErr: custom_error.ErrInvalidListParams},
+		{name: "negative limit", req: DocumentListRequest{Limit: -1}, wantErr: custom_error.ErrInvalidListParams},
+		{name: "negative offset", req: DocumentListRequest{Offset: -1}, wantErr: custom_error.ErrInvalidListParams},
+		{name: "cursor with offset", req: DocumentListRequest{Cursor: "e30", Offset: 10}, wantErr: custom_error.ErrInvalidListParams},
+		{name: "corrupted cursor", req: DocumentListRequest{Cursor: "not a cursor"}, wantErr: custom_error.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.req.Sort != tt.wantSort || tt.req.Order != tt.wantOrder || tt.req.Limit != tt.wantLimit {
+				t.Errorf("sort = %s, order = %s, limit = %d, want %s, %s, %d",
+					tt.req.Sort, tt.req.Order, tt.req.Limit, tt.wantSort, tt.wantOrder, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	document := model.MetaDocument{
+		ID:        42,
+		Name:      "report",
+		Mime:      "text/plain",
+		CreatedAt: time.Date(2026, 10, 18, 12, 30, 15, 123456789, time.UTC),
+		UpdatedAt: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		sort      string
+		wantValue string
+	}{
+		{sort: "name", wantValue: "report"},
+		{sort: "mime", wantValue: "text/plain"},
+		{sort: "created_at", wantValue: "2026-10-18T12:30:15.123456789Z"},
+		{sort: "updated_at", wantValue: "2026-10-19T08:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			req := DocumentListRequest{Sort: tt.sort, Order: SortOrderDesc}
+			req.Cursor = req.NewListCursor(document)
+
+			if err := req.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			cursor, err := req.DecodeCursor()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := ListCursor{Sort: tt.sort, Order: SortOrderDesc, Value: tt.wantValue, ID: document.ID}
+			if cursor != want {
+				t.Errorf("cursor = %+v, want %+v", cursor, want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorErrors(t *testing.T) {
+	encode := func(data string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(data))
+	}
+
+	newCursor := func(sort, order string) string {
+		req := DocumentListRequest{Sort: sort, Order: order}
+
+		return req.NewListCursor(model.MetaDocument{ID: 1, Name: "report", Mime: "text/plain"})
+	}
+
+	byName := DocumentListRequest{Sort: "name", Order: SortOrderAsc}
+
+	tests := []struct {
+		name   string
+		req    DocumentListRequest
+		cursor string
+	}{
+		{name: "not base64", req: byName, cursor: "%%%"},
+		{name: "not json", req: byName, cursor: encode("cursor")},
+		{name: "another sort field", req: byName, cursor: newCursor("mime", SortOrderAsc)},
+		{name: "another order", req: byName, cursor: newCursor("name", SortOrderDesc)},
+		{
+			name:   "invalid date value",
+			req:    DocumentListRequest{Sort: "created_at", Order: SortOrderAsc},
+			cursor: encode(`{"s":"created_at","o":"asc","v":"yesterday","i":1}`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Cursor = tt.cursor
+
+			_, err := tt.req.DecodeCursor()
+			if !errors.Is(err, custom_error.ErrInvalidCursor) {
+				t.Fatalf("error = %v, want %v", err, custom_error.ErrInvalidCursor)
+			}
+
+			var paramErr *ParamError
+			if !errors.As(err, &paramErr) || paramErr.Param != "cursor" {
+				t.Errorf("error = %#v, want parameter error for cursor", err)
+			}
+		})
+	}
+}
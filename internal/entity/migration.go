@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// ContentMigrationReport результат переноса содержимого JSON документов из MongoDB в Postgres
+type ContentMigrationReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Total количество содержимого в MongoDB
+	Total int `json:"total"`
+	// Copied количество содержимого, скопированного за этот запуск
+	Copied int `json:"copied"`
+	// Verified количество содержимого, которое есть в Postgres и совпадает с MongoDB, включая скопированное
+	Verified int `json:"verified"`
+	// Mismatched ключи содержимого, которое есть в Postgres, но отличается от MongoDB
+	Mismatched []string `json:"mismatched"`
+	// Failed ключи содержимого, которое не удалось прочитать или скопировать
+	Failed []string `json:"failed"`
+}
+
+// Complete проверяет, что все содержимое перенесено и совпадает с исходным
+func (r ContentMigrationReport) Complete() bool {
+	return len(r.Mismatched) == 0 && len(r.Failed) == 0 && r.Verified == r.Total
+}
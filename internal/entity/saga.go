@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"fmt"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// SagaListRequest параметры постраничной выборки зависших саг
+type SagaListRequest struct {
+	Limit  int
+	Offset int
+}
+
+// SagaList страница зависших саг. Total - общее количество зависших саг
+type SagaList struct {
+	Sagas []model.Saga `json:"sagas"`
+	Total int64        `json:"total"`
+}
+
+// SagaDetails сага вместе с журналом ее шагов
+type SagaDetails struct {
+	Saga  model.Saga       `json:"saga"`
+	Steps []model.SagaStep `json:"steps"`
+}
+
+// Validate проверяет параметры запроса и заполняет значения по умолчанию
+func (s *SagaListRequest) Validate() error {
+	if s.Limit == 0 {
+		s.Limit = DefaultListLimit
+	}
+
+	if s.Limit < 0 || s.Limit > MaxListLimit {
+		return paramError("limit", fmt.Sprintf("допустимые значения от 1 до %d", MaxListLimit))
+	}
+
+	if s.Offset < 0 {
+		return paramError("offset", "значение не может быть отрицательным")
+	}
+
+	return nil
+}
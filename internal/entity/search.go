@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const maxSearchQueryLength = 256
+
+// SearchRequest запрос полнотекстового поиска.
+// Query поддерживает синтаксис websearch: слова в кавычках, OR и исключение через минус
+type SearchRequest struct {
+	// Viewer логин пользователя, выполняющего запрос
+	Viewer string
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// SearchHit найденный документ. Snippet содержит экранированные для HTML фрагменты текста,
+// в которых найденные слова выделены тегами <b> и </b>
+type SearchHit struct {
+	Meta    model.MetaDocument `json:"meta"`
+	Rank    float64            `json:"rank"`
+	Snippet string             `json:"snippet"`
+}
+
+// Validate проверяет запрос и заполняет значения по умолчанию
+func (s *SearchRequest) Validate() error {
+	s.Query = strings.TrimSpace(s.Query)
+	if s.Query == "" {
+		return searchError("q", "поисковый запрос не может быть пустым")
+	}
+
+	if utf8.RuneCountInString(s.Query) > maxSearchQueryLength {
+		return searchError("q", fmt.Sprintf("длина поискового запроса не должна превышать %d символов", maxSearchQueryLength))
+	}
+
+	if s.Limit == 0 {
+		s.Limit = DefaultListLimit
+	}
+
+	if s.Limit < 0 || s.Limit > MaxListLimit {
+		return searchError("limit", fmt.Sprintf("допустимые значения от 1 до %d", MaxListLimit))
+	}
+
+	if s.Offset < 0 {
+		return searchError("offset", "значение не может быть отрицательным")
+	}
+
+	return nil
+}
+
+// searchError ошибка проверки запроса полнотекстового поиска
+func searchError(param, reason string) *ParamError {
+	return &ParamError{Param: param, Reason: reason, Err: custom_error.ErrInvalidSearchQuery}
+}
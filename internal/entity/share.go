@@ -0,0 +1,109 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	// SharePath путь, по которому документ отдается по токену ссылки
+	SharePath = "/api/share/"
+
+	minSharePasswordLength = 4
+	maxSharePasswordLength = 128
+)
+
+// ShareRequest параметры новой ссылки на документ.
+// Пустые ExpiresAt и Password и нулевой MaxDownloads снимают соответствующее ограничение
+type ShareRequest struct {
+	ExpiresAt    *time.Time `json:"expires_at"`
+	MaxDownloads int        `json:"max_downloads"`
+	Password     string     `json:"password"`
+}
+
+// Validate проверяет параметры ссылки на момент now
+func (s *ShareRequest) Validate(now time.Time) error {
+	if s.ExpiresAt != nil && !s.ExpiresAt.After(now) {
+		return shareError("expires_at", "срок действия должен быть в будущем")
+	}
+
+	if s.MaxDownloads < 0 {
+		return shareError("max_downloads", "значение не может быть отрицательным")
+	}
+
+	if s.Password != "" && (len(s.Password) < minSharePasswordLength || len(s.Password) > maxSharePasswordLength) {
+		return shareError("password", fmt.Sprintf("длина пароля от %d до %d символов", minSharePasswordLength, maxSharePasswordLength))
+	}
+
+	return nil
+}
+
+// NewShare созданная ссылка. Token и адрес URL возвращаются только при создании ссылки
+type NewShare struct {
+	Share model.ShareLink `json:"share"`
+	Token string          `json:"token"`
+	URL   string          `json:"url"`
+}
+
+// ShareClient клиент, обратившийся к документу по ссылке, для журнала обращений
+type ShareClient struct {
+	IP        string
+	UserAgent string
+}
+
+// OpenShareRequest запрос документа по токену ссылки
+type OpenShareRequest struct {
+	Token          string
+	Password       string
+	AcceptEncoding string
+	// Grant разрешение продолжить скачивание, выданное клиенту вместе с засчитанным скачиванием
+	Grant string
+	// Continuation запрос продолжает скачивание: запрашивает диапазон содержимого
+	Continuation bool
+	Client       ShareClient
+}
+
+// SharedDocument документ, полученный по ссылке. Непустой Grant выдается, когда скачивание засчитано,
+// и позволяет клиенту до GrantExpiresAt докачивать документ, не расходуя скачивания ссылки
+type SharedDocument struct {
+	Content        DocumentContent
+	Grant          string
+	GrantExpiresAt time.Time
+}
+
+// ShareAccessListRequest параметры журнала обращений по ссылке
+type ShareAccessListRequest struct {
+	Limit  int
+	Offset int
+}
+
+// ShareAccessList страница журнала обращений. Total - количество обращений без учета постраничной выборки
+type ShareAccessList struct {
+	Accesses []model.ShareAccess `json:"accesses"`
+	Total    int64               `json:"total"`
+}
+
+// Validate проверяет параметры запроса и заполняет значения по умолчанию
+func (s *ShareAccessListRequest) Validate() error {
+	if s.Limit == 0 {
+		s.Limit = DefaultListLimit
+	}
+
+	if s.Limit < 0 || s.Limit > MaxListLimit {
+		return paramError("limit", fmt.Sprintf("допустимые значения от 1 до %d", MaxListLimit))
+	}
+
+	if s.Offset < 0 {
+		return paramError("offset", "значение не может быть отрицательным")
+	}
+
+	return nil
+}
+
+// shareError ошибка проверки параметров ссылки
+func shareError(param, reason string) *ParamError {
+	return &ParamError{Param: param, Reason: reason, Err: custom_error.ErrInvalidShare}
+}
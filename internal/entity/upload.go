@@ -0,0 +1,110 @@
+package entity
+
+import (
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// UploadMimeType тип файла загрузки, если клиент его не передал
+const UploadMimeType = "application/octet-stream"
+
+// UploadRequest параметры новой загрузки файла. Из них создается документ, когда получен весь файл
+type UploadRequest struct {
+	Length int64
+	Name   string
+	Mime   string
+	Public bool
+	Grant  []string
+}
+
+// DirectUploadRequest параметры загрузки файла по подписанной ссылке.
+// Если указан DocumentID, файл становится новой версией документа и параметры документа не меняются
+type DirectUploadRequest struct {
+	DocumentID string   `json:"document_id"`
+	Name       string   `json:"name"`
+	Mime       string   `json:"mime"`
+	Public     bool     `json:"public"`
+	Grant      []string `json:"grant"`
+	// Size размер файла в байтах, который клиент загрузит по ссылке
+	Size int64 `json:"size"`
+	// SHA256 хеш файла в hex. Если он указан, клиент передает его вместе с файлом в заголовках из link.headers,
+	// хранилище само проверяет файл при записи, и при подтверждении загрузки файл не перечитывается сервером
+	SHA256 string `json:"sha256"`
+}
+
+// Validate проверяет параметры загрузки
+func (r *DirectUploadRequest) Validate() error {
+	if r.Size < 0 {
+		return uploadError("size", "значение не может быть отрицательным")
+	}
+
+	if r.DocumentID == "" && r.Name == "" {
+		return uploadError("name", "не указано имя файла")
+	}
+
+	if r.SHA256 != "" {
+		r.SHA256 = strings.ToLower(r.SHA256)
+
+		if !validSHA256(r.SHA256) {
+			return uploadError("sha256", "ожидается SHA-256 в hex длиной 64 символа")
+		}
+	}
+
+	return nil
+}
+
+// DirectUploadCompletion подтверждение клиента, что файл загружен по подписанной ссылке
+type DirectUploadCompletion struct {
+	// SHA256 хеш загруженного файла в hex, с которым сверяется файл в хранилище
+	SHA256 string `json:"sha256"`
+}
+
+// Validate проверяет хеш файла и приводит его к нижнему регистру
+func (c *DirectUploadCompletion) Validate() error {
+	c.SHA256 = strings.ToLower(c.SHA256)
+
+	if !validSHA256(c.SHA256) {
+		return uploadError("sha256", "ожидается SHA-256 в hex длиной 64 символа")
+	}
+
+	return nil
+}
+
+// PresignedURL подписанная ссылка для передачи файла между клиентом и хранилищем без участия сервера
+type PresignedURL struct {
+	URL    string `json:"url"`
+	Method string `json:"method"`
+	// Headers заголовки, которые подписаны вместе со ссылкой и должны быть переданы в запросе
+	Headers   map[string]string `json:"headers,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// DirectUpload загрузка файла по подписанной ссылке вместе со ссылкой для записи файла
+type DirectUpload struct {
+	Upload model.Upload
+	Link   PresignedURL
+}
+
+// DirectUploadResult документ, созданный или обновленный из загруженного по ссылке файла
+type DirectUploadResult struct {
+	DocumentID string `json:"document_id"`
+	Version    int    `json:"version"`
+	Size       int64  `json:"size"`
+	Digest     string `json:"digest"`
+}
+
+// validSHA256 проверяет, что digest - SHA-256 в hex
+func validSHA256(digest string) bool {
+	_, err := hex.DecodeString(digest)
+
+	return err == nil && len(digest) == 64
+}
+
+// uploadError ошибка проверки параметров загрузки
+func uploadError(param, reason string) *ParamError {
+	return &ParamError{Param: param, Reason: reason, Err: custom_error.ErrInvalidUpload}
+}
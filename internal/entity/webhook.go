@@ -0,0 +1,126 @@
+package entity
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	maxWebhookURLLength    = 2048
+	minWebhookSecretLength = 16
+	maxWebhookSecretLength = 256
+)
+
+// WebhookEvents типы событий, на которые можно подписаться
+var WebhookEvents = []string{model.EventDocumentCreated, model.EventDocumentUpdated, model.EventDocumentDeleted}
+
+// WebhookDeliveryStatuses состояния доставки для фильтра журнала доставок
+var WebhookDeliveryStatuses = []string{
+	model.WebhookDeliveryPending,
+	model.WebhookDeliveryRetrying,
+	model.WebhookDeliveryDelivered,
+	model.WebhookDeliveryDead,
+}
+
+// WebhookRequest запрос регистрации webhook.
+// Пустой список Events подписывает на все события, пустой Secret генерируется сервером
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// NewWebhook зарегистрированный webhook. Secret возвращается только при регистрации
+type NewWebhook struct {
+	Webhook model.WebhookSubscription `json:"webhook"`
+	Secret  string                    `json:"secret"`
+}
+
+// DeliveryListRequest параметры журнала доставок webhook
+type DeliveryListRequest struct {
+	Status string
+	Limit  int
+	Offset int
+}
+
+// DeliveryList страница журнала доставок. Total - количество доставок без учета постраничной выборки
+type DeliveryList struct {
+	Deliveries []model.WebhookDelivery `json:"deliveries"`
+	Total      int64                   `json:"total"`
+}
+
+// Validate проверяет адрес, события и секрет webhook
+func (w *WebhookRequest) Validate() error {
+	if len(w.URL) > maxWebhookURLLength {
+		return webhookError("url", fmt.Sprintf("длина адреса не может превышать %d символов", maxWebhookURLLength))
+	}
+
+	target, err := url.Parse(w.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return webhookError("url", "ожидается абсолютный адрес http или https")
+	}
+
+	// адрес, заданный именем, проверяется при каждой доставке после разрешения имени
+	host := target.Hostname()
+	if ip := net.ParseIP(host); strings.EqualFold(host, "localhost") || (ip != nil && !PublicIP(ip)) {
+		return webhookError("url", "адрес получателя должен быть доступен из интернета")
+	}
+
+	for _, event := range w.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return webhookError("events", fmt.Sprintf("событие [%s] не поддерживается, допустимые события: %s", event, strings.Join(WebhookEvents, ", ")))
+		}
+	}
+
+	w.Events = slices.Compact(slices.Sorted(slices.Values(w.Events)))
+
+	if w.Secret != "" && (len(w.Secret) < minWebhookSecretLength || len(w.Secret) > maxWebhookSecretLength) {
+		return webhookError("secret", fmt.Sprintf("длина секрета от %d до %d символов", minWebhookSecretLength, maxWebhookSecretLength))
+	}
+
+	return nil
+}
+
+// PublicIP сообщает, что адрес не относится к локальной, частной или служебной сети.
+// Получатели webhook ограничены такими адресами, чтобы доставки нельзя было направить во внутреннюю сеть сервиса
+func PublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// Validate проверяет параметры запроса и заполняет значения по умолчанию
+func (d *DeliveryListRequest) Validate() error {
+	if d.Status != "" && !slices.Contains(WebhookDeliveryStatuses, d.Status) {
+		return webhookError("status", fmt.Sprintf("допустимые значения: %s", strings.Join(WebhookDeliveryStatuses, ", ")))
+	}
+
+	if d.Limit == 0 {
+		d.Limit = DefaultListLimit
+	}
+
+	if d.Limit < 0 || d.Limit > MaxListLimit {
+		return paramError("limit", fmt.Sprintf("допустимые значения от 1 до %d", MaxListLimit))
+	}
+
+	if d.Offset < 0 {
+		return paramError("offset", "значение не может быть отрицательным")
+	}
+
+	return nil
+}
+
+// webhookError ошибка проверки параметров webhook
+func webhookError(param, reason string) *ParamError {
+	return &ParamError{Param: param, Reason: reason, Err: custom_error.ErrInvalidWebhook}
+}
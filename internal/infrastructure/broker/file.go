@@ -0,0 +1,67 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var _ Sender = (*FileSender)(nil)
+
+// FileSender записывает сообщения в файл в формате JSON Lines.
+// Предназначен для локальной разработки: файл можно читать, например, через tail -f
+type FileSender struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// fileRecord строка файла сообщений
+type fileRecord struct {
+	ID      string          `json:"id"`
+	Subject string          `json:"subject"`
+	SentAt  time.Time       `json:"sent_at"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func NewFileSender(path string) (*FileSender, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSender{file: file}, nil
+}
+
+// Send дописывает сообщение в файл и сбрасывает его на диск до подтверждения
+func (s *FileSender) Send(_ context.Context, msg Message) error {
+	line, err := json.Marshal(fileRecord{
+		ID:      msg.ID,
+		Subject: msg.Subject,
+		SentAt:  time.Now().UTC(),
+		Data:    msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err = s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write message [%s]: %w", msg.ID, err)
+	}
+
+	return s.file.Sync()
+}
+
+func (s *FileSender) Close() error {
+	return s.file.Close()
+}
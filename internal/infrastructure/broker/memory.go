@@ -0,0 +1,42 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+var _ Sender = (*MemorySender)(nil)
+
+// MemorySender передает сообщения подписчикам внутри процесса.
+// Сообщения не сохраняются: подписчик получает только то, что отправлено после подписки
+type MemorySender struct {
+	mu       sync.RWMutex
+	handlers []func(msg Message)
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+// Subscribe добавляет обработчик сообщений. Обработчик вызывается синхронно внутри Send
+func (s *MemorySender) Subscribe(handler func(msg Message)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers = append(s.handlers, handler)
+}
+
+func (s *MemorySender) Send(_ context.Context, msg Message) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, handler := range s.handlers {
+		handler(msg)
+	}
+
+	return nil
+}
+
+func (s *MemorySender) Close() error {
+	return nil
+}
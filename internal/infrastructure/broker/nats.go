@@ -0,0 +1,69 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+)
+
+var _ Sender = (*NatsSender)(nil)
+
+// NatsSender публикует сообщения в поток NATS JetStream.
+// Публикация подтверждается сервером, а идентификатор сообщения передается в заголовке Nats-Msg-Id,
+// поэтому JetStream сам отбрасывает повторы в пределах окна дедупликации потока
+type NatsSender struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+func NewNatsSender(cfg *config.ConfigBroker) (*NatsSender, error) {
+	log.Info("Start connection to NATS")
+
+	conn, err := nats.Connect(cfg.NatsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.PublishTimeout)
+	defer cancel()
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.NatsStream,
+		Subjects: []string{cfg.Subject(">")},
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create NATS stream [%s]: %w", cfg.NatsStream, err)
+	}
+
+	log.Info("Successfully connected to NATS")
+
+	return &NatsSender{
+		conn: conn,
+		js:   js,
+	}, nil
+}
+
+func (s *NatsSender) Send(ctx context.Context, msg Message) error {
+	_, err := s.js.Publish(ctx, msg.Subject, msg.Data, jetstream.WithMsgID(msg.ID))
+	if err != nil {
+		return fmt.Errorf("failed to publish message [%s] to NATS: %w", msg.ID, err)
+	}
+
+	return nil
+}
+
+func (s *NatsSender) Close() error {
+	return s.conn.Drain()
+}
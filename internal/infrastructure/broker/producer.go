@@ -1 +1,27 @@
 package broker
+
+import (
+	"fmt"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+)
+
+const (
+	TypeNats   = "nats"
+	TypeFile   = "file"
+	TypeMemory = "memory"
+)
+
+// NewSender создает отправителя сообщений для брокера из конфигурации
+func NewSender(cfg *config.Config) (Sender, error) {
+	switch cfg.ConfigBroker.Type {
+	case TypeNats:
+		return NewNatsSender(cfg.ConfigBroker)
+	case TypeFile:
+		return NewFileSender(cfg.ConfigBroker.FilePath)
+	case TypeMemory:
+		return NewMemorySender(), nil
+	default:
+		return nil, fmt.Errorf("unknown broker type [%s]", cfg.ConfigBroker.Type)
+	}
+}
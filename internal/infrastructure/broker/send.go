@@ -1 +1,10 @@
 package broker
+
+// Message сообщение для публикации.
+// ID уникален для события и не меняется при повторной отправке,
+// получатели используют его для отбрасывания повторов
+type Message struct {
+	ID      string
+	Subject string
+	Data    []byte
+}
@@ -1,5 +1,11 @@
 package broker
 
+import "context"
+
+// Sender публикует сообщения в брокер.
+// Send возвращает ошибку, если брокер не подтвердил получение сообщения,
+// тогда сообщение будет отправлено повторно
 type Sender interface {
-	Send()
+	Send(ctx context.Context, msg Message) error
+	Close() error
 }
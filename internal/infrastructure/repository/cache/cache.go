@@ -3,30 +3,43 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"strconv"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/AlexJudin/DocumentCacheServer/config"
 	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/compression"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/encryption"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
 )
 
 var _ Document = (*DocumentRepo)(nil)
 
+// DocumentRepo кэш документов в Redis. Если включено шифрование, содержимое документов
+// хранится в кэше зашифрованным ключом, полученным из активного мастер-ключа.
+// Если включено сжатие, содержимое сжимается до шифрования и возвращается сжатым
+// вместе с алгоритмом в поле Encoding метаданных
 type DocumentRepo struct {
 	Cfg         *config.Config
 	RedisClient *redis.Client
+	Keyring     *encryption.Keyring
 }
 
-func NewDocumentRepo(cfg *config.Config, redisClient *redis.Client) *DocumentRepo {
+func NewDocumentRepo(cfg *config.Config, redisClient *redis.Client, keyring *encryption.Keyring) *DocumentRepo {
 	return &DocumentRepo{
 		Cfg:         cfg,
 		RedisClient: redisClient,
+		Keyring:     keyring,
 	}
 }
 
-func (r *DocumentRepo) Set(ctx context.Context, uuid, mime string, data interface{}, isFile bool) {
+func (r *DocumentRepo) Set(ctx context.Context, document model.MetaDocument, data interface{}) {
+	uuid := document.UUID
+
 	log.Infof("setting document [%s] to cache", uuid)
 
 	var (
@@ -44,9 +57,7 @@ func (r *DocumentRepo) Set(ctx context.Context, uuid, mime string, data interfac
 		jsonDocMap = value
 	}
 
-	if isFile {
-		metadata["size"] = len(file)
-	} else {
+	if !document.File {
 		result := entity.ApiResponse{
 			Data: jsonDocMap,
 		}
@@ -58,13 +69,57 @@ func (r *DocumentRepo) Set(ctx context.Context, uuid, mime string, data interfac
 		}
 	}
 
+	size := len(file)
+
+	// JSON документ хранится в кэше ответом API в JSON, какой бы тип ни был указан в метаданных
+	mimeType := document.Mime
+	if !document.File {
+		mimeType = entity.DefaultMimeType
+	}
+
+	if codec := compression.Codec(r.Cfg.ConfigCompression, mimeType, int64(size)); codec != "" {
+		file, err = compression.Compress(codec, file)
+		if err != nil {
+			log.Debugf("failed to compress document data for cache: %+v", err)
+			return
+		}
+
+		metadata["encoding"] = codec
+	}
+
+	if r.Keyring.Enabled() {
+		var keyID string
+
+		file, keyID, err = r.Keyring.SealCache(file, []byte(uuid))
+		if err != nil {
+			log.Debugf("failed to encrypt document data for cache: %+v", err)
+			return
+		}
+
+		metadata["key_id"] = keyID
+	}
+
 	err = r.RedisClient.Set(ctx, "file:data:"+uuid, file, r.Cfg.CacheTTL).Err()
 	if err != nil {
 		log.Debugf("failed to store document data in cache: %+v", err)
 		return
 	}
 
-	metadata["type"] = mime
+	// права доступа храним вместе с содержимым, чтобы чтение из кэша
+	// проверялось так же, как чтение из хранилища
+	grant, err := json.Marshal(document.Grant)
+	if err != nil {
+		log.Debugf("failed to marshal document grant: %+v", err)
+		return
+	}
+
+	metadata["type"] = document.Mime
+	metadata["owner"] = document.Owner
+	metadata["public"] = document.Public
+	metadata["grant"] = grant
+	metadata["size"] = size
+	metadata["hash"] = document.Hash
+	metadata["modified"] = document.UpdatedAt.UnixNano()
 	metadata["created"] = time.Now().Unix()
 
 	err = r.RedisClient.HSet(ctx, "file:meta:"+uuid, metadata).Err()
@@ -73,39 +128,86 @@ func (r *DocumentRepo) Set(ctx context.Context, uuid, mime string, data interfac
 		return
 	}
 
+	err = r.RedisClient.Expire(ctx, "file:meta:"+uuid, r.Cfg.CacheTTL).Err()
+	if err != nil {
+		log.Debugf("failed to set document metadata ttl in cache: %+v", err)
+		return
+	}
+
 	log.Infof("document [%s] successfully cached", uuid)
 }
 
-func (r *DocumentRepo) Get(ctx context.Context, uuid string) ([]byte, string, bool) {
+func (r *DocumentRepo) Get(ctx context.Context, uuid string) ([]byte, model.MetaDocument, bool) {
 	log.Infof("retrieving document [%s] from cache", uuid)
 
+	document := model.MetaDocument{UUID: uuid}
+
 	file, err := r.RedisClient.Get(ctx, "file:data:"+uuid).Bytes()
 	if err != nil {
 		log.Debugf("failed to retrieve document data from cache: %+v", err)
-		return nil, "", false
+		return nil, document, false
 	}
 
 	meta, err := r.RedisClient.HGetAll(ctx, "file:meta:"+uuid).Result()
 	if err != nil {
 		log.Debugf("failed to retrieve document metadata from cache: %+v", err)
-		return nil, "", false
+		return nil, document, false
 	}
 
 	mime, ok := meta["type"]
 	if !ok {
 		log.Debug("document metadata missing MIME type")
-		return nil, "", false
+		return nil, document, false
+	}
+
+	var grant pq.StringArray
+	if err = json.Unmarshal([]byte(meta["grant"]), &grant); err != nil {
+		log.Debugf("failed to unmarshal document grant from cache: %+v", err)
+		return nil, document, false
+	}
+
+	// запись, зашифрованная мастер-ключом, которого уже нет в конфигурации, считается отсутствующей
+	if keyID := meta["key_id"]; keyID != "" {
+		file, err = r.Keyring.OpenCache(file, []byte(uuid), keyID)
+		if err != nil {
+			log.Debugf("failed to decrypt document data from cache: %+v", err)
+			return nil, document, false
+		}
+	}
+
+	document.Mime = mime
+	document.Owner = meta["owner"]
+	document.Public = meta["public"] == "1"
+	document.Grant = grant
+	document.Hash = meta["hash"]
+	document.Size = int64(len(file))
+
+	// для сжатого содержимого размер в метаданных - размер без сжатия
+	if encoding := meta["encoding"]; encoding != "" {
+		size, err := strconv.ParseInt(meta["size"], 10, 64)
+		if err != nil {
+			log.Debugf("failed to parse compressed document size from cache: %+v", err)
+			return nil, document, false
+		}
+
+		document.Encoding = encoding
+		document.Size = size
+	}
+
+	modified, err := strconv.ParseInt(meta["modified"], 10, 64)
+	if err == nil && modified > 0 {
+		document.UpdatedAt = time.Unix(0, modified)
 	}
 
 	log.Infof("document [%s] successfully retrieved from cache", uuid)
 
-	return file, mime, true
+	return file, document, true
 }
 
 func (r *DocumentRepo) Delete(ctx context.Context, uuid string) {
 	log.Infof("deleting document [%s] from cache", uuid)
 
-	err := r.RedisClient.Del(ctx, uuid).Err()
+	err := r.RedisClient.Del(ctx, "file:data:"+uuid, "file:meta:"+uuid).Err()
 	if err != nil {
 		log.Debugf("failed to delete document from cache: %+v", err)
 		return
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// idempotencyInProgress значение ключа, пока первый запрос еще выполняется
+const idempotencyInProgress = "in_progress"
+
+var _ Idempotency = (*IdempotencyRepo)(nil)
+
+// IdempotencyRepo хранит результаты запросов с ключом идемпотентности в Redis
+type IdempotencyRepo struct {
+	RedisClient *redis.Client
+}
+
+func NewIdempotencyRepo(redisClient *redis.Client) *IdempotencyRepo {
+	return &IdempotencyRepo{
+		RedisClient: redisClient,
+	}
+}
+
+// Reserve занимает ключ на время выполнения запроса.
+// Возвращает false, если ключ уже занят другим запросом или хранит его результат
+func (r *IdempotencyRepo) Reserve(ctx context.Context, login, key string, ttl time.Duration) (bool, error) {
+	return r.RedisClient.SetNX(ctx, idempotencyKey(login, key), idempotencyInProgress, ttl).Result()
+}
+
+func (r *IdempotencyRepo) Get(ctx context.Context, login, key string) (entity.IdempotencyRecord, error) {
+	var record entity.IdempotencyRecord
+
+	data, err := r.RedisClient.Get(ctx, idempotencyKey(login, key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return record, custom_error.ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		return record, err
+	}
+
+	if data == idempotencyInProgress {
+		return record, custom_error.ErrIdempotencyKeyInProgress
+	}
+
+	if err = json.Unmarshal([]byte(data), &record); err != nil {
+		return record, err
+	}
+
+	return record, nil
+}
+
+func (r *IdempotencyRepo) Save(ctx context.Context, login, key string, record entity.IdempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return r.RedisClient.Set(ctx, idempotencyKey(login, key), data, ttl).Err()
+}
+
+func (r *IdempotencyRepo) Delete(ctx context.Context, login, key string) error {
+	return r.RedisClient.Del(ctx, idempotencyKey(login, key)).Err()
+}
+
+// idempotencyKey ключи разных пользователей не пересекаются
+func idempotencyKey(login, key string) string {
+	return "idempotency:" + login + ":" + key
+}
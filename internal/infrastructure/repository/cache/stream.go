@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+const (
+	changeStreamKey = "documents:changes"
+	// changeEventField поле записи потока с событием в формате JSON
+	changeEventField = "event"
+)
+
+var _ ChangeStream = (*ChangeStreamRepo)(nil)
+
+// ChangeStreamRepo лента изменений документов в Redis Stream.
+// Поток общий для всех экземпляров сервиса и хранит последние MaxLen событий,
+// поэтому клиент может продолжить чтение с последнего полученного события
+type ChangeStreamRepo struct {
+	Cfg         *config.Config
+	RedisClient *redis.Client
+}
+
+func NewChangeStreamRepo(cfg *config.Config, redisClient *redis.Client) *ChangeStreamRepo {
+	return &ChangeStreamRepo{
+		Cfg:         cfg,
+		RedisClient: redisClient,
+	}
+}
+
+// Append добавляет событие в ленту и возвращает его идентификатор
+func (r *ChangeStreamRepo) Append(ctx context.Context, event entity.ChangeEvent) (string, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+
+	return r.RedisClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: changeStreamKey,
+		MaxLen: r.Cfg.ConfigFeed.MaxLen,
+		Approx: true,
+		Values: map[string]interface{}{changeEventField: data},
+	}).Result()
+}
+
+// LastID возвращает идентификатор последнего события ленты или 0-0 для пустой ленты
+func (r *ChangeStreamRepo) LastID(ctx context.Context) (string, error) {
+	messages, err := r.RedisClient.XRevRangeN(ctx, changeStreamKey, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+
+	if len(messages) == 0 {
+		return "0-0", nil
+	}
+
+	return messages[0].ID, nil
+}
+
+// Read ждет не дольше block события, следующие за afterID
+func (r *ChangeStreamRepo) Read(ctx context.Context, afterID string, count int, block time.Duration) ([]entity.ChangeEvent, error) {
+	streams, err := r.RedisClient.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{changeStreamKey, afterID},
+		Count:   int64(count),
+		Block:   block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []entity.ChangeEvent
+	for _, stream := range streams {
+		events = append(events, decodeChangeEvents(stream.Messages)...)
+	}
+
+	return events, nil
+}
+
+// Range возвращает до count событий, следующих за afterID
+func (r *ChangeStreamRepo) Range(ctx context.Context, afterID string, count int) ([]entity.ChangeEvent, error) {
+	messages, err := r.RedisClient.XRangeN(ctx, changeStreamKey, "("+afterID, "+", int64(count)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeChangeEvents(messages), nil
+}
+
+func decodeChangeEvents(messages []redis.XMessage) []entity.ChangeEvent {
+	events := make([]entity.ChangeEvent, 0, len(messages))
+
+	for _, message := range messages {
+		data, ok := message.Values[changeEventField].(string)
+		if !ok {
+			log.Warnf("change stream entry [%s] has no event", message.ID)
+			continue
+		}
+
+		var event entity.ChangeEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			log.Warnf("failed to decode change stream entry [%s]: %+v", message.ID, err)
+			continue
+		}
+
+		event.ID = message.ID
+		events = append(events, event)
+	}
+
+	return events
+}
@@ -2,10 +2,28 @@ package cache
 
 import (
 	"context"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
 )
 
 type Document interface {
-	Set(ctx context.Context, key, mime string, data interface{}, isFile bool)
-	Get(ctx context.Context, key string) ([]byte, string, bool)
+	Set(ctx context.Context, document model.MetaDocument, data interface{})
+	Get(ctx context.Context, key string) ([]byte, model.MetaDocument, bool)
 	Delete(ctx context.Context, key string)
 }
+
+type ChangeStream interface {
+	Append(ctx context.Context, event entity.ChangeEvent) (string, error)
+	LastID(ctx context.Context) (string, error)
+	Read(ctx context.Context, afterID string, count int, block time.Duration) ([]entity.ChangeEvent, error)
+	Range(ctx context.Context, afterID string, count int) ([]entity.ChangeEvent, error)
+}
+
+type Idempotency interface {
+	Reserve(ctx context.Context, login, key string, ttl time.Duration) (bool, error)
+	Get(ctx context.Context, login, key string) (entity.IdempotencyRecord, error)
+	Save(ctx context.Context, login, key string, record entity.IdempotencyRecord, ttl time.Duration) error
+	Delete(ctx context.Context, login, key string) error
+}
@@ -38,6 +38,34 @@ func NewFileStorageClient(cfg *config.Config) (*minio.Client, error) {
 	return client, nil
 }
 
+// NewPresignClient создает клиент, который подписывает ссылки адресом MinIO, доступным клиентам сервиса.
+// Подпись включает адрес, поэтому ссылку, подписанную внутренним адресом, нельзя отдать клиенту.
+// Регион запрашивается заранее через internal: подписывающий клиент к MinIO не обращается
+func NewPresignClient(cfg *config.Config, internal *minio.Client) (*minio.Client, error) {
+	if cfg.PublicEndpoint == "" || cfg.PublicEndpoint == cfg.Endpoint {
+		return internal, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	region, err := internal.GetBucketLocation(ctx, filestorage.BucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bucket location: %+v", err)
+	}
+
+	client, err := minio.New(cfg.PublicEndpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure: cfg.UseSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file_storage presign client: %w", err)
+	}
+
+	return client, nil
+}
+
 func ensureBucketExists(client *minio.Client) error {
 	log.Info("Check if bucket exists")
 
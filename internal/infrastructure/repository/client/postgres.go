@@ -41,6 +41,20 @@ func (d *Database) Migrate() error {
 
 	err := d.DB.AutoMigrate(
 		&model.MetaDocument{},
+		&model.DocumentVersion{},
+		&model.DocumentSearch{},
+		&model.DocumentContent{},
+		&model.FileBlob{},
+		&model.FileBlobRef{},
+		&model.Saga{},
+		&model.SagaStep{},
+		&model.OutboxEvent{},
+		&model.WebhookSubscription{},
+		&model.WebhookDelivery{},
+		&model.Upload{},
+		&model.UploadPart{},
+		&model.ShareLink{},
+		&model.ShareAccess{},
 		&model.User{},
 		&model.Token{},
 	)
@@ -48,6 +62,16 @@ func (d *Database) Migrate() error {
 		return err
 	}
 
+	// индекс хранится без шифрования, поэтому текст зашифрованных документов, проиндексированный
+	// до того, как индексация стала их пропускать, удаляется из него
+	err = d.DB.Exec(`UPDATE document_searches s
+		SET body = '', vector = setweight(to_tsvector('simple', s.name), 'A')
+		FROM meta_documents m
+		WHERE m.uuid = s.document_uuid AND m.wrapped_key <> '' AND s.body <> ''`).Error
+	if err != nil {
+		return err
+	}
+
 	log.Info("Successfully migrated")
 
 	return nil
@@ -0,0 +1,113 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+)
+
+// Codec возвращает алгоритм, которым сжимается содержимое типа mimeType размера size.
+// Пустая строка означает, что содержимое хранится без сжатия: сжатие выключено,
+// содержимое меньше порога или уже сжато форматом файла
+func Codec(cfg *config.ConfigCompression, mimeType string, size int64) string {
+	if cfg == nil || cfg.Codec == "" || size < cfg.MinSize || !compressible(mimeType) {
+		return ""
+	}
+
+	return cfg.Codec
+}
+
+// compressible сообщает, имеет ли смысл сжимать содержимое типа value.
+// Изображения, архивы и другие двоичные форматы обычно уже сжаты
+func compressible(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/x-ndjson", "application/xml", "application/javascript",
+		"application/yaml", "application/x-yaml", "application/csv", "application/sql":
+		return true
+	}
+
+	return false
+}
+
+// NewWriter возвращает поток, сжимающий данные алгоритмом codec. Close дописывает конец сжатого потока
+func NewWriter(codec string, w io.Writer) (io.WriteCloser, error) {
+	switch codec {
+	case config.CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case config.CompressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown compression codec [%s]", codec)
+	}
+}
+
+// NewReader возвращает поток, распаковывающий данные, сжатые алгоритмом codec.
+// Для пустого codec данные читаются как есть
+func NewReader(codec string, r io.Reader) (io.ReadCloser, error) {
+	switch codec {
+	case "":
+		return io.NopCloser(r), nil
+	case config.CompressionGzip:
+		return gzip.NewReader(r)
+	case config.CompressionZstd:
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unknown compression codec [%s]", codec)
+	}
+}
+
+// Compress сжимает данные алгоритмом codec
+func Compress(codec string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writer, err := NewWriter(codec, &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = writer.Write(data); err != nil {
+		_ = writer.Close()
+		return nil, err
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress распаковывает данные, сжатые алгоритмом codec
+func Decompress(codec string, data []byte) ([]byte, error) {
+	reader, err := NewReader(codec, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
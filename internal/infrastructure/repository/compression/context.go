@@ -0,0 +1,18 @@
+package compression
+
+import "context"
+
+type codecContextKey struct{}
+
+// WithCodec передает хранилищу алгоритм, которым сжимается записываемое содержимое.
+// Пустой codec означает запись без сжатия
+func WithCodec(ctx context.Context, codec string) context.Context {
+	return context.WithValue(ctx, codecContextKey{}, codec)
+}
+
+// codecFrom возвращает алгоритм сжатия из контекста или пустую строку, если содержимое записывается без сжатия
+func codecFrom(ctx context.Context) string {
+	codec, _ := ctx.Value(codecContextKey{}).(string)
+
+	return codec
+}
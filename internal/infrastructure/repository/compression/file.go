@@ -0,0 +1,86 @@
+package compression
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	filestorage "github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/file_storage"
+)
+
+var _ filestorage.FileRepository = (*FileRepo)(nil)
+
+// FileRepo сжимает файлы документов перед записью в файловое хранилище алгоритмом из контекста.
+// Файл читается в том виде, в котором он записан: алгоритм сжатия хранится в метаданных документа,
+// чтобы сжатый файл можно было отдать клиенту без распаковки
+type FileRepo struct {
+	filestorage.FileRepository
+}
+
+func NewFileRepository(files filestorage.FileRepository) *FileRepo {
+	return &FileRepo{FileRepository: files}
+}
+
+// Upload записывает файл размера size. Сжатый файл сначала записывается во временную копию,
+// чтобы хранилище получило его точный размер. Возвращает размер файла без сжатия
+func (r *FileRepo) Upload(ctx context.Context, documentId string, reader io.Reader, size int64) (int64, error) {
+	codec := codecFrom(ctx)
+	if codec == "" {
+		return r.FileRepository.Upload(ctx, documentId, reader, size)
+	}
+
+	compressed, err := os.CreateTemp("", "compressed-*")
+	if err != nil {
+		log.Debugf("failed to compress file: %+v", err)
+		return 0, fmt.Errorf("failed to upload document [%s] file", documentId)
+	}
+	defer func() {
+		_ = compressed.Close()
+		_ = os.Remove(compressed.Name())
+	}()
+
+	compressedSize, err := compress(codec, compressed, reader)
+	if err != nil {
+		log.Debugf("failed to compress file: %+v", err)
+		return 0, fmt.Errorf("failed to upload document [%s] file", documentId)
+	}
+
+	log.Infof("document [%s] file compressed with %s: %d -> %d bytes", documentId, codec, size, compressedSize)
+
+	if _, err = r.FileRepository.Upload(ctx, documentId, compressed, compressedSize); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// compress сжимает src в файл dst и возвращает размер сжатых данных, оставляя файл открытым с начала
+func compress(codec string, dst *os.File, src io.Reader) (int64, error) {
+	writer, err := NewWriter(codec, dst)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = io.Copy(writer, src); err != nil {
+		_ = writer.Close()
+		return 0, err
+	}
+
+	if err = writer.Close(); err != nil {
+		return 0, err
+	}
+
+	size, err := dst.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = dst.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
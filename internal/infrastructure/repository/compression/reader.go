@@ -0,0 +1,116 @@
+package compression
+
+import (
+	"errors"
+	"io"
+)
+
+// decodeReader распаковывает сжатое содержимое известного размера при чтении.
+// Сжатый поток нельзя читать с произвольной позиции, поэтому переход вперед пропускает
+// распакованные данные, а переход назад начинает распаковку сначала
+type decodeReader struct {
+	src   io.ReadSeekCloser
+	codec string
+	size  int64
+
+	// offset позиция чтения, position - позиция распаковки в decoder
+	offset   int64
+	position int64
+	decoder  io.ReadCloser
+}
+
+// NewReadSeekCloser возвращает содержимое src, сжатое алгоритмом codec, в распакованном виде.
+// size размер распакованного содержимого. Для пустого codec src возвращается как есть
+func NewReadSeekCloser(src io.ReadSeekCloser, codec string, size int64) (io.ReadSeekCloser, error) {
+	if codec == "" {
+		return src, nil
+	}
+
+	decoder, err := NewReader(codec, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decodeReader{
+		src:     src,
+		codec:   codec,
+		size:    size,
+		decoder: decoder,
+	}, nil
+}
+
+func (r *decodeReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.offset < r.position {
+		if err := r.restart(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.offset > r.position {
+		skipped, err := io.CopyN(io.Discard, r.decoder, r.offset-r.position)
+		r.position += skipped
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.decoder.Read(p[:min(int64(len(p)), r.size-r.offset)])
+	r.offset += int64(n)
+	r.position += int64(n)
+
+	if errors.Is(err, io.EOF) && r.offset < r.size {
+		return n, io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+// restart начинает распаковку с начала сжатого содержимого
+func (r *decodeReader) restart() error {
+	_ = r.decoder.Close()
+
+	if _, err := r.src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	decoder, err := NewReader(r.codec, r.src)
+	if err != nil {
+		return err
+	}
+
+	r.decoder = decoder
+	r.position = 0
+
+	return nil
+}
+
+func (r *decodeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	r.offset = offset
+
+	return offset, nil
+}
+
+func (r *decodeReader) Close() error {
+	_ = r.decoder.Close()
+
+	return r.src.Close()
+}
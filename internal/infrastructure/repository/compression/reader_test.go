@@ -0,0 +1,174 @@
+package compression
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+)
+
+// countingSource сжатое содержимое, которое считает переходы к началу, то есть перезапуски распаковки
+type countingSource struct {
+	*bytes.Reader
+	rewinds int
+}
+
+func (s *countingSource) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		s.rewinds++
+	}
+
+	return s.Reader.Seek(offset, whence)
+}
+
+func (s *countingSource) Close() error {
+	return nil
+}
+
+func compressTestContent(t *testing.T, codec string, plain []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := NewWriter(codec, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func testContent() []byte {
+	var buf bytes.Buffer
+
+	for i := 0; buf.Len() < 200<<10; i++ {
+		buf.WriteString("line ")
+		buf.WriteByte(byte('a' + i%26))
+		buf.WriteString(" of the compressed document\n")
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecodeReaderSeek(t *testing.T) {
+	plain := testContent()
+
+	for _, codec := range []string{config.CompressionGzip, config.CompressionZstd} {
+		t.Run(codec, func(t *testing.T) {
+			src := &countingSource{Reader: bytes.NewReader(compressTestContent(t, codec, plain))}
+
+			reader, err := NewReadSeekCloser(src, codec, int64(len(plain)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+
+			steps := []struct {
+				name        string
+				offset      int64
+				whence      int
+				length      int
+				wantRewinds int
+			}{
+				{name: "forward skip", offset: 1000, whence: io.SeekStart, length: 100},
+				{name: "forward from current", offset: 50 << 10, whence: io.SeekCurrent, length: 100},
+				{name: "same position", offset: 0, whence: io.SeekCurrent, length: 10},
+				{name: "from end", offset: -64, whence: io.SeekEnd, length: 64},
+				{name: "backward restarts decoding", offset: 10, whence: io.SeekStart, length: 100, wantRewinds: 1},
+				// переход без чтения не распаковывает содержимое
+				{name: "size lookup", offset: 0, whence: io.SeekEnd, length: 0, wantRewinds: 1},
+				{name: "forward after restart", offset: 200, whence: io.SeekStart, length: 100, wantRewinds: 1},
+			}
+
+			for _, step := range steps {
+				position, err := reader.Seek(step.offset, step.whence)
+				if err != nil {
+					t.Fatalf("%s: %v", step.name, err)
+				}
+
+				got := make([]byte, step.length)
+				if _, err = io.ReadFull(reader, got); err != nil {
+					t.Fatalf("%s: %v", step.name, err)
+				}
+
+				if !bytes.Equal(got, plain[position:position+int64(step.length)]) {
+					t.Errorf("%s: content at %d differs", step.name, position)
+				}
+
+				if src.rewinds != step.wantRewinds {
+					t.Errorf("%s: decoding restarted %d times, want %d", step.name, src.rewinds, step.wantRewinds)
+				}
+			}
+
+			if _, err = reader.Seek(int64(len(plain)), io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+
+			if n, err := reader.Read(make([]byte, 10)); n != 0 || !errors.Is(err, io.EOF) {
+				t.Errorf("read at end = %d, %v, want EOF", n, err)
+			}
+
+			if _, err = reader.Seek(-1, io.SeekStart); err == nil {
+				t.Error("seek to negative position succeeded")
+			}
+		})
+	}
+}
+
+func TestDecodeReaderSize(t *testing.T) {
+	plain := testContent()
+	compressed := compressTestContent(t, config.CompressionGzip, plain)
+
+	tests := []struct {
+		name    string
+		size    int64
+		wantLen int
+		wantErr error
+	}{
+		{name: "exact size", size: int64(len(plain)), wantLen: len(plain)},
+		{name: "size smaller than content", size: 100, wantLen: 100},
+		{name: "size larger than content", size: int64(len(plain)) + 1, wantLen: len(plain), wantErr: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewReadSeekCloser(&countingSource{Reader: bytes.NewReader(compressed)}, config.CompressionGzip, tt.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+
+			got, err := io.ReadAll(reader)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !bytes.Equal(got, plain[:tt.wantLen]) {
+				t.Errorf("read %d bytes, want %d", len(got), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestNewReadSeekCloserWithoutCodec(t *testing.T) {
+	src := &countingSource{Reader: bytes.NewReader([]byte("plain"))}
+
+	reader, err := NewReadSeekCloser(src, "", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reader != io.ReadSeekCloser(src) {
+		t.Error("content without codec is wrapped")
+	}
+}
@@ -1,27 +1,90 @@
 package repository
 
 import (
-	"gorm.io/gorm"
+	"fmt"
 
-	"github.com/minio/minio-go/v7"
-	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
 
+	"github.com/AlexJudin/DocumentCacheServer/config"
 	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/client"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/compression"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/encryption"
 	filestorage "github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/file_storage"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/memory"
 	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/mongodb"
 	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
 )
 
 type DocumentRepo struct {
-	*postgres.MetadataRepo
-	*mongodb.ContentRepo
-	*filestorage.FileRepo
+	postgres.MetadataRepository
+	mongodb.ContentRepository
+	filestorage.FileRepository
 }
 
-func NewDocumentRepository(db *gorm.DB, mongoClient *mongo.Client, minioClient *minio.Client, metrics *metric.DatabaseMetrics) *DocumentRepo {
+// NewDocumentRepository создает репозиторий документов. Содержимое и файлы шифруются
+// ключами данных документов, которые хранятся в метаданных. Файлы сжимаются до шифрования
+func NewDocumentRepository(db *gorm.DB, contents mongodb.ContentRepository, files filestorage.FileRepository, keyring *encryption.Keyring, metrics *metric.DatabaseMetrics) *DocumentRepo {
+	keys := postgres.NewEncryptionKeyRepo(db, metrics)
+
 	return &DocumentRepo{
-		MetadataRepo: postgres.NewMetadataRepository(db, metrics),
-		ContentRepo:  mongodb.NewContentRepository(mongoClient, metrics),
-		FileRepo:     filestorage.NewFileRepository(minioClient),
+		MetadataRepository: postgres.NewMetadataRepository(db, metrics),
+		ContentRepository:  encryption.NewContentRepository(contents, keyring, keys),
+		FileRepository:     compression.NewFileRepository(encryption.NewFileRepository(files, keyring, keys)),
+	}
+}
+
+// NewMemoryDocumentRepository создает репозиторий, который хранит метаданные, содержимое и файлы в памяти
+func NewMemoryDocumentRepository(db *memory.Database, files *memory.FileRepo, keyring *encryption.Keyring) *DocumentRepo {
+	keys := memory.NewEncryptionKeyRepo(db)
+
+	return &DocumentRepo{
+		MetadataRepository: memory.NewMetadataRepository(db),
+		ContentRepository:  encryption.NewContentRepository(memory.NewContentRepository(), keyring, keys),
+		FileRepository:     compression.NewFileRepository(encryption.NewFileRepository(files, keyring, keys)),
+	}
+}
+
+// NewFileStorage создает файловое хранилище, выбранное параметром FILE_STORAGE_TYPE
+func NewFileStorage(cfg *config.Config) (filestorage.FileRepository, error) {
+	switch cfg.ConfigFileStorage.Type {
+	case filestorage.TypeMinio:
+		minioClient, err := client.NewFileStorageClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		presignClient, err := client.NewPresignClient(cfg, minioClient)
+		if err != nil {
+			return nil, err
+		}
+
+		fileRepo := filestorage.NewFileRepository(minioClient)
+		fileRepo.PresignClient = presignClient
+
+		return fileRepo, nil
+	case filestorage.TypeLocal:
+		return filestorage.NewLocalFileRepository(cfg.ConfigFileStorage.MainDir)
+	default:
+		return nil, fmt.Errorf("unknown file storage type [%s]", cfg.ConfigFileStorage.Type)
+	}
+}
+
+// NewContentStorage создает хранилище содержимого JSON документов, выбранное параметром CONTENT_STORAGE_TYPE.
+// Вместе с хранилищем возвращается функция закрытия его соединения
+func NewContentStorage(cfg *config.Config, db *gorm.DB, metrics *metric.DatabaseMetrics) (mongodb.ContentRepository, func() error, error) {
+	switch cfg.ConfigContentStorage.Type {
+	case mongodb.TypeMongoDB:
+		mgDb, err := client.NewMongoDBClient(cfg.GetMongoDBSourse())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return mongodb.NewContentRepository(mgDb.Client, cfg.ConfigCompression, metrics), mgDb.Close, nil
+	case postgres.TypePostgres:
+		// содержимое хранится в той же базе, что и метаданные, соединение закрывается вместе с ней
+		return postgres.NewContentRepository(db, metrics), func() error { return nil }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown content storage type [%s]", cfg.ConfigContentStorage.Type)
 	}
 }
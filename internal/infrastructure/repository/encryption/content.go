@@ -0,0 +1,212 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/mongodb"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+)
+
+const (
+	// encryptedField поле, в котором хранилище содержимого хранит зашифрованный документ
+	encryptedField = "_encrypted"
+)
+
+var _ mongodb.ContentRepository = (*ContentRepo)(nil)
+
+// ContentRepo шифрует содержимое JSON документов перед записью в хранилище содержимого.
+// Документ целиком шифруется ключом данных из контекста и хранится строкой в поле _encrypted.
+// Ключ данных для чтения находится по ключу хранения, содержимое без ключа читается как есть
+type ContentRepo struct {
+	mongodb.ContentRepository
+	keyring *Keyring
+	keys    postgres.EncryptionKeys
+}
+
+func NewContentRepository(contents mongodb.ContentRepository, keyring *Keyring, keys postgres.EncryptionKeys) *ContentRepo {
+	return &ContentRepo{
+		ContentRepository: contents,
+		keyring:           keyring,
+		keys:              keys,
+	}
+}
+
+func (r *ContentRepo) Store(ctx context.Context, uuid string, jsonDoc map[string]interface{}) error {
+	key := dataKeyFrom(ctx)
+	if key == nil {
+		return r.ContentRepository.Store(ctx, uuid, jsonDoc)
+	}
+
+	content := make(map[string]interface{}, len(jsonDoc))
+	for field, value := range jsonDoc {
+		if field != entity.ContentIDField {
+			content[field] = value
+		}
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		log.Debugf("failed to encrypt document content: %+v", err)
+		return fmt.Errorf("failed to save document [%s] content", uuid)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		log.Debugf("failed to encrypt document content: %+v", err)
+		return fmt.Errorf("failed to save document [%s] content", uuid)
+	}
+
+	sealed, err := seal(aead, data, []byte(uuid))
+	if err != nil {
+		log.Debugf("failed to encrypt document content: %+v", err)
+		return fmt.Errorf("failed to save document [%s] content", uuid)
+	}
+
+	return r.ContentRepository.Store(ctx, uuid, map[string]interface{}{
+		encryptedField: base64.StdEncoding.EncodeToString(sealed),
+	})
+}
+
+func (r *ContentRepo) GetByDocumentId(ctx context.Context, uuid string) (map[string]interface{}, error) {
+	content, err := r.ContentRepository.GetByDocumentId(ctx, uuid)
+	if err != nil || !r.keyring.Configured() {
+		return content, err
+	}
+
+	keys, err := r.keys.GetDataKeys([]string{uuid})
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, ok := keys[uuid]
+	if !ok {
+		return content, nil
+	}
+
+	key, err := r.keyring.Unwrap(dataKey)
+	if err != nil {
+		log.Debugf("failed to decrypt document content: %+v", err)
+		return nil, fmt.Errorf("failed to decrypt document [%s] content", uuid)
+	}
+
+	return decryptContent(content, uuid, key)
+}
+
+// Find возвращает содержимое, подходящее под условия. Зашифрованное содержимое хранилище проверить не может,
+// поэтому, если заданы мастер-ключи, за один вызов читается не больше limit записей, которые расшифровываются
+// и проверяются здесь. Так объем перебора ограничен размером запроса, а не всей коллекцией
+func (r *ContentRepo) Find(ctx context.Context, conditions []entity.ContentCondition, fields []string, afterKey string, limit int) ([]map[string]interface{}, string, error) {
+	if !r.keyring.Configured() {
+		return r.ContentRepository.Find(ctx, conditions, fields, afterKey, limit)
+	}
+
+	contents, next, err := r.ContentRepository.Find(ctx, nil, nil, afterKey, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	decrypted, err := r.decryptBatch(contents)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]map[string]interface{}, 0, len(decrypted))
+
+	for _, content := range decrypted {
+		if entity.MatchContent(content, conditions) {
+			result = append(result, entity.ProjectContent(content, fields))
+		}
+	}
+
+	return result, next, nil
+}
+
+// decryptBatch расшифровывает пачку содержимого, получая ключи данных одним запросом.
+// Содержимое, ключ данных которого уже удален вместе с документом, пропускается
+func (r *ContentRepo) decryptBatch(contents []map[string]interface{}) ([]map[string]interface{}, error) {
+	storageKeys := make([]string, 0, len(contents))
+	for _, content := range contents {
+		if key, ok := content[entity.ContentIDField].(string); ok {
+			storageKeys = append(storageKeys, key)
+		}
+	}
+
+	keys, err := r.keys.GetDataKeys(storageKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(contents))
+
+	for _, content := range contents {
+		uuid, _ := content[entity.ContentIDField].(string)
+
+		dataKey, ok := keys[uuid]
+		if !ok {
+			if _, encrypted := content[encryptedField]; !encrypted {
+				result = append(result, content)
+			}
+
+			continue
+		}
+
+		key, err := r.keyring.Unwrap(dataKey)
+		if err != nil {
+			log.Debugf("failed to decrypt document content: %+v", err)
+			return nil, fmt.Errorf("failed to decrypt document [%s] content", uuid)
+		}
+
+		decrypted, err := decryptContent(content, uuid, key)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, decrypted)
+	}
+
+	return result, nil
+}
+
+// decryptContent расшифровывает документ из поля _encrypted и возвращает его вместе с ключом в поле _id
+func decryptContent(content map[string]interface{}, uuid string, key []byte) (map[string]interface{}, error) {
+	encoded, ok := content[encryptedField].(string)
+	if !ok {
+		log.Debugf("failed to decrypt document content: content [%s] is not encrypted", uuid)
+		return nil, fmt.Errorf("failed to decrypt document [%s] content", uuid)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		log.Debugf("failed to decrypt document content: %+v", err)
+		return nil, fmt.Errorf("failed to decrypt document [%s] content", uuid)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		log.Debugf("failed to decrypt document content: %+v", err)
+		return nil, fmt.Errorf("failed to decrypt document [%s] content", uuid)
+	}
+
+	data, err := open(aead, sealed, []byte(uuid))
+	if err != nil {
+		log.Debugf("failed to decrypt document content: %+v", err)
+		return nil, fmt.Errorf("failed to decrypt document [%s] content", uuid)
+	}
+
+	var result map[string]interface{}
+
+	if err = json.Unmarshal(data, &result); err != nil {
+		log.Debugf("failed to decrypt document content: %+v", err)
+		return nil, fmt.Errorf("failed to decrypt document [%s] content", uuid)
+	}
+
+	result[entity.ContentIDField] = uuid
+
+	return result, nil
+}
@@ -0,0 +1,17 @@
+package encryption
+
+import "context"
+
+type dataKeyContextKey struct{}
+
+// withDataKey передает хранилищу ключ данных, которым шифруется записываемое содержимое
+func withDataKey(ctx context.Context, key []byte) context.Context {
+	return context.WithValue(ctx, dataKeyContextKey{}, key)
+}
+
+// dataKeyFrom возвращает ключ данных из контекста или nil, если содержимое записывается без шифрования
+func dataKeyFrom(ctx context.Context) []byte {
+	key, _ := ctx.Value(dataKeyContextKey{}).([]byte)
+
+	return key
+}
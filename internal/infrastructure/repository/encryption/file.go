@@ -0,0 +1,96 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+
+	filestorage "github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/file_storage"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+)
+
+var _ filestorage.FileRepository = (*FileRepo)(nil)
+
+// FileRepo шифрует файлы документов перед записью в файловое хранилище.
+// Файл шифруется по блокам ключом данных из контекста, поэтому его можно читать с любой позиции.
+// Ключ данных для чтения находится по ключу хранения, файл без ключа читается как есть
+type FileRepo struct {
+	filestorage.FileRepository
+	keyring *Keyring
+	keys    postgres.EncryptionKeys
+}
+
+func NewFileRepository(files filestorage.FileRepository, keyring *Keyring, keys postgres.EncryptionKeys) *FileRepo {
+	return &FileRepo{
+		FileRepository: files,
+		keyring:        keyring,
+		keys:           keys,
+	}
+}
+
+// Upload записывает файл размера size. Возвращает размер файла без шифрования
+func (r *FileRepo) Upload(ctx context.Context, documentId string, reader io.Reader, size int64) (int64, error) {
+	key := dataKeyFrom(ctx)
+	if key == nil {
+		return r.FileRepository.Upload(ctx, documentId, reader, size)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		log.Debugf("failed to encrypt saga file: %+v", err)
+		return 0, fmt.Errorf("failed to upload saga [%s] file", documentId)
+	}
+
+	_, err = r.FileRepository.Upload(ctx, documentId, newEncryptReader(reader, aead, documentId, size), encryptedSize(size))
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// Download открывает файл для чтения и возвращает его размер без шифрования
+func (r *FileRepo) Download(ctx context.Context, documentId string) (io.ReadSeekCloser, int64, error) {
+	if !r.keyring.Configured() {
+		return r.FileRepository.Download(ctx, documentId)
+	}
+
+	keys, err := r.keys.GetDataKeys([]string{documentId})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dataKey, ok := keys[documentId]
+	if !ok {
+		return r.FileRepository.Download(ctx, documentId)
+	}
+
+	key, err := r.keyring.Unwrap(dataKey)
+	if err != nil {
+		log.Debugf("failed to decrypt saga file: %+v", err)
+		return nil, 0, fmt.Errorf("failed to decrypt saga [%s] file", documentId)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		log.Debugf("failed to decrypt saga file: %+v", err)
+		return nil, 0, fmt.Errorf("failed to decrypt saga [%s] file", documentId)
+	}
+
+	object, size, err := r.FileRepository.Download(ctx, documentId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size, err = plainSize(size)
+	if err != nil {
+		_ = object.Close()
+
+		log.Debugf("failed to decrypt saga file: %+v", err)
+		return nil, 0, fmt.Errorf("failed to decrypt saga [%s] file", documentId)
+	}
+
+	return newDecryptReader(object, aead, documentId, size), size, nil
+}
@@ -0,0 +1,212 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	// keySize размер мастер-ключей и ключей данных, AES-256
+	keySize = 32
+
+	// cacheKeyInfo метка, из которой вместе с мастер-ключом получается ключ шифрования кэша
+	cacheKeyInfo = "document-cache"
+)
+
+var errUnknownMasterKey = errors.New("unknown encryption master key")
+
+// Keyring мастер-ключи из конфигурации. Мастер-ключи шифруют только ключи данных,
+// поэтому смена мастер-ключа не требует перезаписи содержимого документов
+type Keyring struct {
+	activeID string
+	masters  map[string]cipher.AEAD
+	caches   map[string]cipher.AEAD
+}
+
+func NewKeyring(cfg *config.ConfigEncryption) (*Keyring, error) {
+	k := &Keyring{
+		activeID: cfg.KeyID,
+		masters:  make(map[string]cipher.AEAD, len(cfg.MasterKeys)),
+		caches:   make(map[string]cipher.AEAD, len(cfg.MasterKeys)),
+	}
+
+	for id, key := range cfg.MasterKeys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("encryption master key [%s] must be %d bytes long", id, keySize)
+		}
+
+		master, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(cacheKeyInfo))
+
+		cache, err := newAEAD(mac.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
+
+		k.masters[id] = master
+		k.caches[id] = cache
+	}
+
+	if k.activeID != "" && k.masters[k.activeID] == nil {
+		return nil, fmt.Errorf("encryption master key [%s] is not configured", k.activeID)
+	}
+
+	return k, nil
+}
+
+// Enabled проверяет, шифруется ли содержимое новых документов
+func (k *Keyring) Enabled() bool {
+	return k != nil && k.activeID != ""
+}
+
+// Configured проверяет, задан ли хотя бы один мастер-ключ, то есть может ли в хранилище быть зашифрованное содержимое
+func (k *Keyring) Configured() bool {
+	return k != nil && len(k.masters) > 0
+}
+
+// ActiveKeyID возвращает идентификатор мастер-ключа, которым шифруются новые ключи данных
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// NewDataKey создает ключ данных для нового содержимого.
+// Если шифрование отключено, возвращается пустой ключ
+func (k *Keyring) NewDataKey() (model.DataKey, error) {
+	if !k.Enabled() {
+		return model.DataKey{}, nil
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return model.DataKey{}, err
+	}
+
+	return k.wrap(key)
+}
+
+// Context добавляет в контекст ключ данных, которым хранилище зашифрует записываемое содержимое.
+// Для пустого ключа содержимое записывается без шифрования
+func (k *Keyring) Context(ctx context.Context, dataKey model.DataKey) (context.Context, error) {
+	if !dataKey.Encrypted() {
+		return ctx, nil
+	}
+
+	key, err := k.Unwrap(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return withDataKey(ctx, key), nil
+}
+
+// Unwrap расшифровывает ключ данных мастер-ключом, которым он зашифрован
+func (k *Keyring) Unwrap(dataKey model.DataKey) ([]byte, error) {
+	master, ok := k.master(dataKey.KeyID)
+	if !ok {
+		return nil, fmt.Errorf("%w [%s]", errUnknownMasterKey, dataKey.KeyID)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(dataKey.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(master, wrapped, []byte(dataKey.KeyID))
+}
+
+// Rewrap перешифровывает ключ данных активным мастер-ключом
+func (k *Keyring) Rewrap(dataKey model.DataKey) (model.DataKey, error) {
+	key, err := k.Unwrap(dataKey)
+	if err != nil {
+		return model.DataKey{}, err
+	}
+
+	return k.wrap(key)
+}
+
+// SealCache шифрует содержимое для кэша ключом, полученным из активного мастер-ключа.
+// Возвращает идентификатор мастер-ключа, который нужен для расшифровки
+func (k *Keyring) SealCache(data, aad []byte) ([]byte, string, error) {
+	sealed, err := seal(k.caches[k.activeID], data, aad)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return sealed, k.activeID, nil
+}
+
+// OpenCache расшифровывает содержимое из кэша
+func (k *Keyring) OpenCache(sealed, aad []byte, keyID string) ([]byte, error) {
+	cache, ok := k.caches[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w [%s]", errUnknownMasterKey, keyID)
+	}
+
+	return open(cache, sealed, aad)
+}
+
+func (k *Keyring) wrap(key []byte) (model.DataKey, error) {
+	wrapped, err := seal(k.masters[k.activeID], key, []byte(k.activeID))
+	if err != nil {
+		return model.DataKey{}, err
+	}
+
+	return model.DataKey{
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		KeyID:      k.activeID,
+	}, nil
+}
+
+func (k *Keyring) master(id string) (cipher.AEAD, bool) {
+	if k == nil {
+		return nil, false
+	}
+
+	master, ok := k.masters[id]
+
+	return master, ok
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal шифрует данные со случайным nonce, который записывается перед шифротекстом
+func seal(aead cipher.AEAD, data, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, data, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, ciphertext, aad)
+}
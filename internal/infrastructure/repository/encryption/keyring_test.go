@@ -0,0 +1,203 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+)
+
+var (
+	testKeyOld = bytes.Repeat([]byte{1}, keySize)
+	testKeyNew = bytes.Repeat([]byte{2}, keySize)
+)
+
+func newTestKeyring(t *testing.T, activeID string, keys map[string][]byte) *Keyring {
+	t.Helper()
+
+	keyring, err := NewKeyring(&config.ConfigEncryption{KeyID: activeID, MasterKeys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keyring
+}
+
+func TestNewKeyringErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ConfigEncryption
+	}{
+		{name: "short key", cfg: config.ConfigEncryption{KeyID: "k1", MasterKeys: map[string][]byte{"k1": []byte("short")}}},
+		{name: "unknown active key", cfg: config.ConfigEncryption{KeyID: "k2", MasterKeys: map[string][]byte{"k1": testKeyOld}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyring(&tt.cfg); err == nil {
+				t.Error("keyring created with invalid configuration")
+			}
+		})
+	}
+}
+
+func TestKeyringDisabled(t *testing.T) {
+	keyring := newTestKeyring(t, "", nil)
+
+	if keyring.Enabled() || keyring.Configured() {
+		t.Fatal("keyring without master keys is enabled")
+	}
+
+	dataKey, err := keyring.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dataKey.Encrypted() {
+		t.Errorf("data key = %+v, want empty key", dataKey)
+	}
+
+	ctx, err := keyring.Context(context.Background(), dataKey)
+	if err != nil || dataKeyFrom(ctx) != nil {
+		t.Errorf("context of empty data key carries key, error = %v", err)
+	}
+}
+
+func TestKeyringDataKey(t *testing.T) {
+	keyring := newTestKeyring(t, "k1", map[string][]byte{"k1": testKeyOld})
+
+	dataKey, err := keyring.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dataKey.KeyID != "k1" || !dataKey.Encrypted() {
+		t.Fatalf("data key = %+v, want key wrapped by k1", dataKey)
+	}
+
+	key, err := keyring.Unwrap(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(key) != keySize {
+		t.Fatalf("data key length = %d, want %d", len(key), keySize)
+	}
+
+	ctx, err := keyring.Context(context.Background(), dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(dataKeyFrom(ctx), key) {
+		t.Error("context carries another data key")
+	}
+
+	another, err := keyring.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if another.WrappedKey == dataKey.WrappedKey {
+		t.Error("data keys are not unique")
+	}
+
+	// ключ, обернутый одним идентификатором, не открывается под другим
+	dataKey.KeyID = "k2"
+	if _, err = keyring.Unwrap(dataKey); !errors.Is(err, errUnknownMasterKey) {
+		t.Errorf("error = %v, want %v", err, errUnknownMasterKey)
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old := newTestKeyring(t, "k1", map[string][]byte{"k1": testKeyOld})
+
+	dataKey, err := old.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := old.Unwrap(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, keyID, err := old.SealCache([]byte("cached"), []byte("doc-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newTestKeyring(t, "k2", map[string][]byte{"k1": testKeyOld, "k2": testKeyNew})
+
+	// после смены активного ключа старые ключи данных и кэш по-прежнему читаются
+	if unwrapped, err := rotated.Unwrap(dataKey); err != nil || !bytes.Equal(unwrapped, key) {
+		t.Fatalf("unwrap with rotated keyring: error = %v", err)
+	}
+
+	if data, err := rotated.OpenCache(sealed, []byte("doc-1"), keyID); err != nil || string(data) != "cached" {
+		t.Fatalf("open cache with rotated keyring = %q, %v", data, err)
+	}
+
+	rewrapped, err := rotated.Rewrap(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rewrapped.KeyID != "k2" {
+		t.Fatalf("rewrapped key id = %s, want k2", rewrapped.KeyID)
+	}
+
+	// перешифрованный ключ данных тот же, поэтому содержимое не нужно перезаписывать
+	withoutOld := newTestKeyring(t, "k2", map[string][]byte{"k2": testKeyNew})
+
+	if unwrapped, err := withoutOld.Unwrap(rewrapped); err != nil || !bytes.Equal(unwrapped, key) {
+		t.Fatalf("unwrap rewrapped key: error = %v", err)
+	}
+
+	if _, err = withoutOld.Unwrap(dataKey); !errors.Is(err, errUnknownMasterKey) {
+		t.Errorf("error = %v, want %v", err, errUnknownMasterKey)
+	}
+
+	// идентификатор ключа входит в AAD, поэтому подмена KeyID не проходит проверку
+	tampered := rewrapped
+	tampered.KeyID = "k1"
+
+	if _, err = rotated.Unwrap(tampered); err == nil {
+		t.Error("data key opened under another master key")
+	}
+}
+
+func TestKeyringCache(t *testing.T) {
+	keyring := newTestKeyring(t, "k1", map[string][]byte{"k1": testKeyOld})
+
+	sealed, keyID, err := keyring.SealCache([]byte("cached"), []byte("doc-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keyID != "k1" || bytes.Contains(sealed, []byte("cached")) {
+		t.Fatalf("sealed cache = %q, key id = %s", sealed, keyID)
+	}
+
+	tests := []struct {
+		name   string
+		sealed []byte
+		aad    string
+		keyID  string
+	}{
+		{name: "another document", sealed: sealed, aad: "doc-2", keyID: "k1"},
+		{name: "unknown key", sealed: sealed, aad: "doc-1", keyID: "k2"},
+		{name: "corrupted", sealed: append(bytes.Clone(sealed[:len(sealed)-1]), sealed[len(sealed)-1]^1), aad: "doc-1", keyID: "k1"},
+		{name: "too short", sealed: sealed[:4], aad: "doc-1", keyID: "k1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := keyring.OpenCache(tt.sealed, []byte(tt.aad), tt.keyID); err == nil {
+				t.Error("cache opened")
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// chunkSize размер открытого текста в одном блоке файла.
+	// Каждый блок шифруется отдельно, поэтому файл можно читать с любой позиции
+	chunkSize = 64 << 10
+
+	nonceSize = 12
+	tagSize   = 16
+
+	// chunkOverhead nonce перед шифротекстом блока и тег в его конце
+	chunkOverhead = nonceSize + tagSize
+)
+
+var errCorruptedFile = errors.New("encrypted file is corrupted")
+
+// chunkCount количество блоков файла. Пустой файл хранится одним пустым блоком,
+// чтобы усечение зашифрованного файла обнаруживалось при чтении
+func chunkCount(size int64) int64 {
+	if size == 0 {
+		return 1
+	}
+
+	return (size + chunkSize - 1) / chunkSize
+}
+
+// encryptedSize размер зашифрованного файла по размеру открытого текста
+func encryptedSize(size int64) int64 {
+	return size + chunkCount(size)*chunkOverhead
+}
+
+// plainSize размер открытого текста по размеру зашифрованного файла
+func plainSize(size int64) (int64, error) {
+	chunks := (size + chunkSize + chunkOverhead - 1) / (chunkSize + chunkOverhead)
+	if chunks == 0 {
+		return 0, errCorruptedFile
+	}
+
+	plain := size - chunks*chunkOverhead
+	if plain < 0 || chunkCount(plain) != chunks {
+		return 0, errCorruptedFile
+	}
+
+	return plain, nil
+}
+
+// chunkAAD связывает блок с ключом хранения файла, номером блока и признаком последнего блока,
+// поэтому блоки нельзя переставить, перенести в другой файл или отбросить с конца
+func chunkAAD(key string, index int64, last bool) []byte {
+	aad := make([]byte, 0, len(key)+9)
+	aad = append(aad, key...)
+	aad = binary.BigEndian.AppendUint64(aad, uint64(index))
+
+	if last {
+		return append(aad, 1)
+	}
+
+	return append(aad, 0)
+}
+
+// encryptReader шифрует файл известного размера по блокам при чтении
+type encryptReader struct {
+	src    io.Reader
+	aead   cipher.AEAD
+	key    string
+	size   int64
+	chunks int64
+	index  int64
+	plain  []byte
+	out    []byte
+}
+
+func newEncryptReader(src io.Reader, aead cipher.AEAD, key string, size int64) *encryptReader {
+	return &encryptReader{
+		src:    src,
+		aead:   aead,
+		key:    key,
+		size:   size,
+		chunks: chunkCount(size),
+		plain:  make([]byte, chunkSize),
+	}
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.index == r.chunks {
+			return 0, io.EOF
+		}
+
+		if err := r.sealChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+
+	return n, nil
+}
+
+func (r *encryptReader) sealChunk() error {
+	length := min(r.size-r.index*chunkSize, chunkSize)
+
+	if _, err := io.ReadFull(r.src, r.plain[:length]); err != nil {
+		return fmt.Errorf("failed to read chunk %d: %w", r.index, err)
+	}
+
+	nonce := make([]byte, nonceSize, chunkSize+chunkOverhead)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	last := r.index == r.chunks-1
+
+	r.out = r.aead.Seal(nonce, nonce, r.plain[:length], chunkAAD(r.key, r.index, last))
+	r.index++
+
+	return nil
+}
+
+// decryptReader расшифровывает файл по блокам. Переход на позицию читает только
+// блок, в который она попадает, поэтому запросы с диапазоном не читают файл целиком
+type decryptReader struct {
+	src    io.ReadSeekCloser
+	aead   cipher.AEAD
+	key    string
+	size   int64
+	chunks int64
+
+	offset int64
+	// srcOffset позиция в зашифрованном файле, чтобы последовательное чтение не переходило по нему
+	srcOffset int64
+	// chunk расшифрованный блок с номером chunkIndex, -1 - блок еще не прочитан
+	chunk      []byte
+	chunkIndex int64
+	sealed     []byte
+}
+
+func newDecryptReader(src io.ReadSeekCloser, aead cipher.AEAD, key string, size int64) *decryptReader {
+	return &decryptReader{
+		src:        src,
+		aead:       aead,
+		key:        key,
+		size:       size,
+		chunks:     chunkCount(size),
+		chunkIndex: -1,
+		sealed:     make([]byte, chunkSize+chunkOverhead),
+	}
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	index := r.offset / chunkSize
+	if index != r.chunkIndex {
+		if err := r.openChunk(index); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.chunk[r.offset-index*chunkSize:])
+	r.offset += int64(n)
+
+	return n, nil
+}
+
+func (r *decryptReader) openChunk(index int64) error {
+	position := index * (chunkSize + chunkOverhead)
+
+	if position != r.srcOffset {
+		if _, err := r.src.Seek(position, io.SeekStart); err != nil {
+			return err
+		}
+
+		r.srcOffset = position
+	}
+
+	last := index == r.chunks-1
+
+	length := int64(chunkSize + chunkOverhead)
+	if last {
+		length = r.size - index*chunkSize + chunkOverhead
+	}
+
+	n, err := io.ReadFull(r.src, r.sealed[:length])
+	r.srcOffset += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("failed to read chunk %d: %w", index, err)
+	}
+
+	nonce, ciphertext := r.sealed[:nonceSize], r.sealed[nonceSize:length]
+
+	chunk, err := r.aead.Open(r.chunk[:0], nonce, ciphertext, chunkAAD(r.key, index, last))
+	if err != nil {
+		r.chunkIndex = -1
+		return fmt.Errorf("%w: chunk %d", errCorruptedFile, index)
+	}
+
+	r.chunk = chunk
+	r.chunkIndex = index
+
+	return nil
+}
+
+func (r *decryptReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	r.offset = offset
+
+	return offset, nil
+}
+
+func (r *decryptReader) Close() error {
+	return r.src.Close()
+}
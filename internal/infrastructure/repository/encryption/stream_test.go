@@ -0,0 +1,153 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+)
+
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekNopCloser) Close() error {
+	return nil
+}
+
+func encryptTestFile(t *testing.T, plain []byte, key string) ([]byte, *decryptReader) {
+	t.Helper()
+
+	aead, err := newAEAD(testKeyOld)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := io.ReadAll(newEncryptReader(bytes.NewReader(plain), aead, key, int64(len(plain))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sealed, newDecryptReader(readSeekNopCloser{bytes.NewReader(sealed)}, aead, key, int64(len(plain)))
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 100} {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			plain := make([]byte, size)
+			_, _ = rand.Read(plain)
+
+			sealed, reader := encryptTestFile(t, plain, "file-1")
+
+			if int64(len(sealed)) != encryptedSize(int64(size)) {
+				t.Fatalf("encrypted size = %d, want %d", len(sealed), encryptedSize(int64(size)))
+			}
+
+			if got, err := plainSize(int64(len(sealed))); err != nil || got != int64(size) {
+				t.Fatalf("plainSize() = %d, %v, want %d", got, err, size)
+			}
+
+			got, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, plain) {
+				t.Error("decrypted content differs")
+			}
+		})
+	}
+}
+
+func TestStreamSeek(t *testing.T) {
+	plain := make([]byte, 3*chunkSize+100)
+	_, _ = rand.Read(plain)
+
+	_, reader := encryptTestFile(t, plain, "file-1")
+
+	tests := []struct {
+		name   string
+		offset int64
+		whence int
+		want   int64
+		length int
+	}{
+		{name: "inside last chunk", offset: 3*chunkSize + 10, whence: io.SeekStart, want: 3*chunkSize + 10, length: 50},
+		{name: "backward across chunks", offset: 5, whence: io.SeekStart, want: 5, length: 100},
+		{name: "range spanning chunks", offset: chunkSize - 10, whence: io.SeekStart, want: chunkSize - 10, length: 30},
+		{name: "from current", offset: 100, whence: io.SeekCurrent, want: chunkSize + 120, length: 10},
+		{name: "from end", offset: -20, whence: io.SeekEnd, want: int64(len(plain)) - 20, length: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position, err := reader.Seek(tt.offset, tt.whence)
+			if err != nil || position != tt.want {
+				t.Fatalf("Seek() = %d, %v, want %d", position, err, tt.want)
+			}
+
+			got := make([]byte, tt.length)
+			if _, err = io.ReadFull(reader, got); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, plain[position:position+int64(tt.length)]) {
+				t.Errorf("content at %d differs", position)
+			}
+		})
+	}
+
+	if _, err := reader.Seek(-1, io.SeekStart); err == nil {
+		t.Error("seek to negative position succeeded")
+	}
+}
+
+func TestStreamTampering(t *testing.T) {
+	plain := make([]byte, 2*chunkSize+10)
+	_, _ = rand.Read(plain)
+
+	sealed, _ := encryptTestFile(t, plain, "file-1")
+
+	aead, err := newAEAD(testKeyOld)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunk := chunkSize + chunkOverhead
+
+	swapped := bytes.Clone(sealed)
+	copy(swapped[:chunk], sealed[chunk:2*chunk])
+	copy(swapped[chunk:2*chunk], sealed[:chunk])
+
+	flipped := bytes.Clone(sealed)
+	flipped[nonceSize+1] ^= 1
+
+	tests := []struct {
+		name   string
+		sealed []byte
+		key    string
+		size   int64
+	}{
+		{name: "another file", sealed: sealed, key: "file-2", size: int64(len(plain))},
+		{name: "swapped chunks", sealed: swapped, key: "file-1", size: int64(len(plain))},
+		{name: "flipped bit", sealed: flipped, key: "file-1", size: int64(len(plain))},
+		// отброшенный последний блок: предпоследний блок не помечен последним
+		{name: "truncated", sealed: sealed[:2*chunk], key: "file-1", size: 2 * chunkSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := newDecryptReader(readSeekNopCloser{bytes.NewReader(tt.sealed)}, aead, tt.key, tt.size)
+
+			if _, err := io.ReadAll(reader); !errors.Is(err, errCorruptedFile) {
+				t.Errorf("error = %v, want %v", err, errCorruptedFile)
+			}
+		})
+	}
+
+	if _, err = plainSize(int64(chunkOverhead - 1)); !errors.Is(err, errCorruptedFile) {
+		t.Errorf("plainSize() error = %v, want %v", err, errCorruptedFile)
+	}
+}
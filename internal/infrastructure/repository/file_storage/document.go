@@ -1,7 +1,6 @@
 package file_storage
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -12,59 +11,72 @@ import (
 
 var _ FileRepository = (*FileRepo)(nil)
 
-const BucketName = "saga-files"
+const (
+	// TypeMinio файлы хранятся в MinIO
+	TypeMinio = "minio"
+	// TypeLocal файлы хранятся на локальном диске
+	TypeLocal = "local"
+
+	BucketName = "saga-files"
+
+	// partSize ограничивает размер буфера, который клиент MinIO держит в памяти
+	// при потоковой загрузке файла неизвестного размера
+	partSize = 16 << 20
+)
 
 type FileRepo struct {
-	Client     *minio.Client
-	bucketName string
+	Client *minio.Client
+	// PresignClient подписывает ссылки адресом MinIO, доступным клиентам
+	PresignClient *minio.Client
+	bucketName    string
 }
 
 func NewFileRepository(minioClient *minio.Client) *FileRepo {
 	return &FileRepo{
-		Client:     minioClient,
-		bucketName: BucketName,
+		Client:        minioClient,
+		PresignClient: minioClient,
+		bucketName:    BucketName,
 	}
 }
 
-func (r *FileRepo) Upload(ctx context.Context, documentId string, data []byte) error {
+func (r *FileRepo) Upload(ctx context.Context, documentId string, reader io.Reader, size int64) (int64, error) {
 	log.Infof("uploading saga [%s] file", documentId)
 
-	size := int64(len(data))
-
-	reader := bytes.NewReader(data)
-
-	_, err := r.Client.PutObject(ctx, r.bucketName, documentId, reader, size, minio.PutObjectOptions{
+	info, err := r.Client.PutObject(ctx, r.bucketName, documentId, reader, size, minio.PutObjectOptions{
 		ContentType: "application/octet-stream",
+		PartSize:    partSize,
 	})
 	if err != nil {
 		log.Debugf("failed to upload saga file: %+v", err)
-		return fmt.Errorf("failed to upload saga [%s] file", documentId)
+		return 0, fmt.Errorf("failed to upload saga [%s] file", documentId)
 	}
 
 	log.Infof("saga [%s] file uploaded successfully", documentId)
 
-	return nil
+	return info.Size, nil
 }
 
-func (r *FileRepo) Download(ctx context.Context, documentId string) ([]byte, error) {
+func (r *FileRepo) Download(ctx context.Context, documentId string) (io.ReadSeekCloser, int64, error) {
 	log.Infof("downloading saga [%s] file", documentId)
 
 	object, err := r.Client.GetObject(ctx, r.bucketName, documentId, minio.GetObjectOptions{})
 	if err != nil {
 		log.Debugf("failed to get saga file: %+v", err)
-		return nil, fmt.Errorf("failed to get saga [%s] file", documentId)
+		return nil, 0, fmt.Errorf("failed to get saga [%s] file", documentId)
 	}
-	defer object.Close()
 
-	data, err := io.ReadAll(object)
+	// GetObject не обращается к хранилищу, поэтому ошибки проверяем через Stat
+	info, err := object.Stat()
 	if err != nil {
-		log.Debugf("failed to read saga file: %+v", err)
-		return nil, fmt.Errorf("failed to read saga [%s] file", documentId)
+		_ = object.Close()
+
+		log.Debugf("failed to stat saga file: %+v", err)
+		return nil, 0, fmt.Errorf("failed to read saga [%s] file", documentId)
 	}
 
-	log.Infof("saga [%s] file downloaded successfully", documentId)
+	log.Infof("saga [%s] file opened for download", documentId)
 
-	return data, nil
+	return object, info.Size, nil
 }
 
 func (r *FileRepo) Delete(ctx context.Context, documentId string) error {
@@ -80,3 +92,41 @@ func (r *FileRepo) Delete(ctx context.Context, documentId string) error {
 
 	return nil
 }
+
+// Copy копирует файл srcKey в dstKey на стороне MinIO. Файлы больше 5 ГБ копируются частями
+func (r *FileRepo) Copy(ctx context.Context, srcKey, dstKey string) error {
+	log.Infof("copying file [%s] to [%s]", srcKey, dstKey)
+
+	_, err := r.Client.ComposeObject(ctx,
+		minio.CopyDestOptions{Bucket: r.bucketName, Object: dstKey},
+		minio.CopySrcOptions{Bucket: r.bucketName, Object: srcKey},
+	)
+	if err != nil {
+		log.Debugf("failed to copy file: %+v", err)
+		return fmt.Errorf("failed to copy file [%s] to [%s]", srcKey, dstKey)
+	}
+
+	log.Infof("file [%s] copied to [%s] successfully", srcKey, dstKey)
+
+	return nil
+}
+
+// FileKeys возвращает ключи всех файлов в хранилище
+func (r *FileRepo) FileKeys(ctx context.Context) ([]string, error) {
+	log.Info("listing saga files")
+
+	keys := make([]string, 0)
+
+	for object := range r.Client.ListObjects(ctx, r.bucketName, minio.ListObjectsOptions{Recursive: true}) {
+		if object.Err != nil {
+			log.Debugf("failed to list saga files: %+v", object.Err)
+			return nil, fmt.Errorf("failed to list saga files")
+		}
+
+		keys = append(keys, object.Key)
+	}
+
+	log.Infof("%d saga files listed", len(keys))
+
+	return keys, nil
+}
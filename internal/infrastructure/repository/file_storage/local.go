@@ -0,0 +1,300 @@
+package file_storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// localTmpDir каталог незавершенных загрузок. Он лежит в той же файловой системе,
+	// что и файлы, поэтому готовый файл переносится на место атомарным rename
+	localTmpDir = "tmp"
+	// localTmpMaxAge возраст, после которого незавершенная загрузка считается брошенной
+	localTmpMaxAge = 24 * time.Hour
+
+	localDirPerm = 0o750
+)
+
+var _ FileRepository = (*LocalFileRepo)(nil)
+
+// LocalFileRepo хранит файлы на локальном диске в каталоге FILE_MAIN_DIR.
+// Файлы раскладываются по двум уровням каталогов по хешу ключа, чтобы в одном каталоге не копились миллионы файлов
+type LocalFileRepo struct {
+	mainDir string
+	tmpDir  string
+}
+
+func NewLocalFileRepository(mainDir string) (*LocalFileRepo, error) {
+	if mainDir == "" {
+		return nil, fmt.Errorf("directory of local file storage is not set")
+	}
+
+	r := &LocalFileRepo{
+		mainDir: mainDir,
+		tmpDir:  filepath.Join(mainDir, localTmpDir),
+	}
+
+	if err := os.MkdirAll(r.tmpDir, localDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create local file storage [%s]: %w", mainDir, err)
+	}
+
+	r.removeAbandoned()
+
+	return r, nil
+}
+
+func (r *LocalFileRepo) Upload(ctx context.Context, documentId string, reader io.Reader, size int64) (int64, error) {
+	log.Infof("uploading saga [%s] file", documentId)
+
+	path, err := r.path(documentId)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := r.write(ctx, path, documentId, reader, size)
+	if err != nil {
+		log.Debugf("failed to upload saga file: %+v", err)
+		return 0, fmt.Errorf("failed to upload saga [%s] file", documentId)
+	}
+
+	log.Infof("saga [%s] file uploaded successfully", documentId)
+
+	return written, nil
+}
+
+// write записывает файл во временный каталог, сбрасывает его на диск и переносит на место.
+// Читатель видит либо прежний файл, либо новый целиком, но не частично записанный
+func (r *LocalFileRepo) write(ctx context.Context, path, documentId string, reader io.Reader, size int64) (int64, error) {
+	tmp, err := os.CreateTemp(r.tmpDir, documentId+".*")
+	if err != nil {
+		return 0, err
+	}
+
+	renamed := false
+	defer func() {
+		if !renamed {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	written, err := io.Copy(tmp, &contextReader{ctx: ctx, reader: reader})
+	if err != nil {
+		return 0, err
+	}
+
+	if size >= 0 && written != size {
+		return 0, fmt.Errorf("file size mismatch: expected %d bytes, got %d", size, written)
+	}
+
+	if err = tmp.Sync(); err != nil {
+		return 0, err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	dir := filepath.Dir(path)
+
+	if err = os.MkdirAll(dir, localDirPerm); err != nil {
+		return 0, err
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	renamed = true
+
+	// запись о переименовании попадает на диск только вместе с каталогом
+	if err = syncDir(dir); err != nil {
+		return 0, err
+	}
+
+	return written, nil
+}
+
+func (r *LocalFileRepo) Download(ctx context.Context, documentId string) (io.ReadSeekCloser, int64, error) {
+	log.Infof("downloading saga [%s] file", documentId)
+
+	path, err := r.path(documentId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Debugf("failed to get saga file: %+v", err)
+		return nil, 0, fmt.Errorf("failed to get saga [%s] file", documentId)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		log.Debugf("failed to stat saga file: %+v", err)
+		return nil, 0, fmt.Errorf("failed to read saga [%s] file", documentId)
+	}
+
+	log.Infof("saga [%s] file opened for download", documentId)
+
+	return file, info.Size(), nil
+}
+
+// Copy копирует файл srcKey в dstKey. Копия записывается так же, как загружаемый файл
+func (r *LocalFileRepo) Copy(ctx context.Context, srcKey, dstKey string) error {
+	log.Infof("copying file [%s] to [%s]", srcKey, dstKey)
+
+	srcPath, err := r.path(srcKey)
+	if err != nil {
+		return err
+	}
+
+	dstPath, err := r.path(dstKey)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		log.Debugf("failed to copy file: %+v", err)
+		return fmt.Errorf("failed to copy file [%s] to [%s]", srcKey, dstKey)
+	}
+	defer src.Close()
+
+	if _, err = r.write(ctx, dstPath, dstKey, src, -1); err != nil {
+		log.Debugf("failed to copy file: %+v", err)
+		return fmt.Errorf("failed to copy file [%s] to [%s]", srcKey, dstKey)
+	}
+
+	log.Infof("file [%s] copied to [%s] successfully", srcKey, dstKey)
+
+	return nil
+}
+
+// Delete удаляет файл. Как и в MinIO, удаление отсутствующего файла не считается ошибкой
+func (r *LocalFileRepo) Delete(ctx context.Context, documentId string) error {
+	log.Infof("deleting saga [%s] file", documentId)
+
+	path, err := r.path(documentId)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Debugf("failed to delete saga file: %+v", err)
+		return fmt.Errorf("failed to delete saga [%s] file", documentId)
+	}
+
+	log.Infof("saga [%s] file deleted successfully", documentId)
+
+	return nil
+}
+
+// FileKeys возвращает ключи всех файлов в хранилище, кроме незавершенных загрузок
+func (r *LocalFileRepo) FileKeys(ctx context.Context) ([]string, error) {
+	log.Info("listing saga files")
+
+	keys := make([]string, 0)
+
+	err := filepath.WalkDir(r.mainDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path == r.tmpDir {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if entry.Type().IsRegular() {
+			keys = append(keys, entry.Name())
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Debugf("failed to list saga files: %+v", err)
+		return nil, fmt.Errorf("failed to list saga files")
+	}
+
+	log.Infof("%d saga files listed", len(keys))
+
+	return keys, nil
+}
+
+// path возвращает путь файла вида <MainDir>/ab/cd/<ключ>, где abcd - начало SHA-256 ключа.
+// Ключ становится именем файла, поэтому разделители путей и имена, начинающиеся с точки, запрещены
+func (r *LocalFileRepo) path(documentId string) (string, error) {
+	if documentId == "" || strings.HasPrefix(documentId, ".") || strings.ContainsAny(documentId, `/\`) {
+		return "", fmt.Errorf("invalid file key [%s]", documentId)
+	}
+
+	sum := sha256.Sum256([]byte(documentId))
+	shard := hex.EncodeToString(sum[:2])
+
+	return filepath.Join(r.mainDir, shard[:2], shard[2:], documentId), nil
+}
+
+// removeAbandoned удаляет временные файлы загрузок, прерванных остановкой сервиса
+func (r *LocalFileRepo) removeAbandoned() {
+	entries, err := os.ReadDir(r.tmpDir)
+	if err != nil {
+		log.Errorf("failed to read local file storage temp directory: %+v", err)
+		return
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < localTmpMaxAge {
+			continue
+		}
+
+		if err = os.Remove(filepath.Join(r.tmpDir, entry.Name())); err != nil {
+			log.Errorf("failed to remove abandoned upload [%s]: %+v", entry.Name(), err)
+		}
+	}
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// contextReader прерывает чтение при отмене контекста
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
@@ -0,0 +1,81 @@
+package file_storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ MultipartRepository = (*FileRepo)(nil)
+
+// CreateMultipart начинает multipart загрузку файла key и возвращает ее идентификатор
+func (r *FileRepo) CreateMultipart(ctx context.Context, key string) (string, error) {
+	log.Infof("starting multipart upload of file [%s]", key)
+
+	uploadID, err := r.core().NewMultipartUpload(ctx, r.bucketName, key, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		log.Debugf("failed to start multipart upload: %+v", err)
+		return "", fmt.Errorf("failed to start multipart upload of file [%s]", key)
+	}
+
+	return uploadID, nil
+}
+
+// UploadPart записывает часть number multipart загрузки и возвращает ее ETag
+func (r *FileRepo) UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (string, error) {
+	part, err := r.core().PutObjectPart(ctx, r.bucketName, key, uploadID, number, reader, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		log.Debugf("failed to upload file part: %+v", err)
+		return "", fmt.Errorf("failed to upload part %d of file [%s]", number, key)
+	}
+
+	return part.ETag, nil
+}
+
+// CompleteMultipart собирает файл из частей parts, перечисленных по возрастанию номера
+func (r *FileRepo) CompleteMultipart(ctx context.Context, key, uploadID string, parts []model.UploadPart) error {
+	log.Infof("completing multipart upload of file [%s]", key)
+
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.Number, ETag: part.ETag})
+	}
+
+	_, err := r.core().CompleteMultipartUpload(ctx, r.bucketName, key, uploadID, completeParts, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		log.Debugf("failed to complete multipart upload: %+v", err)
+		return fmt.Errorf("failed to complete multipart upload of file [%s]", key)
+	}
+
+	log.Infof("multipart upload of file [%s] completed successfully", key)
+
+	return nil
+}
+
+// AbortMultipart отменяет multipart загрузку и удаляет записанные части.
+// Отмена уже завершенной или отмененной загрузки не считается ошибкой
+func (r *FileRepo) AbortMultipart(ctx context.Context, key, uploadID string) error {
+	log.Infof("aborting multipart upload of file [%s]", key)
+
+	err := r.core().AbortMultipartUpload(ctx, r.bucketName, key, uploadID)
+	if err != nil && minio.ToErrorResponse(err).Code != "NoSuchUpload" {
+		log.Debugf("failed to abort multipart upload: %+v", err)
+		return fmt.Errorf("failed to abort multipart upload of file [%s]", key)
+	}
+
+	return nil
+}
+
+// core возвращает низкоуровневый клиент MinIO, через который выполняются отдельные шаги multipart загрузки
+func (r *FileRepo) core() minio.Core {
+	return minio.Core{Client: r.Client}
+}
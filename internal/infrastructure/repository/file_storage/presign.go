@@ -0,0 +1,96 @@
+package file_storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ Presigner = (*FileRepo)(nil)
+
+// checksumHeader заголовок с SHA-256 файла в base64. Хранилище сверяет с ним записываемый файл
+// и сохраняет хеш в метаданных файла
+const checksumHeader = "X-Amz-Checksum-Sha256"
+
+// PresignPut возвращает ссылку, по которой клиент загружает файл key запросом PUT.
+// Если указан хеш файла digest, ссылка подписывается вместе с заголовком хеша, который клиент
+// должен передать с файлом. Возвращаемые заголовки нужно добавить к запросу PUT
+func (r *FileRepo) PresignPut(ctx context.Context, key string, expiry time.Duration, digest string) (string, map[string]string, error) {
+	headers := make(http.Header)
+
+	if digest != "" {
+		sum, err := hex.DecodeString(digest)
+		if err != nil {
+			log.Debugf("failed to presign file upload: %+v", err)
+			return "", nil, fmt.Errorf("failed to presign upload of file [%s]", key)
+		}
+
+		headers.Set(checksumHeader, base64.StdEncoding.EncodeToString(sum))
+	}
+
+	presigned, err := r.PresignClient.PresignHeader(ctx, http.MethodPut, r.bucketName, key, expiry, nil, headers)
+	if err != nil {
+		log.Debugf("failed to presign file upload: %+v", err)
+		return "", nil, fmt.Errorf("failed to presign upload of file [%s]", key)
+	}
+
+	required := make(map[string]string, len(headers))
+	for name := range headers {
+		required[name] = headers.Get(name)
+	}
+
+	return presigned.String(), required, nil
+}
+
+// PresignGet возвращает ссылку, по которой клиент скачивает файл key запросом GET.
+// Параметры params (response-content-type и другие) задают заголовки ответа хранилища
+func (r *FileRepo) PresignGet(ctx context.Context, key string, expiry time.Duration, params url.Values) (string, error) {
+	presigned, err := r.PresignClient.PresignedGetObject(ctx, r.bucketName, key, expiry, params)
+	if err != nil {
+		log.Debugf("failed to presign file download: %+v", err)
+		return "", fmt.Errorf("failed to presign download of file [%s]", key)
+	}
+
+	return presigned.String(), nil
+}
+
+// StatFile возвращает размер и хеш файла key и признак того, что файл есть в хранилище
+func (r *FileRepo) StatFile(ctx context.Context, key string) (FileInfo, bool, error) {
+	opts := minio.StatObjectOptions{}
+	opts.Checksum = true
+
+	info, err := r.Client.StatObject(ctx, r.bucketName, key, opts)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return FileInfo{}, false, nil
+		}
+
+		log.Debugf("failed to stat file: %+v", err)
+		return FileInfo{}, false, fmt.Errorf("failed to stat file [%s]", key)
+	}
+
+	return FileInfo{Size: info.Size, SHA256: objectSHA256(info)}, true, nil
+}
+
+// objectSHA256 возвращает SHA-256 всего файла в hex. Для файла, собранного из частей,
+// хранилище знает только хеш хешей частей, поэтому возвращается пустая строка
+func objectSHA256(info minio.ObjectInfo) string {
+	if info.ChecksumSHA256 == "" || info.ChecksumMode == "COMPOSITE" || strings.Contains(info.ChecksumSHA256, "-") {
+		return ""
+	}
+
+	sum, err := base64.StdEncoding.DecodeString(info.ChecksumSHA256)
+	if err != nil || len(sum) != 32 {
+		return ""
+	}
+
+	return hex.EncodeToString(sum)
+}
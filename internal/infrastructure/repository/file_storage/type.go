@@ -1,9 +1,54 @@
 package file_storage
 
-import "context"
+import (
+	"context"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	// MinPartSize минимальный размер части multipart загрузки, кроме последней
+	MinPartSize = 5 << 20
+	// MaxParts максимальное количество частей multipart загрузки
+	MaxParts = 10000
+	// MaxPutSize максимальный размер файла, загружаемого одним запросом по подписанной ссылке
+	MaxPutSize = 5 << 30
+)
 
 type FileRepository interface {
-	Upload(ctx context.Context, documentId string, data []byte) error
-	Download(ctx context.Context, documentId string) ([]byte, error)
+	Upload(ctx context.Context, documentId string, reader io.Reader, size int64) (int64, error)
+	Download(ctx context.Context, documentId string) (io.ReadSeekCloser, int64, error)
 	Delete(ctx context.Context, documentId string) error
+	FileKeys(ctx context.Context) ([]string, error)
+	// Copy копирует файл srcKey в dstKey средствами хранилища, без передачи содержимого через сервер
+	Copy(ctx context.Context, srcKey, dstKey string) error
+}
+
+// MultipartRepository файловое хранилище, которое собирает файл из частей, загруженных по отдельности.
+// Собранный файл читается и удаляется как обычный файл хранилища
+type MultipartRepository interface {
+	FileRepository
+
+	CreateMultipart(ctx context.Context, key string) (string, error)
+	UploadPart(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (string, error)
+	CompleteMultipart(ctx context.Context, key, uploadID string, parts []model.UploadPart) error
+	AbortMultipart(ctx context.Context, key, uploadID string) error
+}
+
+// Presigner файловое хранилище, которое выдает подписанные ссылки для передачи файлов
+// между клиентом и хранилищем без участия сервера
+type Presigner interface {
+	PresignPut(ctx context.Context, key string, expiry time.Duration, digest string) (string, map[string]string, error)
+	PresignGet(ctx context.Context, key string, expiry time.Duration, params url.Values) (string, error)
+	StatFile(ctx context.Context, key string) (FileInfo, bool, error)
+}
+
+// FileInfo размер файла в хранилище и его SHA-256 в hex, который хранилище проверило при записи.
+// Пустой SHA256 - клиент не передал хеш вместе с файлом и хранилище его не знает
+type FileInfo struct {
+	Size   int64
+	SHA256 string
 }
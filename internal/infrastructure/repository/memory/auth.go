@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ postgres.TokenStorage = (*TokenStorageRepo)(nil)
+
+type TokenStorageRepo struct {
+	Db *Database
+}
+
+func NewTokenStorageRepo(db *Database) *TokenStorageRepo {
+	return &TokenStorageRepo{Db: db}
+}
+
+func (r *TokenStorageRepo) Save(token model.Token) error {
+	log.Infof("start saving token with user login [%s]", token.Login)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	if _, ok := r.Db.tokens[token.AccessTokenID]; ok {
+		log.Debugf("error create token: token [%s] already exists", token.AccessTokenID)
+		return fmt.Errorf("token [%s] already exists", token.AccessTokenID)
+	}
+
+	r.Db.tokens[token.AccessTokenID] = token
+
+	return nil
+}
+
+// Get возвращает пустой логин, если токен не найден, как и репозиторий Postgres
+func (r *TokenStorageRepo) Get(accessTokenID string) (string, error) {
+	log.Infof("start getting user login with access token [%s]", accessTokenID)
+
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	return r.Db.tokens[accessTokenID].Login, nil
+}
+
+func (r *TokenStorageRepo) Delete(accessTokenID string) error {
+	log.Infof("start deleting user login with access token [%s]", accessTokenID)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	delete(r.Db.tokens, accessTokenID)
+
+	return nil
+}
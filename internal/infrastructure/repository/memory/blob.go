@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// AcquireBlob добавляет ссылку документа на файл blob.Digest и возвращает запись о файле.
+// Новый файл записывается с ключом данных и сжатием из blob, а уже известный файл сохраняет свои
+func (r *MetadataRepo) AcquireBlob(documentUUID string, blob model.FileBlob) (model.FileBlob, error) {
+	digest := blob.Digest
+
+	log.Infof("acquiring file blob [%s] for document [%s]", digest, documentUUID)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	stored, ok := r.Db.blobs[digest]
+	if ok {
+		blob = stored
+	} else {
+		blob.CreatedAt = time.Now()
+		r.Db.blobs[digest] = blob
+		r.Db.blobRefs[digest] = make(map[string]struct{})
+	}
+
+	r.Db.blobRefs[digest][documentUUID] = struct{}{}
+
+	log.Infof("file blob [%s] acquired, stored: %t", digest, blob.Stored)
+
+	return blob, nil
+}
+
+// MarkBlobStored отмечает, что файл записан в хранилище
+func (r *MetadataRepo) MarkBlobStored(digest string) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	if blob, ok := r.Db.blobs[digest]; ok {
+		blob.Stored = true
+		r.Db.blobs[digest] = blob
+	}
+
+	return nil
+}
+
+// ReleaseBlob удаляет ссылку документа на файл. Если ссылок на файл не осталось,
+// remove удаляет его из хранилища под блокировкой, после чего удаляется и запись о файле
+func (r *MetadataRepo) ReleaseBlob(documentUUID, digest string, remove func() error) error {
+	log.Infof("releasing file blob [%s] for document [%s]", digest, documentUUID)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	refs, ok := r.Db.blobRefs[digest]
+	if !ok {
+		return nil
+	}
+
+	delete(refs, documentUUID)
+
+	if len(refs) > 0 {
+		log.Infof("file blob [%s] released", digest)
+		return nil
+	}
+
+	if err := remove(); err != nil {
+		log.Debugf("failed to release file blob: %+v", err)
+		refs[documentUUID] = struct{}{}
+
+		return fmt.Errorf("failed to release file blob [%s]", digest)
+	}
+
+	delete(r.Db.blobs, digest)
+	delete(r.Db.blobRefs, digest)
+
+	log.Infof("file blob [%s] has no references left and was deleted", digest)
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/cache"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ cache.Document = (*DocumentCacheRepo)(nil)
+
+// DocumentCacheRepo кэш документов в памяти вместо Redis.
+// Устаревшие записи удаляются при чтении и при каждой записи в кэш
+type DocumentCacheRepo struct {
+	Cfg *config.Config
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// cacheEntry содержимое документа и метаданные, нужные для проверки прав доступа
+type cacheEntry struct {
+	data      []byte
+	meta      model.MetaDocument
+	expiresAt time.Time
+}
+
+func NewDocumentCacheRepo(cfg *config.Config) *DocumentCacheRepo {
+	return &DocumentCacheRepo{
+		Cfg:     cfg,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func (r *DocumentCacheRepo) Set(_ context.Context, document model.MetaDocument, data interface{}) {
+	uuid := document.UUID
+
+	log.Infof("setting document [%s] to cache", uuid)
+
+	var (
+		file       []byte
+		jsonDocMap map[string]interface{}
+		err        error
+	)
+
+	switch value := data.(type) {
+	case []byte:
+		file = value
+	case map[string]interface{}:
+		jsonDocMap = value
+	}
+
+	if !document.File {
+		file, err = json.Marshal(entity.ApiResponse{Data: jsonDocMap})
+		if err != nil {
+			log.Debugf("failed to marshal document json: %+v", err)
+			return
+		}
+	}
+
+	// права доступа храним вместе с содержимым, чтобы чтение из кэша
+	// проверялось так же, как чтение из хранилища
+	meta := model.MetaDocument{
+		UUID:      uuid,
+		Mime:      document.Mime,
+		Owner:     document.Owner,
+		Public:    document.Public,
+		Grant:     document.Grant,
+		Hash:      document.Hash,
+		Size:      int64(len(file)),
+		UpdatedAt: document.UpdatedAt,
+	}
+
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, entry := range r.entries {
+		if now.After(entry.expiresAt) {
+			delete(r.entries, key)
+		}
+	}
+
+	r.entries[uuid] = cacheEntry{
+		data:      append([]byte(nil), file...),
+		meta:      cloneDocument(meta),
+		expiresAt: now.Add(r.Cfg.CacheTTL),
+	}
+
+	log.Infof("document [%s] successfully cached", uuid)
+}
+
+func (r *DocumentCacheRepo) Get(_ context.Context, uuid string) ([]byte, model.MetaDocument, bool) {
+	log.Infof("retrieving document [%s] from cache", uuid)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[uuid]
+	if !ok {
+		return nil, model.MetaDocument{UUID: uuid}, false
+	}
+
+	if time.Now().After(entry.expiresAt) {
+		delete(r.entries, uuid)
+		return nil, model.MetaDocument{UUID: uuid}, false
+	}
+
+	log.Infof("document [%s] successfully retrieved from cache", uuid)
+
+	return append([]byte(nil), entry.data...), cloneDocument(entry.meta), true
+}
+
+func (r *DocumentCacheRepo) Delete(_ context.Context, uuid string) {
+	log.Infof("deleting document [%s] from cache", uuid)
+
+	r.mu.Lock()
+	delete(r.entries, uuid)
+	r.mu.Unlock()
+
+	log.Infof("document [%s] successfully deleted from cache", uuid)
+}
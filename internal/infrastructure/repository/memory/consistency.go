@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+)
+
+var _ postgres.Consistency = (*ConsistencyRepo)(nil)
+
+type ConsistencyRepo struct {
+	Db *Database
+
+	// checking не дает запустить две проверки согласованности одновременно
+	checking sync.Mutex
+}
+
+// sagaContentMeta поля состояния документа в журнале саги, по которым определяется ключ содержимого
+type sagaContentMeta struct {
+	UUID       string `json:"uuid"`
+	ContentKey string `json:"content_key"`
+	File       bool   `json:"file"`
+}
+
+func NewConsistencyRepo(db *Database) *ConsistencyRepo {
+	return &ConsistencyRepo{Db: db}
+}
+
+// Lock занимает блокировку проверки согласованности.
+// Возвращает false, если проверка уже выполняется
+func (r *ConsistencyRepo) Lock(_ context.Context) (func(), bool, error) {
+	if !r.checking.TryLock() {
+		return nil, false, nil
+	}
+
+	return r.checking.Unlock, true, nil
+}
+
+// GetContentRefs возвращает ключи содержимого, на которые ссылаются документы, их версии,
+// незавершенные саги и загрузки. Для документов и версий, созданных до появления ключей, ключом служит UUID документа
+func (r *ConsistencyRepo) GetContentRefs() ([]entity.ContentRef, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	refs := make([]entity.ContentRef, 0, len(r.Db.documents)+len(r.Db.versions))
+
+	for _, document := range r.Db.documents {
+		refs = append(refs, entity.ContentRef{
+			Source:       entity.ContentRefDocument,
+			DocumentUUID: document.UUID,
+			Version:      document.Version,
+			ContentKey:   document.StorageKey(),
+			File:         document.File,
+			Broken:       document.Broken,
+		})
+	}
+
+	for _, version := range r.Db.versions {
+		refs = append(refs, entity.ContentRef{
+			Source:       entity.ContentRefVersion,
+			DocumentUUID: version.DocumentUUID,
+			Version:      version.Version,
+			ContentKey:   version.StorageKey(),
+			File:         version.File,
+		})
+	}
+
+	// сага хранит в журнале новое и прежнее состояние документа, содержимое обоих нужно ей для восстановления
+	for _, saga := range r.Db.sagas {
+		if saga.IsFinished() {
+			continue
+		}
+
+		var payload struct {
+			Meta    *sagaContentMeta `json:"meta"`
+			OldMeta *sagaContentMeta `json:"old_meta"`
+		}
+
+		if err := json.Unmarshal([]byte(saga.Payload), &payload); err != nil {
+			log.Warnf("failed to decode payload of saga [%s]: %+v", saga.UUID, err)
+			continue
+		}
+
+		for _, meta := range []*sagaContentMeta{payload.Meta, payload.OldMeta} {
+			if meta == nil {
+				continue
+			}
+
+			key := meta.ContentKey
+			if key == "" {
+				key = meta.UUID
+			}
+
+			refs = append(refs, entity.ContentRef{
+				Source:       entity.ContentRefSaga,
+				DocumentUUID: saga.DocumentUUID,
+				ContentKey:   key,
+				File:         meta.File,
+			})
+		}
+	}
+
+	// файл загрузки удаляется после создания документа, до этого на него ссылается загрузка
+	for _, upload := range r.Db.uploads {
+		if upload.Completed() {
+			continue
+		}
+
+		refs = append(refs, entity.ContentRef{
+			Source:     entity.ContentRefUpload,
+			ContentKey: upload.StorageKey(),
+			File:       true,
+		})
+	}
+
+	return refs, nil
+}
+
+// SetBroken устанавливает или снимает признак документа без содержимого
+func (r *ConsistencyRepo) SetBroken(uuids []string, broken bool) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	for i := range r.Db.documents {
+		if slices.Contains(uuids, r.Db.documents[i].UUID) {
+			r.Db.documents[i].Broken = broken
+		}
+	}
+
+	return nil
+}
+
+// DeleteBlob удаляет запись об осиротевшем файле, а remove удаляет файл из хранилища под блокировкой.
+// Если на файл появились ссылки после проверки, файл не удаляется и возвращается false
+func (r *ConsistencyRepo) DeleteBlob(digest string, remove func() error) (bool, error) {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	if len(r.Db.blobRefs[digest]) > 0 {
+		return false, nil
+	}
+
+	if err := remove(); err != nil {
+		log.Debugf("failed to delete orphan file blob: %+v", err)
+		return false, fmt.Errorf("failed to delete file blob [%s]", digest)
+	}
+
+	delete(r.Db.blobs, digest)
+	delete(r.Db.blobRefs, digest)
+
+	return true, nil
+}
@@ -0,0 +1,184 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/mongodb"
+)
+
+var _ mongodb.ContentRepository = (*ContentRepo)(nil)
+
+// ContentRepo хранит содержимое JSON документов в памяти вместо MongoDB.
+// Содержимое хранится в виде JSON, поэтому вызывающий код не может изменить сохраненный документ
+type ContentRepo struct {
+	mu       sync.RWMutex
+	contents map[string][]byte
+}
+
+func NewContentRepository() *ContentRepo {
+	return &ContentRepo{
+		contents: make(map[string][]byte),
+	}
+}
+
+func (r *ContentRepo) Store(_ context.Context, uuid string, jsonDoc map[string]interface{}) error {
+	log.Infof("saving document [%s] content", uuid)
+
+	data, err := json.Marshal(jsonDoc)
+	if err != nil {
+		log.Debugf("failed to save document content: %+v", err)
+		return fmt.Errorf("failed to save document [%s] content", uuid)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.contents[uuid]; ok {
+		log.Debugf("failed to save document content: content [%s] already exists", uuid)
+		return fmt.Errorf("failed to save document [%s] content", uuid)
+	}
+
+	r.contents[uuid] = data
+
+	log.Infof("document [%s] content saved successfully", uuid)
+
+	return nil
+}
+
+func (r *ContentRepo) GetByDocumentId(_ context.Context, uuid string) (map[string]interface{}, error) {
+	log.Infof("retrieving document [%s] content from memory", uuid)
+
+	r.mu.RLock()
+	data, ok := r.contents[uuid]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, custom_error.ErrDocumentNotFound
+	}
+
+	result, err := decodeContent(uuid, data)
+	if err != nil {
+		log.Debugf("failed to retrieve document content: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve document [%s] content", uuid)
+	}
+
+	log.Infof("document [%s] content retrieved successfully", uuid)
+
+	return result, nil
+}
+
+func (r *ContentRepo) DeleteByDocumentId(_ context.Context, uuid string) error {
+	log.Infof("deleting document [%s] content from memory", uuid)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.contents[uuid]; !ok {
+		log.Debugf("failed to delete document content: %+v", custom_error.ErrDocumentNotFound)
+		return custom_error.ErrDocumentNotFound
+	}
+
+	delete(r.contents, uuid)
+
+	log.Infof("document [%s] content deleted successfully", uuid)
+
+	return nil
+}
+
+// ContentKeys возвращает ключи всего хранимого содержимого
+func (r *ContentRepo) ContentKeys(_ context.Context) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]string, 0, len(r.contents))
+	for key := range r.contents {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	log.Infof("%d document content keys retrieved", len(keys))
+
+	return keys, nil
+}
+
+// Find возвращает содержимое, подходящее под условия, в порядке возрастания ключа.
+// Поиск начинается после ключа afterKey, fields ограничивает возвращаемые поля.
+// Ключ содержимого всегда возвращается в поле _id
+func (r *ContentRepo) Find(ctx context.Context, conditions []entity.ContentCondition, fields []string, afterKey string, limit int) ([]map[string]interface{}, string, error) {
+	log.Info("searching documents content")
+
+	for _, condition := range conditions {
+		if !contentOperators[condition.Op] {
+			return nil, "", fmt.Errorf("%w: unknown operator [%s]", custom_error.ErrInvalidContentQuery, condition.Op)
+		}
+	}
+
+	keys, err := r.ContentKeys(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	start := sort.SearchStrings(keys, afterKey)
+	if start < len(keys) && keys[start] == afterKey {
+		start++
+	}
+
+	result := make([]map[string]interface{}, 0, limit)
+
+	for _, key := range keys[start:] {
+		if len(result) == limit {
+			break
+		}
+
+		r.mu.RLock()
+		data, ok := r.contents[key]
+		r.mu.RUnlock()
+
+		// содержимое удалено после получения списка ключей
+		if !ok {
+			continue
+		}
+
+		content, err := decodeContent(key, data)
+		if err != nil {
+			log.Debugf("failed to search documents content: %+v", err)
+			return nil, "", fmt.Errorf("failed to search documents content")
+		}
+
+		if entity.MatchContent(content, conditions) {
+			result = append(result, entity.ProjectContent(content, fields))
+		}
+	}
+
+	next := ""
+	if len(result) == limit {
+		next, _ = result[len(result)-1][entity.ContentIDField].(string)
+	}
+
+	log.Infof("documents content found: %d", len(result))
+
+	return result, next, nil
+}
+
+// decodeContent восстанавливает содержимое вместе с ключом в поле _id
+func decodeContent(key string, data []byte) (map[string]interface{}, error) {
+	var content map[string]interface{}
+
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+
+	content[entity.ContentIDField] = key
+
+	return content, nil
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// Database хранит в памяти таблицы, которые в обычном режиме лежат в Postgres.
+// Репозитории работают с общим экземпляром под одной блокировкой,
+// поэтому изменения нескольких таблиц выполняются атомарно, как в транзакции.
+// Данные теряются при остановке сервиса
+type Database struct {
+	mu sync.RWMutex
+
+	// lastID последний выданный идентификатор, общий для всех таблиц
+	lastID uint
+
+	documents []model.MetaDocument
+	versions  []model.DocumentVersion
+	searches  map[string]model.DocumentSearch
+	blobs     map[string]model.FileBlob
+	// blobRefs документы, ссылающиеся на файл, по хешу файла
+	blobRefs   map[string]map[string]struct{}
+	users      []model.User
+	tokens     map[string]model.Token
+	sagas      []model.Saga
+	sagaSteps  []model.SagaStep
+	outbox     []model.OutboxEvent
+	webhooks   []model.WebhookSubscription
+	deliveries []model.WebhookDelivery
+	uploads    map[string]model.Upload
+	// uploadParts части загрузок по UUID загрузки и номеру части
+	uploadParts map[string]map[int]model.UploadPart
+	shares      []model.ShareLink
+	// shareAccesses журнал обращений по ссылкам в порядке добавления
+	shareAccesses []model.ShareAccess
+}
+
+func NewDatabase() *Database {
+	return &Database{
+		searches:    make(map[string]model.DocumentSearch),
+		blobs:       make(map[string]model.FileBlob),
+		blobRefs:    make(map[string]map[string]struct{}),
+		tokens:      make(map[string]model.Token),
+		uploads:     make(map[string]model.Upload),
+		uploadParts: make(map[string]map[int]model.UploadPart),
+	}
+}
+
+// nextID выдает идентификатор новой записи, вызывается под блокировкой на запись
+func (db *Database) nextID() uint {
+	db.lastID++
+
+	return db.lastID
+}
+
+// cloneDocument копирует документ вместе со списком доступа, чтобы вызывающий код не менял хранимую запись
+func cloneDocument(document model.MetaDocument) model.MetaDocument {
+	document.Grant = slices.Clone(document.Grant)
+
+	return document
+}
+
+func cloneSubscription(subscription model.WebhookSubscription) model.WebhookSubscription {
+	subscription.Events = slices.Clone(subscription.Events)
+
+	return subscription
+}
+
+// cloneUpload копирует загрузку вместе со списком доступа и остатком до части
+func cloneUpload(upload model.Upload) model.Upload {
+	upload.Grant = slices.Clone(upload.Grant)
+	upload.Pending = slices.Clone(upload.Pending)
+	upload.HashState = slices.Clone(upload.HashState)
+
+	return upload
+}
+
+// cloneShareLink копирует ссылку вместе со сроком действия и временем отзыва
+func cloneShareLink(link model.ShareLink) model.ShareLink {
+	if link.ExpiresAt != nil {
+		expiresAt := *link.ExpiresAt
+		link.ExpiresAt = &expiresAt
+	}
+
+	if link.RevokedAt != nil {
+		revokedAt := *link.RevokedAt
+		link.RevokedAt = &revokedAt
+	}
+
+	return link
+}
@@ -0,0 +1,294 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ postgres.MetadataRepository = (*MetadataRepo)(nil)
+
+type MetadataRepo struct {
+	Db *Database
+}
+
+func NewMetadataRepository(db *Database) *MetadataRepo {
+	return &MetadataRepo{Db: db}
+}
+
+// Save сохраняет метаданные нового документа вместе с событиями за одну блокировку
+func (r *MetadataRepo) Save(document *model.MetaDocument, events ...model.OutboxEvent) error {
+	log.Infof("saving document [%s] metadata to memory", document.UUID)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	now := time.Now()
+
+	document.ID = r.Db.nextID()
+	if document.CreatedAt.IsZero() {
+		document.CreatedAt = now
+	}
+	if document.UpdatedAt.IsZero() {
+		document.UpdatedAt = now
+	}
+
+	r.Db.documents = append(r.Db.documents, cloneDocument(*document))
+	r.Db.addEvents(events)
+
+	log.Infof("document [%s] metadata saved successfully", document.UUID)
+
+	return nil
+}
+
+// Update перезаписывает документ с тем же идентификатором целиком.
+// Документ без идентификатора или отсутствующий в хранилище добавляется, как при сохранении в Postgres.
+// События записываются за ту же блокировку
+func (r *MetadataRepo) Update(document *model.MetaDocument, events ...model.OutboxEvent) error {
+	log.Infof("updating document [%s] metadata", document.UUID)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	now := time.Now()
+	document.UpdatedAt = now
+
+	i := slices.IndexFunc(r.Db.documents, func(d model.MetaDocument) bool {
+		return document.ID != 0 && d.ID == document.ID
+	})
+	if i < 0 {
+		if document.ID == 0 {
+			document.ID = r.Db.nextID()
+		}
+		if document.CreatedAt.IsZero() {
+			document.CreatedAt = now
+		}
+
+		r.Db.documents = append(r.Db.documents, cloneDocument(*document))
+		slices.SortFunc(r.Db.documents, func(a, b model.MetaDocument) int {
+			return compareID(a.ID, b.ID)
+		})
+		r.Db.addEvents(events)
+
+		log.Infof("document [%s] metadata updated successfully", document.UUID)
+
+		return nil
+	}
+
+	if document.CreatedAt.IsZero() {
+		document.CreatedAt = r.Db.documents[i].CreatedAt
+	}
+
+	r.Db.documents[i] = cloneDocument(*document)
+	r.Db.addEvents(events)
+
+	log.Infof("document [%s] metadata updated successfully", document.UUID)
+
+	return nil
+}
+
+func (r *MetadataRepo) GetList(req entity.DocumentListRequest) (entity.DocumentList, error) {
+	log.Info("retrieving documents list from memory")
+
+	var list entity.DocumentList
+
+	if _, ok := entity.SortFields[req.Sort]; !ok {
+		return list, fmt.Errorf("%w: unknown sort field [%s]", custom_error.ErrInvalidListParams, req.Sort)
+	}
+
+	var cursor *entity.ListCursor
+
+	if req.Cursor != "" {
+		decoded, err := req.DecodeCursor()
+		if err != nil {
+			return list, err
+		}
+
+		cursor = &decoded
+	}
+
+	documents, err := r.listDocuments(req)
+	if err != nil {
+		return list, err
+	}
+
+	list.Total = int64(len(documents))
+
+	slices.SortFunc(documents, func(a, b model.MetaDocument) int {
+		return compareDocuments(a, b, req.Sort, req.Order)
+	})
+
+	if cursor != nil {
+		after, err := cursorDocument(*cursor)
+		if err != nil {
+			return list, err
+		}
+
+		documents = slices.DeleteFunc(documents, func(d model.MetaDocument) bool {
+			return compareDocuments(d, after, req.Sort, req.Order) <= 0
+		})
+	}
+
+	documents = page(documents, req.Offset, req.Limit+1)
+
+	// лишний документ показывает, что есть следующая страница
+	if len(documents) > req.Limit {
+		documents = documents[:req.Limit]
+		list.NextCursor = req.NewListCursor(documents[len(documents)-1])
+	}
+
+	list.Docs = documents
+
+	log.Info("documents list retrieved successfully")
+
+	return list, nil
+}
+
+func (r *MetadataRepo) Count(req entity.DocumentListRequest) (int64, error) {
+	log.Info("counting documents in memory")
+
+	documents, err := r.listDocuments(req)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Infof("documents counted successfully: %d", len(documents))
+
+	return int64(len(documents)), nil
+}
+
+// GetReadableByStorageKeys возвращает метаданные JSON документов с текущим содержимым по ключам keys,
+// доступные пользователю viewer для чтения
+func (r *MetadataRepo) GetReadableByStorageKeys(viewer string, keys []string) ([]model.MetaDocument, error) {
+	log.Infof("retrieving metadata of %d documents by content keys", len(keys))
+
+	documents := make([]model.MetaDocument, 0, len(keys))
+
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	for _, document := range r.Db.documents {
+		if document.File || !slices.Contains(keys, document.StorageKey()) {
+			continue
+		}
+
+		if document.Public || document.Owner == viewer || slices.Contains(document.Grant, viewer) {
+			documents = append(documents, cloneDocument(document))
+		}
+	}
+
+	log.Infof("metadata of %d documents retrieved successfully", len(documents))
+
+	return documents, nil
+}
+
+// listDocuments возвращает документы, подходящие под фильтры и доступные пользователю
+func (r *MetadataRepo) listDocuments(req entity.DocumentListRequest) ([]model.MetaDocument, error) {
+	filters := req.AllFilters()
+
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	documents := make([]model.MetaDocument, 0)
+
+	for _, document := range r.Db.documents {
+		ok, err := matchFilters(document, filters)
+		if err != nil {
+			log.Debugf("failed to filter documents list: %+v", err)
+			return nil, err
+		}
+
+		if ok && canList(document, req.Login, req.Viewer) {
+			documents = append(documents, cloneDocument(document))
+		}
+	}
+
+	return documents, nil
+}
+
+// canList повторяет проверку прав доступа списка документов в Postgres
+func canList(document model.MetaDocument, login, viewer string) bool {
+	granted := slices.Contains(document.Grant, viewer)
+
+	if login == viewer {
+		// собственные документы и документы, к которым пользователю выдан доступ
+		return document.Owner == viewer || granted
+	}
+
+	// документы другого пользователя, доступные запрашивающему
+	return document.Owner == login && (document.Public || granted)
+}
+
+func (r *MetadataRepo) GetById(uuid string) (model.MetaDocument, error) {
+	log.Infof("retrieving document [%s] metadata", uuid)
+
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	i := slices.IndexFunc(r.Db.documents, func(d model.MetaDocument) bool {
+		return d.UUID == uuid
+	})
+	if i < 0 {
+		log.Infof("document [%s] metadata not found", uuid)
+		return model.MetaDocument{}, custom_error.ErrDocumentNotFound
+	}
+
+	log.Infof("document [%s] metadata retrieved successfully", uuid)
+
+	return cloneDocument(r.Db.documents[i]), nil
+}
+
+// DeleteById удаляет метаданные документа. События записываются за ту же блокировку,
+// только если документ был удален этим вызовом
+func (r *MetadataRepo) DeleteById(id string, events ...model.OutboxEvent) error {
+	log.Infof("deleting document [%s] metadata", id)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	count := len(r.Db.documents)
+
+	r.Db.documents = slices.DeleteFunc(r.Db.documents, func(d model.MetaDocument) bool {
+		return d.UUID == id
+	})
+
+	if len(r.Db.documents) < count {
+		r.Db.addEvents(events)
+	}
+
+	log.Infof("document [%s] metadata deleted successfully", id)
+
+	return nil
+}
+
+// page возвращает не более limit записей, начиная с offset
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return items[:0]
+	}
+
+	items = items[offset:]
+	if limit >= 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	return items
+}
+
+func compareID(a, b uint) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ postgres.EncryptionKeys = (*EncryptionKeyRepo)(nil)
+
+// EncryptionKeyRepo ключи данных содержимого, которые хранятся в метаданных документов, версиях и записях о файлах
+type EncryptionKeyRepo struct {
+	Db *Database
+}
+
+func NewEncryptionKeyRepo(db *Database) *EncryptionKeyRepo {
+	return &EncryptionKeyRepo{Db: db}
+}
+
+// GetDataKeys возвращает ключи данных зашифрованного содержимого по ключам хранения
+func (r *EncryptionKeyRepo) GetDataKeys(storageKeys []string) (map[string]model.DataKey, error) {
+	keys := make(map[string]model.DataKey, len(storageKeys))
+
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	r.Db.eachDataKey(func(storageKey string, dataKey *model.DataKey) {
+		if dataKey.Encrypted() && slices.Contains(storageKeys, storageKey) {
+			keys[storageKey] = *dataKey
+		}
+	})
+
+	return keys, nil
+}
+
+// GetStaleDataKeys возвращает ключи данных, зашифрованные не активным мастер-ключом,
+// в порядке возрастания зашифрованного ключа после afterKey
+func (r *EncryptionKeyRepo) GetStaleDataKeys(activeKeyID, afterKey string, limit int) ([]model.DataKey, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	stale := make(map[string]model.DataKey)
+
+	r.Db.eachDataKey(func(_ string, dataKey *model.DataKey) {
+		if dataKey.Encrypted() && dataKey.KeyID != activeKeyID && dataKey.WrappedKey > afterKey {
+			stale[dataKey.WrappedKey] = *dataKey
+		}
+	})
+
+	keys := make([]model.DataKey, 0, len(stale))
+	for _, dataKey := range stale {
+		keys = append(keys, dataKey)
+	}
+
+	slices.SortFunc(keys, func(a, b model.DataKey) int {
+		return strings.Compare(a.WrappedKey, b.WrappedKey)
+	})
+
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	return keys, nil
+}
+
+// ReplaceDataKey заменяет зашифрованный ключ данных во всех таблицах
+func (r *EncryptionKeyRepo) ReplaceDataKey(oldKey, newKey model.DataKey) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	r.Db.eachDataKey(func(_ string, dataKey *model.DataKey) {
+		if dataKey.WrappedKey == oldKey.WrappedKey {
+			*dataKey = newKey
+		}
+	})
+
+	return nil
+}
+
+// eachDataKey обходит ключи данных документов, версий и файлов вместе с ключами хранения содержимого.
+// Вызывается под блокировкой
+func (db *Database) eachDataKey(fn func(storageKey string, dataKey *model.DataKey)) {
+	for i := range db.documents {
+		fn(db.documents[i].StorageKey(), &db.documents[i].DataKey)
+	}
+
+	for i := range db.versions {
+		fn(db.versions[i].StorageKey(), &db.versions[i].DataKey)
+	}
+
+	for digest, blob := range db.blobs {
+		dataKey := blob.DataKey
+		fn(digest, &blob.DataKey)
+
+		// запись в таблицу только при изменении, чтение выполняется под блокировкой на чтение
+		if blob.DataKey != dataKey {
+			db.blobs[digest] = blob
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	filestorage "github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/file_storage"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ filestorage.MultipartRepository = (*FileRepo)(nil)
+
+// FileRepo хранит файлы в памяти вместо MinIO
+type FileRepo struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+	// multiparts части незавершенных multipart загрузок по идентификатору загрузки
+	multiparts map[string]map[int][]byte
+}
+
+// fileReader содержимое файла для чтения. Файл не меняется после записи,
+// поэтому читатель работает с ним без блокировки
+type fileReader struct {
+	*bytes.Reader
+}
+
+func (fileReader) Close() error {
+	return nil
+}
+
+func NewFileRepository() *FileRepo {
+	return &FileRepo{
+		files:      make(map[string][]byte),
+		multiparts: make(map[string]map[int][]byte),
+	}
+}
+
+func (r *FileRepo) Upload(ctx context.Context, documentId string, reader io.Reader, size int64) (int64, error) {
+	log.Infof("uploading saga [%s] file", documentId)
+
+	data, err := io.ReadAll(reader)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil && size >= 0 && int64(len(data)) != size {
+		err = fmt.Errorf("file size mismatch: expected %d bytes, got %d", size, len(data))
+	}
+	if err != nil {
+		log.Debugf("failed to upload saga file: %+v", err)
+		return 0, fmt.Errorf("failed to upload saga [%s] file", documentId)
+	}
+
+	r.mu.Lock()
+	r.files[documentId] = data
+	r.mu.Unlock()
+
+	log.Infof("saga [%s] file uploaded successfully", documentId)
+
+	return int64(len(data)), nil
+}
+
+func (r *FileRepo) Download(_ context.Context, documentId string) (io.ReadSeekCloser, int64, error) {
+	log.Infof("downloading saga [%s] file", documentId)
+
+	r.mu.RLock()
+	data, ok := r.files[documentId]
+	r.mu.RUnlock()
+
+	if !ok {
+		log.Debugf("failed to get saga file: file [%s] not found", documentId)
+		return nil, 0, fmt.Errorf("failed to get saga [%s] file", documentId)
+	}
+
+	log.Infof("saga [%s] file opened for download", documentId)
+
+	return fileReader{Reader: bytes.NewReader(data)}, int64(len(data)), nil
+}
+
+func (r *FileRepo) Delete(_ context.Context, documentId string) error {
+	log.Infof("deleting saga [%s] file", documentId)
+
+	r.mu.Lock()
+	delete(r.files, documentId)
+	r.mu.Unlock()
+
+	log.Infof("saga [%s] file deleted successfully", documentId)
+
+	return nil
+}
+
+// Copy копирует файл srcKey в dstKey. Файл не меняется после записи, поэтому копия разделяет его содержимое
+func (r *FileRepo) Copy(_ context.Context, srcKey, dstKey string) error {
+	log.Infof("copying file [%s] to [%s]", srcKey, dstKey)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, ok := r.files[srcKey]
+	if !ok {
+		log.Debugf("failed to copy file: file [%s] not found", srcKey)
+		return fmt.Errorf("failed to copy file [%s] to [%s]", srcKey, dstKey)
+	}
+
+	r.files[dstKey] = data
+
+	return nil
+}
+
+// FileKeys возвращает ключи всех хранимых файлов
+func (r *FileRepo) FileKeys(_ context.Context) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]string, 0, len(r.files))
+	for key := range r.files {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys, nil
+}
+
+// CreateMultipart начинает multipart загрузку файла key и возвращает ее идентификатор
+func (r *FileRepo) CreateMultipart(_ context.Context, key string) (string, error) {
+	log.Infof("starting multipart upload of file [%s]", key)
+
+	uploadID := uuid.NewString()
+
+	r.mu.Lock()
+	r.multiparts[uploadID] = make(map[int][]byte)
+	r.mu.Unlock()
+
+	return uploadID, nil
+}
+
+// UploadPart записывает часть number multipart загрузки и возвращает ее ETag
+func (r *FileRepo) UploadPart(_ context.Context, key, uploadID string, number int, reader io.Reader, size int64) (string, error) {
+	data, err := io.ReadAll(reader)
+	if err == nil && int64(len(data)) != size {
+		err = fmt.Errorf("part size mismatch: expected %d bytes, got %d", size, len(data))
+	}
+	if err != nil {
+		log.Debugf("failed to upload file part: %+v", err)
+		return "", fmt.Errorf("failed to upload part %d of file [%s]", number, key)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parts, ok := r.multiparts[uploadID]
+	if !ok {
+		log.Debugf("failed to upload file part: multipart upload [%s] not found", uploadID)
+		return "", fmt.Errorf("failed to upload part %d of file [%s]", number, key)
+	}
+
+	parts[number] = data
+
+	return strconv.Itoa(number), nil
+}
+
+// CompleteMultipart собирает файл из частей parts, перечисленных по возрастанию номера
+func (r *FileRepo) CompleteMultipart(_ context.Context, key, uploadID string, parts []model.UploadPart) error {
+	log.Infof("completing multipart upload of file [%s]", key)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.multiparts[uploadID]
+	if !ok {
+		log.Debugf("failed to complete multipart upload: multipart upload [%s] not found", uploadID)
+		return fmt.Errorf("failed to complete multipart upload of file [%s]", key)
+	}
+
+	var data []byte
+
+	for _, part := range parts {
+		content, ok := stored[part.Number]
+		if !ok {
+			log.Debugf("failed to complete multipart upload: part %d not found", part.Number)
+			return fmt.Errorf("failed to complete multipart upload of file [%s]", key)
+		}
+
+		data = append(data, content...)
+	}
+
+	r.files[key] = data
+	delete(r.multiparts, uploadID)
+
+	log.Infof("multipart upload of file [%s] completed successfully", key)
+
+	return nil
+}
+
+// AbortMultipart отменяет multipart загрузку и удаляет записанные части
+func (r *FileRepo) AbortMultipart(_ context.Context, key, uploadID string) error {
+	log.Infof("aborting multipart upload of file [%s]", key)
+
+	r.mu.Lock()
+	delete(r.multiparts, uploadID)
+	r.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// matchFilters проверяет документ на все условия фильтров, как это делает запрос к Postgres
+func matchFilters(document model.MetaDocument, filters []entity.DocumentFilter) (bool, error) {
+	for _, filter := range filters {
+		ok, err := matchFilter(document, filter)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func matchFilter(document model.MetaDocument, filter entity.DocumentFilter) (bool, error) {
+	switch filter.Field {
+	case "name", "mime":
+		value := document.Name
+		if filter.Field == "mime" {
+			value = document.Mime
+		}
+
+		switch filter.Op {
+		case entity.FilterOpEq:
+			return value == filter.Value, nil
+		case entity.FilterOpNe:
+			return value != filter.Value, nil
+		case entity.FilterOpIn:
+			return slices.Contains(filter.Values, value), nil
+		case entity.FilterOpPrefix:
+			return strings.HasPrefix(value, filter.Value), nil
+		}
+	case "file", "public":
+		value := document.File
+		if filter.Field == "public" {
+			value = document.Public
+		}
+
+		switch filter.Op {
+		case entity.FilterOpEq:
+			return value == filter.BoolValue(), nil
+		case entity.FilterOpNe:
+			return value != filter.BoolValue(), nil
+		}
+	case "created_at":
+		if filter.Op == entity.FilterOpRange {
+			if filter.From != nil && document.CreatedAt.Before(*filter.From) {
+				return false, nil
+			}
+
+			if filter.To != nil && document.CreatedAt.After(*filter.To) {
+				return false, nil
+			}
+
+			return true, nil
+		}
+	default:
+		return false, fmt.Errorf("%w: unknown field [%s]", custom_error.ErrInvalidFilter, filter.Field)
+	}
+
+	return false, fmt.Errorf("%w: unknown operator [%s]", custom_error.ErrInvalidFilter, filter.Op)
+}
+
+// compareDocuments сравнивает документы по полю сортировки, при равенстве - по идентификатору
+func compareDocuments(a, b model.MetaDocument, sort, order string) int {
+	var result int
+
+	switch sort {
+	case "name":
+		result = cmp.Compare(a.Name, b.Name)
+	case "mime":
+		result = cmp.Compare(a.Mime, b.Mime)
+	case "created_at":
+		result = a.CreatedAt.Compare(b.CreatedAt)
+	case "updated_at":
+		result = a.UpdatedAt.Compare(b.UpdatedAt)
+	}
+
+	if result == 0 {
+		result = compareID(a.ID, b.ID)
+	}
+
+	if order == entity.SortOrderDesc {
+		return -result
+	}
+
+	return result
+}
+
+// cursorDocument восстанавливает из курсора значения полей последнего документа страницы
+func cursorDocument(cursor entity.ListCursor) (model.MetaDocument, error) {
+	document := model.MetaDocument{ID: cursor.ID}
+
+	switch cursor.Sort {
+	case "name":
+		document.Name = cursor.Value
+	case "mime":
+		document.Mime = cursor.Value
+	case "created_at", "updated_at":
+		date, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return document, fmt.Errorf("%w: %w", custom_error.ErrInvalidCursor, err)
+		}
+
+		document.CreatedAt = date
+		document.UpdatedAt = date
+	}
+
+	return document, nil
+}
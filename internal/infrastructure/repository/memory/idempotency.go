@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/cache"
+)
+
+var _ cache.Idempotency = (*IdempotencyRepo)(nil)
+
+// IdempotencyRepo хранит результаты запросов с ключом идемпотентности в памяти вместо Redis
+type IdempotencyRepo struct {
+	mu      sync.Mutex
+	records map[string]idempotencyEntry
+}
+
+// idempotencyEntry запись ключа. Пустой record означает, что первый запрос еще выполняется
+type idempotencyEntry struct {
+	record    *entity.IdempotencyRecord
+	expiresAt time.Time
+}
+
+func NewIdempotencyRepo() *IdempotencyRepo {
+	return &IdempotencyRepo{
+		records: make(map[string]idempotencyEntry),
+	}
+}
+
+// Reserve занимает ключ на время выполнения запроса.
+// Возвращает false, если ключ уже занят другим запросом или хранит его результат
+func (r *IdempotencyRepo) Reserve(_ context.Context, login, key string, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.get(idempotencyKey(login, key)); ok {
+		return false, nil
+	}
+
+	r.records[idempotencyKey(login, key)] = idempotencyEntry{expiresAt: time.Now().Add(ttl)}
+
+	return true, nil
+}
+
+func (r *IdempotencyRepo) Get(_ context.Context, login, key string) (entity.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.get(idempotencyKey(login, key))
+	if !ok {
+		return entity.IdempotencyRecord{}, custom_error.ErrIdempotencyKeyNotFound
+	}
+
+	if entry.record == nil {
+		return entity.IdempotencyRecord{}, custom_error.ErrIdempotencyKeyInProgress
+	}
+
+	return *entry.record, nil
+}
+
+func (r *IdempotencyRepo) Save(_ context.Context, login, key string, record entity.IdempotencyRecord, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record.Body = append([]byte(nil), record.Body...)
+
+	r.records[idempotencyKey(login, key)] = idempotencyEntry{
+		record:    &record,
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRepo) Delete(_ context.Context, login, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.records, idempotencyKey(login, key))
+
+	return nil
+}
+
+// get возвращает действующую запись и удаляет устаревшие, вызывается под блокировкой
+func (r *IdempotencyRepo) get(key string) (idempotencyEntry, bool) {
+	now := time.Now()
+
+	for k, entry := range r.records {
+		if now.After(entry.expiresAt) {
+			delete(r.records, k)
+		}
+	}
+
+	entry, ok := r.records[key]
+
+	return entry, ok
+}
+
+// idempotencyKey ключи разных пользователей не пересекаются
+func idempotencyKey(login, key string) string {
+	return login + ":" + key
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ postgres.Outbox = (*OutboxRepo)(nil)
+
+type OutboxRepo struct {
+	Db *Database
+
+	// publishing не дает двум вызовам PublishPending отправлять события одновременно
+	publishing sync.Mutex
+}
+
+func NewOutboxRepo(db *Database) *OutboxRepo {
+	return &OutboxRepo{Db: db}
+}
+
+// addEvents записывает события в outbox вместе с изменением метаданных, вызывается под блокировкой
+func (db *Database) addEvents(events []model.OutboxEvent) {
+	now := time.Now()
+
+	for _, event := range events {
+		event.ID = db.nextID()
+		event.CreatedAt = now
+
+		db.outbox = append(db.outbox, event)
+	}
+}
+
+// PublishPending передает publish неопубликованные события в порядке записи и отмечает их опубликованными.
+// На первой ошибке публикация останавливается, чтобы не нарушить порядок событий документа,
+// ошибка сохраняется в событии. Пока события публикует один вызов, остальные пропускаются.
+// Возвращает количество опубликованных событий
+func (r *OutboxRepo) PublishPending(limit int, publish func(event model.OutboxEvent) error) (int, error) {
+	if !r.publishing.TryLock() {
+		return 0, nil
+	}
+	defer r.publishing.Unlock()
+
+	var published int
+
+	// блокировка хранилища не удерживается во время отправки, чтобы не останавливать запросы к документам
+	for _, event := range r.pending(limit) {
+		if pubErr := publish(event); pubErr != nil {
+			log.Debugf("failed to publish event [%s]: %+v", event.UUID, pubErr)
+
+			r.updateEvent(event.ID, func(e *model.OutboxEvent) {
+				e.Attempts++
+				e.Error = pubErr.Error()
+			})
+
+			break
+		}
+
+		publishedAt := time.Now()
+
+		r.updateEvent(event.ID, func(e *model.OutboxEvent) {
+			e.PublishedAt = &publishedAt
+		})
+
+		published++
+	}
+
+	if published > 0 {
+		log.Infof("%d outbox events published", published)
+	}
+
+	return published, nil
+}
+
+// DeletePublished удаляет события, опубликованные до момента before
+func (r *OutboxRepo) DeletePublished(before time.Time) (int64, error) {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	events := r.Db.outbox[:0]
+
+	for _, event := range r.Db.outbox {
+		if event.PublishedAt == nil || !event.PublishedAt.Before(before) {
+			events = append(events, event)
+		}
+	}
+
+	deleted := int64(len(r.Db.outbox) - len(events))
+	r.Db.outbox = events
+
+	return deleted, nil
+}
+
+// pending возвращает не более limit неопубликованных событий в порядке записи
+func (r *OutboxRepo) pending(limit int) []model.OutboxEvent {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	events := make([]model.OutboxEvent, 0, limit)
+
+	for _, event := range r.Db.outbox {
+		if len(events) == limit {
+			break
+		}
+
+		if event.PublishedAt == nil {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+func (r *OutboxRepo) updateEvent(id uint, update func(event *model.OutboxEvent)) {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	for i := range r.Db.outbox {
+		if r.Db.outbox[i].ID == id {
+			update(&r.Db.outbox[i])
+			return
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package memory
+
+import "github.com/AlexJudin/DocumentCacheServer/internal/entity"
+
+// contentOperators операторы условий поиска по содержимому, которые умеет проверять хранилище
+var contentOperators = map[string]bool{
+	entity.ContentOpEq:       true,
+	entity.ContentOpNe:       true,
+	entity.ContentOpGt:       true,
+	entity.ContentOpGte:      true,
+	entity.ContentOpLt:       true,
+	entity.ContentOpLte:      true,
+	entity.ContentOpExists:   true,
+	entity.ContentOpContains: true,
+}
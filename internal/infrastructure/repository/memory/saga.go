@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ postgres.SagaLog = (*SagaLogRepo)(nil)
+
+type SagaLogRepo struct {
+	Db *Database
+}
+
+func NewSagaLogRepo(db *Database) *SagaLogRepo {
+	return &SagaLogRepo{Db: db}
+}
+
+func (r *SagaLogRepo) Create(saga *model.Saga) error {
+	log.Debugf("saving saga [%s] of document [%s]", saga.UUID, saga.DocumentUUID)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	if slices.ContainsFunc(r.Db.sagas, func(s model.Saga) bool { return s.UUID == saga.UUID }) {
+		log.Debugf("failed to save saga: saga [%s] already exists", saga.UUID)
+		return fmt.Errorf("failed to save saga [%s]", saga.UUID)
+	}
+
+	now := time.Now()
+
+	saga.ID = r.Db.nextID()
+	if saga.CreatedAt.IsZero() {
+		saga.CreatedAt = now
+	}
+	if saga.UpdatedAt.IsZero() {
+		saga.UpdatedAt = now
+	}
+
+	r.Db.sagas = append(r.Db.sagas, *saga)
+
+	return nil
+}
+
+func (r *SagaLogRepo) Update(saga *model.Saga) error {
+	log.Debugf("updating saga [%s] status [%s]", saga.UUID, saga.Status)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	i := r.Db.sagaIndex(saga.UUID)
+	if i < 0 {
+		log.Debugf("failed to update saga: saga [%s] not found", saga.UUID)
+		return fmt.Errorf("failed to update saga [%s]", saga.UUID)
+	}
+
+	saga.ID = r.Db.sagas[i].ID
+	saga.UpdatedAt = time.Now()
+
+	r.Db.sagas[i] = *saga
+
+	return nil
+}
+
+// Touch продлевает незавершенную сагу, чтобы ее не забрало восстановление
+func (r *SagaLogRepo) Touch(uuid string) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	i := r.Db.sagaIndex(uuid)
+	if i >= 0 && isUnfinishedSaga(r.Db.sagas[i]) {
+		r.Db.sagas[i].UpdatedAt = time.Now()
+	}
+
+	return nil
+}
+
+// Claim забирает сагу для восстановления, если с момента чтения ее никто не изменил.
+// Возвращает false, если сагу уже забрал другой обработчик
+func (r *SagaLogRepo) Claim(saga *model.Saga) (bool, error) {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	i := r.Db.sagaIndex(saga.UUID)
+	if i < 0 || !r.Db.sagas[i].UpdatedAt.Equal(saga.UpdatedAt) {
+		return false, nil
+	}
+
+	now := time.Now()
+
+	r.Db.sagas[i].UpdatedAt = now
+	r.Db.sagas[i].Attempts++
+
+	saga.UpdatedAt = now
+	saga.Attempts++
+
+	return true, nil
+}
+
+// SaveStep сохраняет состояние шага саги
+func (r *SagaLogRepo) SaveStep(step model.SagaStep) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	now := time.Now()
+
+	i := slices.IndexFunc(r.Db.sagaSteps, func(s model.SagaStep) bool {
+		return s.SagaUUID == step.SagaUUID && s.Name == step.Name
+	})
+	if i >= 0 {
+		r.Db.sagaSteps[i].Status = step.Status
+		r.Db.sagaSteps[i].Attempts = step.Attempts
+		r.Db.sagaSteps[i].Error = step.Error
+		r.Db.sagaSteps[i].UpdatedAt = now
+
+		return nil
+	}
+
+	step.ID = r.Db.nextID()
+	step.CreatedAt = now
+	step.UpdatedAt = now
+
+	r.Db.sagaSteps = append(r.Db.sagaSteps, step)
+
+	return nil
+}
+
+func (r *SagaLogRepo) Get(uuid string) (model.Saga, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	i := r.Db.sagaIndex(uuid)
+	if i < 0 {
+		return model.Saga{}, custom_error.ErrSagaNotFound
+	}
+
+	return r.Db.sagas[i], nil
+}
+
+func (r *SagaLogRepo) GetSteps(uuid string) ([]model.SagaStep, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	steps := make([]model.SagaStep, 0)
+
+	for _, step := range r.Db.sagaSteps {
+		if step.SagaUUID == uuid {
+			steps = append(steps, step)
+		}
+	}
+
+	return steps, nil
+}
+
+// GetStale возвращает незавершенные саги, которые не обновлялись с момента before
+func (r *SagaLogRepo) GetStale(before time.Time, limit int) ([]model.Saga, error) {
+	sagas := r.Db.findSagas(func(saga model.Saga) bool {
+		return isUnfinishedSaga(saga) && saga.UpdatedAt.Before(before)
+	})
+
+	return page(sagas, 0, limit), nil
+}
+
+// GetStuck возвращает саги, восстановление которых не удалось,
+// и незавершенные саги, которые не обновлялись с момента before
+func (r *SagaLogRepo) GetStuck(before time.Time, limit, offset int) ([]model.Saga, int64, error) {
+	sagas := r.Db.findSagas(func(saga model.Saga) bool {
+		return saga.Status == model.SagaStatusFailed || (isUnfinishedSaga(saga) && saga.UpdatedAt.Before(before))
+	})
+
+	return page(sagas, offset, limit), int64(len(sagas)), nil
+}
+
+// findSagas возвращает подходящие саги в порядке времени последнего обновления
+func (db *Database) findSagas(match func(saga model.Saga) bool) []model.Saga {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	sagas := make([]model.Saga, 0)
+
+	for _, saga := range db.sagas {
+		if match(saga) {
+			sagas = append(sagas, saga)
+		}
+	}
+
+	slices.SortStableFunc(sagas, func(a, b model.Saga) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+
+	return sagas
+}
+
+// sagaIndex возвращает позицию саги в журнале или -1, вызывается под блокировкой
+func (db *Database) sagaIndex(uuid string) int {
+	return slices.IndexFunc(db.sagas, func(s model.Saga) bool {
+		return s.UUID == uuid
+	})
+}
+
+func isUnfinishedSaga(saga model.Saga) bool {
+	return saga.Status == model.SagaStatusRunning || saga.Status == model.SagaStatusCompensating
+}
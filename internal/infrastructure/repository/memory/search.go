@@ -0,0 +1,284 @@
+package memory
+
+import (
+	"cmp"
+	"html"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	// веса совпадений в названии и в тексте документа, как у весов A и B функции ts_rank
+	searchNameWeight = 1.0
+	searchBodyWeight = 0.4
+
+	// snippetWords длина фрагмента текста с найденными словами
+	snippetWords = 20
+)
+
+// IndexDocument сохраняет текст документа в полнотекстовый индекс
+func (r *MetadataRepo) IndexDocument(index model.DocumentSearch) error {
+	log.Infof("indexing document [%s]", index.DocumentUUID)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	index.UpdatedAt = time.Now()
+	r.Db.searches[index.DocumentUUID] = index
+
+	log.Infof("document [%s] indexed successfully", index.DocumentUUID)
+
+	return nil
+}
+
+// DeleteDocumentIndex удаляет документ из полнотекстового индекса
+func (r *MetadataRepo) DeleteDocumentIndex(uuid string) error {
+	log.Infof("deleting document [%s] from search index", uuid)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	delete(r.Db.searches, uuid)
+
+	log.Infof("document [%s] deleted from search index successfully", uuid)
+
+	return nil
+}
+
+// searchTerm слово или фраза запроса. Фраза совпадает, если ее слова идут в тексте подряд
+type searchTerm struct {
+	words   []string
+	exclude bool
+}
+
+// Search ищет документы, доступные пользователю, по словам запроса в порядке убывания релевантности.
+// Запрос разбирается как websearch_to_tsquery в Postgres: документ подходит, если хотя бы одна альтернатива
+// запроса совпадает с ним целиком. Слова сравниваются без учета регистра и без стемминга, как в конфигурации simple
+func (r *MetadataRepo) Search(req entity.SearchRequest) ([]entity.SearchHit, error) {
+	log.Info("searching documents")
+
+	groups := parseSearchQuery(req.Query)
+	include := includedWords(groups)
+
+	r.Db.mu.RLock()
+
+	hits := make([]entity.SearchHit, 0)
+
+	for _, document := range r.Db.documents {
+		if !document.CanRead(req.Viewer) {
+			continue
+		}
+
+		index, ok := r.Db.searches[document.UUID]
+		if !ok {
+			continue
+		}
+
+		rank, ok := searchRank(index, groups)
+		if !ok {
+			continue
+		}
+
+		hits = append(hits, entity.SearchHit{
+			Meta:    cloneDocument(document),
+			Rank:    rank,
+			Snippet: searchSnippet(index.Name+" "+index.Body, include),
+		})
+	}
+
+	r.Db.mu.RUnlock()
+
+	slices.SortStableFunc(hits, func(a, b entity.SearchHit) int {
+		return cmp.Compare(b.Rank, a.Rank)
+	})
+
+	hits = page(hits, req.Offset, req.Limit)
+
+	log.Infof("documents found: %d", len(hits))
+
+	return hits, nil
+}
+
+// parseSearchQuery разбирает запрос на альтернативы, разделенные словом OR. Слова и фразы в кавычках
+// внутри альтернативы объединяются условием И, слово или фраза с минусом исключается.
+// Слово, которое делится на несколько, например через дефис, ищется как фраза
+func parseSearchQuery(query string) [][]searchTerm {
+	var (
+		groups [][]searchTerm
+		group  []searchTerm
+	)
+
+	for query != "" {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		if query == "" {
+			break
+		}
+
+		term := searchTerm{}
+
+		if strings.HasPrefix(query, "-") {
+			term.exclude = true
+			query = query[1:]
+		}
+
+		var text string
+
+		if strings.HasPrefix(query, `"`) {
+			text, query, _ = strings.Cut(query[1:], `"`)
+		} else {
+			end := strings.IndexFunc(query, func(r rune) bool {
+				return unicode.IsSpace(r) || r == '"'
+			})
+			if end < 0 {
+				end = len(query)
+			}
+
+			text, query = query[:end], query[end:]
+
+			if !term.exclude && strings.EqualFold(text, "or") {
+				if len(group) > 0 {
+					groups = append(groups, group)
+					group = nil
+				}
+
+				continue
+			}
+		}
+
+		term.words = searchWords(text)
+		if len(term.words) > 0 {
+			group = append(group, term)
+		}
+	}
+
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// includedWords возвращает слова запроса без исключенных, которые выделяются во фрагменте текста
+func includedWords(groups [][]searchTerm) []string {
+	var words []string
+
+	for _, group := range groups {
+		for _, term := range group {
+			if !term.exclude {
+				words = append(words, term.words...)
+			}
+		}
+	}
+
+	return words
+}
+
+// searchWords делит текст на слова из букв и цифр в нижнем регистре
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchRank считает взвешенное количество вхождений слов и фраз лучшей совпавшей альтернативы запроса.
+// Как и вектор в Postgres, текст документа - это слова названия, за которыми идут слова тела.
+// Возвращает false, если документ не подходит под запрос
+func searchRank(index model.DocumentSearch, groups [][]searchTerm) (float64, bool) {
+	nameWords := searchWords(index.Name)
+	words := slices.Concat(nameWords, searchWords(index.Body))
+
+	var (
+		rank    float64
+		matched bool
+	)
+
+	for _, group := range groups {
+		groupRank, ok := groupSearchRank(words, len(nameWords), group)
+		if ok && (!matched || groupRank > rank) {
+			rank, matched = groupRank, true
+		}
+	}
+
+	return rank, matched
+}
+
+// groupSearchRank проверяет, что в тексте есть все слова и фразы альтернативы и нет исключенных.
+// Первые nameWords слов текста относятся к названию документа
+func groupSearchRank(words []string, nameWords int, group []searchTerm) (float64, bool) {
+	var rank float64
+
+	for _, term := range group {
+		var weight float64
+
+		for i := range words {
+			if !slices.Equal(words[i:min(i+len(term.words), len(words))], term.words) {
+				continue
+			}
+
+			if i < nameWords {
+				weight += searchNameWeight
+			} else {
+				weight += searchBodyWeight
+			}
+		}
+
+		if term.exclude {
+			if weight > 0 {
+				return 0, false
+			}
+
+			continue
+		}
+
+		if weight == 0 {
+			return 0, false
+		}
+
+		rank += weight
+	}
+
+	return rank, true
+}
+
+// searchSnippet возвращает фрагмент текста вокруг первого найденного слова, экранированный для HTML.
+// Найденные слова выделяются тегами <b> и </b>
+func searchSnippet(text string, include []string) string {
+	words := strings.Fields(text)
+
+	first := slices.IndexFunc(words, func(word string) bool {
+		return containsSearchWord(word, include)
+	})
+
+	start := max(first-snippetWords/4, 0)
+	end := min(start+snippetWords, len(words))
+
+	fragment := make([]string, 0, end-start)
+
+	for _, word := range words[start:end] {
+		escaped := html.EscapeString(word)
+		if containsSearchWord(word, include) {
+			escaped = "<b>" + escaped + "</b>"
+		}
+
+		fragment = append(fragment, escaped)
+	}
+
+	return strings.Join(fragment, " ")
+}
+
+func containsSearchWord(word string, include []string) bool {
+	for _, part := range searchWords(word) {
+		if slices.Contains(include, part) {
+			return true
+		}
+	}
+
+	return false
+}
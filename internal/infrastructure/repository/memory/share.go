@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ postgres.Share = (*ShareRepo)(nil)
+
+type ShareRepo struct {
+	Db *Database
+}
+
+func NewShareRepo(db *Database) *ShareRepo {
+	return &ShareRepo{Db: db}
+}
+
+func (r *ShareRepo) CreateShareLink(link *model.ShareLink) error {
+	log.Infof("saving share link [%s] of document [%s]", link.UUID, link.DocumentUUID)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	exists := slices.ContainsFunc(r.Db.shares, func(l model.ShareLink) bool {
+		return l.UUID == link.UUID || l.TokenHash == link.TokenHash
+	})
+	if exists {
+		log.Debugf("failed to save share link: share link [%s] already exists", link.UUID)
+		return fmt.Errorf("failed to save share link [%s]", link.UUID)
+	}
+
+	now := time.Now()
+
+	link.ID = r.Db.nextID()
+	link.CreatedAt = now
+	link.UpdatedAt = now
+
+	r.Db.shares = append(r.Db.shares, cloneShareLink(*link))
+
+	log.Infof("share link [%s] saved successfully", link.UUID)
+
+	return nil
+}
+
+func (r *ShareRepo) GetShareLink(login, uuid string) (model.ShareLink, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	i := r.Db.shareIndex(func(l model.ShareLink) bool {
+		return l.Owner == login && l.UUID == uuid
+	})
+	if i < 0 {
+		return model.ShareLink{}, custom_error.ErrShareNotFound
+	}
+
+	return cloneShareLink(r.Db.shares[i]), nil
+}
+
+// GetShareLinkByToken возвращает ссылку по SHA-256 ее токена
+func (r *ShareRepo) GetShareLinkByToken(tokenHash string) (model.ShareLink, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	i := r.Db.shareIndex(func(l model.ShareLink) bool {
+		return l.TokenHash == tokenHash
+	})
+	if i < 0 {
+		return model.ShareLink{}, custom_error.ErrShareNotFound
+	}
+
+	return cloneShareLink(r.Db.shares[i]), nil
+}
+
+// GetShareLinks возвращает ссылки пользователя, в том числе отозванные и истекшие.
+// Непустой documentUUID оставляет только ссылки на этот документ
+func (r *ShareRepo) GetShareLinks(login, documentUUID string) ([]model.ShareLink, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	links := make([]model.ShareLink, 0)
+
+	for _, link := range r.Db.shares {
+		if link.Owner == login && (documentUUID == "" || link.DocumentUUID == documentUUID) {
+			links = append(links, cloneShareLink(link))
+		}
+	}
+
+	return links, nil
+}
+
+// RevokeShareLink отзывает ссылку. Отозванная ссылка вместе с журналом обращений остается в списке ссылок.
+// Повторный отзыв не меняет время отзыва
+func (r *ShareRepo) RevokeShareLink(login, uuid string, now time.Time) error {
+	log.Infof("revoking share link [%s] of user [%s]", uuid, login)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	i := r.Db.shareIndex(func(l model.ShareLink) bool {
+		return l.Owner == login && l.UUID == uuid
+	})
+	if i < 0 {
+		return custom_error.ErrShareNotFound
+	}
+
+	link := &r.Db.shares[i]
+
+	if link.RevokedAt == nil {
+		link.RevokedAt = &now
+		link.UpdatedAt = now
+	}
+
+	log.Infof("share link [%s] revoked successfully", uuid)
+
+	return nil
+}
+
+// ClaimShareDownload засчитывает скачивание по ссылке, если к моменту now ссылка не отозвана, не истекла
+// и количество скачиваний не исчерпано
+func (r *ShareRepo) ClaimShareDownload(uuid string, now time.Time) (bool, error) {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	i := r.Db.shareIndex(func(l model.ShareLink) bool {
+		return l.UUID == uuid
+	})
+	if i < 0 {
+		return false, nil
+	}
+
+	link := &r.Db.shares[i]
+
+	if link.Revoked() || link.Expired(now) || link.Exhausted() {
+		return false, nil
+	}
+
+	link.Downloads++
+	link.UpdatedAt = now
+
+	return true, nil
+}
+
+func (r *ShareRepo) CreateShareAccess(access *model.ShareAccess) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	access.ID = r.Db.nextID()
+	access.CreatedAt = time.Now()
+
+	r.Db.shareAccesses = append(r.Db.shareAccesses, *access)
+
+	return nil
+}
+
+// GetShareAccesses возвращает страницу журнала обращений по ссылке от новых к старым
+// и общее количество обращений
+func (r *ShareRepo) GetShareAccesses(shareUUID string, limit, offset int) ([]model.ShareAccess, int64, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	accesses := make([]model.ShareAccess, 0, limit)
+
+	for i := len(r.Db.shareAccesses) - 1; i >= 0; i-- {
+		if access := r.Db.shareAccesses[i]; access.ShareUUID == shareUUID {
+			accesses = append(accesses, access)
+		}
+	}
+
+	return page(accesses, offset, limit), int64(len(accesses)), nil
+}
+
+// shareIndex возвращает позицию первой ссылки, для которой выполняется match, или -1, вызывается под блокировкой
+func (db *Database) shareIndex(match func(model.ShareLink) bool) int {
+	return slices.IndexFunc(db.shares, match)
+}
@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/cache"
+)
+
+var _ cache.ChangeStream = (*ChangeStreamRepo)(nil)
+
+// ChangeStreamRepo лента изменений документов в памяти вместо Redis Stream.
+// Идентификаторы событий имеют тот же вид <миллисекунды>-<номер>, что и в Redis,
+// лента хранит последние MaxLen событий
+type ChangeStreamRepo struct {
+	Cfg *config.Config
+
+	mu     sync.Mutex
+	events []entity.ChangeEvent
+	lastID streamID
+	// appended закрывается при добавлении события, чтобы разбудить ожидающих чтения
+	appended chan struct{}
+}
+
+// streamID идентификатор события ленты
+type streamID struct {
+	ms  uint64
+	seq uint64
+}
+
+func NewChangeStreamRepo(cfg *config.Config) *ChangeStreamRepo {
+	return &ChangeStreamRepo{
+		Cfg:      cfg,
+		appended: make(chan struct{}),
+	}
+}
+
+// Append добавляет событие в ленту и возвращает его идентификатор
+func (r *ChangeStreamRepo) Append(_ context.Context, event entity.ChangeEvent) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := streamID{ms: uint64(time.Now().UnixMilli())}
+	if id.ms <= r.lastID.ms {
+		id = streamID{ms: r.lastID.ms, seq: r.lastID.seq + 1}
+	}
+
+	r.lastID = id
+	event.ID = id.String()
+
+	r.events = append(r.events, event)
+	if maxLen := int(r.Cfg.ConfigFeed.MaxLen); maxLen > 0 && len(r.events) > maxLen {
+		r.events = append(r.events[:0:0], r.events[len(r.events)-maxLen:]...)
+	}
+
+	close(r.appended)
+	r.appended = make(chan struct{})
+
+	return event.ID, nil
+}
+
+// LastID возвращает идентификатор последнего события ленты или 0-0 для пустой ленты
+func (r *ChangeStreamRepo) LastID(_ context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.events) == 0 {
+		return "0-0", nil
+	}
+
+	return r.events[len(r.events)-1].ID, nil
+}
+
+// Read ждет не дольше block события, следующие за afterID
+func (r *ChangeStreamRepo) Read(ctx context.Context, afterID string, count int, block time.Duration) ([]entity.ChangeEvent, error) {
+	timer := time.NewTimer(block)
+	defer timer.Stop()
+
+	for {
+		r.mu.Lock()
+		events, err := r.after(afterID, count)
+		appended := r.appended
+		r.mu.Unlock()
+
+		if err != nil || len(events) > 0 {
+			return events, err
+		}
+
+		select {
+		case <-appended:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Range возвращает до count событий, следующих за afterID
+func (r *ChangeStreamRepo) Range(_ context.Context, afterID string, count int) ([]entity.ChangeEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.after(afterID, count)
+}
+
+// after возвращает до count событий, следующих за afterID, вызывается под блокировкой
+func (r *ChangeStreamRepo) after(afterID string, count int) ([]entity.ChangeEvent, error) {
+	after, err := parseStreamID(afterID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]entity.ChangeEvent, 0)
+
+	for _, event := range r.events {
+		if len(events) == count {
+			break
+		}
+
+		id, _ := parseStreamID(event.ID)
+		if id.after(after) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (id streamID) after(other streamID) bool {
+	if id.ms != other.ms {
+		return id.ms > other.ms
+	}
+
+	return id.seq > other.seq
+}
+
+func (id streamID) String() string {
+	return fmt.Sprintf("%d-%d", id.ms, id.seq)
+}
+
+// parseStreamID разбирает идентификатор события, номер после дефиса можно не указывать, как в Redis
+func parseStreamID(value string) (streamID, error) {
+	var id streamID
+
+	ms, seq, found := strings.Cut(value, "-")
+
+	var err error
+
+	id.ms, err = strconv.ParseUint(ms, 10, 64)
+	if err == nil && found {
+		id.seq, err = strconv.ParseUint(seq, 10, 64)
+	}
+
+	if err != nil {
+		return id, fmt.Errorf("invalid stream ID specified [%s]", value)
+	}
+
+	return id, nil
+}
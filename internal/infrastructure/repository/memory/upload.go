@@ -0,0 +1,254 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ postgres.Upload = (*UploadRepo)(nil)
+
+type UploadRepo struct {
+	Db *Database
+}
+
+func NewUploadRepo(db *Database) *UploadRepo {
+	return &UploadRepo{Db: db}
+}
+
+func (r *UploadRepo) CreateUpload(upload *model.Upload) error {
+	log.Infof("saving upload [%s] of user [%s]", upload.UUID, upload.Owner)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	if _, ok := r.Db.uploads[upload.UUID]; ok {
+		log.Debugf("failed to save upload: upload [%s] already exists", upload.UUID)
+		return fmt.Errorf("failed to save upload [%s]", upload.UUID)
+	}
+
+	now := time.Now()
+
+	upload.ID = r.Db.nextID()
+	upload.CreatedAt = now
+	upload.UpdatedAt = now
+
+	r.Db.uploads[upload.UUID] = cloneUpload(*upload)
+
+	log.Infof("upload [%s] saved successfully", upload.UUID)
+
+	return nil
+}
+
+// GetUpload возвращает загрузку без полученных байт, которых еще не хватает на часть
+func (r *UploadRepo) GetUpload(uuid string) (model.Upload, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	upload, ok := r.Db.uploads[uuid]
+	if !ok {
+		return model.Upload{}, custom_error.ErrUploadNotFound
+	}
+
+	upload.Pending = nil
+
+	return cloneUpload(upload), nil
+}
+
+// GetUploadPending возвращает полученные байты загрузки, которых еще не хватает на часть
+func (r *UploadRepo) GetUploadPending(uuid string) ([]byte, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	upload, ok := r.Db.uploads[uuid]
+	if !ok {
+		return nil, custom_error.ErrUploadNotFound
+	}
+
+	return slices.Clone(upload.Pending), nil
+}
+
+// GetUploadParts возвращает записанные части загрузки по возрастанию номера
+func (r *UploadRepo) GetUploadParts(uuid string) ([]model.UploadPart, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	parts := make([]model.UploadPart, 0, len(r.Db.uploadParts[uuid]))
+	for _, part := range r.Db.uploadParts[uuid] {
+		parts = append(parts, part)
+	}
+
+	slices.SortFunc(parts, func(a, b model.UploadPart) int {
+		return a.Number - b.Number
+	})
+
+	return parts, nil
+}
+
+// AdvanceUpload переносит смещение загрузки с from на to вместе с записанной частью part
+// и состоянием хеша полученных байт hashState.
+// Если смещение загрузки уже не равно from, возвращается ErrUploadOffsetMismatch
+func (r *UploadRepo) AdvanceUpload(uuid string, from, to int64, pending, hashState []byte, part *model.UploadPart) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	upload, ok := r.Db.uploads[uuid]
+	if !ok || upload.Offset != from {
+		return custom_error.ErrUploadOffsetMismatch
+	}
+
+	if part != nil {
+		if r.Db.uploadParts[uuid] == nil {
+			r.Db.uploadParts[uuid] = make(map[int]model.UploadPart)
+		}
+
+		r.Db.uploadParts[uuid][part.Number] = *part
+	}
+
+	upload.Offset = to
+	upload.Pending = slices.Clone(pending)
+	upload.HashState = slices.Clone(hashState)
+	upload.UpdatedAt = time.Now()
+
+	r.Db.uploads[uuid] = upload
+
+	return nil
+}
+
+// LockUpload занимает загрузку для holder до until. Блокировку, которую уже держит holder, продлевает.
+// Возвращает false, если загрузку занял другой обработчик
+func (r *UploadRepo) LockUpload(uuid, holder string, until time.Time) (bool, error) {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	upload, ok := r.Db.uploads[uuid]
+	if !ok || (upload.LockedBy != holder && !upload.LockedUntil.Before(time.Now())) {
+		return false, nil
+	}
+
+	upload.LockedBy = holder
+	upload.LockedUntil = until
+
+	r.Db.uploads[uuid] = upload
+
+	return true, nil
+}
+
+// UnlockUpload освобождает загрузку, если ее держит holder
+func (r *UploadRepo) UnlockUpload(uuid, holder string) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	upload, ok := r.Db.uploads[uuid]
+	if !ok || upload.LockedBy != holder {
+		return nil
+	}
+
+	upload.LockedBy = ""
+	upload.LockedUntil = time.Time{}
+
+	r.Db.uploads[uuid] = upload
+
+	return nil
+}
+
+// MarkUploadAssembled отмечает, что части загрузки собраны в файл хранилища
+func (r *UploadRepo) MarkUploadAssembled(uuid string) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	upload, ok := r.Db.uploads[uuid]
+	if !ok {
+		return custom_error.ErrUploadNotFound
+	}
+
+	upload.Assembled = true
+	upload.Pending = nil
+
+	r.Db.uploads[uuid] = upload
+
+	return nil
+}
+
+// ReserveUploadDocument записывает документ или номер версии, которые создаст завершение загрузки
+func (r *UploadRepo) ReserveUploadDocument(uuid, documentUUID string, version int) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	upload, ok := r.Db.uploads[uuid]
+	if !ok {
+		return custom_error.ErrUploadNotFound
+	}
+
+	upload.PendingUUID = documentUUID
+	upload.PendingVersion = version
+
+	r.Db.uploads[uuid] = upload
+
+	return nil
+}
+
+// CompleteUpload связывает загрузку с созданным из нее документом. Записи о частях больше не нужны и удаляются
+func (r *UploadRepo) CompleteUpload(uuid, documentUUID string) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	upload, ok := r.Db.uploads[uuid]
+	if !ok {
+		return custom_error.ErrUploadNotFound
+	}
+
+	upload.DocumentUUID = documentUUID
+
+	r.Db.uploads[uuid] = upload
+	delete(r.Db.uploadParts, uuid)
+
+	log.Infof("upload [%s] completed with document [%s]", uuid, documentUUID)
+
+	return nil
+}
+
+// DeleteUpload удаляет загрузку вместе с записями о ее частях
+func (r *UploadRepo) DeleteUpload(uuid string) error {
+	log.Infof("deleting upload [%s]", uuid)
+
+	r.Db.mu.Lock()
+	delete(r.Db.uploads, uuid)
+	delete(r.Db.uploadParts, uuid)
+	r.Db.mu.Unlock()
+
+	log.Infof("upload [%s] deleted successfully", uuid)
+
+	return nil
+}
+
+// GetExpiredUploads возвращает загрузки, срок которых истек к моменту now и которые никем не заняты
+func (r *UploadRepo) GetExpiredUploads(now time.Time, limit int) ([]model.Upload, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	uploads := make([]model.Upload, 0)
+
+	for _, upload := range r.Db.uploads {
+		if upload.ExpiresAt.Before(now) && upload.LockedUntil.Before(now) {
+			upload.Pending = nil
+			uploads = append(uploads, cloneUpload(upload))
+		}
+	}
+
+	slices.SortFunc(uploads, func(a, b model.Upload) int {
+		return a.ExpiresAt.Compare(b.ExpiresAt)
+	})
+
+	if len(uploads) > limit {
+		uploads = uploads[:limit]
+	}
+
+	return uploads, nil
+}
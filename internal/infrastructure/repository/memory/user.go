@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ postgres.User = (*UserRepo)(nil)
+
+type UserRepo struct {
+	Db *Database
+}
+
+func NewUserRepo(db *Database) *UserRepo {
+	return &UserRepo{Db: db}
+}
+
+func (r *UserRepo) Save(user model.User) error {
+	log.Infof("start saving user with login [%s]", user.Login)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	user.ID = r.Db.nextID()
+	user.CreatedAt = time.Now()
+	user.AdminToken = ""
+	user.Password = ""
+
+	r.Db.users = append(r.Db.users, user)
+
+	log.Infof("end saving user with login [%s]", user.Login)
+
+	return nil
+}
+
+// GetByLogin возвращает пустого пользователя, если логин не найден, как и репозиторий Postgres
+func (r *UserRepo) GetByLogin(login string) (model.User, error) {
+	log.Infof("start getting user by login [%s]", login)
+
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	var user model.User
+
+	i := slices.IndexFunc(r.Db.users, func(u model.User) bool {
+		return u.Login == login
+	})
+	if i >= 0 {
+		user = r.Db.users[i]
+	}
+
+	log.Infof("end getting user by login [%s]", user.Login)
+
+	return user, nil
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+func (r *MetadataRepo) CreateVersion(version model.DocumentVersion) error {
+	log.Infof("saving document [%s] version [%d]", version.DocumentUUID, version.Version)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	exists := slices.ContainsFunc(r.Db.versions, func(v model.DocumentVersion) bool {
+		return v.DocumentUUID == version.DocumentUUID && v.Version == version.Version
+	})
+	if exists {
+		log.Debugf("failed to save document version: version [%d] already exists", version.Version)
+		return fmt.Errorf("failed to save document [%s] version [%d]", version.DocumentUUID, version.Version)
+	}
+
+	version.ID = r.Db.nextID()
+	if version.CreatedAt.IsZero() {
+		version.CreatedAt = time.Now()
+	}
+
+	r.Db.versions = append(r.Db.versions, version)
+
+	log.Infof("document [%s] version [%d] saved successfully", version.DocumentUUID, version.Version)
+
+	return nil
+}
+
+func (r *MetadataRepo) GetVersions(uuid string) ([]model.DocumentVersion, error) {
+	log.Infof("retrieving document [%s] versions", uuid)
+
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	var versions []model.DocumentVersion
+
+	for _, version := range r.Db.versions {
+		if version.DocumentUUID == uuid {
+			versions = append(versions, version)
+		}
+	}
+
+	slices.SortFunc(versions, func(a, b model.DocumentVersion) int {
+		return b.Version - a.Version
+	})
+
+	log.Infof("document [%s] versions retrieved successfully", uuid)
+
+	return versions, nil
+}
+
+func (r *MetadataRepo) GetVersion(uuid string, version int) (model.DocumentVersion, error) {
+	log.Infof("retrieving document [%s] version [%d]", uuid, version)
+
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	i := slices.IndexFunc(r.Db.versions, func(v model.DocumentVersion) bool {
+		return v.DocumentUUID == uuid && v.Version == version
+	})
+	if i < 0 {
+		log.Infof("document [%s] version [%d] not found", uuid, version)
+		return model.DocumentVersion{}, custom_error.ErrVersionNotFound
+	}
+
+	log.Infof("document [%s] version [%d] retrieved successfully", uuid, version)
+
+	return r.Db.versions[i], nil
+}
+
+func (r *MetadataRepo) DeleteVersions(uuid string, versions []int) error {
+	log.Infof("deleting document [%s] versions %v", uuid, versions)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	r.Db.versions = slices.DeleteFunc(r.Db.versions, func(v model.DocumentVersion) bool {
+		return v.DocumentUUID == uuid && slices.Contains(versions, v.Version)
+	})
+
+	log.Infof("document [%s] versions deleted successfully", uuid)
+
+	return nil
+}
+
+func (r *MetadataRepo) DeleteVersionsByDocumentId(uuid string) error {
+	log.Infof("deleting all document [%s] versions", uuid)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	r.Db.versions = slices.DeleteFunc(r.Db.versions, func(v model.DocumentVersion) bool {
+		return v.DocumentUUID == uuid
+	})
+
+	log.Infof("all document [%s] versions deleted successfully", uuid)
+
+	return nil
+}
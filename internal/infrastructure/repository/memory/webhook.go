@@ -0,0 +1,256 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ postgres.Webhook = (*WebhookRepo)(nil)
+
+type WebhookRepo struct {
+	Db *Database
+}
+
+func NewWebhookRepo(db *Database) *WebhookRepo {
+	return &WebhookRepo{Db: db}
+}
+
+func (r *WebhookRepo) CreateSubscription(subscription *model.WebhookSubscription) error {
+	log.Infof("saving webhook [%s] of user [%s]", subscription.UUID, subscription.Login)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	if r.Db.subscriptionIndex(subscription.UUID) >= 0 {
+		log.Debugf("failed to save webhook: webhook [%s] already exists", subscription.UUID)
+		return fmt.Errorf("failed to save webhook [%s]", subscription.UUID)
+	}
+
+	now := time.Now()
+
+	subscription.ID = r.Db.nextID()
+	subscription.CreatedAt = now
+	subscription.UpdatedAt = now
+
+	r.Db.webhooks = append(r.Db.webhooks, cloneSubscription(*subscription))
+
+	log.Infof("webhook [%s] saved successfully", subscription.UUID)
+
+	return nil
+}
+
+func (r *WebhookRepo) GetSubscriptions(login string) ([]model.WebhookSubscription, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	subscriptions := make([]model.WebhookSubscription, 0)
+
+	for _, subscription := range r.Db.webhooks {
+		if subscription.Login == login {
+			subscriptions = append(subscriptions, cloneSubscription(subscription))
+		}
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepo) GetSubscription(login, uuid string) (model.WebhookSubscription, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	i := r.Db.subscriptionIndex(uuid)
+	if i < 0 || r.Db.webhooks[i].Login != login {
+		return model.WebhookSubscription{}, custom_error.ErrWebhookNotFound
+	}
+
+	return cloneSubscription(r.Db.webhooks[i]), nil
+}
+
+// DeleteSubscription удаляет подписку вместе с журналом ее доставок
+func (r *WebhookRepo) DeleteSubscription(login, uuid string) error {
+	log.Infof("deleting webhook [%s] of user [%s]", uuid, login)
+
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	i := r.Db.subscriptionIndex(uuid)
+	if i < 0 || r.Db.webhooks[i].Login != login {
+		return custom_error.ErrWebhookNotFound
+	}
+
+	r.Db.webhooks = slices.Delete(r.Db.webhooks, i, i+1)
+	r.Db.deliveries = slices.DeleteFunc(r.Db.deliveries, func(d model.WebhookDelivery) bool {
+		return d.SubscriptionUUID == uuid
+	})
+
+	log.Infof("webhook [%s] deleted successfully", uuid)
+
+	return nil
+}
+
+// GetSubscribers возвращает подписки пользователей logins на события типа eventType.
+// Подписка без списка событий получает события всех типов
+func (r *WebhookRepo) GetSubscribers(logins []string, eventType string) ([]model.WebhookSubscription, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	subscriptions := make([]model.WebhookSubscription, 0)
+
+	for _, subscription := range r.Db.webhooks {
+		if !slices.Contains(logins, subscription.Login) {
+			continue
+		}
+
+		if len(subscription.Events) == 0 || slices.Contains(subscription.Events, eventType) {
+			subscriptions = append(subscriptions, cloneSubscription(subscription))
+		}
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepo) CreateDeliveries(deliveries []model.WebhookDelivery) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	now := time.Now()
+
+	for _, delivery := range deliveries {
+		delivery.ID = r.Db.nextID()
+		delivery.Subscription = nil
+		delivery.CreatedAt = now
+		delivery.UpdatedAt = now
+
+		r.Db.deliveries = append(r.Db.deliveries, delivery)
+	}
+
+	return nil
+}
+
+// ClaimDeliveries забирает доставки, срок отправки которых наступил, и откладывает их на время lease.
+// Пока доставка отправляется, ее не заберет следующий вызов,
+// а если отправка прервется, доставка снова станет доступна после lease
+func (r *WebhookRepo) ClaimDeliveries(limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	now := time.Now()
+
+	due := make([]int, 0, limit)
+
+	for i, delivery := range r.Db.deliveries {
+		if delivery.Status != model.WebhookDeliveryPending && delivery.Status != model.WebhookDeliveryRetrying {
+			continue
+		}
+
+		if !delivery.NextAttemptAt.After(now) {
+			due = append(due, i)
+		}
+	}
+
+	slices.SortStableFunc(due, func(a, b int) int {
+		return r.Db.deliveries[a].NextAttemptAt.Compare(r.Db.deliveries[b].NextAttemptAt)
+	})
+
+	due = page(due, 0, limit)
+
+	deliveries := make([]model.WebhookDelivery, 0, len(due))
+
+	for _, i := range due {
+		r.Db.deliveries[i].NextAttemptAt = now.Add(lease)
+
+		delivery := r.Db.deliveries[i]
+
+		if j := r.Db.subscriptionIndex(delivery.SubscriptionUUID); j >= 0 {
+			subscription := cloneSubscription(r.Db.webhooks[j])
+			delivery.Subscription = &subscription
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) UpdateDelivery(delivery *model.WebhookDelivery) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	i := slices.IndexFunc(r.Db.deliveries, func(d model.WebhookDelivery) bool {
+		return d.ID == delivery.ID
+	})
+	if i < 0 {
+		log.Debugf("failed to update webhook delivery: delivery [%s] not found", delivery.UUID)
+		return fmt.Errorf("failed to update webhook delivery [%s]", delivery.UUID)
+	}
+
+	delivery.UpdatedAt = time.Now()
+
+	stored := *delivery
+	stored.Subscription = nil
+
+	r.Db.deliveries[i] = stored
+
+	return nil
+}
+
+// GetDeliveries возвращает журнал доставок подписки от новых к старым.
+// Пустой status возвращает доставки в любом состоянии
+func (r *WebhookRepo) GetDeliveries(subscriptionUUID, status string, limit, offset int) ([]model.WebhookDelivery, int64, error) {
+	r.Db.mu.RLock()
+	defer r.Db.mu.RUnlock()
+
+	deliveries := make([]model.WebhookDelivery, 0, limit)
+
+	for i := len(r.Db.deliveries) - 1; i >= 0; i-- {
+		delivery := r.Db.deliveries[i]
+
+		if delivery.SubscriptionUUID == subscriptionUUID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	return page(deliveries, offset, limit), int64(len(deliveries)), nil
+}
+
+// RedeliverDelivery возвращает доставку из состояния dead в очередь с новым запасом попыток
+func (r *WebhookRepo) RedeliverDelivery(subscriptionUUID, uuid string) error {
+	r.Db.mu.Lock()
+	defer r.Db.mu.Unlock()
+
+	i := slices.IndexFunc(r.Db.deliveries, func(d model.WebhookDelivery) bool {
+		return d.SubscriptionUUID == subscriptionUUID && d.UUID == uuid
+	})
+	if i < 0 {
+		return custom_error.ErrDeliveryNotFound
+	}
+
+	delivery := &r.Db.deliveries[i]
+
+	if delivery.Status != model.WebhookDeliveryDead {
+		return custom_error.ErrDeliveryNotDead
+	}
+
+	delivery.Status = model.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.UpdatedAt = time.Now()
+
+	log.Infof("webhook delivery [%s] queued for redelivery", uuid)
+
+	return nil
+}
+
+// subscriptionIndex возвращает позицию подписки или -1, вызывается под блокировкой
+func (db *Database) subscriptionIndex(uuid string) int {
+	return slices.IndexFunc(db.webhooks, func(s model.WebhookSubscription) bool {
+		return s.UUID == uuid
+	})
+}
@@ -0,0 +1,84 @@
+package mongodb
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/compression"
+)
+
+const (
+	// encodingField поле, в котором хранится алгоритм сжатия содержимого
+	encodingField = "_encoding"
+	// compressedField поле, в котором хранится сжатое содержимое в JSON
+	compressedField = "_compressed"
+)
+
+// compressContent сжимает содержимое, размер которого в JSON не меньше порога сжатия.
+// Сжатое содержимое хранится в поле _compressed вместе с алгоритмом в поле _encoding,
+// поэтому каждая запись описывает себя сама и читается независимо от текущих настроек
+func (r *ContentRepo) compressContent(uuid string, jsonDoc map[string]interface{}) (map[string]interface{}, error) {
+	if r.Compression == nil || r.Compression.Codec == "" {
+		return jsonDoc, nil
+	}
+
+	content := make(map[string]interface{}, len(jsonDoc))
+	for field, value := range jsonDoc {
+		if field != entity.ContentIDField {
+			content[field] = value
+		}
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	codec := compression.Codec(r.Compression, entity.DefaultMimeType, int64(len(data)))
+	if codec == "" {
+		return jsonDoc, nil
+	}
+
+	compressed, err := compression.Compress(codec, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		entity.ContentIDField: uuid,
+		encodingField:         codec,
+		compressedField:       compressed,
+	}, nil
+}
+
+// decompressContent распаковывает сжатое содержимое и возвращает его вместе с ключом в поле _id.
+// Содержимое без сжатия возвращается как есть
+func decompressContent(content map[string]interface{}) (map[string]interface{}, error) {
+	codec, ok := content[encodingField].(string)
+	if !ok {
+		return content, nil
+	}
+
+	compressed, ok := content[compressedField].(primitive.Binary)
+	if !ok {
+		return nil, fmt.Errorf("compressed content [%v] has no data", content[entity.ContentIDField])
+	}
+
+	data, err := compression.Decompress(codec, compressed.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+
+	if err = json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	result[entity.ContentIDField] = content[entity.ContentIDField]
+
+	return result, nil
+}
@@ -9,30 +9,40 @@ import (
 	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/AlexJudin/DocumentCacheServer/config"
 	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
 	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
 	"github.com/AlexJudin/DocumentCacheServer/internal/model"
 )
 
 const (
+	// TypeMongoDB содержимое JSON документов хранится в MongoDB
+	TypeMongoDB = "mongodb"
+
 	dataBaseType = "mongodb"
 
 	saveDocumentContent       = "save_document_content"
 	getDocumentContentById    = "get_document_content_by_id"
 	deleteDocumentContentById = "delete_document_content_by_id"
+	getDocumentContentKeys    = "get_document_content_keys"
 )
 
 var _ ContentRepository = (*ContentRepo)(nil)
 
+// ContentRepo хранит содержимое JSON документов в MongoDB.
+// Содержимое, размер которого не меньше порога сжатия, хранится сжатым
 type ContentRepo struct {
 	Client       *mongo.Client
+	Compression  *config.ConfigCompression
 	QueryObserve metric.QueryObserver
 }
 
-func NewContentRepository(client *mongo.Client, metrics *metric.DatabaseMetrics) *ContentRepo {
+func NewContentRepository(client *mongo.Client, compression *config.ConfigCompression, metrics *metric.DatabaseMetrics) *ContentRepo {
 	return &ContentRepo{
 		Client:       client,
+		Compression:  compression,
 		QueryObserve: metrics,
 	}
 }
@@ -46,8 +56,14 @@ func (r *ContentRepo) Store(ctx context.Context, uuid string, jsonDoc map[string
 
 	jsonDoc["_id"] = uuid
 
+	document, err := r.compressContent(uuid, jsonDoc)
+	if err != nil {
+		log.Debugf("failed to compress document content: %+v", err)
+		return fmt.Errorf("failed to save document [%s] content", uuid)
+	}
+
 	fn := func() error {
-		_, err := collection.InsertOne(ctx, jsonDoc)
+		_, err := collection.InsertOne(ctx, document)
 		if err != nil {
 			return err
 		}
@@ -55,7 +71,7 @@ func (r *ContentRepo) Store(ctx context.Context, uuid string, jsonDoc map[string
 		return nil
 	}
 
-	err := r.QueryObserve.Observe(fn, dataBaseType, saveDocumentContent)
+	err = r.QueryObserve.Observe(fn, dataBaseType, saveDocumentContent)
 	if err != nil {
 		log.Debugf("failed to save document content: %+v", err)
 		return fmt.Errorf("failed to save document [%s] content", uuid)
@@ -94,6 +110,12 @@ func (r *ContentRepo) GetByDocumentId(ctx context.Context, uuid string) (map[str
 		return nil, fmt.Errorf("failed to retrieve document [%s] content", uuid)
 	}
 
+	result, err = decompressContent(result)
+	if err != nil {
+		log.Debugf("failed to decompress document content: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve document [%s] content", uuid)
+	}
+
 	log.Infof("document [%s] content retrieved successfully", uuid)
 
 	return result, nil
@@ -129,3 +151,43 @@ func (r *ContentRepo) DeleteByDocumentId(ctx context.Context, uuid string) error
 
 	return nil
 }
+
+// ContentKeys возвращает ключи всего содержимого коллекции.
+// Записи с нестроковым идентификатором сервис не создает, поэтому они пропускаются
+func (r *ContentRepo) ContentKeys(ctx context.Context) ([]string, error) {
+	log.Info("retrieving document content keys from database")
+
+	collection := r.Client.Database(model.MongoDbName).Collection(model.MongoCollectionName)
+
+	keys := make([]string, 0)
+
+	fn := func() error {
+		cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			key, ok := cursor.Current.Lookup("_id").StringValueOK()
+			if !ok {
+				log.Warnf("document content with non-string id [%s] skipped", cursor.Current.Lookup("_id"))
+				continue
+			}
+
+			keys = append(keys, key)
+		}
+
+		return cursor.Err()
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getDocumentContentKeys)
+	if err != nil {
+		log.Debugf("failed to retrieve document content keys: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve document content keys")
+	}
+
+	log.Infof("%d document content keys retrieved", len(keys))
+
+	return keys, nil
+}
@@ -0,0 +1,155 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const findDocumentContent = "find_document_content"
+
+// contentOperators сопоставляет операторы запроса с операторами MongoDB
+var contentOperators = map[string]string{
+	entity.ContentOpEq:     "$eq",
+	entity.ContentOpNe:     "$ne",
+	entity.ContentOpGt:     "$gt",
+	entity.ContentOpGte:    "$gte",
+	entity.ContentOpLt:     "$lt",
+	entity.ContentOpLte:    "$lte",
+	entity.ContentOpExists: "$exists",
+}
+
+// Find возвращает содержимое, подходящее под условия, в порядке возрастания ключа.
+// Поиск начинается после ключа afterKey, fields ограничивает возвращаемые поля.
+// Ключ содержимого всегда возвращается в поле _id.
+// Сжатое содержимое MongoDB проверить не может, поэтому оно читается вместе с найденным,
+// распаковывается и проверяется здесь. За один вызов читается не больше limit записей,
+// поэтому найденного может оказаться меньше limit и до конца просмотра
+func (r *ContentRepo) Find(ctx context.Context, conditions []entity.ContentCondition, fields []string, afterKey string, limit int) ([]map[string]interface{}, string, error) {
+	log.Info("searching documents content")
+
+	contents, err := r.find(ctx, conditions, fields, afterKey, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := make([]map[string]interface{}, 0, len(contents))
+
+	for _, content := range contents {
+		if _, compressed := content[encodingField]; !compressed {
+			result = append(result, content)
+			continue
+		}
+
+		content, err = decompressContent(content)
+		if err != nil {
+			log.Debugf("failed to decompress document content: %+v", err)
+			return nil, "", fmt.Errorf("failed to search documents content")
+		}
+
+		if entity.MatchContent(content, conditions) {
+			result = append(result, entity.ProjectContent(content, fields))
+		}
+	}
+
+	next := ""
+	if len(contents) == limit {
+		next, _ = contents[len(contents)-1][entity.ContentIDField].(string)
+	}
+
+	log.Infof("documents content found: %d", len(result))
+
+	return result, next, nil
+}
+
+// find читает пачку содержимого, подходящего под условия, и все сжатое содержимое после ключа afterKey
+func (r *ContentRepo) find(ctx context.Context, conditions []entity.ContentCondition, fields []string, afterKey string, limit int) ([]map[string]interface{}, error) {
+	filter, err := contentFilter(conditions, afterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	if len(fields) > 0 {
+		projection := bson.D{{Key: encodingField, Value: 1}, {Key: compressedField, Value: 1}}
+		for _, field := range fields {
+			projection = append(projection, bson.E{Key: field, Value: 1})
+		}
+
+		opts.SetProjection(projection)
+	}
+
+	collection := r.Client.Database(model.MongoDbName).Collection(model.MongoCollectionName)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result := make([]map[string]interface{}, 0, limit)
+
+	fn := func() error {
+		cursor, err := collection.Find(ctx, filter, opts)
+		if err != nil {
+			return err
+		}
+
+		return cursor.All(ctx, &result)
+	}
+
+	err = r.QueryObserve.Observe(fn, dataBaseType, findDocumentContent)
+	if err != nil {
+		log.Debugf("failed to search documents content: %+v", err)
+		return nil, fmt.Errorf("failed to search documents content")
+	}
+
+	return result, nil
+}
+
+// contentFilter переводит условия запроса в фильтр MongoDB. Сжатое содержимое подходит под фильтр
+// всегда, условия к нему применяются после распаковки.
+// Пути и значения проверены заранее, поэтому в фильтр не попадают операторы из запроса
+func contentFilter(conditions []entity.ContentCondition, afterKey string) (bson.M, error) {
+	matches := bson.A{}
+
+	for _, condition := range conditions {
+		if condition.Op == entity.ContentOpContains {
+			matches = append(matches, bson.M{condition.Path: bson.M{"$elemMatch": bson.M{"$eq": condition.Value}}})
+			continue
+		}
+
+		operator, ok := contentOperators[condition.Op]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown operator [%s]", custom_error.ErrInvalidContentQuery, condition.Op)
+		}
+
+		matches = append(matches, bson.M{condition.Path: bson.M{operator: condition.Value}})
+	}
+
+	and := bson.A{}
+
+	if afterKey != "" {
+		and = append(and, bson.M{"_id": bson.M{"$gt": afterKey}})
+	}
+
+	if len(matches) > 0 {
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"$and": matches},
+			bson.M{encodingField: bson.M{"$exists": true}},
+		}})
+	}
+
+	if len(and) == 0 {
+		return bson.M{}, nil
+	}
+
+	return bson.M{"$and": and}, nil
+}
@@ -0,0 +1,83 @@
+package mongodb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+func TestContentFilter(t *testing.T) {
+	compressed := bson.M{encodingField: bson.M{"$exists": true}}
+
+	tests := []struct {
+		name       string
+		conditions []entity.ContentCondition
+		afterKey   string
+		want       bson.M
+	}{
+		{
+			name: "no conditions",
+			want: bson.M{},
+		},
+		{
+			name:     "cursor only",
+			afterKey: "key-1",
+			want:     bson.M{"$and": bson.A{bson.M{"_id": bson.M{"$gt": "key-1"}}}},
+		},
+		{
+			name: "comparison operators",
+			conditions: []entity.ContentCondition{
+				{Path: "address.city", Op: entity.ContentOpEq, Value: "Moscow"},
+				{Path: "age", Op: entity.ContentOpGte, Value: 18.0},
+				{Path: "deleted", Op: entity.ContentOpExists, Value: false},
+			},
+			want: bson.M{"$and": bson.A{bson.M{"$or": bson.A{
+				bson.M{"$and": bson.A{
+					bson.M{"address.city": bson.M{"$eq": "Moscow"}},
+					bson.M{"age": bson.M{"$gte": 18.0}},
+					bson.M{"deleted": bson.M{"$exists": false}},
+				}},
+				compressed,
+			}}}},
+		},
+		{
+			name:       "contains matches array element",
+			conditions: []entity.ContentCondition{{Path: "tags", Op: entity.ContentOpContains, Value: "urgent"}},
+			afterKey:   "key-1",
+			want: bson.M{"$and": bson.A{
+				bson.M{"_id": bson.M{"$gt": "key-1"}},
+				bson.M{"$or": bson.A{
+					bson.M{"$and": bson.A{bson.M{"tags": bson.M{"$elemMatch": bson.M{"$eq": "urgent"}}}}},
+					compressed,
+				}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := contentFilter(tt.conditions, tt.afterKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentFilterUnknownOperator(t *testing.T) {
+	for _, op := range []string{"$where", "regex", ""} {
+		_, err := contentFilter([]entity.ContentCondition{{Path: "name", Op: op, Value: "a"}}, "")
+		if !errors.Is(err, custom_error.ErrInvalidContentQuery) {
+			t.Errorf("operator [%s]: error = %v, want %v", op, err, custom_error.ErrInvalidContentQuery)
+		}
+	}
+}
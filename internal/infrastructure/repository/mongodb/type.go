@@ -1,9 +1,17 @@
 package mongodb
 
-import "context"
+import (
+	"context"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
 
 type ContentRepository interface {
 	Store(ctx context.Context, uuid string, jsonDoc map[string]interface{}) error
 	GetByDocumentId(ctx context.Context, uuid string) (map[string]interface{}, error)
 	DeleteByDocumentId(ctx context.Context, uuid string) error
+	ContentKeys(ctx context.Context) ([]string, error)
+	// Find возвращает найденное содержимое и ключ, после которого продолжается просмотр,
+	// пустой, если содержимое просмотрено до конца. Найденного может быть меньше limit и до конца просмотра
+	Find(ctx context.Context, conditions []entity.ContentCondition, fields []string, afterKey string, limit int) ([]map[string]interface{}, string, error)
 }
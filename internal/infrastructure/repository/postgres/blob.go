@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	acquireFileBlob    = "acquire_file_blob"
+	markFileBlobStored = "mark_file_blob_stored"
+	releaseFileBlob    = "release_file_blob"
+)
+
+// AcquireBlob добавляет ссылку документа на файл blob.Digest и возвращает запись о файле.
+// Если файл уже записан в хранилище (Stored), загружать его повторно не нужно.
+// Новый файл записывается с ключом данных и сжатием из blob, а уже известный файл сохраняет свои.
+// Ссылка документа на один файл учитывается один раз, сколько бы версий на него ни ссылалось
+func (r *MetadataRepo) AcquireBlob(documentUUID string, blob model.FileBlob) (model.FileBlob, error) {
+	digest := blob.Digest
+
+	log.Infof("acquiring file blob [%s] for document [%s]", digest, documentUUID)
+
+	fn := func() error {
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&blob).Error
+			if err != nil {
+				return err
+			}
+
+			// блокировка ждет удаления последней ссылки, которое выполняется одновременно
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("digest = ?", digest).
+				Take(&blob).Error
+			if err != nil {
+				return err
+			}
+
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&model.FileBlobRef{Digest: digest, DocumentUUID: documentUUID}).Error
+		})
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, acquireFileBlob)
+	if err != nil {
+		log.Debugf("failed to acquire file blob: %+v", err)
+		return model.FileBlob{}, fmt.Errorf("failed to acquire file blob [%s]", digest)
+	}
+
+	log.Infof("file blob [%s] acquired, stored: %t", digest, blob.Stored)
+
+	return blob, nil
+}
+
+// MarkBlobStored отмечает, что файл записан в хранилище
+func (r *MetadataRepo) MarkBlobStored(digest string) error {
+	fn := func() error {
+		err := r.Db.Model(&model.FileBlob{}).
+			Where("digest = ?", digest).
+			Update("stored", true).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, markFileBlobStored)
+	if err != nil {
+		log.Debugf("failed to mark file blob stored: %+v", err)
+		return fmt.Errorf("failed to mark file blob [%s] stored", digest)
+	}
+
+	return nil
+}
+
+// ReleaseBlob удаляет ссылку документа на файл. Если ссылок на файл не осталось,
+// удаляется запись о файле, а remove удаляет его из хранилища, пока запись заблокирована.
+// Повторный вызов для уже удаленной ссылки ничего не делает
+func (r *MetadataRepo) ReleaseBlob(documentUUID, digest string, remove func() error) error {
+	log.Infof("releasing file blob [%s] for document [%s]", digest, documentUUID)
+
+	removed := false
+
+	fn := func() error {
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			var blobs []model.FileBlob
+
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("digest = ?", digest).
+				Limit(1).
+				Find(&blobs).Error
+			if err != nil {
+				return err
+			}
+
+			if len(blobs) == 0 {
+				return nil
+			}
+
+			err = tx.Where("digest = ? AND document_uuid = ?", digest, documentUUID).
+				Delete(&model.FileBlobRef{}).Error
+			if err != nil {
+				return err
+			}
+
+			var refs int64
+
+			err = tx.Model(&model.FileBlobRef{}).
+				Where("digest = ?", digest).
+				Count(&refs).Error
+			if err != nil {
+				return err
+			}
+
+			if refs > 0 {
+				return nil
+			}
+
+			// запись удаляется до файла: если файл удалить не удалось, откат транзакции вернет ее
+			err = tx.Where("digest = ?", digest).Delete(&model.FileBlob{}).Error
+			if err != nil {
+				return err
+			}
+
+			if err = remove(); err != nil {
+				return err
+			}
+
+			removed = true
+
+			return nil
+		})
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, releaseFileBlob)
+	if err != nil {
+		log.Debugf("failed to release file blob: %+v", err)
+		return fmt.Errorf("failed to release file blob [%s]", digest)
+	}
+
+	if removed {
+		log.Infof("file blob [%s] has no references left and was deleted", digest)
+	} else {
+		log.Infof("file blob [%s] released", digest)
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	getDocumentContentRefs = "get_document_content_refs"
+	getVersionContentRefs  = "get_version_content_refs"
+	getSagaContentRefs     = "get_saga_content_refs"
+	getUploadContentRefs   = "get_upload_content_refs"
+	setDocumentsBroken     = "set_documents_broken"
+	deleteOrphanFileBlob   = "delete_orphan_file_blob"
+
+	// consistencyLockID ключ advisory lock, под которым выполняется проверка согласованности хранилищ
+	consistencyLockID = 7540002
+)
+
+var _ Consistency = (*ConsistencyRepo)(nil)
+
+type ConsistencyRepo struct {
+	Db           *gorm.DB
+	QueryObserve metric.QueryObserver
+}
+
+func NewConsistencyRepo(db *gorm.DB, metrics *metric.DatabaseMetrics) *ConsistencyRepo {
+	return &ConsistencyRepo{
+		Db:           db,
+		QueryObserve: metrics,
+	}
+}
+
+// Lock занимает advisory lock проверки согласованности на отдельном соединении,
+// чтобы проверку одновременно выполнял только один экземпляр сервиса.
+// Возвращает false, если проверка уже выполняется
+func (r *ConsistencyRepo) Lock(ctx context.Context) (func(), bool, error) {
+	sqlDB, err := r.Db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var locked bool
+
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", consistencyLockID).Scan(&locked)
+	if err != nil || !locked {
+		_ = conn.Close()
+		return nil, false, err
+	}
+
+	unlock := func() {
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", consistencyLockID)
+		if err != nil {
+			log.Errorf("failed to release consistency check lock: %+v", err)
+		}
+
+		_ = conn.Close()
+	}
+
+	return unlock, true, nil
+}
+
+// GetContentRefs возвращает ключи содержимого, на которые ссылаются документы, их версии,
+// незавершенные саги и загрузки. Для документов и версий, созданных до появления ключей, ключом служит UUID документа
+func (r *ConsistencyRepo) GetContentRefs() ([]entity.ContentRef, error) {
+	refs := make([]entity.ContentRef, 0)
+
+	var documents []entity.ContentRef
+
+	fn := func() error {
+		return r.Db.Model(&model.MetaDocument{}).
+			Select("? AS source, uuid AS document_uuid, version, COALESCE(NULLIF(content_key, ''), uuid) AS content_key, file, broken",
+				entity.ContentRefDocument).
+			Scan(&documents).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getDocumentContentRefs)
+	if err != nil {
+		log.Debugf("failed to retrieve document content refs: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve document content refs")
+	}
+
+	var versions []entity.ContentRef
+
+	fn = func() error {
+		return r.Db.Model(&model.DocumentVersion{}).
+			Select("? AS source, document_uuid, version, COALESCE(NULLIF(content_key, ''), document_uuid) AS content_key, file",
+				entity.ContentRefVersion).
+			Scan(&versions).Error
+	}
+
+	err = r.QueryObserve.Observe(fn, dataBaseType, getVersionContentRefs)
+	if err != nil {
+		log.Debugf("failed to retrieve version content refs: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve version content refs")
+	}
+
+	var sagas []entity.ContentRef
+
+	// сага хранит в журнале новое и прежнее состояние документа, содержимое обоих нужно ей для восстановления
+	fn = func() error {
+		return r.Db.Raw(`SELECT ? AS source, s.document_uuid,
+				COALESCE(NULLIF(p.meta->>'content_key', ''), p.meta->>'uuid') AS content_key,
+				COALESCE((p.meta->>'file')::boolean, false) AS file
+			FROM sagas s
+			CROSS JOIN LATERAL (VALUES (s.payload->'meta'), (s.payload->'old_meta')) AS p(meta)
+			WHERE s.status NOT IN ? AND jsonb_typeof(p.meta) = 'object'`,
+			entity.ContentRefSaga, []string{model.SagaStatusCompleted, model.SagaStatusCompensated}).
+			Scan(&sagas).Error
+	}
+
+	err = r.QueryObserve.Observe(fn, dataBaseType, getSagaContentRefs)
+	if err != nil {
+		log.Debugf("failed to retrieve saga content refs: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve saga content refs")
+	}
+
+	var uploads []entity.ContentRef
+
+	// файл загрузки удаляется после создания документа, до этого на него ссылается загрузка
+	fn = func() error {
+		return r.Db.Model(&model.Upload{}).
+			Select("? AS source, 'upload-' || uuid AS content_key, true AS file", entity.ContentRefUpload).
+			Where("document_uuid = ''").
+			Scan(&uploads).Error
+	}
+
+	err = r.QueryObserve.Observe(fn, dataBaseType, getUploadContentRefs)
+	if err != nil {
+		log.Debugf("failed to retrieve upload content refs: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve upload content refs")
+	}
+
+	refs = append(refs, documents...)
+	refs = append(refs, versions...)
+	refs = append(refs, sagas...)
+	refs = append(refs, uploads...)
+
+	return refs, nil
+}
+
+// SetBroken устанавливает или снимает признак документа без содержимого
+func (r *ConsistencyRepo) SetBroken(uuids []string, broken bool) error {
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	fn := func() error {
+		return r.Db.Model(&model.MetaDocument{}).
+			Where("uuid IN ?", uuids).
+			UpdateColumn("broken", broken).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, setDocumentsBroken)
+	if err != nil {
+		log.Debugf("failed to mark broken documents: %+v", err)
+		return fmt.Errorf("failed to mark %d documents as broken [%t]", len(uuids), broken)
+	}
+
+	return nil
+}
+
+// DeleteBlob удаляет запись об осиротевшем файле, а remove удаляет файл из хранилища, пока запись заблокирована.
+// Если на файл появились ссылки после проверки (то же содержимое загружено заново), файл не удаляется
+// и возвращается false
+func (r *ConsistencyRepo) DeleteBlob(digest string, remove func() error) (bool, error) {
+	removed := false
+
+	fn := func() error {
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			// запись создается, если ее нет, чтобы одновременная загрузка того же содержимого
+			// дождалась удаления файла и записала его заново
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&model.FileBlob{Digest: digest}).Error
+			if err != nil {
+				return err
+			}
+
+			var blob model.FileBlob
+
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("digest = ?", digest).
+				Take(&blob).Error
+			if err != nil {
+				return err
+			}
+
+			var refs int64
+
+			err = tx.Model(&model.FileBlobRef{}).
+				Where("digest = ?", digest).
+				Count(&refs).Error
+			if err != nil {
+				return err
+			}
+
+			if refs > 0 {
+				return nil
+			}
+
+			// запись удаляется до файла: если файл удалить не удалось, откат транзакции вернет ее
+			err = tx.Where("digest = ?", digest).Delete(&model.FileBlob{}).Error
+			if err != nil {
+				return err
+			}
+
+			if err = remove(); err != nil {
+				return err
+			}
+
+			removed = true
+
+			return nil
+		})
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, deleteOrphanFileBlob)
+	if err != nil {
+		log.Debugf("failed to delete orphan file blob: %+v", err)
+		return false, fmt.Errorf("failed to delete file blob [%s]", digest)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/mongodb"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	// TypePostgres содержимое JSON документов хранится в Postgres в колонке JSONB
+	TypePostgres = "postgres"
+
+	saveDocumentContent       = "save_document_content"
+	getDocumentContentById    = "get_document_content_by_id"
+	deleteDocumentContentById = "delete_document_content_by_id"
+	getDocumentContentKeys    = "get_document_content_keys"
+	findDocumentContent       = "find_document_content"
+)
+
+var _ mongodb.ContentRepository = (*ContentRepo)(nil)
+
+// ContentRepo хранит содержимое JSON документов в Postgres вместо MongoDB.
+// Ключ содержимого не хранится внутри JSON и возвращается в поле _id, как из коллекции MongoDB
+type ContentRepo struct {
+	Db           *gorm.DB
+	QueryObserve metric.QueryObserver
+}
+
+func NewContentRepository(db *gorm.DB, metrics *metric.DatabaseMetrics) *ContentRepo {
+	return &ContentRepo{
+		Db:           db,
+		QueryObserve: metrics,
+	}
+}
+
+func (r *ContentRepo) Store(ctx context.Context, uuid string, jsonDoc map[string]interface{}) error {
+	log.Infof("saving document [%s] content", uuid)
+
+	content := make(map[string]interface{}, len(jsonDoc))
+	for key, value := range jsonDoc {
+		if key != entity.ContentIDField {
+			content[key] = value
+		}
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		log.Debugf("failed to save document content: %+v", err)
+		return fmt.Errorf("failed to save document [%s] content", uuid)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	fn := func() error {
+		err := r.Db.WithContext(ctx).Create(&model.DocumentContent{
+			ContentKey: uuid,
+			Content:    string(data),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err = r.QueryObserve.Observe(fn, dataBaseType, saveDocumentContent)
+	if err != nil {
+		log.Debugf("failed to save document content: %+v", err)
+		return fmt.Errorf("failed to save document [%s] content", uuid)
+	}
+
+	log.Infof("document [%s] content saved successfully", uuid)
+
+	return nil
+}
+
+func (r *ContentRepo) GetByDocumentId(ctx context.Context, uuid string) (map[string]interface{}, error) {
+	log.Infof("retrieving document [%s] content from database", uuid)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var rows []model.DocumentContent
+
+	fn := func() error {
+		err := r.Db.WithContext(ctx).
+			Where("content_key = ?", uuid).
+			Limit(1).
+			Find(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getDocumentContentById)
+	if err != nil {
+		log.Debugf("failed to retrieve document content: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve document [%s] content", uuid)
+	}
+
+	if len(rows) == 0 {
+		return nil, custom_error.ErrDocumentNotFound
+	}
+
+	result, err := decodeContent(rows[0])
+	if err != nil {
+		log.Debugf("failed to retrieve document content: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve document [%s] content", uuid)
+	}
+
+	log.Infof("document [%s] content retrieved successfully", uuid)
+
+	return result, nil
+}
+
+func (r *ContentRepo) DeleteByDocumentId(ctx context.Context, uuid string) error {
+	log.Infof("deleting document [%s] content from database", uuid)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	fn := func() error {
+		result := r.Db.WithContext(ctx).
+			Where("content_key = ?", uuid).
+			Delete(&model.DocumentContent{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete document [%s] content", uuid)
+		}
+
+		if result.RowsAffected == 0 {
+			return custom_error.ErrDocumentNotFound
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, deleteDocumentContentById)
+	if err != nil {
+		log.Debugf("failed to delete document content: %+v", err)
+		return err
+	}
+
+	log.Infof("document [%s] content deleted successfully", uuid)
+
+	return nil
+}
+
+// ContentKeys возвращает ключи всего хранимого содержимого в порядке возрастания
+func (r *ContentRepo) ContentKeys(ctx context.Context) ([]string, error) {
+	log.Info("retrieving document content keys from database")
+
+	keys := make([]string, 0)
+
+	fn := func() error {
+		err := r.Db.WithContext(ctx).
+			Model(&model.DocumentContent{}).
+			Order("content_key").
+			Pluck("content_key", &keys).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getDocumentContentKeys)
+	if err != nil {
+		log.Debugf("failed to retrieve document content keys: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve document content keys")
+	}
+
+	log.Infof("%d document content keys retrieved", len(keys))
+
+	return keys, nil
+}
+
+// Find возвращает содержимое, подходящее под условия, в порядке возрастания ключа.
+// Поиск начинается после ключа afterKey, fields ограничивает возвращаемые поля.
+// Ключ содержимого всегда возвращается в поле _id
+func (r *ContentRepo) Find(ctx context.Context, conditions []entity.ContentCondition, fields []string, afterKey string, limit int) ([]map[string]interface{}, string, error) {
+	log.Info("searching documents content")
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	query := r.Db.WithContext(ctx).Model(&model.DocumentContent{})
+
+	if afterKey != "" {
+		query = query.Where("content_key > ?", afterKey)
+	}
+
+	for _, condition := range conditions {
+		where, args, err := contentCondition(condition)
+		if err != nil {
+			return nil, "", err
+		}
+
+		query = query.Where(where, args...)
+	}
+
+	var rows []model.DocumentContent
+
+	fn := func() error {
+		err := query.Order("content_key").Limit(limit).Find(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, findDocumentContent)
+	if err != nil {
+		log.Debugf("failed to search documents content: %+v", err)
+		return nil, "", fmt.Errorf("failed to search documents content")
+	}
+
+	result := make([]map[string]interface{}, 0, len(rows))
+
+	for _, row := range rows {
+		content, err := decodeContent(row)
+		if err != nil {
+			log.Debugf("failed to search documents content: %+v", err)
+			return nil, "", fmt.Errorf("failed to search documents content")
+		}
+
+		result = append(result, entity.ProjectContent(content, fields))
+	}
+
+	next := ""
+	if len(rows) == limit {
+		next = rows[len(rows)-1].ContentKey
+	}
+
+	log.Infof("documents content found: %d", len(result))
+
+	return result, next, nil
+}
+
+// decodeContent восстанавливает содержимое вместе с ключом в поле _id
+func decodeContent(row model.DocumentContent) (map[string]interface{}, error) {
+	var content map[string]interface{}
+
+	if err := json.Unmarshal([]byte(row.Content), &content); err != nil {
+		return nil, err
+	}
+
+	content[entity.ContentIDField] = row.ContentKey
+
+	return content, nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// contentOperators сопоставляет операторы запроса с операторами сравнения jsonpath
+var contentOperators = map[string]string{
+	entity.ContentOpEq:  "==",
+	entity.ContentOpGt:  ">",
+	entity.ContentOpGte: ">=",
+	entity.ContentOpLt:  "<",
+	entity.ContentOpLte: "<=",
+}
+
+// contentCondition переводит условие запроса в условие SQL на колонку content.
+// Условия строятся на операторах @@ и @>, которые поддерживает GIN индекс jsonb_path_ops.
+// В режиме lax путь jsonpath проходит внутрь массивов, а сравнение выполняется, если подходит любой элемент,
+// поэтому результат совпадает с MongoDB. Значения разных типов несравнимы, как в MongoDB.
+// Выражения передаются параметрами запроса, а сегменты пути проверены заранее
+func contentCondition(condition entity.ContentCondition) (string, []interface{}, error) {
+	path := jsonPath(condition.Path)
+
+	switch condition.Op {
+	case entity.ContentOpExists:
+		exists, _ := condition.Value.(bool)
+		if exists {
+			return "content @@ ?::jsonpath", []interface{}{existsPredicate(path)}, nil
+		}
+
+		return "NOT content @@ ?::jsonpath", []interface{}{existsPredicate(path)}, nil
+	case entity.ContentOpContains:
+		value, err := containsDocument(condition.Path, condition.Value)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return "content @> ?::jsonb", []interface{}{value}, nil
+	case entity.ContentOpEq, entity.ContentOpNe:
+		where, args, err := equalCondition(path, condition.Value)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if condition.Op == entity.ContentOpNe {
+			where = "NOT COALESCE(" + where + ", false)"
+		}
+
+		return where, args, nil
+	}
+
+	operator, ok := contentOperators[condition.Op]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: unknown operator [%s]", custom_error.ErrInvalidContentQuery, condition.Op)
+	}
+
+	predicate, err := comparePredicate(path, operator, condition.Value)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return "content @@ ?::jsonpath", []interface{}{predicate}, nil
+}
+
+// equalCondition условие равенства. Условие на null выполняется и для отсутствующего поля
+func equalCondition(path string, value interface{}) (string, []interface{}, error) {
+	predicate, err := comparePredicate(path, "==", value)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if value == nil {
+		return "(content @@ ?::jsonpath OR NOT content @@ ?::jsonpath)", []interface{}{predicate, existsPredicate(path)}, nil
+	}
+
+	return "content @@ ?::jsonpath", []interface{}{predicate}, nil
+}
+
+// jsonPath переводит путь через точку в путь jsonpath, каждый сегмент берется в кавычки
+func jsonPath(path string) string {
+	var builder strings.Builder
+
+	builder.WriteString("$")
+
+	for _, segment := range strings.Split(path, ".") {
+		builder.WriteString(`."`)
+		builder.WriteString(segment)
+		builder.WriteString(`"`)
+	}
+
+	return builder.String()
+}
+
+func existsPredicate(path string) string {
+	return "exists(" + path + ")"
+}
+
+// comparePredicate сравнение значения по пути со скалярным значением.
+// Значение записывается как литерал JSON, синтаксис которого совпадает с литералами jsonpath
+func comparePredicate(path, operator string, value interface{}) (string, error) {
+	literal, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid value for [%s]", custom_error.ErrInvalidContentQuery, path)
+	}
+
+	return path + " " + operator + " " + string(literal), nil
+}
+
+// containsDocument строит документ для оператора @>, в котором массив по пути содержит значение
+func containsDocument(path string, value interface{}) (string, error) {
+	var document interface{} = []interface{}{value}
+
+	segments := strings.Split(path, ".")
+	for i := len(segments) - 1; i >= 0; i-- {
+		document = map[string]interface{}{segments[i]: document}
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid value for [%s]", custom_error.ErrInvalidContentQuery, path)
+	}
+
+	return string(data), nil
+}
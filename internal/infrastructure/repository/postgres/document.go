@@ -17,8 +17,11 @@ const (
 	dataBaseType = "postgres"
 
 	saveDocumentMetaData       = "save_document_meta_data"
+	updateDocumentMetaData     = "update_document_meta_data"
 	getListDocumentMetaData    = "get_list_document_meta_data"
+	countDocumentMetaData      = "count_document_meta_data"
 	getDocumentMetaDataById    = "get_document_meta_data_by_id"
+	getDocumentMetaDataByKeys  = "get_document_meta_data_by_keys"
 	deleteDocumentMetaDataById = "delete_document_meta_data_by_id"
 )
 
@@ -36,16 +39,19 @@ func NewMetadataRepository(db *gorm.DB, metrics *metric.DatabaseMetrics) *Metada
 	}
 }
 
-func (r *MetadataRepo) Save(document *model.MetaDocument) error {
+// Save сохраняет метаданные нового документа вместе с событиями в одной транзакции
+func (r *MetadataRepo) Save(document *model.MetaDocument, events ...model.OutboxEvent) error {
 	log.Infof("saving document [%s] metadata to database", document.UUID)
 
 	fn := func() error {
-		err := r.Db.Create(&document).Error
-		if err != nil {
-			return err
-		}
-
-		return nil
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Create(&document).Error
+			if err != nil {
+				return err
+			}
+
+			return addEvents(tx, events)
+		})
 	}
 
 	err := r.QueryObserve.Observe(fn, dataBaseType, saveDocumentMetaData)
@@ -59,20 +65,77 @@ func (r *MetadataRepo) Save(document *model.MetaDocument) error {
 	return nil
 }
 
-func (r *MetadataRepo) GetList(req entity.DocumentListRequest) ([]model.MetaDocument, error) {
+// Update перезаписывает метаданные документа вместе с записью событий в одной транзакции
+func (r *MetadataRepo) Update(document *model.MetaDocument, events ...model.OutboxEvent) error {
+	log.Infof("updating document [%s] metadata", document.UUID)
+
+	fn := func() error {
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Save(document).Error
+			if err != nil {
+				return err
+			}
+
+			return addEvents(tx, events)
+		})
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, updateDocumentMetaData)
+	if err != nil {
+		log.Debugf("failed to update document metadata: %+v", err)
+		return fmt.Errorf("failed to update document [%s] metadata", document.UUID)
+	}
+
+	log.Infof("document [%s] metadata updated successfully", document.UUID)
+
+	return nil
+}
+
+func (r *MetadataRepo) GetList(req entity.DocumentListRequest) (entity.DocumentList, error) {
 	log.Info("retrieving documents list from database")
 
-	documents := make([]model.MetaDocument, req.Limit)
+	var list entity.DocumentList
+
+	query, err := r.listQuery(req)
+	if err != nil {
+		return list, err
+	}
+
+	column, ok := sortColumns[req.Sort]
+	if !ok {
+		return list, fmt.Errorf("%w: unknown sort field [%s]", custom_error.ErrInvalidListParams, req.Sort)
+	}
+
+	page := query.Session(&gorm.Session{})
+
+	if req.Cursor != "" {
+		cursor, err := req.DecodeCursor()
+		if err != nil {
+			return list, err
+		}
+
+		page, err = applyCursor(page, column, req.Order, cursor)
+		if err != nil {
+			return list, err
+		}
+	}
+
+	documents := make([]model.MetaDocument, 0, req.Limit+1)
 
 	fn := func() error {
-		err := r.Db.Model(&model.MetaDocument{}).
-			Where(fmt.Sprintf("%s = ?", req.Key), req.Value).
-			Where("? = ANY(meta_documents.grant)", req.Login).
-			Limit(req.Limit).
+		err := query.Session(&gorm.Session{}).
+			Count(&list.Total).Error
+		if err != nil {
+			return err
+		}
+
+		// лишний документ показывает, что есть следующая страница
+		err = page.
+			Order(fmt.Sprintf("%s %s", column, req.Order)).
+			Order(fmt.Sprintf("meta_documents.id %s", req.Order)).
+			Limit(req.Limit + 1).
 			Offset(req.Offset).
-			Find(&documents).
-			Order("name asc").
-			Order("created_at desc").Error
+			Find(&documents).Error
 		if err != nil {
 			return err
 		}
@@ -80,17 +143,109 @@ func (r *MetadataRepo) GetList(req entity.DocumentListRequest) ([]model.MetaDocu
 		return nil
 	}
 
-	err := r.QueryObserve.Observe(fn, dataBaseType, getListDocumentMetaData)
+	err = r.QueryObserve.Observe(fn, dataBaseType, getListDocumentMetaData)
 	if err != nil {
 		log.Debugf("failed to retrieve documents list: %+v", err)
-		return nil, fmt.Errorf("failed to retrieve documents list")
+		return list, fmt.Errorf("failed to retrieve documents list")
+	}
+
+	if len(documents) > req.Limit {
+		documents = documents[:req.Limit]
+		list.NextCursor = req.NewListCursor(documents[len(documents)-1])
 	}
 
+	list.Docs = documents
+
 	log.Info("documents list retrieved successfully")
 
+	return list, nil
+}
+
+func (r *MetadataRepo) Count(req entity.DocumentListRequest) (int64, error) {
+	log.Info("counting documents in database")
+
+	var total int64
+
+	query, err := r.listQuery(req)
+	if err != nil {
+		return total, err
+	}
+
+	fn := func() error {
+		err := query.Count(&total).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err = r.QueryObserve.Observe(fn, dataBaseType, countDocumentMetaData)
+	if err != nil {
+		log.Debugf("failed to count documents: %+v", err)
+		return total, fmt.Errorf("failed to count documents")
+	}
+
+	log.Infof("documents counted successfully: %d", total)
+
+	return total, nil
+}
+
+// GetReadableByStorageKeys возвращает метаданные JSON документов с текущим содержимым по ключам keys,
+// доступные пользователю viewer для чтения
+func (r *MetadataRepo) GetReadableByStorageKeys(viewer string, keys []string) ([]model.MetaDocument, error) {
+	log.Infof("retrieving metadata of %d documents by content keys", len(keys))
+
+	documents := make([]model.MetaDocument, 0, len(keys))
+
+	if len(keys) == 0 {
+		return documents, nil
+	}
+
+	fn := func() error {
+		err := r.Db.Model(&model.MetaDocument{}).
+			Where("meta_documents.file = ?", false).
+			Where("COALESCE(NULLIF(meta_documents.content_key, ''), meta_documents.uuid) IN ?", keys).
+			Where("(meta_documents.public OR meta_documents.owner = ? OR ? = ANY(meta_documents.grant))", viewer, viewer).
+			Find(&documents).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getDocumentMetaDataByKeys)
+	if err != nil {
+		log.Debugf("failed to retrieve documents metadata by content keys: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve documents metadata by content keys")
+	}
+
+	log.Infof("metadata of %d documents retrieved successfully", len(documents))
+
 	return documents, nil
 }
 
+// listQuery строит запрос списка документов с фильтрами и проверкой прав доступа
+func (r *MetadataRepo) listQuery(req entity.DocumentListRequest) (*gorm.DB, error) {
+	query, err := applyFilters(r.Db.Model(&model.MetaDocument{}), req.AllFilters())
+	if err != nil {
+		log.Debugf("failed to build documents list query: %+v", err)
+		return nil, err
+	}
+
+	if req.Login == req.Viewer {
+		// собственные документы и документы, к которым пользователю выдан доступ
+		query = query.Where("(meta_documents.owner = ? OR ? = ANY(meta_documents.grant))", req.Viewer, req.Viewer)
+	} else {
+		// документы другого пользователя, доступные запрашивающему
+		query = query.Where("meta_documents.owner = ?", req.Login).
+			Where("(meta_documents.public OR ? = ANY(meta_documents.grant))", req.Viewer)
+	}
+
+	return query, nil
+}
+
 func (r *MetadataRepo) GetById(uuid string) (model.MetaDocument, error) {
 	log.Infof("retrieving document [%s] metadata", uuid)
 
@@ -123,18 +278,26 @@ func (r *MetadataRepo) GetById(uuid string) (model.MetaDocument, error) {
 	return document, nil
 }
 
-func (r *MetadataRepo) DeleteById(id string) error {
+// DeleteById удаляет метаданные документа вместе с записью событий в одной транзакции.
+// События записываются, только если документ был удален этим вызовом, поэтому повтор удаления их не дублирует
+func (r *MetadataRepo) DeleteById(id string, events ...model.OutboxEvent) error {
 	log.Infof("deleting document [%s] metadata", id)
 
 	fn := func() error {
-		err := r.Db.Model(&model.MetaDocument{}).
-			Where("uuid = ?", id).
-			Delete(&model.MetaDocument{}).Error
-		if err != nil {
-			return err
-		}
-
-		return nil
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&model.MetaDocument{}).
+				Where("uuid = ?", id).
+				Delete(&model.MetaDocument{})
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected == 0 {
+				return nil
+			}
+
+			return addEvents(tx, events)
+		})
 	}
 
 	err := r.QueryObserve.Observe(fn, dataBaseType, deleteDocumentMetaDataById)
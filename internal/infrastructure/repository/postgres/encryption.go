@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	getContentDataKeys = "get_content_data_keys"
+	getStaleDataKeys   = "get_stale_data_keys"
+	replaceDataKey     = "replace_data_key"
+)
+
+// dataKeyTables таблицы, в которых хранятся ключи данных содержимого
+var dataKeyTables = []interface{}{&model.MetaDocument{}, &model.DocumentVersion{}, &model.FileBlob{}}
+
+var _ EncryptionKeys = (*EncryptionKeyRepo)(nil)
+
+// EncryptionKeyRepo ключи данных содержимого, которые хранятся в метаданных документов, версиях и записях о файлах
+type EncryptionKeyRepo struct {
+	Db           *gorm.DB
+	QueryObserve metric.QueryObserver
+}
+
+func NewEncryptionKeyRepo(db *gorm.DB, metrics *metric.DatabaseMetrics) *EncryptionKeyRepo {
+	return &EncryptionKeyRepo{
+		Db:           db,
+		QueryObserve: metrics,
+	}
+}
+
+// dataKeyRow ключ данных вместе с ключом хранения содержимого
+type dataKeyRow struct {
+	StorageKey string
+	model.DataKey
+}
+
+// GetDataKeys возвращает ключи данных зашифрованного содержимого по ключам хранения.
+// Для содержимого без шифрования ключ не возвращается
+func (r *EncryptionKeyRepo) GetDataKeys(storageKeys []string) (map[string]model.DataKey, error) {
+	keys := make(map[string]model.DataKey, len(storageKeys))
+
+	if len(storageKeys) == 0 {
+		return keys, nil
+	}
+
+	var rows []dataKeyRow
+
+	// содержимое одного ключа хранения у всех документов и версий зашифровано одним ключом данных,
+	// поэтому подходит любая найденная запись
+	fn := func() error {
+		return r.Db.Raw(`SELECT content_key AS storage_key, wrapped_key, key_id FROM meta_documents
+				WHERE wrapped_key <> '' AND content_key IN ?
+			UNION
+			SELECT content_key, wrapped_key, key_id FROM document_versions
+				WHERE wrapped_key <> '' AND content_key IN ?
+			UNION
+			SELECT digest, wrapped_key, key_id FROM file_blobs
+				WHERE wrapped_key <> '' AND digest IN ?`,
+			storageKeys, storageKeys, storageKeys).
+			Scan(&rows).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getContentDataKeys)
+	if err != nil {
+		log.Debugf("failed to retrieve content data keys: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve content data keys")
+	}
+
+	for _, row := range rows {
+		keys[row.StorageKey] = row.DataKey
+	}
+
+	return keys, nil
+}
+
+// GetStaleDataKeys возвращает ключи данных, зашифрованные не активным мастер-ключом,
+// в порядке возрастания зашифрованного ключа после afterKey
+func (r *EncryptionKeyRepo) GetStaleDataKeys(activeKeyID, afterKey string, limit int) ([]model.DataKey, error) {
+	var keys []model.DataKey
+
+	fn := func() error {
+		return r.Db.Raw(`SELECT wrapped_key, key_id FROM (
+				SELECT wrapped_key, key_id FROM meta_documents
+				UNION
+				SELECT wrapped_key, key_id FROM document_versions
+				UNION
+				SELECT wrapped_key, key_id FROM file_blobs
+			) AS k
+			WHERE wrapped_key <> '' AND key_id <> ? AND wrapped_key > ?
+			ORDER BY wrapped_key
+			LIMIT ?`,
+			activeKeyID, afterKey, limit).
+			Scan(&keys).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getStaleDataKeys)
+	if err != nil {
+		log.Debugf("failed to retrieve stale data keys: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve stale data keys")
+	}
+
+	return keys, nil
+}
+
+// ReplaceDataKey заменяет зашифрованный ключ данных во всех таблицах одной транзакцией.
+// Время изменения документов не меняется: содержимое остается прежним
+func (r *EncryptionKeyRepo) ReplaceDataKey(oldKey, newKey model.DataKey) error {
+	fn := func() error {
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			for _, table := range dataKeyTables {
+				err := tx.Model(table).
+					Where("wrapped_key = ?", oldKey.WrappedKey).
+					UpdateColumns(map[string]interface{}{
+						"wrapped_key": newKey.WrappedKey,
+						"key_id":      newKey.KeyID,
+					}).Error
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, replaceDataKey)
+	if err != nil {
+		log.Debugf("failed to replace data key: %+v", err)
+		return fmt.Errorf("failed to replace data key of master key [%s]", oldKey.KeyID)
+	}
+
+	return nil
+}
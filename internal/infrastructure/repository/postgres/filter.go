@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// filterColumns сопоставляет поля фильтров с колонками таблицы.
+// В запрос попадают только имена колонок из этого списка
+var filterColumns = map[string]string{
+	"name":       "meta_documents.name",
+	"mime":       "meta_documents.mime",
+	"file":       "meta_documents.file",
+	"public":     "meta_documents.public",
+	"created_at": "meta_documents.created_at",
+}
+
+// sortColumns сопоставляет поля сортировки с колонками таблицы
+var sortColumns = map[string]string{
+	"name":       "meta_documents.name",
+	"mime":       "meta_documents.mime",
+	"created_at": "meta_documents.created_at",
+	"updated_at": "meta_documents.updated_at",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// applyFilters добавляет к запросу условия фильтров, значения передаются только параметрами
+func applyFilters(query *gorm.DB, filters []entity.DocumentFilter) (*gorm.DB, error) {
+	for _, filter := range filters {
+		column, ok := filterColumns[filter.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field [%s]", custom_error.ErrInvalidFilter, filter.Field)
+		}
+
+		var value interface{} = filter.Value
+		if filter.IsBool() {
+			value = filter.BoolValue()
+		}
+
+		switch filter.Op {
+		case entity.FilterOpEq:
+			query = query.Where(column+" = ?", value)
+		case entity.FilterOpNe:
+			query = query.Where(column+" <> ?", value)
+		case entity.FilterOpIn:
+			query = query.Where(column+" IN ?", filter.Values)
+		case entity.FilterOpPrefix:
+			query = query.Where(column+` LIKE ? ESCAPE '\'`, likeEscaper.Replace(filter.Value)+"%")
+		case entity.FilterOpRange:
+			if filter.From != nil {
+				query = query.Where(column+" >= ?", *filter.From)
+			}
+
+			if filter.To != nil {
+				query = query.Where(column+" <= ?", *filter.To)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown operator [%s]", custom_error.ErrInvalidFilter, filter.Op)
+		}
+	}
+
+	return query, nil
+}
+
+// applyCursor оставляет документы, следующие за курсором в порядке сортировки.
+// Идентификатор документа делает порядок однозначным при совпадающих значениях
+func applyCursor(query *gorm.DB, column, order string, cursor entity.ListCursor) (*gorm.DB, error) {
+	cmp := ">"
+	if order == entity.SortOrderDesc {
+		cmp = "<"
+	}
+
+	var value interface{} = cursor.Value
+	if entity.SortFields[cursor.Sort] {
+		date, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", custom_error.ErrInvalidCursor, err)
+		}
+
+		value = date
+	}
+
+	return query.Where(fmt.Sprintf("(%s, meta_documents.id) %s (?, ?)", column, cmp), value, cursor.ID), nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	publishOutboxEvents = "publish_outbox_events"
+	deleteOutboxEvents  = "delete_outbox_events"
+
+	// outboxLockKey ключ блокировки, под которой события публикует только один экземпляр сервиса
+	outboxLockKey = 7540001
+)
+
+var _ Outbox = (*OutboxRepo)(nil)
+
+type OutboxRepo struct {
+	Db           *gorm.DB
+	QueryObserve metric.QueryObserver
+}
+
+func NewOutboxRepo(db *gorm.DB, metrics *metric.DatabaseMetrics) *OutboxRepo {
+	return &OutboxRepo{
+		Db:           db,
+		QueryObserve: metrics,
+	}
+}
+
+// addEvents записывает события в outbox в рамках транзакции изменения метаданных
+func addEvents(tx *gorm.DB, events []model.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	return tx.Create(&events).Error
+}
+
+// PublishPending передает publish неопубликованные события в порядке записи и отмечает их опубликованными.
+// На первой ошибке публикация останавливается, чтобы не нарушить порядок событий документа,
+// ошибка сохраняется в событии. События публикует только один экземпляр сервиса:
+// остальные пропускают вызов, пока блокировка занята. Возвращает количество опубликованных событий
+func (r *OutboxRepo) PublishPending(limit int, publish func(event model.OutboxEvent) error) (int, error) {
+	var published int
+
+	fn := func() error {
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			var locked bool
+
+			err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxLockKey).Scan(&locked).Error
+			if err != nil || !locked {
+				return err
+			}
+
+			events := make([]model.OutboxEvent, 0, limit)
+
+			err = tx.Where("published_at IS NULL").
+				Order("id").
+				Limit(limit).
+				Find(&events).Error
+			if err != nil {
+				return err
+			}
+
+			for _, event := range events {
+				if pubErr := publish(event); pubErr != nil {
+					log.Debugf("failed to publish event [%s]: %+v", event.UUID, pubErr)
+
+					return tx.Model(&event).Updates(map[string]interface{}{
+						"attempts": gorm.Expr("attempts + 1"),
+						"error":    pubErr.Error(),
+					}).Error
+				}
+
+				// событие отмечается сразу после публикации: при сбое транзакции оно будет отправлено повторно
+				err = tx.Model(&event).Update("published_at", time.Now()).Error
+				if err != nil {
+					return err
+				}
+
+				published++
+			}
+
+			return nil
+		})
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, publishOutboxEvents)
+	if err != nil {
+		log.Debugf("failed to publish outbox events: %+v", err)
+		return 0, fmt.Errorf("failed to publish outbox events")
+	}
+
+	if published > 0 {
+		log.Infof("%d outbox events published", published)
+	}
+
+	return published, nil
+}
+
+// DeletePublished удаляет события, опубликованные до момента before
+func (r *OutboxRepo) DeletePublished(before time.Time) (int64, error) {
+	var deleted int64
+
+	fn := func() error {
+		result := r.Db.Where("published_at < ?", before).Delete(&model.OutboxEvent{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		deleted = result.RowsAffected
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, deleteOutboxEvents)
+	if err != nil {
+		log.Debugf("failed to delete published outbox events: %+v", err)
+		return 0, fmt.Errorf("failed to delete published outbox events")
+	}
+
+	return deleted, nil
+}
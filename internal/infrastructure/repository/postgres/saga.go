@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	saveSaga       = "save_saga"
+	updateSaga     = "update_saga"
+	touchSaga      = "touch_saga"
+	claimSaga      = "claim_saga"
+	saveSagaStep   = "save_saga_step"
+	getSaga        = "get_saga"
+	getSagaSteps   = "get_saga_steps"
+	getStaleSagas  = "get_stale_sagas"
+	getStuckSagas  = "get_stuck_sagas"
+	countStuckSaga = "count_stuck_sagas"
+)
+
+var unfinishedSagaStatuses = []string{model.SagaStatusRunning, model.SagaStatusCompensating}
+
+var _ SagaLog = (*SagaLogRepo)(nil)
+
+type SagaLogRepo struct {
+	Db           *gorm.DB
+	QueryObserve metric.QueryObserver
+}
+
+func NewSagaLogRepo(db *gorm.DB, metrics *metric.DatabaseMetrics) *SagaLogRepo {
+	return &SagaLogRepo{
+		Db:           db,
+		QueryObserve: metrics,
+	}
+}
+
+func (r *SagaLogRepo) Create(saga *model.Saga) error {
+	log.Debugf("saving saga [%s] of document [%s]", saga.UUID, saga.DocumentUUID)
+
+	fn := func() error {
+		return r.Db.Create(saga).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, saveSaga)
+	if err != nil {
+		log.Debugf("failed to save saga: %+v", err)
+		return fmt.Errorf("failed to save saga [%s]", saga.UUID)
+	}
+
+	return nil
+}
+
+func (r *SagaLogRepo) Update(saga *model.Saga) error {
+	log.Debugf("updating saga [%s] status [%s]", saga.UUID, saga.Status)
+
+	fn := func() error {
+		return r.Db.Save(saga).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, updateSaga)
+	if err != nil {
+		log.Debugf("failed to update saga: %+v", err)
+		return fmt.Errorf("failed to update saga [%s]", saga.UUID)
+	}
+
+	return nil
+}
+
+// Touch продлевает незавершенную сагу, чтобы ее не забрало восстановление
+func (r *SagaLogRepo) Touch(uuid string) error {
+	fn := func() error {
+		return r.Db.Model(&model.Saga{}).
+			Where("uuid = ? AND status IN ?", uuid, unfinishedSagaStatuses).
+			Update("updated_at", time.Now()).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, touchSaga)
+	if err != nil {
+		log.Debugf("failed to touch saga: %+v", err)
+		return fmt.Errorf("failed to touch saga [%s]", uuid)
+	}
+
+	return nil
+}
+
+// Claim забирает сагу для восстановления, если с момента чтения ее никто не изменил.
+// Возвращает false, если сагу уже забрал другой экземпляр сервиса
+func (r *SagaLogRepo) Claim(saga *model.Saga) (bool, error) {
+	var claimed bool
+
+	now := time.Now()
+
+	fn := func() error {
+		result := r.Db.Model(&model.Saga{}).
+			Where("uuid = ? AND updated_at = ?", saga.UUID, saga.UpdatedAt).
+			Updates(map[string]interface{}{
+				"updated_at": now,
+				"attempts":   gorm.Expr("attempts + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		claimed = result.RowsAffected == 1
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, claimSaga)
+	if err != nil {
+		log.Debugf("failed to claim saga: %+v", err)
+		return false, fmt.Errorf("failed to claim saga [%s]", saga.UUID)
+	}
+
+	if claimed {
+		saga.UpdatedAt = now
+		saga.Attempts++
+	}
+
+	return claimed, nil
+}
+
+// SaveStep сохраняет состояние шага саги
+func (r *SagaLogRepo) SaveStep(step model.SagaStep) error {
+	fn := func() error {
+		return r.Db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "saga_uuid"}, {Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "attempts", "error", "updated_at"}),
+		}).Create(&step).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, saveSagaStep)
+	if err != nil {
+		log.Debugf("failed to save saga step: %+v", err)
+		return fmt.Errorf("failed to save saga [%s] step [%s]", step.SagaUUID, step.Name)
+	}
+
+	return nil
+}
+
+func (r *SagaLogRepo) Get(uuid string) (model.Saga, error) {
+	var saga model.Saga
+
+	fn := func() error {
+		return r.Db.Where("uuid = ?", uuid).First(&saga).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getSaga)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return saga, custom_error.ErrSagaNotFound
+		}
+
+		log.Debugf("failed to retrieve saga: %+v", err)
+		return saga, fmt.Errorf("failed to retrieve saga [%s]", uuid)
+	}
+
+	return saga, nil
+}
+
+func (r *SagaLogRepo) GetSteps(uuid string) ([]model.SagaStep, error) {
+	steps := make([]model.SagaStep, 0)
+
+	fn := func() error {
+		return r.Db.Where("saga_uuid = ?", uuid).
+			Order("id").
+			Find(&steps).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getSagaSteps)
+	if err != nil {
+		log.Debugf("failed to retrieve saga steps: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve saga [%s] steps", uuid)
+	}
+
+	return steps, nil
+}
+
+// GetStale возвращает незавершенные саги, которые не обновлялись с момента before
+func (r *SagaLogRepo) GetStale(before time.Time, limit int) ([]model.Saga, error) {
+	sagas := make([]model.Saga, 0)
+
+	fn := func() error {
+		return r.Db.Where("status IN ? AND updated_at < ?", unfinishedSagaStatuses, before).
+			Order("updated_at").
+			Limit(limit).
+			Find(&sagas).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getStaleSagas)
+	if err != nil {
+		log.Debugf("failed to retrieve stale sagas: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve stale sagas")
+	}
+
+	return sagas, nil
+}
+
+// GetStuck возвращает саги, восстановление которых не удалось,
+// и незавершенные саги, которые не обновлялись с момента before
+func (r *SagaLogRepo) GetStuck(before time.Time, limit, offset int) ([]model.Saga, int64, error) {
+	var total int64
+
+	sagas := make([]model.Saga, 0)
+
+	query := r.Db.Model(&model.Saga{}).
+		Where("status = ? OR (status IN ? AND updated_at < ?)", model.SagaStatusFailed, unfinishedSagaStatuses, before)
+
+	fn := func() error {
+		return query.Session(&gorm.Session{}).Count(&total).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, countStuckSaga)
+	if err != nil {
+		log.Debugf("failed to count stuck sagas: %+v", err)
+		return nil, 0, fmt.Errorf("failed to count stuck sagas")
+	}
+
+	fn = func() error {
+		return query.Session(&gorm.Session{}).
+			Order("updated_at").
+			Limit(limit).
+			Offset(offset).
+			Find(&sagas).Error
+	}
+
+	err = r.QueryObserve.Observe(fn, dataBaseType, getStuckSagas)
+	if err != nil {
+		log.Debugf("failed to retrieve stuck sagas: %+v", err)
+		return nil, 0, fmt.Errorf("failed to retrieve stuck sagas")
+	}
+
+	return sagas, total, nil
+}
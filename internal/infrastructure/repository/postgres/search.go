@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	saveDocumentSearchIndex   = "save_document_search_index"
+	deleteDocumentSearchIndex = "delete_document_search_index"
+	searchDocuments           = "search_documents"
+)
+
+// Конфигурация simple не зависит от языка документа: слова приводятся к нижнему регистру без стемминга,
+// поэтому одинаково работает для русских и английских текстов
+const (
+	upsertSearchIndexQuery = `
+INSERT INTO document_searches (document_uuid, name, body, vector, updated_at)
+VALUES (?, ?, ?, setweight(to_tsvector('simple', ?), 'A') || setweight(to_tsvector('simple', ?), 'B'), ?)
+ON CONFLICT (document_uuid) DO UPDATE
+SET name = EXCLUDED.name, body = EXCLUDED.body, vector = EXCLUDED.vector, updated_at = EXCLUDED.updated_at`
+
+	// фрагменты текста строятся только для строк страницы, так как ts_headline обрабатывает текст целиком.
+	// Текст экранируется для HTML до выделения слов, чтобы во фрагменте были только теги выделения
+	searchDocumentsQuery = `
+SELECT page.*, ts_headline('simple',
+	replace(replace(replace(replace(replace(s.name || ' ' || s.body,
+		'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;'),
+	websearch_to_tsquery('simple', ?),
+	'StartSel=<b>, StopSel=</b>, MaxFragments=2, MaxWords=20, MinWords=5') AS snippet
+FROM (
+	SELECT m.*, ts_rank(s.vector, query) AS rank
+	FROM document_searches s
+	JOIN meta_documents m ON m.uuid = s.document_uuid,
+		websearch_to_tsquery('simple', ?) query
+	WHERE s.vector @@ query
+		AND (m.public OR m.owner = ? OR ? = ANY(m.grant))
+	ORDER BY rank DESC, m.id
+	LIMIT ? OFFSET ?
+) page
+JOIN document_searches s ON s.document_uuid = page.uuid
+ORDER BY page.rank DESC, page.id`
+)
+
+// searchRow строка результата поиска
+type searchRow struct {
+	model.MetaDocument `gorm:"embedded"`
+	Rank               float64
+	Snippet            string
+}
+
+// IndexDocument сохраняет текст документа в полнотекстовый индекс
+func (r *MetadataRepo) IndexDocument(index model.DocumentSearch) error {
+	log.Infof("indexing document [%s]", index.DocumentUUID)
+
+	fn := func() error {
+		err := r.Db.Exec(upsertSearchIndexQuery,
+			index.DocumentUUID, index.Name, index.Body, index.Name, index.Body, time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, saveDocumentSearchIndex)
+	if err != nil {
+		log.Debugf("failed to index document: %+v", err)
+		return fmt.Errorf("failed to index document [%s]", index.DocumentUUID)
+	}
+
+	log.Infof("document [%s] indexed successfully", index.DocumentUUID)
+
+	return nil
+}
+
+// DeleteDocumentIndex удаляет документ из полнотекстового индекса
+func (r *MetadataRepo) DeleteDocumentIndex(uuid string) error {
+	log.Infof("deleting document [%s] from search index", uuid)
+
+	fn := func() error {
+		err := r.Db.Where("document_uuid = ?", uuid).
+			Delete(&model.DocumentSearch{}).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, deleteDocumentSearchIndex)
+	if err != nil {
+		log.Debugf("failed to delete document from search index: %+v", err)
+		return fmt.Errorf("failed to delete document [%s] from search index", uuid)
+	}
+
+	log.Infof("document [%s] deleted from search index successfully", uuid)
+
+	return nil
+}
+
+// Search ищет документы, доступные пользователю, по словам запроса в порядке убывания релевантности
+func (r *MetadataRepo) Search(req entity.SearchRequest) ([]entity.SearchHit, error) {
+	log.Info("searching documents")
+
+	var rows []searchRow
+
+	fn := func() error {
+		err := r.Db.Raw(searchDocumentsQuery,
+			req.Query, req.Query, req.Viewer, req.Viewer, req.Limit, req.Offset).
+			Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, searchDocuments)
+	if err != nil {
+		log.Debugf("failed to search documents: %+v", err)
+		return nil, fmt.Errorf("failed to search documents")
+	}
+
+	hits := make([]entity.SearchHit, 0, len(rows))
+	for _, row := range rows {
+		hits = append(hits, entity.SearchHit{
+			Meta:    row.MetaDocument,
+			Rank:    row.Rank,
+			Snippet: row.Snippet,
+		})
+	}
+
+	log.Infof("documents found: %d", len(hits))
+
+	return hits, nil
+}
@@ -0,0 +1,245 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	saveShareLink       = "save_share_link"
+	getShareLink        = "get_share_link"
+	getShareLinkByToken = "get_share_link_by_token"
+	getShareLinks       = "get_share_links"
+	revokeShareLink     = "revoke_share_link"
+	claimShareDownload  = "claim_share_download"
+	saveShareAccess     = "save_share_access"
+	getShareAccesses    = "get_share_accesses"
+	countShareAccesses  = "count_share_accesses"
+)
+
+var _ Share = (*ShareRepo)(nil)
+
+type ShareRepo struct {
+	Db           *gorm.DB
+	QueryObserve metric.QueryObserver
+}
+
+func NewShareRepo(db *gorm.DB, metrics *metric.DatabaseMetrics) *ShareRepo {
+	return &ShareRepo{
+		Db:           db,
+		QueryObserve: metrics,
+	}
+}
+
+func (r *ShareRepo) CreateShareLink(link *model.ShareLink) error {
+	log.Infof("saving share link [%s] of document [%s]", link.UUID, link.DocumentUUID)
+
+	fn := func() error {
+		return r.Db.Create(link).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, saveShareLink)
+	if err != nil {
+		log.Debugf("failed to save share link: %+v", err)
+		return fmt.Errorf("failed to save share link [%s]", link.UUID)
+	}
+
+	log.Infof("share link [%s] saved successfully", link.UUID)
+
+	return nil
+}
+
+func (r *ShareRepo) GetShareLink(login, uuid string) (model.ShareLink, error) {
+	var link model.ShareLink
+
+	fn := func() error {
+		return r.Db.Where("owner = ? AND uuid = ?", login, uuid).
+			First(&link).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getShareLink)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return link, custom_error.ErrShareNotFound
+		}
+
+		log.Debugf("failed to retrieve share link: %+v", err)
+		return link, fmt.Errorf("failed to retrieve share link [%s]", uuid)
+	}
+
+	return link, nil
+}
+
+// GetShareLinkByToken возвращает ссылку по SHA-256 ее токена
+func (r *ShareRepo) GetShareLinkByToken(tokenHash string) (model.ShareLink, error) {
+	var link model.ShareLink
+
+	fn := func() error {
+		return r.Db.Where("token_hash = ?", tokenHash).
+			First(&link).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getShareLinkByToken)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return link, custom_error.ErrShareNotFound
+		}
+
+		log.Debugf("failed to retrieve share link: %+v", err)
+		return link, fmt.Errorf("failed to retrieve share link by token")
+	}
+
+	return link, nil
+}
+
+// GetShareLinks возвращает ссылки пользователя, в том числе отозванные и истекшие.
+// Непустой documentUUID оставляет только ссылки на этот документ
+func (r *ShareRepo) GetShareLinks(login, documentUUID string) ([]model.ShareLink, error) {
+	links := make([]model.ShareLink, 0)
+
+	query := r.Db.Where("owner = ?", login)
+
+	if documentUUID != "" {
+		query = query.Where("document_uuid = ?", documentUUID)
+	}
+
+	fn := func() error {
+		return query.Order("id").
+			Find(&links).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getShareLinks)
+	if err != nil {
+		log.Debugf("failed to retrieve share links: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve share links of user [%s]", login)
+	}
+
+	return links, nil
+}
+
+// RevokeShareLink отзывает ссылку. Отозванная ссылка вместе с журналом обращений остается в списке ссылок.
+// Повторный отзыв не меняет время отзыва
+func (r *ShareRepo) RevokeShareLink(login, uuid string, now time.Time) error {
+	log.Infof("revoking share link [%s] of user [%s]", uuid, login)
+
+	var found int64
+
+	fn := func() error {
+		result := r.Db.Model(&model.ShareLink{}).
+			Where("owner = ? AND uuid = ?", login, uuid).
+			Update("revoked_at", gorm.Expr("COALESCE(revoked_at, ?)", now))
+		if result.Error != nil {
+			return result.Error
+		}
+
+		found = result.RowsAffected
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, revokeShareLink)
+	if err != nil {
+		log.Debugf("failed to revoke share link: %+v", err)
+		return fmt.Errorf("failed to revoke share link [%s]", uuid)
+	}
+
+	if found == 0 {
+		return custom_error.ErrShareNotFound
+	}
+
+	log.Infof("share link [%s] revoked successfully", uuid)
+
+	return nil
+}
+
+// ClaimShareDownload засчитывает скачивание по ссылке, если к моменту now ссылка не отозвана, не истекла
+// и количество скачиваний не исчерпано. Проверка и счетчик меняются одним запросом,
+// поэтому одновременные скачивания не превышают MaxDownloads
+func (r *ShareRepo) ClaimShareDownload(uuid string, now time.Time) (bool, error) {
+	var claimed int64
+
+	fn := func() error {
+		result := r.Db.Model(&model.ShareLink{}).
+			Where("uuid = ? AND revoked_at IS NULL", uuid).
+			Where("(expires_at IS NULL OR expires_at > ?)", now).
+			Where("(max_downloads = 0 OR downloads < max_downloads)").
+			UpdateColumns(map[string]interface{}{
+				"downloads":  gorm.Expr("downloads + 1"),
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		claimed = result.RowsAffected
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, claimShareDownload)
+	if err != nil {
+		log.Debugf("failed to claim share link download: %+v", err)
+		return false, fmt.Errorf("failed to claim download of share link [%s]", uuid)
+	}
+
+	return claimed > 0, nil
+}
+
+func (r *ShareRepo) CreateShareAccess(access *model.ShareAccess) error {
+	fn := func() error {
+		return r.Db.Create(access).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, saveShareAccess)
+	if err != nil {
+		log.Debugf("failed to save share link access: %+v", err)
+		return fmt.Errorf("failed to save access of share link [%s]", access.ShareUUID)
+	}
+
+	return nil
+}
+
+// GetShareAccesses возвращает страницу журнала обращений по ссылке от новых к старым
+// и общее количество обращений
+func (r *ShareRepo) GetShareAccesses(shareUUID string, limit, offset int) ([]model.ShareAccess, int64, error) {
+	var total int64
+
+	accesses := make([]model.ShareAccess, 0, limit)
+
+	query := r.Db.Model(&model.ShareAccess{}).
+		Where("share_uuid = ?", shareUUID)
+
+	fn := func() error {
+		return query.Session(&gorm.Session{}).Count(&total).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, countShareAccesses)
+	if err != nil {
+		log.Debugf("failed to count share link accesses: %+v", err)
+		return nil, 0, fmt.Errorf("failed to count accesses of share link [%s]", shareUUID)
+	}
+
+	fn = func() error {
+		return query.Session(&gorm.Session{}).
+			Order("id desc").
+			Limit(limit).
+			Offset(offset).
+			Find(&accesses).Error
+	}
+
+	err = r.QueryObserve.Observe(fn, dataBaseType, getShareAccesses)
+	if err != nil {
+		log.Debugf("failed to retrieve share link accesses: %+v", err)
+		return nil, 0, fmt.Errorf("failed to retrieve accesses of share link [%s]", shareUUID)
+	}
+
+	return accesses, total, nil
+}
@@ -1,15 +1,106 @@
 package postgres
 
 import (
+	"context"
+	"time"
+
 	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
 	"github.com/AlexJudin/DocumentCacheServer/internal/model"
 )
 
 type MetadataRepository interface {
-	Save(document *model.MetaDocument) error
-	GetList(req entity.DocumentListRequest) ([]model.MetaDocument, error)
+	Save(document *model.MetaDocument, events ...model.OutboxEvent) error
+	Update(document *model.MetaDocument, events ...model.OutboxEvent) error
+	GetList(req entity.DocumentListRequest) (entity.DocumentList, error)
+	Count(req entity.DocumentListRequest) (int64, error)
 	GetById(uuid string) (model.MetaDocument, error)
-	DeleteById(id string) error
+	GetReadableByStorageKeys(viewer string, keys []string) ([]model.MetaDocument, error)
+	DeleteById(id string, events ...model.OutboxEvent) error
+
+	CreateVersion(version model.DocumentVersion) error
+	GetVersions(uuid string) ([]model.DocumentVersion, error)
+	GetVersion(uuid string, version int) (model.DocumentVersion, error)
+	DeleteVersions(uuid string, versions []int) error
+	DeleteVersionsByDocumentId(uuid string) error
+
+	IndexDocument(index model.DocumentSearch) error
+	DeleteDocumentIndex(uuid string) error
+	Search(req entity.SearchRequest) ([]entity.SearchHit, error)
+
+	AcquireBlob(documentUUID string, blob model.FileBlob) (model.FileBlob, error)
+	MarkBlobStored(digest string) error
+	ReleaseBlob(documentUUID, digest string, remove func() error) error
+}
+
+type SagaLog interface {
+	Create(saga *model.Saga) error
+	Update(saga *model.Saga) error
+	Touch(uuid string) error
+	Claim(saga *model.Saga) (bool, error)
+	SaveStep(step model.SagaStep) error
+	Get(uuid string) (model.Saga, error)
+	GetSteps(uuid string) ([]model.SagaStep, error)
+	GetStale(before time.Time, limit int) ([]model.Saga, error)
+	GetStuck(before time.Time, limit, offset int) ([]model.Saga, int64, error)
+}
+
+type Outbox interface {
+	PublishPending(limit int, publish func(event model.OutboxEvent) error) (int, error)
+	DeletePublished(before time.Time) (int64, error)
+}
+
+type Consistency interface {
+	Lock(ctx context.Context) (func(), bool, error)
+	GetContentRefs() ([]entity.ContentRef, error)
+	SetBroken(uuids []string, broken bool) error
+	DeleteBlob(digest string, remove func() error) (bool, error)
+}
+
+type EncryptionKeys interface {
+	GetDataKeys(storageKeys []string) (map[string]model.DataKey, error)
+	GetStaleDataKeys(activeKeyID, afterKey string, limit int) ([]model.DataKey, error)
+	ReplaceDataKey(oldKey, newKey model.DataKey) error
+}
+
+type Webhook interface {
+	CreateSubscription(subscription *model.WebhookSubscription) error
+	GetSubscriptions(login string) ([]model.WebhookSubscription, error)
+	GetSubscription(login, uuid string) (model.WebhookSubscription, error)
+	DeleteSubscription(login, uuid string) error
+	GetSubscribers(logins []string, eventType string) ([]model.WebhookSubscription, error)
+
+	CreateDeliveries(deliveries []model.WebhookDelivery) error
+	ClaimDeliveries(limit int, lease time.Duration) ([]model.WebhookDelivery, error)
+	UpdateDelivery(delivery *model.WebhookDelivery) error
+	GetDeliveries(subscriptionUUID, status string, limit, offset int) ([]model.WebhookDelivery, int64, error)
+	RedeliverDelivery(subscriptionUUID, uuid string) error
+}
+
+type Upload interface {
+	CreateUpload(upload *model.Upload) error
+	GetUpload(uuid string) (model.Upload, error)
+	GetUploadPending(uuid string) ([]byte, error)
+	GetUploadParts(uuid string) ([]model.UploadPart, error)
+	AdvanceUpload(uuid string, from, to int64, pending, hashState []byte, part *model.UploadPart) error
+	LockUpload(uuid, holder string, until time.Time) (bool, error)
+	UnlockUpload(uuid, holder string) error
+	MarkUploadAssembled(uuid string) error
+	ReserveUploadDocument(uuid, documentUUID string, version int) error
+	CompleteUpload(uuid, documentUUID string) error
+	DeleteUpload(uuid string) error
+	GetExpiredUploads(now time.Time, limit int) ([]model.Upload, error)
+}
+
+type Share interface {
+	CreateShareLink(link *model.ShareLink) error
+	GetShareLink(login, uuid string) (model.ShareLink, error)
+	GetShareLinkByToken(tokenHash string) (model.ShareLink, error)
+	GetShareLinks(login, documentUUID string) ([]model.ShareLink, error)
+	RevokeShareLink(login, uuid string, now time.Time) error
+	ClaimShareDownload(uuid string, now time.Time) (bool, error)
+
+	CreateShareAccess(access *model.ShareAccess) error
+	GetShareAccesses(shareUUID string, limit, offset int) ([]model.ShareAccess, int64, error)
 }
 
 type User interface {
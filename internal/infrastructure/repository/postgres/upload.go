@@ -0,0 +1,341 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	saveUpload        = "save_upload"
+	getUpload         = "get_upload"
+	getUploadPending  = "get_upload_pending"
+	getUploadParts    = "get_upload_parts"
+	advanceUpload     = "advance_upload"
+	lockUpload        = "lock_upload"
+	unlockUpload      = "unlock_upload"
+	assembleUpload    = "assemble_upload"
+	reserveUpload     = "reserve_upload"
+	completeUpload    = "complete_upload"
+	deleteUpload      = "delete_upload"
+	getExpiredUploads = "get_expired_uploads"
+
+	// uploadPendingColumn колонка с остатком загрузки до части, он читается только при записи новых байт
+	uploadPendingColumn = "pending"
+)
+
+var _ Upload = (*UploadRepo)(nil)
+
+type UploadRepo struct {
+	Db           *gorm.DB
+	QueryObserve metric.QueryObserver
+}
+
+func NewUploadRepo(db *gorm.DB, metrics *metric.DatabaseMetrics) *UploadRepo {
+	return &UploadRepo{
+		Db:           db,
+		QueryObserve: metrics,
+	}
+}
+
+func (r *UploadRepo) CreateUpload(upload *model.Upload) error {
+	log.Infof("saving upload [%s] of user [%s]", upload.UUID, upload.Owner)
+
+	fn := func() error {
+		return r.Db.Create(upload).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, saveUpload)
+	if err != nil {
+		log.Debugf("failed to save upload: %+v", err)
+		return fmt.Errorf("failed to save upload [%s]", upload.UUID)
+	}
+
+	log.Infof("upload [%s] saved successfully", upload.UUID)
+
+	return nil
+}
+
+// GetUpload возвращает загрузку без полученных байт, которых еще не хватает на часть
+func (r *UploadRepo) GetUpload(uuid string) (model.Upload, error) {
+	var upload model.Upload
+
+	fn := func() error {
+		return r.Db.Omit(uploadPendingColumn).
+			Where("uuid = ?", uuid).
+			First(&upload).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getUpload)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return upload, custom_error.ErrUploadNotFound
+		}
+
+		log.Debugf("failed to retrieve upload: %+v", err)
+		return upload, fmt.Errorf("failed to retrieve upload [%s]", uuid)
+	}
+
+	return upload, nil
+}
+
+// GetUploadPending возвращает полученные байты загрузки, которых еще не хватает на часть
+func (r *UploadRepo) GetUploadPending(uuid string) ([]byte, error) {
+	var upload model.Upload
+
+	fn := func() error {
+		return r.Db.Select(uploadPendingColumn).
+			Where("uuid = ?", uuid).
+			First(&upload).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getUploadPending)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, custom_error.ErrUploadNotFound
+		}
+
+		log.Debugf("failed to retrieve upload pending data: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve pending data of upload [%s]", uuid)
+	}
+
+	return upload.Pending, nil
+}
+
+// GetUploadParts возвращает записанные части загрузки по возрастанию номера
+func (r *UploadRepo) GetUploadParts(uuid string) ([]model.UploadPart, error) {
+	parts := make([]model.UploadPart, 0)
+
+	fn := func() error {
+		return r.Db.Where("upload_uuid = ?", uuid).
+			Order("number").
+			Find(&parts).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getUploadParts)
+	if err != nil {
+		log.Debugf("failed to retrieve upload parts: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve parts of upload [%s]", uuid)
+	}
+
+	return parts, nil
+}
+
+// AdvanceUpload переносит смещение загрузки с from на to вместе с записанной частью part
+// и состоянием хеша полученных байт hashState.
+// Если смещение загрузки уже не равно from, возвращается ErrUploadOffsetMismatch
+func (r *UploadRepo) AdvanceUpload(uuid string, from, to int64, pending, hashState []byte, part *model.UploadPart) error {
+	fn := func() error {
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			if part != nil {
+				err := tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "upload_uuid"}, {Name: "number"}},
+					DoUpdates: clause.AssignmentColumns([]string{"e_tag", "size"}),
+				}).Create(part).Error
+				if err != nil {
+					return err
+				}
+			}
+
+			result := tx.Model(&model.Upload{}).
+				Where("uuid = ? AND \"offset\" = ?", uuid, from).
+				Updates(map[string]interface{}{
+					"offset":     to,
+					"pending":    pending,
+					"hash_state": hashState,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected == 0 {
+				return custom_error.ErrUploadOffsetMismatch
+			}
+
+			return nil
+		})
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, advanceUpload)
+	if err != nil {
+		if errors.Is(err, custom_error.ErrUploadOffsetMismatch) {
+			return err
+		}
+
+		log.Debugf("failed to advance upload: %+v", err)
+		return fmt.Errorf("failed to advance upload [%s]", uuid)
+	}
+
+	return nil
+}
+
+// LockUpload занимает загрузку для holder до until. Блокировку, которую уже держит holder, продлевает.
+// Возвращает false, если загрузку занял другой обработчик
+func (r *UploadRepo) LockUpload(uuid, holder string, until time.Time) (bool, error) {
+	var locked int64
+
+	fn := func() error {
+		result := r.Db.Model(&model.Upload{}).
+			Where("uuid = ? AND (locked_by = ? OR locked_until < ?)", uuid, holder, time.Now()).
+			Updates(map[string]interface{}{
+				"locked_by":    holder,
+				"locked_until": until,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		locked = result.RowsAffected
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, lockUpload)
+	if err != nil {
+		log.Debugf("failed to lock upload: %+v", err)
+		return false, fmt.Errorf("failed to lock upload [%s]", uuid)
+	}
+
+	return locked > 0, nil
+}
+
+// UnlockUpload освобождает загрузку, если ее держит holder
+func (r *UploadRepo) UnlockUpload(uuid, holder string) error {
+	fn := func() error {
+		return r.Db.Model(&model.Upload{}).
+			Where("uuid = ? AND locked_by = ?", uuid, holder).
+			Updates(map[string]interface{}{
+				"locked_by":    "",
+				"locked_until": time.Time{},
+			}).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, unlockUpload)
+	if err != nil {
+		log.Debugf("failed to unlock upload: %+v", err)
+		return fmt.Errorf("failed to unlock upload [%s]", uuid)
+	}
+
+	return nil
+}
+
+// MarkUploadAssembled отмечает, что части загрузки собраны в файл хранилища
+func (r *UploadRepo) MarkUploadAssembled(uuid string) error {
+	fn := func() error {
+		return r.Db.Model(&model.Upload{}).
+			Where("uuid = ?", uuid).
+			Updates(map[string]interface{}{
+				"assembled": true,
+				"pending":   nil,
+			}).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, assembleUpload)
+	if err != nil {
+		log.Debugf("failed to mark upload assembled: %+v", err)
+		return fmt.Errorf("failed to mark upload [%s] assembled", uuid)
+	}
+
+	return nil
+}
+
+// ReserveUploadDocument записывает документ или номер версии, которые создаст завершение загрузки
+func (r *UploadRepo) ReserveUploadDocument(uuid, documentUUID string, version int) error {
+	fn := func() error {
+		return r.Db.Model(&model.Upload{}).
+			Where("uuid = ?", uuid).
+			Updates(map[string]interface{}{
+				"pending_uuid":    documentUUID,
+				"pending_version": version,
+			}).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, reserveUpload)
+	if err != nil {
+		log.Debugf("failed to reserve upload document: %+v", err)
+		return fmt.Errorf("failed to reserve document of upload [%s]", uuid)
+	}
+
+	return nil
+}
+
+// CompleteUpload связывает загрузку с созданным из нее документом. Записи о частях больше не нужны и удаляются
+func (r *UploadRepo) CompleteUpload(uuid, documentUUID string) error {
+	fn := func() error {
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Where("upload_uuid = ?", uuid).Delete(&model.UploadPart{}).Error
+			if err != nil {
+				return err
+			}
+
+			return tx.Model(&model.Upload{}).
+				Where("uuid = ?", uuid).
+				Update("document_uuid", documentUUID).Error
+		})
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, completeUpload)
+	if err != nil {
+		log.Debugf("failed to complete upload: %+v", err)
+		return fmt.Errorf("failed to complete upload [%s]", uuid)
+	}
+
+	log.Infof("upload [%s] completed with document [%s]", uuid, documentUUID)
+
+	return nil
+}
+
+// DeleteUpload удаляет загрузку вместе с записями о ее частях
+func (r *UploadRepo) DeleteUpload(uuid string) error {
+	log.Infof("deleting upload [%s]", uuid)
+
+	fn := func() error {
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Where("upload_uuid = ?", uuid).Delete(&model.UploadPart{}).Error
+			if err != nil {
+				return err
+			}
+
+			return tx.Where("uuid = ?", uuid).Delete(&model.Upload{}).Error
+		})
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, deleteUpload)
+	if err != nil {
+		log.Debugf("failed to delete upload: %+v", err)
+		return fmt.Errorf("failed to delete upload [%s]", uuid)
+	}
+
+	log.Infof("upload [%s] deleted successfully", uuid)
+
+	return nil
+}
+
+// GetExpiredUploads возвращает загрузки, срок которых истек к моменту now и которые никем не заняты
+func (r *UploadRepo) GetExpiredUploads(now time.Time, limit int) ([]model.Upload, error) {
+	uploads := make([]model.Upload, 0, limit)
+
+	fn := func() error {
+		return r.Db.Omit(uploadPendingColumn).
+			Where("expires_at < ? AND locked_until < ?", now, now).
+			Order("expires_at").
+			Limit(limit).
+			Find(&uploads).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getExpiredUploads)
+	if err != nil {
+		log.Debugf("failed to retrieve expired uploads: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve expired uploads")
+	}
+
+	return uploads, nil
+}
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	saveDocumentVersion          = "save_document_version"
+	getDocumentVersions          = "get_document_versions"
+	getDocumentVersion           = "get_document_version"
+	deleteDocumentVersions       = "delete_document_versions"
+	deleteDocumentVersionsByUUID = "delete_document_versions_by_uuid"
+)
+
+func (r *MetadataRepo) CreateVersion(version model.DocumentVersion) error {
+	log.Infof("saving document [%s] version [%d]", version.DocumentUUID, version.Version)
+
+	fn := func() error {
+		err := r.Db.Create(&version).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, saveDocumentVersion)
+	if err != nil {
+		log.Debugf("failed to save document version: %+v", err)
+		return fmt.Errorf("failed to save document [%s] version [%d]", version.DocumentUUID, version.Version)
+	}
+
+	log.Infof("document [%s] version [%d] saved successfully", version.DocumentUUID, version.Version)
+
+	return nil
+}
+
+func (r *MetadataRepo) GetVersions(uuid string) ([]model.DocumentVersion, error) {
+	log.Infof("retrieving document [%s] versions", uuid)
+
+	var versions []model.DocumentVersion
+
+	fn := func() error {
+		err := r.Db.Model(&model.DocumentVersion{}).
+			Where("document_uuid = ?", uuid).
+			Order("version desc").
+			Find(&versions).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getDocumentVersions)
+	if err != nil {
+		log.Debugf("failed to retrieve document versions: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve document [%s] versions", uuid)
+	}
+
+	log.Infof("document [%s] versions retrieved successfully", uuid)
+
+	return versions, nil
+}
+
+func (r *MetadataRepo) GetVersion(uuid string, version int) (model.DocumentVersion, error) {
+	log.Infof("retrieving document [%s] version [%d]", uuid, version)
+
+	var documentVersion model.DocumentVersion
+
+	fn := func() error {
+		err := r.Db.Model(&documentVersion).
+			Where("document_uuid = ? AND version = ?", uuid, version).
+			First(&documentVersion).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getDocumentVersion)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Infof("document [%s] version [%d] not found", uuid, version)
+			return documentVersion, custom_error.ErrVersionNotFound
+		}
+
+		log.Debugf("failed to retrieve document version: %+v", err)
+		return documentVersion, fmt.Errorf("failed to retrieve document [%s] version [%d]", uuid, version)
+	}
+
+	log.Infof("document [%s] version [%d] retrieved successfully", uuid, version)
+
+	return documentVersion, nil
+}
+
+func (r *MetadataRepo) DeleteVersions(uuid string, versions []int) error {
+	log.Infof("deleting document [%s] versions %v", uuid, versions)
+
+	if len(versions) == 0 {
+		return nil
+	}
+
+	fn := func() error {
+		err := r.Db.Where("document_uuid = ? AND version IN ?", uuid, versions).
+			Delete(&model.DocumentVersion{}).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, deleteDocumentVersions)
+	if err != nil {
+		log.Debugf("failed to delete document versions: %+v", err)
+		return fmt.Errorf("failed to delete document [%s] versions", uuid)
+	}
+
+	log.Infof("document [%s] versions deleted successfully", uuid)
+
+	return nil
+}
+
+func (r *MetadataRepo) DeleteVersionsByDocumentId(uuid string) error {
+	log.Infof("deleting all document [%s] versions", uuid)
+
+	fn := func() error {
+		err := r.Db.Where("document_uuid = ?", uuid).
+			Delete(&model.DocumentVersion{}).Error
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, deleteDocumentVersionsByUUID)
+	if err != nil {
+		log.Debugf("failed to delete document versions: %+v", err)
+		return fmt.Errorf("failed to delete document [%s] versions", uuid)
+	}
+
+	log.Infof("all document [%s] versions deleted successfully", uuid)
+
+	return nil
+}
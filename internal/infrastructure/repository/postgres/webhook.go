@@ -0,0 +1,317 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	saveWebhookSubscription   = "save_webhook_subscription"
+	getWebhookSubscriptions   = "get_webhook_subscriptions"
+	getWebhookSubscription    = "get_webhook_subscription"
+	deleteWebhookSubscription = "delete_webhook_subscription"
+	getWebhookSubscribers     = "get_webhook_subscribers"
+	saveWebhookDeliveries     = "save_webhook_deliveries"
+	claimWebhookDeliveries    = "claim_webhook_deliveries"
+	updateWebhookDelivery     = "update_webhook_delivery"
+	getWebhookDeliveries      = "get_webhook_deliveries"
+	countWebhookDeliveries    = "count_webhook_deliveries"
+	redeliverWebhookDelivery  = "redeliver_webhook_delivery"
+)
+
+var _ Webhook = (*WebhookRepo)(nil)
+
+type WebhookRepo struct {
+	Db           *gorm.DB
+	QueryObserve metric.QueryObserver
+}
+
+func NewWebhookRepo(db *gorm.DB, metrics *metric.DatabaseMetrics) *WebhookRepo {
+	return &WebhookRepo{
+		Db:           db,
+		QueryObserve: metrics,
+	}
+}
+
+func (r *WebhookRepo) CreateSubscription(subscription *model.WebhookSubscription) error {
+	log.Infof("saving webhook [%s] of user [%s]", subscription.UUID, subscription.Login)
+
+	fn := func() error {
+		return r.Db.Create(subscription).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, saveWebhookSubscription)
+	if err != nil {
+		log.Debugf("failed to save webhook: %+v", err)
+		return fmt.Errorf("failed to save webhook [%s]", subscription.UUID)
+	}
+
+	log.Infof("webhook [%s] saved successfully", subscription.UUID)
+
+	return nil
+}
+
+func (r *WebhookRepo) GetSubscriptions(login string) ([]model.WebhookSubscription, error) {
+	subscriptions := make([]model.WebhookSubscription, 0)
+
+	fn := func() error {
+		return r.Db.Where("login = ?", login).
+			Order("id").
+			Find(&subscriptions).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getWebhookSubscriptions)
+	if err != nil {
+		log.Debugf("failed to retrieve webhooks: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve webhooks of user [%s]", login)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepo) GetSubscription(login, uuid string) (model.WebhookSubscription, error) {
+	var subscription model.WebhookSubscription
+
+	fn := func() error {
+		return r.Db.Where("login = ? AND uuid = ?", login, uuid).
+			First(&subscription).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getWebhookSubscription)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return subscription, custom_error.ErrWebhookNotFound
+		}
+
+		log.Debugf("failed to retrieve webhook: %+v", err)
+		return subscription, fmt.Errorf("failed to retrieve webhook [%s]", uuid)
+	}
+
+	return subscription, nil
+}
+
+// DeleteSubscription удаляет подписку вместе с журналом ее доставок
+func (r *WebhookRepo) DeleteSubscription(login, uuid string) error {
+	log.Infof("deleting webhook [%s] of user [%s]", uuid, login)
+
+	var deleted int64
+
+	fn := func() error {
+		result := r.Db.Where("login = ? AND uuid = ?", login, uuid).
+			Delete(&model.WebhookSubscription{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		deleted = result.RowsAffected
+
+		return nil
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, deleteWebhookSubscription)
+	if err != nil {
+		log.Debugf("failed to delete webhook: %+v", err)
+		return fmt.Errorf("failed to delete webhook [%s]", uuid)
+	}
+
+	if deleted == 0 {
+		return custom_error.ErrWebhookNotFound
+	}
+
+	log.Infof("webhook [%s] deleted successfully", uuid)
+
+	return nil
+}
+
+// GetSubscribers возвращает подписки пользователей logins на события типа eventType.
+// Подписка без списка событий получает события всех типов
+func (r *WebhookRepo) GetSubscribers(logins []string, eventType string) ([]model.WebhookSubscription, error) {
+	subscriptions := make([]model.WebhookSubscription, 0)
+
+	fn := func() error {
+		return r.Db.Where("login IN ?", logins).
+			Where("cardinality(events) = 0 OR ? = ANY(events)", eventType).
+			Find(&subscriptions).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, getWebhookSubscribers)
+	if err != nil {
+		log.Debugf("failed to retrieve webhook subscribers: %+v", err)
+		return nil, fmt.Errorf("failed to retrieve subscribers of event [%s]", eventType)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepo) CreateDeliveries(deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	fn := func() error {
+		return r.Db.Omit(clause.Associations).Create(&deliveries).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, saveWebhookDeliveries)
+	if err != nil {
+		log.Debugf("failed to save webhook deliveries: %+v", err)
+		return fmt.Errorf("failed to save webhook deliveries of event [%s]", deliveries[0].EventID)
+	}
+
+	return nil
+}
+
+// ClaimDeliveries забирает доставки, срок отправки которых наступил, и откладывает их на время lease.
+// Пока доставка отправляется, другие экземпляры сервиса ее не заберут,
+// а если экземпляр остановится, доставка снова станет доступна после lease
+func (r *WebhookRepo) ClaimDeliveries(limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	deliveries := make([]model.WebhookDelivery, 0, limit)
+
+	fn := func() error {
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			now := time.Now()
+
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("status IN ? AND next_attempt_at <= ?",
+					[]string{model.WebhookDeliveryPending, model.WebhookDeliveryRetrying}, now).
+				Order("next_attempt_at").
+				Limit(limit).
+				Find(&deliveries).Error
+			if err != nil || len(deliveries) == 0 {
+				return err
+			}
+
+			ids := make([]uint, 0, len(deliveries))
+			for _, delivery := range deliveries {
+				ids = append(ids, delivery.ID)
+			}
+
+			err = tx.Model(&model.WebhookDelivery{}).
+				Where("id IN ?", ids).
+				Update("next_attempt_at", now.Add(lease)).Error
+			if err != nil {
+				return err
+			}
+
+			return tx.Preload("Subscription").
+				Where("id IN ?", ids).
+				Order("next_attempt_at").
+				Find(&deliveries).Error
+		})
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, claimWebhookDeliveries)
+	if err != nil {
+		log.Debugf("failed to claim webhook deliveries: %+v", err)
+		return nil, fmt.Errorf("failed to claim webhook deliveries")
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) UpdateDelivery(delivery *model.WebhookDelivery) error {
+	fn := func() error {
+		return r.Db.Omit(clause.Associations).Save(delivery).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, updateWebhookDelivery)
+	if err != nil {
+		log.Debugf("failed to update webhook delivery: %+v", err)
+		return fmt.Errorf("failed to update webhook delivery [%s]", delivery.UUID)
+	}
+
+	return nil
+}
+
+// GetDeliveries возвращает журнал доставок подписки от новых к старым.
+// Пустой status возвращает доставки в любом состоянии
+func (r *WebhookRepo) GetDeliveries(subscriptionUUID, status string, limit, offset int) ([]model.WebhookDelivery, int64, error) {
+	var total int64
+
+	deliveries := make([]model.WebhookDelivery, 0, limit)
+
+	query := r.Db.Model(&model.WebhookDelivery{}).
+		Where("subscription_uuid = ?", subscriptionUUID)
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	fn := func() error {
+		return query.Session(&gorm.Session{}).Count(&total).Error
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, countWebhookDeliveries)
+	if err != nil {
+		log.Debugf("failed to count webhook deliveries: %+v", err)
+		return nil, 0, fmt.Errorf("failed to count deliveries of webhook [%s]", subscriptionUUID)
+	}
+
+	fn = func() error {
+		return query.Session(&gorm.Session{}).
+			Order("id desc").
+			Limit(limit).
+			Offset(offset).
+			Find(&deliveries).Error
+	}
+
+	err = r.QueryObserve.Observe(fn, dataBaseType, getWebhookDeliveries)
+	if err != nil {
+		log.Debugf("failed to retrieve webhook deliveries: %+v", err)
+		return nil, 0, fmt.Errorf("failed to retrieve deliveries of webhook [%s]", subscriptionUUID)
+	}
+
+	return deliveries, total, nil
+}
+
+// RedeliverDelivery возвращает доставку из состояния dead в очередь с новым запасом попыток
+func (r *WebhookRepo) RedeliverDelivery(subscriptionUUID, uuid string) error {
+	fn := func() error {
+		return r.Db.Transaction(func(tx *gorm.DB) error {
+			var delivery model.WebhookDelivery
+
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("subscription_uuid = ? AND uuid = ?", subscriptionUUID, uuid).
+				First(&delivery).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return custom_error.ErrDeliveryNotFound
+			}
+			if err != nil {
+				return err
+			}
+
+			if delivery.Status != model.WebhookDeliveryDead {
+				return custom_error.ErrDeliveryNotDead
+			}
+
+			return tx.Model(&delivery).Updates(map[string]interface{}{
+				"status":          model.WebhookDeliveryPending,
+				"attempts":        0,
+				"next_attempt_at": time.Now(),
+			}).Error
+		})
+	}
+
+	err := r.QueryObserve.Observe(fn, dataBaseType, redeliverWebhookDelivery)
+	if err != nil {
+		if errors.Is(err, custom_error.ErrDeliveryNotFound) || errors.Is(err, custom_error.ErrDeliveryNotDead) {
+			return err
+		}
+
+		log.Debugf("failed to redeliver webhook delivery: %+v", err)
+		return fmt.Errorf("failed to redeliver webhook delivery [%s]", uuid)
+	}
+
+	log.Infof("webhook delivery [%s] queued for redelivery", uuid)
+
+	return nil
+}
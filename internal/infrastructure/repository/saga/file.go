@@ -0,0 +1,212 @@
+package saga
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/compression"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// fileSpool временная копия загружаемого файла на локальном диске.
+// Файл читается целиком до начала саги, чтобы его хеш стал ключом хранения
+// еще до записи в журнал и чтобы уже сохраненный файл не загружался в хранилище повторно.
+// Файл, который уже записан в хранилище с проверенным хешем, не копируется на диск: staged - его ключ в хранилище
+type fileSpool struct {
+	file   *os.File
+	staged string
+	digest string
+	size   int64
+}
+
+// spoolStaged сохраняет во временную копию файл, записанный в хранилище под ключом key
+func (s *DocumentOrchestrator) spoolStaged(ctx context.Context, key string) (*fileSpool, error) {
+	file, _, err := s.DocumentRepository.Download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return spoolFile(file)
+}
+
+func spoolFile(content io.Reader) (*fileSpool, error) {
+	file, err := os.CreateTemp("", "document-*")
+	if err != nil {
+		return nil, err
+	}
+
+	spool := &fileSpool{file: file}
+
+	hasher := sha256.New()
+
+	spool.size, err = io.Copy(io.MultiWriter(file, hasher), content)
+	if err != nil {
+		spool.Close()
+		return nil, err
+	}
+
+	spool.digest = hex.EncodeToString(hasher.Sum(nil))
+
+	return spool, nil
+}
+
+// Close удаляет временную копию файла
+func (f *fileSpool) Close() {
+	if f == nil || f.file == nil {
+		return
+	}
+
+	if err := f.file.Close(); err != nil {
+		log.Error("failed to close spooled file", "file", f.file.Name(), "error", err)
+	}
+
+	if err := os.Remove(f.file.Name()); err != nil {
+		log.Error("failed to remove spooled file", "file", f.file.Name(), "error", err)
+	}
+}
+
+// prepareFile сохраняет файл документа во временную копию и заполняет
+// размер, хеш, ключ содержимого и алгоритм сжатия в метаданных. Ключом файла становится его хеш.
+// Если задан ожидаемый хеш файла, документ с другим содержимым не сохраняется.
+// Файл, уже записанный в хранилище, читается, только если его нужно зашифровать или сжать
+func (s *DocumentOrchestrator) prepareFile(ctx context.Context, document *entity.Document) (*fileSpool, error) {
+	if document.File.StagedKey != "" {
+		encoding := compression.Codec(s.Cfg.ConfigCompression, document.Meta.Mime, document.File.Size)
+
+		if !document.Meta.DataKey.Encrypted() && encoding == "" {
+			document.Meta.Size = document.File.Size
+			document.Meta.Hash = document.File.Digest
+			document.Meta.ContentKey = document.File.Digest
+			document.Meta.Encoding = ""
+
+			spool := &fileSpool{
+				staged: document.File.StagedKey,
+				digest: document.File.Digest,
+				size:   document.File.Size,
+			}
+
+			return spool, nil
+		}
+
+		file, _, err := s.DocumentRepository.Download(ctx, document.File.StagedKey)
+		if err != nil {
+			log.Error("failed to read staged file", "uuid", document.Meta.UUID, "error", err)
+			return nil, err
+		}
+		defer file.Close()
+
+		document.File.Content = file
+	}
+
+	spool, err := spoolFile(document.File.Content)
+	if err != nil {
+		log.Error("failed to read file content", "uuid", document.Meta.UUID, "error", err)
+		return nil, err
+	}
+
+	if document.File.Digest != "" && document.File.Digest != spool.digest {
+		log.Error("file content checksum mismatch",
+			"uuid", document.Meta.UUID,
+			"expected", document.File.Digest,
+			"actual", spool.digest)
+		spool.Close()
+
+		return nil, custom_error.ErrChecksumMismatch
+	}
+
+	document.Meta.Size = spool.size
+	document.Meta.Hash = spool.digest
+	document.Meta.ContentKey = spool.digest
+	document.Meta.Encoding = compression.Codec(s.Cfg.ConfigCompression, document.Meta.Mime, spool.size)
+
+	return spool, nil
+}
+
+// storeFile добавляет ссылку документа на файл и загружает файл в хранилище,
+// только если такого содержимого там еще нет. Документ получает ключ шифрования и алгоритм сжатия файла:
+// у файла, который уже есть в хранилище, они свои, а не выбранные для документа
+func (s *DocumentOrchestrator) storeFile(ctx context.Context, metaDoc *model.MetaDocument, spool *fileSpool) error {
+	blob, err := s.DocumentRepository.AcquireBlob(metaDoc.UUID, model.FileBlob{
+		Digest:   spool.digest,
+		Size:     spool.size,
+		DataKey:  metaDoc.DataKey,
+		Encoding: metaDoc.Encoding,
+	})
+	if err != nil {
+		return err
+	}
+
+	metaDoc.DataKey = blob.DataKey
+	metaDoc.Encoding = blob.Encoding
+
+	if blob.Stored {
+		log.Info("file content already stored, upload skipped", "uuid", metaDoc.UUID, "digest", spool.digest)
+		return nil
+	}
+
+	if spool.file == nil {
+		if !blob.DataKey.Encrypted() && blob.Encoding == "" {
+			if err = s.DocumentRepository.Copy(ctx, spool.staged, spool.digest); err != nil {
+				return err
+			}
+
+			return s.DocumentRepository.MarkBlobStored(spool.digest)
+		}
+
+		// прерванная запись того же содержимого выбрала для файла шифрование или сжатие,
+		// поэтому файл приходится прочитать и загрузить заново
+		staged, err := s.spoolStaged(ctx, spool.staged)
+		if err != nil {
+			return err
+		}
+		defer staged.Close()
+
+		if staged.digest != spool.digest {
+			log.Error("file content checksum mismatch",
+				"uuid", metaDoc.UUID,
+				"expected", spool.digest,
+				"actual", staged.digest)
+
+			return custom_error.ErrChecksumMismatch
+		}
+
+		spool = staged
+	}
+
+	ctx, err = s.Keyring.Context(ctx, blob.DataKey)
+	if err != nil {
+		return err
+	}
+
+	ctx = compression.WithCodec(ctx, blob.Encoding)
+
+	if _, err = spool.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err = s.DocumentRepository.Upload(ctx, spool.digest, spool.file, spool.size); err != nil {
+		return err
+	}
+
+	return s.DocumentRepository.MarkBlobStored(spool.digest)
+}
+
+// removeFile удаляет ссылку документа на файл, а сам файл - вместе с последней ссылкой.
+// Файлы, загруженные до появления дедупликации, принадлежат одному документу и удаляются сразу
+func (s *DocumentOrchestrator) removeFile(ctx context.Context, documentUUID, key string) error {
+	if !model.IsFileDigest(key) {
+		return s.DocumentRepository.Delete(ctx, key)
+	}
+
+	return s.DocumentRepository.ReleaseBlob(documentUUID, key, func() error {
+		return ignoreNotFound(s.DocumentRepository.Delete(ctx, key))
+	})
+}
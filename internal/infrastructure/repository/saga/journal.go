@@ -0,0 +1,260 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	stepSaveMetadata   = "save_metadata"
+	stepStoreContent   = "store_content"
+	stepUpdateMetadata = "update_metadata"
+	stepCreateVersion  = "create_version"
+	stepDeleteMetadata = "delete_metadata"
+	stepDeleteContent  = "delete_content"
+	stepDeleteVersions = "delete_versions"
+
+	stepCompensateMetadata = "compensate_metadata"
+	stepCompensateContent  = "compensate_content"
+	stepCompensateVersion  = "compensate_version"
+)
+
+// sagaPayload состояние документа, необходимое для восстановления саги.
+// Meta - новое состояние документа, OldMeta - состояние до изменения
+type sagaPayload struct {
+	Meta    documentSnapshot  `json:"meta"`
+	OldMeta *documentSnapshot `json:"old_meta,omitempty"`
+}
+
+// documentSnapshot копия всех полей метаданных документа, включая скрытые из API
+type documentSnapshot struct {
+	ID         uint      `json:"id"`
+	UUID       string    `json:"uuid"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Owner      string    `json:"owner"`
+	Name       string    `json:"name"`
+	File       bool      `json:"file"`
+	Public     bool      `json:"public"`
+	Mime       string    `json:"mime"`
+	Grant      []string  `json:"grant"`
+	Size       int64     `json:"size"`
+	Hash       string    `json:"hash"`
+	ContentKey string    `json:"content_key"`
+	Version    int       `json:"version"`
+	WrappedKey string    `json:"wrapped_key,omitempty"`
+	KeyID      string    `json:"key_id,omitempty"`
+	Encoding   string    `json:"encoding,omitempty"`
+}
+
+func newSnapshot(meta model.MetaDocument) documentSnapshot {
+	return documentSnapshot{
+		ID:         meta.ID,
+		UUID:       meta.UUID,
+		CreatedAt:  meta.CreatedAt,
+		UpdatedAt:  meta.UpdatedAt,
+		Owner:      meta.Owner,
+		Name:       meta.Name,
+		File:       meta.File,
+		Public:     meta.Public,
+		Mime:       meta.Mime,
+		Grant:      meta.Grant,
+		Size:       meta.Size,
+		Hash:       meta.Hash,
+		ContentKey: meta.ContentKey,
+		Version:    meta.Version,
+		WrappedKey: meta.WrappedKey,
+		KeyID:      meta.KeyID,
+		Encoding:   meta.Encoding,
+	}
+}
+
+func (d documentSnapshot) meta() model.MetaDocument {
+	return model.MetaDocument{
+		ID:         d.ID,
+		UUID:       d.UUID,
+		CreatedAt:  d.CreatedAt,
+		UpdatedAt:  d.UpdatedAt,
+		Owner:      d.Owner,
+		Name:       d.Name,
+		File:       d.File,
+		Public:     d.Public,
+		Mime:       d.Mime,
+		Grant:      d.Grant,
+		Size:       d.Size,
+		Hash:       d.Hash,
+		ContentKey: d.ContentKey,
+		Version:    d.Version,
+		DataKey:    model.DataKey{WrappedKey: d.WrappedKey, KeyID: d.KeyID},
+		Encoding:   d.Encoding,
+	}
+}
+
+// journal записывает ход выполнения саги в постоянный журнал.
+// Пока сага выполняется, журнал периодически продлевает ее,
+// чтобы восстановление не приняло долгий шаг (например, загрузку большого файла) за сбой
+type journal struct {
+	sagaLog postgres.SagaLog
+	cfg     *config.ConfigSaga
+	saga    *model.Saga
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// begin создает запись о новой саге. Без записи в журнале сага не запускается,
+// так как после сбоя ее нельзя было бы восстановить
+func (s *DocumentOrchestrator) begin(sagaType, documentUUID string, payload sagaPayload) (*journal, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	saga := &model.Saga{
+		UUID:         uuid.NewString(),
+		Type:         sagaType,
+		DocumentUUID: documentUUID,
+		Status:       model.SagaStatusRunning,
+		Payload:      string(data),
+	}
+
+	if err = s.SagaLog.Create(saga); err != nil {
+		log.Error("failed to save saga log", "uuid", documentUUID, "error", err)
+		return nil, err
+	}
+
+	return s.newJournal(saga), nil
+}
+
+func (s *DocumentOrchestrator) newJournal(saga *model.Saga) *journal {
+	j := &journal{
+		sagaLog: s.SagaLog,
+		cfg:     s.Cfg.ConfigSaga,
+		saga:    saga,
+		stop:    make(chan struct{}),
+	}
+
+	go j.heartbeat()
+
+	return j
+}
+
+func (j *journal) heartbeat() {
+	ticker := time.NewTicker(j.cfg.StaleAfter / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.stop:
+			return
+		case <-ticker.C:
+			if err := j.sagaLog.Touch(j.saga.UUID); err != nil {
+				log.Error("failed to touch saga", "saga", j.saga.UUID, "error", err)
+			}
+		}
+	}
+}
+
+// step выполняет шаг один раз. Так выполняются шаги, которые нельзя безопасно повторить:
+// создание записей и запись содержимого из потока запроса
+func (j *journal) step(ctx context.Context, name string, fn func() error) error {
+	return j.run(ctx, name, 1, fn)
+}
+
+// retryStep выполняет идемпотентный шаг, повторяя его с экспоненциальной задержкой
+func (j *journal) retryStep(ctx context.Context, name string, fn func() error) error {
+	return j.run(ctx, name, j.cfg.StepAttempts, fn)
+}
+
+func (j *journal) run(ctx context.Context, name string, attempts int, fn func() error) error {
+	j.saga.Step = name
+	backoff := j.cfg.RetryBackoff
+
+	var err error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(); err == nil {
+			j.saveStep(name, model.SagaStepDone, attempt, nil)
+			return nil
+		}
+
+		log.Error("saga step failed",
+			"saga", j.saga.UUID,
+			"step", name,
+			"attempt", attempt,
+			"error", err)
+
+		if attempt == attempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			j.saveStep(name, model.SagaStepFailed, attempt, err)
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+
+	j.saveStep(name, model.SagaStepFailed, attempts, err)
+
+	return err
+}
+
+func (j *journal) saveStep(name, status string, attempts int, stepErr error) {
+	step := model.SagaStep{
+		SagaUUID: j.saga.UUID,
+		Name:     name,
+		Status:   status,
+		Attempts: attempts,
+	}
+
+	if stepErr != nil {
+		step.Error = stepErr.Error()
+	}
+
+	if err := j.sagaLog.SaveStep(step); err != nil {
+		log.Error("failed to save saga step", "saga", j.saga.UUID, "step", name, "error", err)
+	}
+}
+
+// compensating отмечает начало отката саги
+func (j *journal) compensating(cause error) {
+	j.saga.Status = model.SagaStatusCompensating
+	j.saga.Error = cause.Error()
+
+	if err := j.sagaLog.Update(j.saga); err != nil {
+		log.Error("failed to update saga log", "saga", j.saga.UUID, "error", err)
+	}
+}
+
+// finish сохраняет итоговое состояние саги и останавливает продление.
+// Незавершенный статус оставляет сагу восстановлению
+func (j *journal) finish(status string, cause error) {
+	j.once.Do(func() {
+		close(j.stop)
+	})
+
+	j.saga.Status = status
+	if cause != nil {
+		j.saga.Error = cause.Error()
+	}
+
+	if err := j.sagaLog.Update(j.saga); err != nil {
+		log.Error("failed to update saga log", "saga", j.saga.UUID, "error", err)
+	}
+}
+
+func (j *journal) complete() {
+	j.finish(model.SagaStatusCompleted, nil)
+}
@@ -0,0 +1,176 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const recoveryBatchSize = 100
+
+var errSagaInterrupted = errors.New("saga interrupted")
+
+// Run восстанавливает прерванные саги: сразу при запуске и далее с периодом RecoveryInterval.
+// Несколько экземпляров сервиса могут работать одновременно, сагу восстанавливает тот, кто первым ее забрал
+func (s *DocumentOrchestrator) Run(ctx context.Context) {
+	s.recoverStale(ctx)
+
+	ticker := time.NewTicker(s.Cfg.RecoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.recoverStale(ctx)
+		}
+	}
+}
+
+func (s *DocumentOrchestrator) recoverStale(ctx context.Context) {
+	sagas, err := s.SagaLog.GetStale(time.Now().Add(-s.Cfg.StaleAfter), recoveryBatchSize)
+	if err != nil {
+		log.Error("failed to get stale sagas", "error", err)
+		return
+	}
+
+	for i := range sagas {
+		saga := &sagas[i]
+
+		claimed, err := s.SagaLog.Claim(saga)
+		if err != nil {
+			log.Error("failed to claim saga", "saga", saga.UUID, "error", err)
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		if saga.Attempts > s.Cfg.RecoveryAttempts {
+			log.Error("saga recovery attempts exhausted", "saga", saga.UUID, "uuid", saga.DocumentUUID)
+
+			saga.Status = model.SagaStatusFailed
+			if err = s.SagaLog.Update(saga); err != nil {
+				log.Error("failed to update saga log", "saga", saga.UUID, "error", err)
+			}
+
+			continue
+		}
+
+		s.recover(ctx, saga)
+	}
+}
+
+// RetrySaga повторно запускает восстановление саги с новым запасом попыток.
+// Сага, которая еще выполняется, не трогается
+func (s *DocumentOrchestrator) RetrySaga(ctx context.Context, uuid string) (model.Saga, error) {
+	saga, err := s.SagaLog.Get(uuid)
+	if err != nil {
+		return saga, err
+	}
+
+	if saga.IsFinished() {
+		return saga, custom_error.ErrSagaFinished
+	}
+
+	if saga.Status != model.SagaStatusFailed && saga.UpdatedAt.After(time.Now().Add(-s.Cfg.StaleAfter)) {
+		return saga, custom_error.ErrSagaInProgress
+	}
+
+	claimed, err := s.SagaLog.Claim(&saga)
+	if err != nil {
+		return saga, err
+	}
+
+	if !claimed {
+		return saga, custom_error.ErrSagaInProgress
+	}
+
+	saga.Attempts = 0
+
+	s.recover(ctx, &saga)
+
+	return saga, nil
+}
+
+// recover доводит прерванную сагу до конечного состояния по журналу выполненных шагов.
+// Если последний шаг саги выполнен, она завершается, иначе изменения откатываются.
+// Удаление документа после удаления содержимого откатить нельзя, поэтому оно доводится до конца
+func (s *DocumentOrchestrator) recover(ctx context.Context, saga *model.Saga) {
+	log.Info("recovering saga", "saga", saga.UUID, "type", saga.Type, "uuid", saga.DocumentUUID)
+
+	j := s.newJournal(saga)
+
+	var payload sagaPayload
+	if err := json.Unmarshal([]byte(saga.Payload), &payload); err != nil {
+		log.Error("failed to decode saga payload", "saga", saga.UUID, "error", err)
+
+		j.finish(model.SagaStatusFailed, err)
+
+		return
+	}
+
+	steps, err := s.SagaLog.GetSteps(saga.UUID)
+	if err != nil {
+		log.Error("failed to get saga steps", "saga", saga.UUID, "error", err)
+
+		j.finish(saga.Status, nil)
+
+		return
+	}
+
+	done := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		done[step.Name] = step.Status == model.SagaStepDone
+	}
+
+	cause := errSagaInterrupted
+	if saga.Error != "" {
+		cause = errors.New(saga.Error)
+	}
+
+	metaDoc := payload.Meta.meta()
+
+	switch saga.Type {
+	case model.SagaTypeSave:
+		if done[stepCreateVersion] {
+			j.complete()
+			return
+		}
+
+		s.rollbackSave(ctx, j, metaDoc, cause)
+	case model.SagaTypeUpdate, model.SagaTypeRestore:
+		if done[stepCreateVersion] {
+			j.complete()
+			s.pruneVersions(ctx, metaDoc)
+
+			return
+		}
+
+		if payload.OldMeta == nil {
+			j.finish(model.SagaStatusFailed, fmt.Errorf("saga [%s] payload has no previous metadata", saga.UUID))
+			return
+		}
+
+		// при восстановлении версии содержимое общее со старой версией и не удаляется
+		s.rollbackUpdate(ctx, j, payload.OldMeta.meta(), metaDoc, saga.Type == model.SagaTypeUpdate, cause)
+	case model.SagaTypeDelete:
+		if done[stepDeleteContent] {
+			s.finishDelete(ctx, j, metaDoc)
+			return
+		}
+
+		s.rollbackDelete(ctx, j, metaDoc, cause)
+	default:
+		j.finish(model.SagaStatusFailed, fmt.Errorf("unknown saga type [%s]", saga.Type))
+	}
+}
@@ -4,9 +4,13 @@ import (
 	"context"
 
 	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
 )
 
 type Orchestrator interface {
 	SaveDocument(ctx context.Context, document *entity.Document) error
+	UpdateDocument(ctx context.Context, document *entity.Document) error
 	DeleteDocument(ctx context.Context, uuid string) error
+	RestoreVersion(ctx context.Context, uuid string, version int) error
+	RetrySaga(ctx context.Context, uuid string) (model.Saga, error)
 }
@@ -0,0 +1,196 @@
+package saga
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// RestoreVersion делает указанную версию текущей, создавая новую версию
+// с тем же содержимым. Содержимое не копируется: обе версии ссылаются на один ключ
+func (s *DocumentOrchestrator) RestoreVersion(ctx context.Context, uuid string, version int) error {
+	oldMeta, err := s.currentVersion(uuid)
+	if err != nil {
+		return err
+	}
+
+	restored, err := s.DocumentRepository.GetVersion(uuid, version)
+	if err != nil {
+		log.Error("failed to get saga version", "uuid", uuid, "version", version, "error", err)
+		return err
+	}
+
+	newMeta := oldMeta
+	newMeta.Name = restored.Name
+	newMeta.File = restored.File
+	newMeta.Mime = restored.Mime
+	newMeta.Size = restored.Size
+	newMeta.Hash = restored.Hash
+	newMeta.ContentKey = restored.StorageKey()
+	newMeta.DataKey = restored.DataKey
+	newMeta.Encoding = restored.Encoding
+	newMeta.Version = oldMeta.Version + 1
+
+	oldSnapshot := newSnapshot(oldMeta)
+
+	j, err := s.begin(model.SagaTypeRestore, uuid, sagaPayload{
+		Meta:    newSnapshot(newMeta),
+		OldMeta: &oldSnapshot,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = j.retryStep(ctx, stepUpdateMetadata, func() error {
+		return s.DocumentRepository.Update(&newMeta,
+			versionEvents(model.EventDocumentUpdated, &oldMeta, newMeta)...)
+	})
+	if err != nil {
+		log.Error("failed to update saga metadata", "uuid", uuid, "error", err)
+
+		s.rollbackUpdate(ctx, j, oldMeta, newMeta, false, err)
+
+		return err
+	}
+
+	err = j.step(ctx, stepCreateVersion, func() error {
+		return s.DocumentRepository.CreateVersion(model.NewDocumentVersion(newMeta))
+	})
+	if err != nil {
+		log.Error("failed to save document version", "uuid", uuid, "error", err)
+
+		s.rollbackUpdate(ctx, j, oldMeta, newMeta, false, err)
+
+		return err
+	}
+
+	j.complete()
+	log.Info("saga version restored successfully", "uuid", uuid, "version", version)
+
+	s.pruneVersions(ctx, newMeta)
+
+	return nil
+}
+
+// currentVersion возвращает метаданные документа. Документы, созданные до появления
+// версий, получают первую версию из текущего состояния, чтобы их содержимое не потерялось
+func (s *DocumentOrchestrator) currentVersion(uuid string) (model.MetaDocument, error) {
+	metaDoc, err := s.DocumentRepository.GetById(uuid)
+	if err != nil {
+		log.Error("failed to get saga metadata", "uuid", uuid, "error", err)
+		return metaDoc, err
+	}
+
+	if metaDoc.Version > 0 {
+		return metaDoc, nil
+	}
+
+	metaDoc.Version = 1
+
+	if err = s.DocumentRepository.CreateVersion(model.NewDocumentVersion(metaDoc)); err != nil {
+		log.Error("failed to save initial document version", "uuid", uuid, "error", err)
+		return metaDoc, err
+	}
+
+	if err = s.DocumentRepository.Update(&metaDoc); err != nil {
+		log.Error("failed to update saga metadata", "uuid", uuid, "error", err)
+
+		if compErr := s.DocumentRepository.DeleteVersions(uuid, []int{metaDoc.Version}); compErr != nil {
+			log.Error("compensation failed: unable to delete initial version",
+				"uuid", uuid,
+				"compensationError", compErr,
+				"originalError", err)
+		}
+
+		return metaDoc, err
+	}
+
+	return metaDoc, nil
+}
+
+// pruneVersions удаляет версии, вышедшие за пределы политики хранения.
+// Текущая версия не удаляется никогда, а содержимое удаляется только тогда,
+// когда на него не ссылается ни одна из оставшихся версий
+func (s *DocumentOrchestrator) pruneVersions(ctx context.Context, current model.MetaDocument) {
+	policy := s.Cfg.ConfigVersions
+	if policy.KeepLast <= 0 && policy.KeepFor <= 0 {
+		return
+	}
+
+	versions, err := s.DocumentRepository.GetVersions(current.UUID)
+	if err != nil {
+		log.Error("failed to get saga versions for pruning", "uuid", current.UUID, "error", err)
+		return
+	}
+
+	var (
+		expired    []model.DocumentVersion
+		numbers    []int
+		keptKeys   = map[string]struct{}{current.StorageKey(): {}}
+		expiration = time.Now().Add(-policy.KeepFor)
+	)
+
+	// версии отсортированы от новых к старым
+	for i, version := range versions {
+		tooMany := policy.KeepLast > 0 && i >= policy.KeepLast
+		tooOld := policy.KeepFor > 0 && version.CreatedAt.Before(expiration)
+
+		if version.Version == current.Version || !(tooMany || tooOld) {
+			keptKeys[version.StorageKey()] = struct{}{}
+			continue
+		}
+
+		expired = append(expired, version)
+		numbers = append(numbers, version.Version)
+	}
+
+	if len(expired) == 0 {
+		return
+	}
+
+	if err = s.DocumentRepository.DeleteVersions(current.UUID, numbers); err != nil {
+		log.Error("failed to delete expired versions", "uuid", current.UUID, "error", err)
+		return
+	}
+
+	s.removeVersionsContent(ctx, expired, keptKeys)
+}
+
+// removeVersions удаляет все версии удаленного документа вместе с их содержимым.
+// Записи версий удаляются последними, поэтому при ошибке шаг можно повторить
+func (s *DocumentOrchestrator) removeVersions(ctx context.Context, metaDoc model.MetaDocument) error {
+	versions, err := s.DocumentRepository.GetVersions(metaDoc.UUID)
+	if err != nil {
+		log.Error("failed to get saga versions", "uuid", metaDoc.UUID, "error", err)
+		return err
+	}
+
+	// содержимое текущей версии уже удалено вместе с документом
+	removedKeys := map[string]struct{}{metaDoc.StorageKey(): {}}
+
+	s.removeVersionsContent(ctx, versions, removedKeys)
+
+	return s.DocumentRepository.DeleteVersionsByDocumentId(metaDoc.UUID)
+}
+
+// removeVersionsContent удаляет содержимое версий, пропуская ключи из skipKeys.
+// Ошибки только логируются: неудаленное содержимое не влияет на работу с документом
+func (s *DocumentOrchestrator) removeVersionsContent(ctx context.Context, versions []model.DocumentVersion, skipKeys map[string]struct{}) {
+	for _, version := range versions {
+		key := version.StorageKey()
+		if _, ok := skipKeys[key]; ok {
+			continue
+		}
+		skipKeys[key] = struct{}{}
+
+		if err := s.removeContent(ctx, version.DocumentUUID, version.File, key); err != nil {
+			log.Error("failed to delete version content",
+				"uuid", version.DocumentUUID,
+				"version", version.Version,
+				"error", err)
+		}
+	}
+}
@@ -2,76 +2,187 @@ package saga
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
 
+	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
 	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
 	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/encryption"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
 	"github.com/AlexJudin/DocumentCacheServer/internal/model"
 )
 
 var _ Orchestrator = (*DocumentOrchestrator)(nil)
 
 type DocumentOrchestrator struct {
-	DocumentRepository *repository.DocumentRepo
+	Cfg                *config.Config
+	DocumentRepository repository.DocumentRepository
+	SagaLog            postgres.SagaLog
+	Keyring            *encryption.Keyring
 }
 
-func NewDocumentOrchestrator(documentRepository *repository.DocumentRepo) *DocumentOrchestrator {
+func NewDocumentOrchestrator(cfg *config.Config, documentRepository repository.DocumentRepository, sagaLog postgres.SagaLog, keyring *encryption.Keyring) *DocumentOrchestrator {
 	return &DocumentOrchestrator{
+		Cfg:                cfg,
 		DocumentRepository: documentRepository,
+		SagaLog:            sagaLog,
+		Keyring:            keyring,
 	}
 }
 
+// SaveDocument сохраняет новый документ. Содержимое записывается до метаданных,
+// так как файл, который уже есть в хранилище, передает документу свой ключ шифрования
 func (s *DocumentOrchestrator) SaveDocument(ctx context.Context, document *entity.Document) error {
 	uuidDoc := document.Meta.UUID
 
-	err := s.DocumentRepository.Save(document.Meta)
+	document.Meta.ContentKey = uuidDoc
+	document.Meta.Version = 1
+
+	spool, err := s.prepareContent(ctx, document)
 	if err != nil {
-		log.Error("failed to save saga metadata",
+		return err
+	}
+	defer spool.Close()
+
+	j, err := s.begin(model.SagaTypeSave, uuidDoc, sagaPayload{Meta: newSnapshot(*document.Meta)})
+	if err != nil {
+		return err
+	}
+
+	err = j.step(ctx, stepStoreContent, func() error {
+		return s.storeContent(ctx, document, spool)
+	})
+	if err != nil {
+		log.Error("failed to store document content",
 			"uuid", uuidDoc,
 			"error", err)
+
+		s.rollbackSave(ctx, j, *document.Meta, err)
+
 		return err
 	}
 
-	if document.Meta.File {
-		if err = s.DocumentRepository.Upload(ctx, uuidDoc, document.File.Content); err != nil {
-			log.Error("failed to upload file content",
-				"uuid", uuidDoc,
-				"error", err)
-
-			if compErr := s.DocumentRepository.DeleteById(uuidDoc); compErr != nil {
-				log.Error("compensation failed: failed to delete metadata after upload failure",
-					"uuid", uuidDoc,
-					"compensationError", compErr,
-					"originalError", err)
-			}
+	err = j.step(ctx, stepSaveMetadata, func() error {
+		return s.DocumentRepository.Save(document.Meta,
+			versionEvents(model.EventDocumentCreated, nil, *document.Meta)...)
+	})
+	if err != nil {
+		log.Error("failed to save saga metadata",
+			"uuid", uuidDoc,
+			"error", err)
 
-			return err
-		}
-		log.Info("saga saved successfully", "uuid", uuidDoc)
+		s.rollbackSave(ctx, j, *document.Meta, err)
 
-		return nil
+		return err
 	}
 
-	if err = s.DocumentRepository.Store(ctx, uuidDoc, document.Json); err != nil {
-		log.Error("failed to save JSON content",
+	err = j.step(ctx, stepCreateVersion, func() error {
+		return s.DocumentRepository.CreateVersion(model.NewDocumentVersion(*document.Meta))
+	})
+	if err != nil {
+		log.Error("failed to save document version",
 			"uuid", uuidDoc,
 			"error", err)
 
-		if compErr := s.DocumentRepository.DeleteById(uuidDoc); compErr != nil {
-			log.Error("compensation failed: failed to delete metadata after JSON save failure",
-				"uuid", uuidDoc,
-				"compensationError", compErr,
-				"originalError", err)
-		}
+		s.rollbackSave(ctx, j, *document.Meta, err)
 
 		return err
 	}
+
+	j.complete()
 	log.Info("saga saved successfully", "uuid", uuidDoc)
 
 	return nil
 }
 
+// UpdateDocument заменяет содержимое и метаданные документа, создавая новую версию.
+// Новое содержимое сохраняется под новым ключом (файл - под своим хешем), а старое остается за предыдущей версией
+func (s *DocumentOrchestrator) UpdateDocument(ctx context.Context, document *entity.Document) error {
+	uuidDoc := document.Meta.UUID
+
+	oldMeta, err := s.currentVersion(uuidDoc)
+	if err != nil {
+		return err
+	}
+
+	document.Meta.ID = oldMeta.ID
+	document.Meta.CreatedAt = oldMeta.CreatedAt
+	document.Meta.Owner = oldMeta.Owner
+	document.Meta.ContentKey = uuid.NewString()
+	document.Meta.Version = oldMeta.Version + 1
+
+	spool, err := s.prepareContent(ctx, document)
+	if err != nil {
+		return err
+	}
+	defer spool.Close()
+
+	oldSnapshot := newSnapshot(oldMeta)
+
+	j, err := s.begin(model.SagaTypeUpdate, uuidDoc, sagaPayload{
+		Meta:    newSnapshot(*document.Meta),
+		OldMeta: &oldSnapshot,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = j.step(ctx, stepStoreContent, func() error {
+		return s.storeContent(ctx, document, spool)
+	})
+	if err != nil {
+		log.Error("failed to store new document content",
+			"uuid", uuidDoc,
+			"error", err)
+
+		s.rollbackUpdate(ctx, j, oldMeta, *document.Meta, true, err)
+
+		return err
+	}
+
+	err = j.retryStep(ctx, stepUpdateMetadata, func() error {
+		return s.DocumentRepository.Update(document.Meta,
+			versionEvents(model.EventDocumentUpdated, &oldMeta, *document.Meta)...)
+	})
+	if err != nil {
+		log.Error("failed to update saga metadata",
+			"uuid", uuidDoc,
+			"error", err)
+
+		s.rollbackUpdate(ctx, j, oldMeta, *document.Meta, true, err)
+
+		return err
+	}
+
+	err = j.step(ctx, stepCreateVersion, func() error {
+		return s.DocumentRepository.CreateVersion(model.NewDocumentVersion(*document.Meta))
+	})
+	if err != nil {
+		log.Error("failed to save document version",
+			"uuid", uuidDoc,
+			"error", err)
+
+		s.rollbackUpdate(ctx, j, oldMeta, *document.Meta, true, err)
+
+		return err
+	}
+
+	j.complete()
+	log.Info("saga updated successfully", "uuid", uuidDoc)
+
+	s.pruneVersions(ctx, *document.Meta)
+
+	return nil
+}
+
 func (s *DocumentOrchestrator) DeleteDocument(ctx context.Context, uuid string) error {
 	var metaDoc model.MetaDocument
 	metaDoc, err := s.DocumentRepository.GetById(uuid)
@@ -80,41 +191,332 @@ func (s *DocumentOrchestrator) DeleteDocument(ctx context.Context, uuid string)
 		return err
 	}
 
-	err = s.DocumentRepository.DeleteById(uuid)
+	j, err := s.begin(model.SagaTypeDelete, uuid, sagaPayload{Meta: newSnapshot(metaDoc)})
+	if err != nil {
+		return err
+	}
+
+	err = j.retryStep(ctx, stepDeleteMetadata, func() error {
+		return s.DocumentRepository.DeleteById(uuid, model.NewOutboxEvent(model.EventDocumentDeleted, metaDoc))
+	})
 	if err != nil {
 		log.Error("failed to delete saga metadata", "uuid", uuid, "error", err)
+
+		s.rollbackDelete(ctx, j, metaDoc, err)
+
 		return err
 	}
 
-	if metaDoc.File {
-		if err = s.DocumentRepository.Delete(ctx, uuid); err != nil {
-			log.Error("failed to delete file from storage", "uuid", uuid, "error", err)
+	err = j.retryStep(ctx, stepDeleteContent, func() error {
+		return ignoreNotFound(s.removeContent(ctx, metaDoc.UUID, metaDoc.File, metaDoc.StorageKey()))
+	})
+	if err != nil {
+		log.Error("failed to delete document content", "uuid", uuid, "error", err)
 
-			if compErr := s.DocumentRepository.Save(&metaDoc); compErr != nil {
-				log.Error("compensation failed: unable to restore metadata",
-					"uuid", uuid,
-					"error", compErr)
-			}
+		s.rollbackDelete(ctx, j, metaDoc, err)
+
+		return err
+	}
+	log.Info("saga delete successfully", "uuid", uuid)
+
+	// документ уже удален, поэтому при ошибке версии дочищаются восстановлением, а не откатом
+	s.finishDelete(ctx, j, metaDoc)
+
+	return nil
+}
+
+// rollbackSave отменяет сохранение нового документа. Все действия отката идемпотентны,
+// поэтому их можно повторять при восстановлении независимо от того, какие шаги успели выполниться
+func (s *DocumentOrchestrator) rollbackSave(ctx context.Context, j *journal, metaDoc model.MetaDocument, originalErr error) {
+	j.compensating(originalErr)
+
+	err := j.retryStep(ctx, stepCompensateContent, func() error {
+		return ignoreNotFound(s.removeContent(ctx, metaDoc.UUID, metaDoc.File, metaDoc.StorageKey()))
+	})
+	if err != nil {
+		log.Error("compensation failed: failed to delete document content",
+			"uuid", metaDoc.UUID,
+			"compensationError", err,
+			"originalError", originalErr)
+
+		j.finish(model.SagaStatusCompensating, err)
+
+		return
+	}
+
+	err = j.retryStep(ctx, stepCompensateVersion, func() error {
+		return s.DocumentRepository.DeleteVersionsByDocumentId(metaDoc.UUID)
+	})
+	if err != nil {
+		log.Error("compensation failed: failed to delete document versions",
+			"uuid", metaDoc.UUID,
+			"compensationError", err,
+			"originalError", originalErr)
+
+		j.finish(model.SagaStatusCompensating, err)
+
+		return
+	}
+
+	// событие создания уже записано вместе с метаданными, поэтому удаление сопровождается событием удаления
+	err = j.retryStep(ctx, stepCompensateMetadata, func() error {
+		return s.DocumentRepository.DeleteById(metaDoc.UUID, model.NewOutboxEvent(model.EventDocumentDeleted, metaDoc))
+	})
+	if err != nil {
+		log.Error("compensation failed: failed to delete metadata",
+			"uuid", metaDoc.UUID,
+			"compensationError", err,
+			"originalError", originalErr)
+
+		j.finish(model.SagaStatusCompensating, err)
+
+		return
+	}
 
+	j.finish(model.SagaStatusCompensated, nil)
+}
+
+// rollbackUpdate возвращает документу прежние метаданные и удаляет созданную версию.
+// Метаданные и версия откатываются, только если они все еще принадлежат этой саге.
+// Содержимое удаляется, если removeContent: при восстановлении версии оно общее со старой версией
+func (s *DocumentOrchestrator) rollbackUpdate(ctx context.Context, j *journal, oldMeta, newMeta model.MetaDocument, removeContent bool, originalErr error) {
+	j.compensating(originalErr)
+
+	err := j.retryStep(ctx, stepCompensateMetadata, func() error {
+		current, err := s.DocumentRepository.GetById(newMeta.UUID)
+		if errors.Is(err, custom_error.ErrDocumentNotFound) {
+			return nil
+		}
+		if err != nil {
 			return err
 		}
-		log.Info("saga delete successfully", "uuid", uuid)
 
-		return nil
+		if current.Version != newMeta.Version {
+			return nil
+		}
+
+		return s.DocumentRepository.Update(&oldMeta, revertEvents(newMeta, oldMeta)...)
+	})
+	if err != nil {
+		log.Error("compensation failed: unable to restore metadata",
+			"uuid", newMeta.UUID,
+			"compensationError", err,
+			"originalError", originalErr)
+
+		j.finish(model.SagaStatusCompensating, err)
+
+		return
+	}
+
+	err = j.retryStep(ctx, stepCompensateVersion, func() error {
+		version, err := s.DocumentRepository.GetVersion(newMeta.UUID, newMeta.Version)
+		if errors.Is(err, custom_error.ErrVersionNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if version.StorageKey() != newMeta.StorageKey() {
+			return nil
+		}
+
+		return s.DocumentRepository.DeleteVersions(newMeta.UUID, []int{newMeta.Version})
+	})
+	if err != nil {
+		log.Error("compensation failed: unable to delete new version",
+			"uuid", newMeta.UUID,
+			"compensationError", err,
+			"originalError", originalErr)
+
+		j.finish(model.SagaStatusCompensating, err)
+
+		return
 	}
 
-	if err = s.DocumentRepository.DeleteByDocumentId(ctx, uuid); err != nil {
-		log.Error("failed to delete JSON data", "uuid", uuid, "error", err)
+	if removeContent {
+		err = j.retryStep(ctx, stepCompensateContent, func() error {
+			// файл с тем же хешем может принадлежать и другой версии документа
+			shared, err := s.contentShared(newMeta)
+			if err != nil || shared {
+				return err
+			}
+
+			return ignoreNotFound(s.removeContent(ctx, newMeta.UUID, newMeta.File, newMeta.StorageKey()))
+		})
+		if err != nil {
+			log.Error("compensation failed: failed to delete new content",
+				"uuid", newMeta.UUID,
+				"compensationError", err,
+				"originalError", originalErr)
 
-		if compErr := s.DocumentRepository.Save(&metaDoc); compErr != nil {
-			log.Error("compensation failed: unable to restore metadata",
-				"uuid", uuid,
-				"error", compErr)
+			j.finish(model.SagaStatusCompensating, err)
+
+			return
 		}
+	}
+
+	j.finish(model.SagaStatusCompensated, nil)
+}
+
+// contentShared проверяет, ссылается ли на содержимое документа другая его версия
+func (s *DocumentOrchestrator) contentShared(metaDoc model.MetaDocument) (bool, error) {
+	versions, err := s.DocumentRepository.GetVersions(metaDoc.UUID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, version := range versions {
+		if version.Version != metaDoc.Version && version.StorageKey() == metaDoc.StorageKey() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// rollbackDelete возвращает метаданные документа, если они уже удалены
+func (s *DocumentOrchestrator) rollbackDelete(ctx context.Context, j *journal, metaDoc model.MetaDocument, originalErr error) {
+	j.compensating(originalErr)
+
+	err := j.retryStep(ctx, stepCompensateMetadata, func() error {
+		_, err := s.DocumentRepository.GetById(metaDoc.UUID)
+		if !errors.Is(err, custom_error.ErrDocumentNotFound) {
+			return err
+		}
+
+		return s.DocumentRepository.Save(&metaDoc, model.NewOutboxEvent(model.EventDocumentCreated, metaDoc))
+	})
+	if err != nil {
+		log.Error("compensation failed: unable to restore metadata",
+			"uuid", metaDoc.UUID,
+			"compensationError", err,
+			"originalError", originalErr)
+
+		j.finish(model.SagaStatusCompensating, err)
+
+		return
+	}
+
+	j.finish(model.SagaStatusCompensated, nil)
+}
+
+// finishDelete удаляет версии удаленного документа и завершает сагу
+func (s *DocumentOrchestrator) finishDelete(ctx context.Context, j *journal, metaDoc model.MetaDocument) {
+	err := j.retryStep(ctx, stepDeleteVersions, func() error {
+		return s.removeVersions(ctx, metaDoc)
+	})
+	if err != nil {
+		log.Error("failed to delete document versions", "uuid", metaDoc.UUID, "error", err)
+
+		j.finish(model.SagaStatusRunning, err)
+
+		return
+	}
 
+	j.complete()
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// prepareContent заполняет размер, хеш и ключ шифрования содержимого в метаданных до начала саги.
+// Файл при этом сохраняется во временную копию, которую нужно закрыть после завершения саги
+func (s *DocumentOrchestrator) prepareContent(ctx context.Context, document *entity.Document) (*fileSpool, error) {
+	dataKey, err := s.Keyring.NewDataKey()
+	if err != nil {
+		log.Error("failed to create content data key",
+			"uuid", document.Meta.UUID,
+			"error", err)
+		return nil, err
+	}
+
+	document.Meta.DataKey = dataKey
+	document.Meta.Encoding = ""
+
+	if document.Meta.File {
+		return s.prepareFile(ctx, document)
+	}
+
+	data, err := json.Marshal(document.Json)
+	if err != nil {
+		log.Error("failed to marshal JSON content",
+			"uuid", document.Meta.UUID,
+			"error", err)
+		return nil, err
+	}
+
+	document.Meta.Size = int64(len(data))
+	document.Meta.Hash = contentHash(data)
+
+	return nil, nil
+}
+
+// storeContent сохраняет содержимое документа под ключом ContentKey, зашифровав его ключом данных документа
+func (s *DocumentOrchestrator) storeContent(ctx context.Context, document *entity.Document, spool *fileSpool) error {
+	if document.Meta.File {
+		return s.storeFile(ctx, document.Meta, spool)
+	}
+
+	ctx, err := s.Keyring.Context(ctx, document.Meta.DataKey)
+	if err != nil {
 		return err
 	}
-	log.Info("saga delete successfully", "uuid", uuid)
 
-	return nil
+	return s.DocumentRepository.Store(ctx, document.Meta.StorageKey(), document.Json)
+}
+
+func (s *DocumentOrchestrator) removeContent(ctx context.Context, documentUUID string, isFile bool, key string) error {
+	if isFile {
+		return s.removeFile(ctx, documentUUID, key)
+	}
+
+	return s.DocumentRepository.DeleteByDocumentId(ctx, key)
+}
+
+// versionEvents события, которые записываются вместе с метаданными новой версии документа.
+// Если изменились настройки доступа, добавляется событие document.shared
+func versionEvents(eventType string, oldMeta *model.MetaDocument, metaDoc model.MetaDocument) []model.OutboxEvent {
+	events := []model.OutboxEvent{model.NewOutboxEvent(eventType, metaDoc)}
+
+	if oldMeta != nil && sharingChanged(*oldMeta, metaDoc) {
+		events = append(events, model.NewOutboxEvent(model.EventDocumentShared, metaDoc))
+	}
+
+	return append(events, model.NewOutboxEvent(model.EventDocumentVersionCreated, metaDoc))
+}
+
+// revertEvents события, которые записываются при возврате документу прежних метаданных oldMeta
+// после отката изменения newMeta. Версия newMeta удаляется, поэтому document.version_created не записывается
+func revertEvents(newMeta, oldMeta model.MetaDocument) []model.OutboxEvent {
+	events := []model.OutboxEvent{model.NewOutboxEvent(model.EventDocumentUpdated, oldMeta)}
+
+	if sharingChanged(newMeta, oldMeta) {
+		events = append(events, model.NewOutboxEvent(model.EventDocumentShared, oldMeta))
+	}
+
+	return events
+}
+
+func sharingChanged(oldMeta, newMeta model.MetaDocument) bool {
+	if oldMeta.Public != newMeta.Public {
+		return true
+	}
+
+	oldGrant := slices.Sorted(slices.Values(oldMeta.Grant))
+	newGrant := slices.Sorted(slices.Values(newMeta.Grant))
+
+	return !slices.Equal(slices.Compact(oldGrant), slices.Compact(newGrant))
+}
+
+// ignoreNotFound считает отсутствие содержимого успешным удалением
+func ignoreNotFound(err error) error {
+	if errors.Is(err, custom_error.ErrDocumentNotFound) {
+		return nil
+	}
+
+	return err
 }
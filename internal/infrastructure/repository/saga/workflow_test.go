@@ -0,0 +1,206 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/encryption"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/memory"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var errVersionFailed = errors.New("version storage failed")
+
+// failingVersions репозиторий, в котором не удается сохранить версию документа
+type failingVersions struct {
+	repository.DocumentRepository
+	fail bool
+}
+
+func (r *failingVersions) CreateVersion(version model.DocumentVersion) error {
+	if r.fail {
+		return errVersionFailed
+	}
+
+	return r.DocumentRepository.CreateVersion(version)
+}
+
+type testSaga struct {
+	orchestrator *DocumentOrchestrator
+	documents    *failingVersions
+	outbox       *memory.OutboxRepo
+}
+
+func newTestSaga(t *testing.T) *testSaga {
+	t.Helper()
+
+	keyring, err := encryption.NewKeyring(&config.ConfigEncryption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := memory.NewDatabase()
+	documents := &failingVersions{
+		DocumentRepository: repository.NewMemoryDocumentRepository(db, memory.NewFileRepository(), keyring),
+	}
+
+	cfg := &config.Config{
+		ConfigSaga:        &config.ConfigSaga{StepAttempts: 1},
+		ConfigVersions:    &config.ConfigVersions{},
+		ConfigCompression: &config.ConfigCompression{},
+	}
+
+	return &testSaga{
+		orchestrator: NewDocumentOrchestrator(cfg, documents, memory.NewSagaLogRepo(db), keyring),
+		documents:    documents,
+		outbox:       memory.NewOutboxRepo(db),
+	}
+}
+
+// events возвращает типы неопубликованных событий и отмечает их опубликованными
+func (s *testSaga) events(t *testing.T) []string {
+	t.Helper()
+
+	var types []string
+
+	_, err := s.outbox.PublishPending(100, func(event model.OutboxEvent) error {
+		types = append(types, event.Type)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return types
+}
+
+func (s *testSaga) save(t *testing.T, meta model.MetaDocument) error {
+	t.Helper()
+
+	return s.orchestrator.SaveDocument(context.Background(), &entity.Document{
+		Meta: &meta,
+		Json: map[string]interface{}{"name": meta.Name},
+	})
+}
+
+func TestSaveDocumentEvents(t *testing.T) {
+	s := newTestSaga(t)
+
+	meta := model.MetaDocument{UUID: uuid.NewString(), Name: "report", Owner: "alice"}
+
+	if err := s.save(t, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{model.EventDocumentCreated, model.EventDocumentVersionCreated}
+	if got := s.events(t); !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestSaveDocumentCompensationEvents(t *testing.T) {
+	s := newTestSaga(t)
+	s.documents.fail = true
+
+	meta := model.MetaDocument{UUID: uuid.NewString(), Name: "report", Owner: "alice"}
+
+	if err := s.save(t, meta); !errors.Is(err, errVersionFailed) {
+		t.Fatalf("error = %v, want %v", err, errVersionFailed)
+	}
+
+	if _, err := s.documents.GetById(meta.UUID); !errors.Is(err, custom_error.ErrDocumentNotFound) {
+		t.Fatalf("document of compensated saga: error = %v, want %v", err, custom_error.ErrDocumentNotFound)
+	}
+
+	// событие создания записано вместе с метаданными, а откат записывает событие удаления
+	want := []string{model.EventDocumentCreated, model.EventDocumentVersionCreated, model.EventDocumentDeleted}
+	if got := s.events(t); !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateDocumentEvents(t *testing.T) {
+	s := newTestSaga(t)
+
+	meta := model.MetaDocument{UUID: uuid.NewString(), Name: "report", Owner: "alice"}
+	if err := s.save(t, meta); err != nil {
+		t.Fatal(err)
+	}
+	s.events(t)
+
+	update := func() error {
+		return s.orchestrator.UpdateDocument(context.Background(), &entity.Document{
+			Meta: &model.MetaDocument{UUID: meta.UUID, Name: "report", Public: true},
+			Json: map[string]interface{}{"name": "updated"},
+		})
+	}
+
+	t.Run("failed version restores previous metadata", func(t *testing.T) {
+		s.documents.fail = true
+		defer func() { s.documents.fail = false }()
+
+		if err := update(); !errors.Is(err, errVersionFailed) {
+			t.Fatalf("error = %v, want %v", err, errVersionFailed)
+		}
+
+		current, err := s.documents.GetById(meta.UUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if current.Public || current.Version != 1 {
+			t.Errorf("document after rollback: public = %v, version = %d", current.Public, current.Version)
+		}
+
+		want := []string{
+			model.EventDocumentUpdated, model.EventDocumentShared, model.EventDocumentVersionCreated,
+			model.EventDocumentUpdated, model.EventDocumentShared,
+		}
+		if got := s.events(t); !slices.Equal(got, want) {
+			t.Errorf("events = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("updated", func(t *testing.T) {
+		if err := update(); err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{model.EventDocumentUpdated, model.EventDocumentShared, model.EventDocumentVersionCreated}
+		if got := s.events(t); !slices.Equal(got, want) {
+			t.Errorf("events = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestDeleteDocumentEvents(t *testing.T) {
+	s := newTestSaga(t)
+
+	meta := model.MetaDocument{UUID: uuid.NewString(), Name: "report", Owner: "alice"}
+	if err := s.save(t, meta); err != nil {
+		t.Fatal(err)
+	}
+	s.events(t)
+
+	if err := s.orchestrator.DeleteDocument(context.Background(), meta.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	// повторное удаление метаданных при восстановлении саги не дублирует событие
+	if err := s.documents.DeleteById(meta.UUID, model.NewOutboxEvent(model.EventDocumentDeleted, meta)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{model.EventDocumentDeleted}
+	if got := s.events(t); !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"encoding/hex"
+	"time"
+)
+
+// FileBlob файл в файловом хранилище, сохраненный под SHA-256 своего содержимого.
+// Одинаковые файлы разных документов и версий хранятся одним объектом
+type FileBlob struct {
+	Digest string `gorm:"primarykey"`
+	Size   int64
+	// Stored объект записан в файловое хранилище. Пока первая загрузка не завершена,
+	// загрузки того же содержимого тоже записывают объект
+	Stored bool
+	// DataKey ключ шифрования файла, который получают все документы, ссылающиеся на файл
+	DataKey `gorm:"embedded"`
+	// Encoding алгоритм сжатия файла, который получают все документы, ссылающиеся на файл
+	Encoding  string
+	CreatedAt time.Time
+}
+
+// FileBlobRef ссылка документа на файл. Количество ссылок - счетчик использования файла:
+// файл удаляется из хранилища, когда удаляется последняя ссылка на него
+type FileBlobRef struct {
+	Digest       string `gorm:"primarykey"`
+	DocumentUUID string `gorm:"primarykey;index"`
+	CreatedAt    time.Time
+}
+
+// IsFileDigest проверяет, что ключ содержимого - SHA-256 файла в hex.
+// Файлы, загруженные до появления дедупликации, хранятся под случайным UUID и ссылок не имеют
+func IsFileDigest(key string) bool {
+	if len(key) != 64 {
+		return false
+	}
+
+	_, err := hex.DecodeString(key)
+
+	return err == nil
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// DocumentContent содержимое JSON документа в Postgres, когда содержимое хранится в колонке JSONB вместо MongoDB.
+// GIN индекс с классом jsonb_path_ops ускоряет поиск по содержимому операторами @> и @@
+type DocumentContent struct {
+	ContentKey string `gorm:"primarykey"`
+	Content    string `gorm:"type:jsonb;not null;index:idx_document_contents_content,type:gin,expression:content jsonb_path_ops"`
+	CreatedAt  time.Time
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"slices"
 	"time"
 
 	"github.com/lib/pq"
@@ -13,11 +14,101 @@ const (
 
 type MetaDocument struct {
 	ID        uint           `gorm:"primarykey" json:"-"`
-	UUID      string         `gorm:"index" json:"-"`
+	UUID      string         `gorm:"index" json:"id"`
 	CreatedAt time.Time      `json:"-"`
+	UpdatedAt time.Time      `json:"-"`
+	Owner     string         `gorm:"index" json:"owner"`
 	Name      string         `json:"name"`
 	File      bool           `json:"file"`
 	Public    bool           `json:"public"`
 	Mime      string         `json:"mime"`
-	Grant     pq.StringArray `gorm:"type:text[]" json:"grant"`
+	Grant     pq.StringArray `gorm:"type:text[]" json:"grant" swaggertype:"array,string"`
+	// Size размер содержимого документа в байтах
+	Size int64 `json:"-"`
+	// Hash SHA-256 содержимого документа в hex, используется как ETag
+	Hash string `json:"digest"`
+	// ContentKey ключ содержимого документа в MongoDB или MinIO.
+	// Для документов, созданных до появления обновлений, ключ пустой.
+	// Файлы хранятся под SHA-256 содержимого, поэтому для них ключ совпадает с Hash
+	ContentKey string `json:"-"`
+	// Version номер текущей версии документа
+	Version int `json:"version"`
+	// Broken содержимое документа не найдено при проверке согласованности хранилищ.
+	// Признак снимается при следующем обновлении документа
+	Broken bool `gorm:"index" json:"-"`
+	// DataKey ключ шифрования содержимого. Содержимое одного ключа хранения
+	// у всех документов и версий зашифровано одним ключом
+	DataKey `gorm:"embedded"`
+	// Encoding алгоритм, которым сжат файл документа в хранилище, пустая строка - файл не сжат
+	Encoding string `json:"-"`
+}
+
+// StorageKey возвращает ключ, под которым содержимое документа лежит в хранилище
+func (d MetaDocument) StorageKey() string {
+	if d.ContentKey != "" {
+		return d.ContentKey
+	}
+
+	return d.UUID
+}
+
+// CanRead проверяет право пользователя на чтение документа.
+// Публичные документы доступны всем, включая неавторизованных пользователей (пустой login)
+func (d MetaDocument) CanRead(login string) bool {
+	if d.Public {
+		return true
+	}
+
+	if login == "" {
+		return false
+	}
+
+	return d.Owner == login || slices.Contains(d.Grant, login)
+}
+
+// CanWrite проверяет право пользователя на изменение и удаление документа
+func (d MetaDocument) CanWrite(login string) bool {
+	return login != "" && d.Owner == login
+}
+
+// DocumentVersion снимок документа на момент создания версии.
+// Каждая версия указывает на собственное содержимое в MongoDB или MinIO
+type DocumentVersion struct {
+	ID           uint      `gorm:"primarykey" json:"-"`
+	DocumentUUID string    `gorm:"uniqueIndex:idx_document_version" json:"-"`
+	Version      int       `gorm:"uniqueIndex:idx_document_version" json:"version"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	File         bool      `json:"file"`
+	Mime         string    `json:"mime"`
+	Size         int64     `json:"size"`
+	Hash         string    `json:"hash"`
+	ContentKey   string    `json:"-"`
+	DataKey      `gorm:"embedded"`
+	Encoding     string `json:"-"`
+}
+
+// NewDocumentVersion создает версию из текущего состояния документа
+func NewDocumentVersion(document MetaDocument) DocumentVersion {
+	return DocumentVersion{
+		DocumentUUID: document.UUID,
+		Version:      document.Version,
+		Name:         document.Name,
+		File:         document.File,
+		Mime:         document.Mime,
+		Size:         document.Size,
+		Hash:         document.Hash,
+		ContentKey:   document.StorageKey(),
+		DataKey:      document.DataKey,
+		Encoding:     document.Encoding,
+	}
+}
+
+// StorageKey возвращает ключ, под которым содержимое версии лежит в хранилище
+func (v DocumentVersion) StorageKey() string {
+	if v.ContentKey != "" {
+		return v.ContentKey
+	}
+
+	return v.DocumentUUID
 }
@@ -0,0 +1,14 @@
+package model
+
+// DataKey ключ данных, которым зашифровано содержимое документа.
+// Ключ хранится зашифрованным мастер-ключом KeyID из конфигурации.
+// Пустой ключ означает, что содержимое хранится без шифрования
+type DataKey struct {
+	WrappedKey string `json:"-"`
+	KeyID      string `json:"-"`
+}
+
+// Encrypted проверяет, зашифровано ли содержимое
+func (k DataKey) Encrypted() bool {
+	return k.WrappedKey != ""
+}
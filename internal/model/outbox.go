@@ -0,0 +1,63 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventDocumentCreated        = "document.created"
+	EventDocumentUpdated        = "document.updated"
+	EventDocumentDeleted        = "document.deleted"
+	EventDocumentShared         = "document.shared"
+	EventDocumentVersionCreated = "document.version_created"
+)
+
+// OutboxEvent событие жизненного цикла документа, ожидающее публикации в брокер.
+// Событие записывается в одной транзакции с изменением метаданных, поэтому не теряется при сбое.
+// UUID события передается получателям для отбрасывания повторов
+type OutboxEvent struct {
+	ID           uint   `gorm:"primarykey"`
+	UUID         string `gorm:"uniqueIndex"`
+	Type         string `gorm:"index"`
+	DocumentUUID string `gorm:"index"`
+	Payload      string `gorm:"type:jsonb"`
+	Attempts     int
+	Error        string
+	CreatedAt    time.Time
+	PublishedAt  *time.Time `gorm:"index"`
+}
+
+// DocumentEvent содержимое события, которое получают подписчики
+type DocumentEvent struct {
+	ID         string       `json:"id"`
+	Type       string       `json:"type"`
+	DocumentID string       `json:"document_id"`
+	Version    int          `json:"version"`
+	Document   MetaDocument `json:"document"`
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// NewOutboxEvent создает событие с новым идентификатором по текущему состоянию документа
+func NewOutboxEvent(eventType string, document MetaDocument) OutboxEvent {
+	event := DocumentEvent{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		DocumentID: document.UUID,
+		Version:    document.Version,
+		Document:   document,
+		OccurredAt: time.Now().UTC(),
+	}
+
+	// MetaDocument состоит из простых полей и всегда сериализуется
+	payload, _ := json.Marshal(event)
+
+	return OutboxEvent{
+		UUID:         event.ID,
+		Type:         eventType,
+		DocumentUUID: document.UUID,
+		Payload:      string(payload),
+	}
+}
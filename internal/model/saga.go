@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+const (
+	SagaTypeSave    = "save"
+	SagaTypeUpdate  = "update"
+	SagaTypeDelete  = "delete"
+	SagaTypeRestore = "restore"
+
+	// SagaStatusRunning шаги саги выполняются
+	SagaStatusRunning = "running"
+	// SagaStatusCompensating шаг завершился ошибкой, выполняется откат
+	SagaStatusCompensating = "compensating"
+	// SagaStatusCompleted все шаги выполнены
+	SagaStatusCompleted = "completed"
+	// SagaStatusCompensated изменения саги отменены
+	SagaStatusCompensated = "compensated"
+	// SagaStatusFailed восстановление не удалось после всех попыток, нужно вмешательство администратора
+	SagaStatusFailed = "failed"
+
+	SagaStepDone   = "done"
+	SagaStepFailed = "failed"
+)
+
+// Saga журнал выполнения саги над документом.
+// Payload содержит состояние документа, необходимое для восстановления после сбоя
+type Saga struct {
+	ID           uint      `gorm:"primarykey" json:"-"`
+	UUID         string    `gorm:"uniqueIndex" json:"id"`
+	Type         string    `json:"type"`
+	DocumentUUID string    `gorm:"index" json:"document_id"`
+	Status       string    `gorm:"index" json:"status"`
+	Step         string    `json:"step"`
+	Attempts     int       `json:"attempts"`
+	Error        string    `json:"error"`
+	Payload      string    `gorm:"type:jsonb" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `gorm:"index" json:"updated_at"`
+}
+
+// SagaStep состояние шага саги
+type SagaStep struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	SagaUUID  string    `gorm:"uniqueIndex:idx_saga_step" json:"-"`
+	Name      string    `gorm:"uniqueIndex:idx_saga_step" json:"name"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsFinished сообщает, что сага находится в конечном состоянии
+func (s Saga) IsFinished() bool {
+	return s.Status == SagaStatusCompleted || s.Status == SagaStatusCompensated
+}
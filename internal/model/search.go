@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// DocumentSearch полнотекстовый индекс документа.
+// Vector заполняется при записи индекса из Name (вес A) и Body (вес B)
+type DocumentSearch struct {
+	DocumentUUID string `gorm:"primarykey"`
+	Name         string
+	Body         string
+	Vector       string `gorm:"type:tsvector;index:idx_document_search_vector,type:gin;->"`
+	UpdatedAt    time.Time
+}
@@ -0,0 +1,67 @@
+package model
+
+import "time"
+
+const (
+	// ShareAccessGranted содержимое документа отдано по ссылке
+	ShareAccessGranted = "granted"
+	// ShareAccessWrongPassword пароль ссылки не передан или неверен
+	ShareAccessWrongPassword = "wrong_password"
+	// ShareAccessExpired срок действия ссылки истек
+	ShareAccessExpired = "expired"
+	// ShareAccessRevoked ссылка отозвана владельцем
+	ShareAccessRevoked = "revoked"
+	// ShareAccessExhausted исчерпано количество скачиваний по ссылке
+	ShareAccessExhausted = "exhausted"
+	// ShareAccessNotFound документ ссылки удален
+	ShareAccessNotFound = "not_found"
+)
+
+// ShareLink ссылка на документ, по которой его может получить любой, кто знает токен ссылки.
+// Токен не хранится: ссылка находится по SHA-256 токена, поэтому токен возвращается только при создании ссылки.
+// Документ отдается по ссылке от имени ее владельца
+type ShareLink struct {
+	ID           uint   `gorm:"primarykey" json:"-"`
+	UUID         string `gorm:"uniqueIndex" json:"id"`
+	TokenHash    string `gorm:"uniqueIndex" json:"-"`
+	Owner        string `gorm:"index" json:"-"`
+	DocumentUUID string `gorm:"index" json:"document_id"`
+	// PasswordHash хеш пароля ссылки, пустое значение - ссылка без пароля
+	PasswordHash string `json:"-"`
+	Protected    bool   `json:"protected"`
+	// ExpiresAt срок действия ссылки, пустое значение - ссылка бессрочная
+	ExpiresAt *time.Time `json:"expires_at"`
+	// MaxDownloads максимальное количество скачиваний, 0 - без ограничения
+	MaxDownloads int        `json:"max_downloads"`
+	Downloads    int        `json:"downloads"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ShareAccess обращение к документу по ссылке. Журнал обращений хранится вместе со ссылкой
+type ShareAccess struct {
+	ID        uint       `gorm:"primarykey" json:"-"`
+	ShareUUID string     `gorm:"index" json:"-"`
+	Share     *ShareLink `gorm:"foreignKey:ShareUUID;references:UUID;constraint:OnDelete:CASCADE" json:"-"`
+	// Result результат обращения: granted, wrong_password, expired, revoked, exhausted или not_found
+	Result    string    `json:"result"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Expired проверяет, что срок действия ссылки истек к моменту now
+func (l ShareLink) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(now)
+}
+
+// Revoked проверяет, что ссылка отозвана владельцем
+func (l ShareLink) Revoked() bool {
+	return l.RevokedAt != nil
+}
+
+// Exhausted проверяет, что исчерпано количество скачиваний по ссылке
+func (l ShareLink) Exhausted() bool {
+	return l.MaxDownloads > 0 && l.Downloads >= l.MaxDownloads
+}
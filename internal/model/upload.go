@@ -0,0 +1,76 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// UploadLockTTL время, на которое загрузка занимается обработчиком запроса или очисткой.
+// Пока запрос выполняется, блокировка продлевается, а после остановки экземпляра сервиса освобождается по истечении
+const UploadLockTTL = time.Minute
+
+// Upload возобновляемая загрузка файла по протоколу tus или прямая загрузка по подписанной ссылке.
+// Части файла записываются в хранилище как части multipart загрузки, остаток меньше части хранится в Pending.
+// При прямой загрузке клиент сам записывает файл в хранилище под ключом StorageKey.
+// Когда получен весь файл, из него создается документ DocumentUUID
+type Upload struct {
+	ID     uint           `gorm:"primarykey" json:"-"`
+	UUID   string         `gorm:"uniqueIndex" json:"id"`
+	Owner  string         `gorm:"index" json:"-"`
+	Name   string         `json:"name"`
+	Mime   string         `json:"mime"`
+	Public bool           `json:"public"`
+	Grant  pq.StringArray `gorm:"type:text[]" json:"grant"`
+	// Length размер файла, Offset - количество полученных байт
+	Length int64 `json:"length"`
+	Offset int64 `json:"offset"`
+	// MultipartID идентификатор multipart загрузки в хранилище
+	MultipartID string `json:"-"`
+	// Pending полученные байты, которых еще не хватает на часть multipart загрузки
+	Pending []byte `json:"-"`
+	// HashState состояние SHA-256 полученных байт. Хеш файла считается при приеме,
+	// поэтому собранный файл не перечитывается, чтобы получить ключ хранения
+	HashState []byte `json:"-"`
+	// Assembled части собраны в файл хранилища
+	Assembled bool `json:"-"`
+	// Direct файл загружается клиентом в хранилище по подписанной ссылке
+	Direct bool `json:"direct"`
+	// TargetUUID документ, новой версией которого становится файл. Пустое значение - создается новый документ
+	TargetUUID string `json:"-"`
+	// PendingUUID и PendingVersion документ и номер версии, которые создает завершение загрузки.
+	// Записываются до вызова саги, поэтому повтор завершения после сбоя находит уже созданный
+	// документ или версию и не создает их второй раз
+	PendingUUID    string    `json:"-"`
+	PendingVersion int       `json:"-"`
+	DocumentUUID   string    `json:"document_id"`
+	ExpiresAt      time.Time `gorm:"index" json:"expires_at"`
+	// LockedBy и LockedUntil обработчик, который занял загрузку, и срок его блокировки
+	LockedBy    string    `json:"-"`
+	LockedUntil time.Time `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UploadPart часть multipart загрузки, записанная в хранилище
+type UploadPart struct {
+	UploadUUID string `gorm:"primaryKey"`
+	Number     int    `gorm:"primaryKey"`
+	ETag       string
+	Size       int64
+}
+
+// StorageKey возвращает ключ, под которым файл загрузки собирается в хранилище
+func (u Upload) StorageKey() string {
+	return "upload-" + u.UUID
+}
+
+// Received проверяет, что получен весь файл
+func (u Upload) Received() bool {
+	return u.Offset == u.Length
+}
+
+// Completed проверяет, что из загрузки создан документ
+func (u Upload) Completed() bool {
+	return u.DocumentUUID != ""
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// WebhookDeliveryPending доставка ожидает первой отправки
+	WebhookDeliveryPending = "pending"
+	// WebhookDeliveryRetrying отправка не удалась, доставка будет повторена
+	WebhookDeliveryRetrying = "retrying"
+	// WebhookDeliveryDelivered получатель подтвердил доставку ответом 2xx
+	WebhookDeliveryDelivered = "delivered"
+	// WebhookDeliveryDead все попытки исчерпаны, доставку можно повторить вручную
+	WebhookDeliveryDead = "dead"
+)
+
+// WebhookSubscription подписка пользователя на события документов.
+// Подписка получает события документов, владельцем которых является пользователь
+// или к которым ему выдан доступ
+type WebhookSubscription struct {
+	ID        uint           `gorm:"primarykey" json:"-"`
+	UUID      string         `gorm:"uniqueIndex" json:"id"`
+	Login     string         `gorm:"index" json:"-"`
+	URL       string         `json:"url"`
+	Events    pq.StringArray `gorm:"type:text[]" json:"events"`
+	Secret    string         `json:"-"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// WebhookDelivery доставка события подписке. Журнал доставок хранится вместе с подпиской
+type WebhookDelivery struct {
+	ID               uint                 `gorm:"primarykey" json:"-"`
+	UUID             string               `gorm:"uniqueIndex" json:"id"`
+	SubscriptionUUID string               `gorm:"index" json:"-"`
+	Subscription     *WebhookSubscription `gorm:"foreignKey:SubscriptionUUID;references:UUID;constraint:OnDelete:CASCADE" json:"-"`
+	EventID          string               `json:"event_id"`
+	EventType        string               `json:"event_type"`
+	DocumentUUID     string               `json:"document_id"`
+	Payload          string               `gorm:"type:jsonb" json:"-"`
+	Status           string               `gorm:"index:idx_webhook_delivery_due" json:"status"`
+	Attempts         int                  `json:"attempts"`
+	NextAttemptAt    time.Time            `gorm:"index:idx_webhook_delivery_due" json:"next_attempt_at"`
+	// ResponseStatus код ответа получателя на последнюю попытку, 0 - ответ не получен
+	ResponseStatus int        `json:"response_status"`
+	Error          string     `json:"error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -25,10 +25,10 @@ const (
 
 type AuthService struct {
 	Config       *config.Config
-	TokenStorage *postgres.TokenStorageRepo
+	TokenStorage postgres.TokenStorage
 }
 
-func NewAuthService(cfg *config.Config, repo *postgres.TokenStorageRepo) AuthService {
+func NewAuthService(cfg *config.Config, repo postgres.TokenStorage) AuthService {
 	return AuthService{
 		Config:       cfg,
 		TokenStorage: repo,
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/app/metric"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// ConsistencyChecker сверяет содержимое MongoDB и MinIO с метаданными в Postgres.
+// Сначала читаются ключи хранилищ, затем ссылки на них из Postgres: содержимое,
+// записанное выполняющейся сагой, к этому моменту уже упомянуто в ее журнале и не считается осиротевшим
+type ConsistencyChecker struct {
+	cfg       *config.ConfigConsistency
+	repo      postgres.Consistency
+	documents repository.DocumentRepository
+	metrics   *metric.ConsistencyMetrics
+}
+
+func NewConsistencyChecker(cfg *config.Config, repo postgres.Consistency, documents repository.DocumentRepository, metrics *metric.ConsistencyMetrics) *ConsistencyChecker {
+	return &ConsistencyChecker{
+		cfg:       cfg.ConfigConsistency,
+		repo:      repo,
+		documents: documents,
+		metrics:   metrics,
+	}
+}
+
+// Run выполняет проверку с периодом Interval в режиме Mode до отмены контекста
+func (c *ConsistencyChecker) Run(ctx context.Context) {
+	if c.cfg.Mode == config.ConsistencyModeOff {
+		return
+	}
+
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, err := c.Check(ctx, c.cfg.Mode == config.ConsistencyModeRepair)
+		switch {
+		case errors.Is(err, custom_error.ErrConsistencyCheckRunning):
+			log.Info("consistency check skipped: already running on another instance")
+		case err != nil:
+			log.Errorf("consistency check failed: %+v", err)
+		}
+	}
+}
+
+// Check сверяет хранилища и возвращает найденные расхождения.
+// В режиме repair осиротевшее содержимое удаляется, а документы без содержимого помечаются как поврежденные
+func (c *ConsistencyChecker) Check(ctx context.Context, repair bool) (entity.ConsistencyReport, error) {
+	mode := config.ConsistencyModeDryRun
+	if repair {
+		mode = config.ConsistencyModeRepair
+	}
+
+	unlock, locked, err := c.repo.Lock(ctx)
+	if err != nil {
+		c.metrics.Failed(mode)
+		return entity.ConsistencyReport{}, err
+	}
+
+	if !locked {
+		return entity.ConsistencyReport{}, custom_error.ErrConsistencyCheckRunning
+	}
+	defer unlock()
+
+	log.Infof("consistency check started in [%s] mode", mode)
+
+	report, err := c.check(ctx, repair)
+	if err != nil {
+		c.metrics.Failed(mode)
+		return report, err
+	}
+
+	c.metrics.Observe(mode, report)
+
+	log.Infof("consistency check finished: %d orphan contents, %d orphan files, %d broken documents, %d missing versions, %d repaired, %d repair failed",
+		len(report.OrphanContents), len(report.OrphanFiles), len(report.BrokenDocuments), len(report.MissingVersions),
+		report.Repaired, report.RepairFailed)
+
+	return report, nil
+}
+
+func (c *ConsistencyChecker) check(ctx context.Context, repair bool) (entity.ConsistencyReport, error) {
+	report := entity.ConsistencyReport{
+		Repair:            repair,
+		StartedAt:         time.Now().UTC(),
+		OrphanContents:    make([]string, 0),
+		OrphanFiles:       make([]string, 0),
+		BrokenDocuments:   make([]string, 0),
+		RestoredDocuments: make([]string, 0),
+		MissingVersions:   make([]entity.MissingVersion, 0),
+	}
+
+	contentKeys, err := c.documents.ContentKeys(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	fileKeys, err := c.documents.FileKeys(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	refs, err := c.repo.GetContentRefs()
+	if err != nil {
+		return report, err
+	}
+
+	contents := toSet(contentKeys)
+	files := toSet(fileKeys)
+
+	referenced := make(map[string]struct{}, len(refs))
+	// документы, с которыми работают саги, могут временно не иметь содержимого
+	inSaga := make(map[string]struct{})
+
+	for _, ref := range refs {
+		referenced[ref.ContentKey] = struct{}{}
+
+		if ref.Source == entity.ContentRefSaga {
+			inSaga[ref.DocumentUUID] = struct{}{}
+		}
+	}
+
+	for _, key := range contentKeys {
+		if _, ok := referenced[key]; !ok {
+			report.OrphanContents = append(report.OrphanContents, key)
+		}
+	}
+
+	for _, key := range fileKeys {
+		if _, ok := referenced[key]; !ok {
+			report.OrphanFiles = append(report.OrphanFiles, key)
+		}
+	}
+
+	// документы, которые будут помечены как поврежденные, без уже помеченных ранее
+	var broken []string
+
+	for _, ref := range refs {
+		// содержимое саг и файлы загрузок могут быть еще не записаны, их отсутствие не считается расхождением
+		if ref.Source == entity.ContentRefSaga || ref.Source == entity.ContentRefUpload {
+			continue
+		}
+
+		if _, ok := inSaga[ref.DocumentUUID]; ok {
+			continue
+		}
+
+		stored := contents
+		if ref.File {
+			stored = files
+		}
+
+		_, exists := stored[ref.ContentKey]
+
+		switch {
+		case ref.Source == entity.ContentRefVersion && !exists:
+			report.MissingVersions = append(report.MissingVersions, entity.MissingVersion{
+				DocumentUUID: ref.DocumentUUID,
+				Version:      ref.Version,
+				ContentKey:   ref.ContentKey,
+			})
+		case ref.Source == entity.ContentRefDocument && !exists:
+			report.BrokenDocuments = append(report.BrokenDocuments, ref.DocumentUUID)
+
+			if !ref.Broken {
+				broken = append(broken, ref.DocumentUUID)
+			}
+		case ref.Source == entity.ContentRefDocument && ref.Broken:
+			report.RestoredDocuments = append(report.RestoredDocuments, ref.DocumentUUID)
+		}
+	}
+
+	if repair {
+		c.repair(ctx, &report, broken)
+	}
+
+	report.FinishedAt = time.Now().UTC()
+
+	return report, nil
+}
+
+// repair удаляет осиротевшее содержимое и обновляет признак поврежденных документов.
+// Ошибка исправления одного расхождения не прерывает исправление остальных
+func (c *ConsistencyChecker) repair(ctx context.Context, report *entity.ConsistencyReport, broken []string) {
+	repaired := func(err error, what, key string) {
+		if err != nil && !errors.Is(err, custom_error.ErrDocumentNotFound) {
+			log.Errorf("failed to repair %s [%s]: %+v", what, key, err)
+			report.RepairFailed++
+			return
+		}
+
+		report.Repaired++
+	}
+
+	for _, key := range report.OrphanContents {
+		repaired(c.documents.DeleteByDocumentId(ctx, key), "orphan content", key)
+	}
+
+	for _, key := range report.OrphanFiles {
+		if !model.IsFileDigest(key) {
+			repaired(c.documents.Delete(ctx, key), "orphan file", key)
+			continue
+		}
+
+		// файл удаляется под блокировкой записи о нем: одновременная загрузка того же содержимого
+		// либо дождется удаления и запишет файл заново, либо успеет сослаться на него, и файл останется
+		removed, err := c.repo.DeleteBlob(key, func() error {
+			err := c.documents.Delete(ctx, key)
+			if errors.Is(err, custom_error.ErrDocumentNotFound) {
+				return nil
+			}
+
+			return err
+		})
+		if err == nil && !removed {
+			log.Infof("orphan file [%s] is referenced again and was kept", key)
+			continue
+		}
+
+		repaired(err, "orphan file", key)
+	}
+
+	if err := c.repo.SetBroken(broken, true); err != nil {
+		log.Errorf("failed to mark broken documents: %+v", err)
+		report.RepairFailed += len(broken)
+	} else {
+		report.Repaired += len(broken)
+	}
+
+	if err := c.repo.SetBroken(report.RestoredDocuments, false); err != nil {
+		log.Errorf("failed to unmark restored documents: %+v", err)
+	}
+}
+
+func toSet(keys []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+
+	return set
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/mongodb"
+)
+
+// ContentMigrator переносит содержимое JSON документов из одного хранилища в другое
+// (из MongoDB в Postgres) и сверяет копию с исходным содержимым.
+// Уже перенесенное содержимое не копируется повторно, поэтому прерванный перенос можно запустить снова
+type ContentMigrator struct {
+	source mongodb.ContentRepository
+	target mongodb.ContentRepository
+}
+
+func NewContentMigrator(source, target mongodb.ContentRepository) *ContentMigrator {
+	return &ContentMigrator{
+		source: source,
+		target: target,
+	}
+}
+
+// Migrate копирует отсутствующее в target содержимое и сверяет все содержимое source с target.
+// Ошибка переноса одного содержимого не прерывает перенос остальных и попадает в отчет
+func (m *ContentMigrator) Migrate(ctx context.Context) (entity.ContentMigrationReport, error) {
+	report := entity.ContentMigrationReport{
+		StartedAt:  time.Now().UTC(),
+		Mismatched: make([]string, 0),
+		Failed:     make([]string, 0),
+	}
+
+	log.Info("content migration started")
+
+	keys, err := m.source.ContentKeys(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	report.Total = len(keys)
+
+	for _, key := range keys {
+		if err = ctx.Err(); err != nil {
+			return report, err
+		}
+
+		m.migrate(ctx, key, &report)
+	}
+
+	report.FinishedAt = time.Now().UTC()
+
+	log.Infof("content migration finished: %d total, %d copied, %d verified, %d mismatched, %d failed",
+		report.Total, report.Copied, report.Verified, len(report.Mismatched), len(report.Failed))
+
+	return report, nil
+}
+
+// migrate копирует одно содержимое, если его нет в target, и сверяет копию
+func (m *ContentMigrator) migrate(ctx context.Context, key string, report *entity.ContentMigrationReport) {
+	content, err := m.source.GetByDocumentId(ctx, key)
+	if err != nil {
+		log.Errorf("failed to read content [%s] from source: %+v", key, err)
+		report.Failed = append(report.Failed, key)
+		return
+	}
+
+	copied, err := m.target.GetByDocumentId(ctx, key)
+	switch {
+	case errors.Is(err, custom_error.ErrDocumentNotFound):
+		if err = m.target.Store(ctx, key, content); err != nil {
+			log.Errorf("failed to copy content [%s]: %+v", key, err)
+			report.Failed = append(report.Failed, key)
+			return
+		}
+
+		report.Copied++
+
+		copied, err = m.target.GetByDocumentId(ctx, key)
+		if err != nil {
+			log.Errorf("failed to read copied content [%s]: %+v", key, err)
+			report.Failed = append(report.Failed, key)
+			return
+		}
+	case err != nil:
+		log.Errorf("failed to read content [%s] from target: %+v", key, err)
+		report.Failed = append(report.Failed, key)
+		return
+	}
+
+	equal, err := sameContent(content, copied)
+	if err != nil {
+		log.Errorf("failed to compare content [%s]: %+v", key, err)
+		report.Failed = append(report.Failed, key)
+		return
+	}
+
+	if !equal {
+		log.Warnf("copied content [%s] differs from source", key)
+		report.Mismatched = append(report.Mismatched, key)
+		return
+	}
+
+	report.Verified++
+}
+
+// sameContent сравнивает содержимое в представлении JSON без поля с ключом:
+// хранилища по-разному возвращают числа и вложенные объекты, но одинаково их сериализуют
+func sameContent(source, target map[string]interface{}) (bool, error) {
+	sourceJson, err := normalizeContent(source)
+	if err != nil {
+		return false, err
+	}
+
+	targetJson, err := normalizeContent(target)
+	if err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(sourceJson, targetJson), nil
+}
+
+func normalizeContent(content map[string]interface{}) (map[string]interface{}, error) {
+	withoutID := make(map[string]interface{}, len(content))
+	for key, value := range content {
+		if key != entity.ContentIDField {
+			withoutID[key] = value
+		}
+	}
+
+	data, err := json.Marshal(withoutID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err = json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
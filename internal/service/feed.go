@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/cache"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	// feedBatchSize количество событий, которое читается из ленты за один запрос
+	feedBatchSize = 100
+	// feedReadBlock время ожидания новых событий ленты
+	feedReadBlock = 5 * time.Second
+	// feedRetryDelay пауза после ошибки чтения ленты
+	feedRetryDelay = time.Second
+	// feedPublishTimeout время записи события в ленту
+	feedPublishTimeout = 2 * time.Second
+	// feedSubscriberBuffer количество событий, которые ждут отправки подписчику.
+	// Подписчик, не успевающий их забирать, отключается и переподключается с Last-Event-ID
+	feedSubscriberBuffer = 256
+)
+
+// ChangeFeed лента изменений документов.
+// События пишутся в общий Redis Stream, каждый экземпляр сервиса читает его
+// и рассылает события своим подписчикам, которым документ доступен для чтения.
+// Без потока (Redis недоступен при запуске) лента отключена: события не публикуются, подписка недоступна
+type ChangeFeed struct {
+	stream cache.ChangeStream
+
+	mu          sync.Mutex
+	subscribers map[*feedSubscriber]struct{}
+}
+
+type feedSubscriber struct {
+	login  string
+	events chan entity.ChangeEvent
+}
+
+func NewChangeFeed(stream cache.ChangeStream) *ChangeFeed {
+	return &ChangeFeed{
+		stream:      stream,
+		subscribers: make(map[*feedSubscriber]struct{}),
+	}
+}
+
+// Publish добавляет событие документа в ленту.
+// Ошибки только логируются: сбой ленты не должен влиять на изменение документа
+func (f *ChangeFeed) Publish(eventType string, metaDoc model.MetaDocument) {
+	if f.stream == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), feedPublishTimeout)
+	defer cancel()
+
+	event := entity.ChangeEvent{
+		Type:       eventType,
+		DocumentID: metaDoc.UUID,
+		Document:   metaDoc,
+		OccurredAt: time.Now().UTC(),
+	}
+
+	if _, err := f.stream.Append(ctx, event); err != nil {
+		log.Errorf("failed to publish change of document [%s]: %+v", metaDoc.UUID, err)
+	}
+}
+
+// Run читает новые события ленты и рассылает их подписчикам до отмены контекста
+func (f *ChangeFeed) Run(ctx context.Context) {
+	if f.stream == nil {
+		log.Warn("change feed is disabled: change stream is not available")
+		return
+	}
+
+	lastID := ""
+
+	for ctx.Err() == nil {
+		if lastID == "" {
+			id, err := f.stream.LastID(ctx)
+			if err != nil {
+				log.Errorf("failed to get last change event: %+v", err)
+				sleep(ctx, feedRetryDelay)
+				continue
+			}
+
+			lastID = id
+		}
+
+		events, err := f.stream.Read(ctx, lastID, feedBatchSize, feedReadBlock)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Errorf("failed to read change events: %+v", err)
+				sleep(ctx, feedRetryDelay)
+			}
+			continue
+		}
+
+		for _, event := range events {
+			lastID = event.ID
+			f.broadcast(event)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for subscriber := range f.subscribers {
+		f.unsubscribe(subscriber)
+	}
+}
+
+// Subscribe подписывает пользователя на события доступных ему документов.
+// Если передан lastEventID, сначала отправляются пропущенные события, которые еще хранятся в ленте.
+// Канал закрывается при отмене контекста или если подписчик не успевает забирать события
+func (f *ChangeFeed) Subscribe(ctx context.Context, login, lastEventID string) (<-chan entity.ChangeEvent, error) {
+	if f.stream == nil {
+		return nil, custom_error.ErrFeedUnavailable
+	}
+
+	subscriber := &feedSubscriber{
+		login:  login,
+		events: make(chan entity.ChangeEvent, feedSubscriberBuffer),
+	}
+
+	// подписка оформляется до чтения пропущенных событий, чтобы не потерять события между ними
+	f.mu.Lock()
+	f.subscribers[subscriber] = struct{}{}
+	f.mu.Unlock()
+
+	var missed []entity.ChangeEvent
+
+	for afterID := lastEventID; afterID != ""; {
+		events, err := f.stream.Range(ctx, afterID, feedBatchSize)
+		if err != nil {
+			f.remove(subscriber)
+			return nil, err
+		}
+
+		for _, event := range events {
+			if event.Document.CanRead(login) {
+				missed = append(missed, event)
+			}
+		}
+
+		afterID = ""
+		if len(events) == feedBatchSize {
+			afterID = events[len(events)-1].ID
+		}
+	}
+
+	out := make(chan entity.ChangeEvent)
+
+	go func() {
+		defer close(out)
+		defer f.remove(subscriber)
+
+		lastID := lastEventID
+
+		for _, event := range missed {
+			select {
+			case out <- event:
+				lastID = event.ID
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for {
+			select {
+			case event, ok := <-subscriber.events:
+				if !ok {
+					return
+				}
+
+				// события, уже отправленные из ленты, не повторяются
+				if lastID != "" && !entity.ChangeEventAfter(event.ID, lastID) {
+					continue
+				}
+
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (f *ChangeFeed) broadcast(event entity.ChangeEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for subscriber := range f.subscribers {
+		if !event.Document.CanRead(subscriber.login) {
+			continue
+		}
+
+		select {
+		case subscriber.events <- event:
+		default:
+			log.Warnf("change feed subscriber [%s] is too slow, disconnecting", subscriber.login)
+			f.unsubscribe(subscriber)
+		}
+	}
+}
+
+func (f *ChangeFeed) remove(subscriber *feedSubscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.unsubscribe(subscriber)
+}
+
+// unsubscribe вызывается под f.mu
+func (f *ChangeFeed) unsubscribe(subscriber *feedSubscriber) {
+	if _, ok := f.subscribers[subscriber]; !ok {
+		return
+	}
+
+	delete(f.subscribers, subscriber)
+	close(subscriber.events)
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/encryption"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+)
+
+// keyRotationBatch количество ключей данных, читаемых за один проход
+const keyRotationBatch = 100
+
+// KeyRotator перешифровывает ключи данных документов активным мастер-ключом.
+// Содержимое документов не перезаписывается: меняются только зашифрованные ключи в метаданных.
+// Ключ, который не удалось перешифровать, остается прежним, поэтому команду можно запустить снова
+type KeyRotator struct {
+	repo    postgres.EncryptionKeys
+	keyring *encryption.Keyring
+}
+
+func NewKeyRotator(repo postgres.EncryptionKeys, keyring *encryption.Keyring) *KeyRotator {
+	return &KeyRotator{
+		repo:    repo,
+		keyring: keyring,
+	}
+}
+
+// Rotate перешифровывает все ключи данных, зашифрованные не активным мастер-ключом
+func (r *KeyRotator) Rotate(ctx context.Context) (entity.KeyRotationReport, error) {
+	report := entity.KeyRotationReport{
+		StartedAt: time.Now().UTC(),
+		KeyID:     r.keyring.ActiveKeyID(),
+	}
+
+	if !r.keyring.Enabled() {
+		return report, errors.New("active encryption master key is not set")
+	}
+
+	log.Infof("key rotation to master key [%s] started", report.KeyID)
+
+	afterKey := ""
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		keys, err := r.repo.GetStaleDataKeys(report.KeyID, afterKey, keyRotationBatch)
+		if err != nil {
+			return report, err
+		}
+
+		if len(keys) == 0 {
+			break
+		}
+
+		for _, dataKey := range keys {
+			afterKey = dataKey.WrappedKey
+
+			rewrapped, err := r.keyring.Rewrap(dataKey)
+			if err != nil {
+				log.Errorf("failed to rewrap data key of master key [%s]: %+v", dataKey.KeyID, err)
+				report.Failed++
+
+				continue
+			}
+
+			if err = r.repo.ReplaceDataKey(dataKey, rewrapped); err != nil {
+				log.Errorf("failed to save rewrapped data key: %+v", err)
+				report.Failed++
+
+				continue
+			}
+
+			report.Rewrapped++
+		}
+	}
+
+	report.FinishedAt = time.Now().UTC()
+
+	log.Infof("key rotation finished: %d rewrapped, %d failed", report.Rewrapped, report.Failed)
+
+	return report, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/broker"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// outboxCleanupInterval период удаления опубликованных событий
+const outboxCleanupInterval = time.Hour
+
+// OutboxRelay публикует события из outbox в брокер.
+// Событие отмечается опубликованным только после подтверждения брокера, поэтому доставка
+// выполняется хотя бы один раз: после сбоя событие может прийти повторно с тем же идентификатором
+type OutboxRelay struct {
+	cfg    *config.ConfigBroker
+	outbox postgres.Outbox
+	sender broker.Sender
+}
+
+func NewOutboxRelay(cfg *config.Config, outbox postgres.Outbox, sender broker.Sender) *OutboxRelay {
+	return &OutboxRelay{
+		cfg:    cfg.ConfigBroker,
+		outbox: outbox,
+		sender: sender,
+	}
+}
+
+// Run публикует события с периодом OutboxPollInterval до отмены контекста
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.OutboxPollInterval)
+	defer ticker.Stop()
+
+	cleanup := time.NewTicker(outboxCleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.relay(ctx)
+		case <-cleanup.C:
+			r.cleanup()
+		}
+	}
+}
+
+// relay публикует накопившиеся события пачками, пока пачки заполнены целиком
+func (r *OutboxRelay) relay(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := r.outbox.PublishPending(r.cfg.OutboxBatchSize, func(event model.OutboxEvent) error {
+			return r.publish(ctx, event)
+		})
+		if err != nil {
+			log.Errorf("failed to relay outbox events: %+v", err)
+			return
+		}
+
+		if published < r.cfg.OutboxBatchSize {
+			return
+		}
+	}
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, event model.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.PublishTimeout)
+	defer cancel()
+
+	return r.sender.Send(ctx, broker.Message{
+		ID:      event.UUID,
+		Subject: r.cfg.Subject(event.Type),
+		Data:    []byte(event.Payload),
+	})
+}
+
+func (r *OutboxRelay) cleanup() {
+	deleted, err := r.outbox.DeletePublished(time.Now().Add(-r.cfg.OutboxRetention))
+	if err != nil {
+		log.Errorf("failed to clean up outbox: %+v", err)
+		return
+	}
+
+	if deleted > 0 {
+		log.Infof("%d published outbox events deleted", deleted)
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io"
+	"mime"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/compression"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	searchWorkers   = 4
+	searchQueueSize = 256
+
+	// maxIndexedText ограничивает объем текста одного документа в индексе
+	maxIndexedText = 256 << 10
+)
+
+// searchTask задача индексации документа, remove означает удаление документа из индекса
+type searchTask struct {
+	uuid   string
+	remove bool
+}
+
+// SearchIndexer асинхронно обновляет полнотекстовый индекс документов.
+// Задачи одного документа всегда попадают в одну очередь и выполняются по порядку
+type SearchIndexer struct {
+	ctx        context.Context
+	repository repository.DocumentRepository
+	queues     []chan searchTask
+}
+
+func NewSearchIndexer(repo repository.DocumentRepository) *SearchIndexer {
+	indexer := &SearchIndexer{
+		ctx:        context.Background(),
+		repository: repo,
+		queues:     make([]chan searchTask, searchWorkers),
+	}
+
+	for i := range indexer.queues {
+		indexer.queues[i] = make(chan searchTask, searchQueueSize)
+		go indexer.worker(indexer.queues[i])
+	}
+
+	return indexer
+}
+
+// Index ставит документ в очередь на индексацию текущего содержимого
+func (s *SearchIndexer) Index(uuid string) {
+	s.enqueue(searchTask{uuid: uuid})
+}
+
+// Remove ставит в очередь удаление документа из индекса
+func (s *SearchIndexer) Remove(uuid string) {
+	s.enqueue(searchTask{uuid: uuid, remove: true})
+}
+
+func (s *SearchIndexer) enqueue(task searchTask) {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(task.uuid))
+
+	select {
+	case s.queues[hash.Sum32()%uint32(len(s.queues))] <- task:
+	default:
+		log.Warnf("search index queue is full, document [%s] skipped", task.uuid)
+	}
+}
+
+func (s *SearchIndexer) worker(tasks <-chan searchTask) {
+	for task := range tasks {
+		if err := s.process(task); err != nil {
+			log.Errorf("failed to update search index of document [%s]: %+v", task.uuid, err)
+		}
+	}
+}
+
+func (s *SearchIndexer) process(task searchTask) error {
+	if task.remove {
+		return s.repository.DeleteDocumentIndex(task.uuid)
+	}
+
+	metaDoc, err := s.repository.GetById(task.uuid)
+	if errors.Is(err, custom_error.ErrDocumentNotFound) {
+		return s.repository.DeleteDocumentIndex(task.uuid)
+	}
+	if err != nil {
+		return err
+	}
+
+	text, err := s.documentText(metaDoc)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.IndexDocument(model.DocumentSearch{
+		DocumentUUID: metaDoc.UUID,
+		Name:         sanitizeText(metaDoc.Name),
+		Body:         sanitizeText(text),
+	})
+}
+
+// documentText извлекает текст документа: строковые значения JSON документа
+// или содержимое текстового файла. Для остальных файлов индексируется только имя.
+// Индекс хранится без шифрования, поэтому у зашифрованных документов тоже индексируется только имя
+func (s *SearchIndexer) documentText(metaDoc model.MetaDocument) (string, error) {
+	if metaDoc.Encrypted() {
+		return "", nil
+	}
+
+	if !metaDoc.File {
+		content, err := s.repository.GetByDocumentId(s.ctx, metaDoc.StorageKey())
+		if err != nil {
+			return "", err
+		}
+
+		delete(content, "_id")
+
+		return jsonText(content)
+	}
+
+	if !isTextMime(metaDoc.Mime) {
+		return "", nil
+	}
+
+	file, _, err := s.repository.Download(s.ctx, metaDoc.StorageKey())
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// файл хранится сжатым, если для документа записан алгоритм сжатия
+	text, err := compression.NewReader(metaDoc.Encoding, file)
+	if err != nil {
+		return "", err
+	}
+	defer text.Close()
+
+	data, err := io.ReadAll(io.LimitReader(text, maxIndexedText))
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// isTextMime сообщает, можно ли индексировать содержимое файла как текст
+func isTextMime(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" ||
+		strings.HasSuffix(mediaType, "+json") ||
+		mediaType == "application/csv" ||
+		mediaType == "application/x-ndjson"
+}
+
+// jsonText собирает строковые значения JSON документа через пробел
+func jsonText(content map[string]interface{}) (string, error) {
+	// повторный разбор приводит типы MongoDB к стандартным типам JSON
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+
+	var value interface{}
+	if err = json.Unmarshal(data, &value); err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	collectStrings(value, &builder)
+
+	return builder.String(), nil
+}
+
+func collectStrings(value interface{}, builder *strings.Builder) {
+	if builder.Len() >= maxIndexedText {
+		return
+	}
+
+	switch v := value.(type) {
+	case string:
+		builder.WriteString(v)
+		builder.WriteByte(' ')
+	case map[string]interface{}:
+		for _, item := range v {
+			collectStrings(item, builder)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectStrings(item, builder)
+		}
+	}
+}
+
+// sanitizeText обрезает текст до допустимого объема и удаляет символы,
+// которые нельзя сохранить в текстовом поле Postgres
+func sanitizeText(text string) string {
+	if len(text) > maxIndexedText {
+		text = text[:maxIndexedText]
+	}
+
+	text = strings.ToValidUTF8(text, " ")
+
+	return strings.ReplaceAll(text, "\x00", " ")
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	filestorage "github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/file_storage"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+// uploadCleanupBatchSize количество загрузок, удаляемых за один проход
+const uploadCleanupBatchSize = 100
+
+// UploadCleaner удаляет загрузки, срок которых истек, вместе с записанными частями и собранным или загруженным клиентом файлом
+type UploadCleaner struct {
+	cfg     *config.ConfigUpload
+	repo    postgres.Upload
+	storage filestorage.MultipartRepository
+}
+
+func NewUploadCleaner(cfg *config.Config, repo postgres.Upload, storage filestorage.MultipartRepository) *UploadCleaner {
+	return &UploadCleaner{
+		cfg:     cfg.ConfigUpload,
+		repo:    repo,
+		storage: storage,
+	}
+}
+
+// Run удаляет загрузки с истекшим сроком с периодом CleanupInterval до отмены контекста
+func (c *UploadCleaner) Run(ctx context.Context) {
+	if c.storage == nil {
+		return
+	}
+
+	ticker := time.NewTicker(c.cfg.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.cleanup(ctx)
+	}
+}
+
+func (c *UploadCleaner) cleanup(ctx context.Context) {
+	now := time.Now()
+
+	uploads, err := c.repo.GetExpiredUploads(now, uploadCleanupBatchSize)
+	if err != nil {
+		log.Errorf("failed to clean up expired uploads: %+v", err)
+		return
+	}
+
+	holder := uuid.NewString()
+	removed := 0
+
+	for _, upload := range uploads {
+		// загрузку мог занять запрос, пришедший после выборки, или очистка на другом экземпляре сервиса
+		locked, err := c.repo.LockUpload(upload.UUID, holder, now.Add(model.UploadLockTTL))
+		if err != nil || !locked {
+			continue
+		}
+
+		if err = c.Discard(ctx, upload); err != nil {
+			log.Errorf("failed to clean up expired upload [%s]: %+v", upload.UUID, err)
+			continue
+		}
+
+		removed++
+	}
+
+	if removed > 0 {
+		log.Infof("%d expired uploads cleaned up", removed)
+	}
+}
+
+// Discard отменяет multipart загрузку, удаляет собранный или загруженный клиентом файл и саму загрузку.
+// Документ, уже созданный из загрузки, не удаляется
+func (c *UploadCleaner) Discard(ctx context.Context, upload model.Upload) error {
+	if upload.MultipartID != "" && !upload.Assembled {
+		if err := c.storage.AbortMultipart(ctx, upload.StorageKey(), upload.MultipartID); err != nil {
+			return err
+		}
+	}
+
+	// файл прямой загрузки может быть записан клиентом в любой момент до истечения ссылки,
+	// удаление отсутствующего файла ошибкой не считается
+	if upload.Assembled || upload.Direct {
+		if err := c.storage.Delete(ctx, upload.StorageKey()); err != nil {
+			return err
+		}
+	}
+
+	return c.repo.DeleteUpload(upload.UUID)
+}
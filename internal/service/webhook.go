@@ -0,0 +1,282 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+	webhookTimestampHeader = "X-Webhook-Timestamp"
+	webhookSignatureHeader = "X-Webhook-Signature"
+
+	// maxWebhookError ограничивает размер текста ошибки в журнале доставок
+	maxWebhookError = 1024
+)
+
+// WebhookDispatcher ставит события документов в очередь доставки подписчикам и доставляет их.
+// Очередь хранится в Postgres, поэтому доставки переживают перезапуск сервиса
+type WebhookDispatcher struct {
+	cfg    *config.ConfigWebhook
+	repo   postgres.Webhook
+	client *http.Client
+	wake   chan struct{}
+}
+
+func NewWebhookDispatcher(cfg *config.Config, repo postgres.Webhook) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		cfg:  cfg.ConfigWebhook,
+		repo: repo,
+		client: &http.Client{
+			Timeout:   cfg.ConfigWebhook.Timeout,
+			Transport: newWebhookTransport(),
+			// перенаправление меняет получателя события, поэтому не выполняется
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// newWebhookTransport создает транспорт доставок, который соединяется только с публичными адресами.
+// Адрес проверяется при установке соединения, уже после разрешения имени, поэтому получателя
+// нельзя направить во внутреннюю сеть и подменой DNS-записи после регистрации webhook.
+// Прокси из окружения не используется: через него проверялся бы адрес прокси, а не получателя
+func newWebhookTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || !entity.PublicIP(ip) {
+				return fmt.Errorf("webhook address %s is not allowed", host)
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return transport
+}
+
+// Notify создает доставки события подпискам владельца документа и пользователей, которым выдан доступ.
+// Ошибки только логируются: сбой доставки не должен влиять на изменение документа
+func (d *WebhookDispatcher) Notify(eventType string, metaDoc model.MetaDocument) {
+	logins := append([]string{metaDoc.Owner}, metaDoc.Grant...)
+
+	subscriptions, err := d.repo.GetSubscribers(logins, eventType)
+	if err != nil {
+		log.Errorf("failed to get webhook subscribers of document [%s]: %+v", metaDoc.UUID, err)
+		return
+	}
+
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	event := model.NewOutboxEvent(eventType, metaDoc)
+	now := time.Now()
+
+	deliveries := make([]model.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, model.WebhookDelivery{
+			UUID:             uuid.NewString(),
+			SubscriptionUUID: subscription.UUID,
+			EventID:          event.UUID,
+			EventType:        eventType,
+			DocumentUUID:     metaDoc.UUID,
+			Payload:          event.Payload,
+			Status:           model.WebhookDeliveryPending,
+			NextAttemptAt:    now,
+		})
+	}
+
+	if err = d.repo.CreateDeliveries(deliveries); err != nil {
+		log.Errorf("failed to queue webhook deliveries of document [%s]: %+v", metaDoc.UUID, err)
+		return
+	}
+
+	d.Wake()
+}
+
+// Wake запускает внеочередную проверку доставок
+func (d *WebhookDispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run доставляет события с периодом PollInterval до отмены контекста
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+
+		d.dispatch(ctx)
+	}
+}
+
+// dispatch доставляет пачку доставок, срок отправки которых наступил
+func (d *WebhookDispatcher) dispatch(ctx context.Context) {
+	// доставка забирается на время, за которое гарантированно завершится попытка
+	lease := 2 * d.cfg.Timeout
+
+	deliveries, err := d.repo.ClaimDeliveries(d.cfg.Workers*4, lease)
+	if err != nil {
+		log.Errorf("failed to claim webhook deliveries: %+v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, d.cfg.Workers)
+
+	for i := range deliveries {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(delivery *model.WebhookDelivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			d.deliver(ctx, delivery)
+		}(&deliveries[i])
+	}
+
+	wg.Wait()
+}
+
+// deliver выполняет попытку доставки и сохраняет ее результат.
+// После неудачной попытки следующая откладывается с экспоненциальной задержкой,
+// после MaxAttempts попыток доставка переходит в состояние dead
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	delivery.Attempts++
+
+	status, err := d.send(ctx, delivery)
+
+	delivery.ResponseStatus = status
+	delivery.Error = ""
+
+	switch {
+	case err == nil:
+		now := time.Now()
+
+		delivery.Status = model.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		log.Errorf("webhook delivery [%s] failed after %d attempts: %+v", delivery.UUID, delivery.Attempts, err)
+
+		delivery.Status = model.WebhookDeliveryDead
+		delivery.Error = truncate(err.Error(), maxWebhookError)
+	default:
+		log.Warnf("webhook delivery [%s] attempt %d failed: %+v", delivery.UUID, delivery.Attempts, err)
+
+		delivery.Status = model.WebhookDeliveryRetrying
+		delivery.Error = truncate(err.Error(), maxWebhookError)
+		delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+	}
+
+	if err = d.repo.UpdateDelivery(delivery); err != nil {
+		log.Errorf("failed to save webhook delivery [%s]: %+v", delivery.UUID, err)
+	}
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, delivery *model.WebhookDelivery) (int, error) {
+	if delivery.Subscription == nil {
+		return 0, fmt.Errorf("webhook [%s] not found", delivery.SubscriptionUUID)
+	}
+
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, delivery.EventType)
+	req.Header.Set(webhookDeliveryHeader, delivery.UUID)
+	req.Header.Set(webhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhookSignatureHeader, SignWebhook(delivery.Subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	backoff := d.cfg.RetryBackoff
+	for i := 1; i < attempts && backoff < d.cfg.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, d.cfg.MaxBackoff)
+}
+
+// SignWebhook возвращает подпись тела webhook: HMAC-SHA256 строки "<timestamp>.<body>" на секрете подписки
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+
+	return s[:limit]
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"event":"document.created"}`)
+
+	// подпись вычислена независимо: HMAC-SHA256 строки "1760788800.<body>" на ключе secret
+	want := "sha256=04bc9db382781535275c30c1271a5d326b007afc65facb672bf069749ab629a0"
+	if got := SignWebhook("secret", 1760788800, body); got != want {
+		t.Fatalf("SignWebhook() = %s, want %s", got, want)
+	}
+
+	if SignWebhook("secret", 1760788801, body) == want {
+		t.Error("signature does not depend on timestamp")
+	}
+
+	if SignWebhook("other", 1760788800, body) == want {
+		t.Error("signature does not depend on secret")
+	}
+
+	if SignWebhook("secret", 1760788800, []byte(`{"event":"document.deleted"}`)) == want {
+		t.Error("signature does not depend on body")
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	d := &WebhookDispatcher{cfg: &config.ConfigWebhook{RetryBackoff: 10 * time.Second, MaxBackoff: time.Minute}}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 10 * time.Second},
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 4, want: time.Minute},
+		{attempts: 100, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempts), func(t *testing.T) {
+			if got := d.backoff(tt.attempts); got != tt.want {
+				t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	var received *http.Request
+
+	status := http.StatusNoContent
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	delivery := &model.WebhookDelivery{
+		UUID:      "delivery-1",
+		EventType: model.EventDocumentCreated,
+		Payload:   `{"uuid":"doc-1"}`,
+		Subscription: &model.WebhookSubscription{
+			URL:    server.URL,
+			Secret: "secret",
+		},
+	}
+
+	// тестовый сервер слушает loopback, поэтому проверка адресов получателя здесь не используется
+	d := &WebhookDispatcher{cfg: &config.ConfigWebhook{}, client: server.Client()}
+
+	if _, err := d.send(context.Background(), delivery); err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp, err := strconv.ParseInt(received.Header.Get(webhookTimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := received.Header.Get(webhookSignatureHeader), SignWebhook("secret", timestamp, []byte(delivery.Payload)); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+
+	if received.Header.Get(webhookEventHeader) != delivery.EventType || received.Header.Get(webhookDeliveryHeader) != delivery.UUID {
+		t.Errorf("headers = %v", received.Header)
+	}
+
+	status = http.StatusInternalServerError
+
+	if code, err := d.send(context.Background(), delivery); err == nil || code != status {
+		t.Errorf("send() = %d, %v, want error with status %d", code, err, status)
+	}
+}
+
+func TestWebhookTransportRejectsLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached loopback address")
+	}))
+	defer server.Close()
+
+	d := NewWebhookDispatcher(&config.Config{ConfigWebhook: &config.ConfigWebhook{Timeout: time.Second}}, nil)
+
+	delivery := &model.WebhookDelivery{
+		Payload:      `{}`,
+		Subscription: &model.WebhookSubscription{URL: server.URL},
+	}
+
+	if _, err := d.send(context.Background(), delivery); err == nil {
+		t.Error("delivery to loopback address succeeded")
+	}
+}
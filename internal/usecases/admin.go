@@ -0,0 +1,65 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/saga"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+var _ Admin = (*AdminUsecase)(nil)
+
+type AdminUsecase struct {
+	Ctx          context.Context
+	Cfg          *config.Config
+	SagaLog      postgres.SagaLog
+	Orchestrator saga.Orchestrator
+}
+
+func NewAdminUsecase(cfg *config.Config, sagaLog postgres.SagaLog, orchestrator saga.Orchestrator) *AdminUsecase {
+	return &AdminUsecase{
+		Ctx:          context.Background(),
+		Cfg:          cfg,
+		SagaLog:      sagaLog,
+		Orchestrator: orchestrator,
+	}
+}
+
+// GetStuckSagas возвращает саги, которые не удалось восстановить,
+// и незавершенные саги, которые не обновлялись дольше SAGA_STALE_AFTER
+func (u *AdminUsecase) GetStuckSagas(req entity.SagaListRequest) (entity.SagaList, error) {
+	if err := req.Validate(); err != nil {
+		return entity.SagaList{}, err
+	}
+
+	before := time.Now().Add(-u.Cfg.StaleAfter)
+
+	sagas, total, err := u.SagaLog.GetStuck(before, req.Limit, req.Offset)
+	if err != nil {
+		return entity.SagaList{}, err
+	}
+
+	return entity.SagaList{Sagas: sagas, Total: total}, nil
+}
+
+func (u *AdminUsecase) GetSaga(uuid string) (entity.SagaDetails, error) {
+	sagaLog, err := u.SagaLog.Get(uuid)
+	if err != nil {
+		return entity.SagaDetails{}, err
+	}
+
+	steps, err := u.SagaLog.GetSteps(uuid)
+	if err != nil {
+		return entity.SagaDetails{}, err
+	}
+
+	return entity.SagaDetails{Saga: sagaLog, Steps: steps}, nil
+}
+
+func (u *AdminUsecase) RetrySaga(uuid string) (model.Saga, error) {
+	return u.Orchestrator.RetrySaga(u.Ctx, uuid)
+}
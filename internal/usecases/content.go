@@ -0,0 +1,99 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const (
+	// minContentBatch минимальный размер пачки содержимого, читаемой из MongoDB за один проход
+	minContentBatch = 50
+	// maxContentBatches ограничивает число проходов за один запрос, если большая часть найденного
+	// содержимого недоступна пользователю, относится к старым версиям документов или не подходит
+	// под условия после расшифровки. Запрос просматривает не больше maxContentBatches пачек
+	maxContentBatches = 10
+)
+
+// SearchContent ищет JSON документы по содержимому.
+// Содержимое читается из MongoDB пачками в порядке ключей и соединяется с метаданными документов,
+// доступных пользователю. Содержимое старых версий в выдачу не попадает, так как
+// метаданные ссылаются только на текущее содержимое
+func (t *DocumentUsecase) SearchContent(ctx context.Context, query entity.ContentQuery) (entity.ContentSearchResult, error) {
+	result := entity.ContentSearchResult{
+		Docs: make([]entity.ContentSearchItem, 0, query.Limit),
+	}
+
+	if err := query.Validate(); err != nil {
+		return result, err
+	}
+
+	afterKey, err := query.AfterKey()
+	if err != nil {
+		return result, err
+	}
+
+	batchSize := max(query.Limit*2, minContentBatch)
+
+	for range maxContentBatches {
+		contents, next, err := t.DocumentRepository.Find(ctx, query.Where, query.Fields, afterKey, batchSize)
+		if err != nil {
+			return result, err
+		}
+
+		keys := make([]string, 0, len(contents))
+		for _, content := range contents {
+			if key, ok := content["_id"].(string); ok {
+				keys = append(keys, key)
+			}
+		}
+
+		documents, err := t.DocumentRepository.GetReadableByStorageKeys(query.Viewer, keys)
+		if err != nil {
+			return result, err
+		}
+
+		metaByKey := make(map[string]model.MetaDocument, len(documents))
+		for _, document := range documents {
+			metaByKey[document.StorageKey()] = document
+		}
+
+		for _, content := range contents {
+			key, ok := content["_id"].(string)
+			if !ok {
+				continue
+			}
+
+			afterKey = key
+
+			metaDoc, ok := metaByKey[key]
+			if !ok {
+				continue
+			}
+
+			delete(content, "_id")
+
+			result.Docs = append(result.Docs, entity.ContentSearchItem{
+				Meta: metaDoc,
+				Json: content,
+			})
+
+			if len(result.Docs) == query.Limit {
+				result.NextCursor = entity.NewContentCursor(key)
+				return result, nil
+			}
+		}
+
+		if next == "" {
+			return result, nil
+		}
+
+		afterKey = next
+	}
+
+	// страница заполнена не полностью, поиск продолжится с последнего просмотренного ключа
+	result.NextCursor = entity.NewContentCursor(afterKey)
+
+	return result, nil
+}
@@ -1,84 +1,320 @@
 package usecases
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/google/uuid"
 
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
 	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
 	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
 	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/cache"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/compression"
+	filestorage "github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/file_storage"
 	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/saga"
 	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+	"github.com/AlexJudin/DocumentCacheServer/internal/service"
 )
 
 var _ Document = (*DocumentUsecase)(nil)
 
 type DocumentUsecase struct {
-	Ctx                context.Context
+	Cfg                *config.Config
 	DocumentRepository repository.DocumentRepository
+	Presigner          filestorage.Presigner
 	Cache              cache.Document
+	SearchIndexer      *service.SearchIndexer
+	Webhooks           *service.WebhookDispatcher
+	ChangeFeed         *service.ChangeFeed
 	sagaOrchestrator   saga.Orchestrator
 }
 
-func NewDocumentUsecase(docRepo repository.DocumentRepository, cache cache.Document, sagaOrchestrator *saga.DocumentOrchestrator) *DocumentUsecase {
+func NewDocumentUsecase(cfg *config.Config, docRepo repository.DocumentRepository, presigner filestorage.Presigner, cache cache.Document, sagaOrchestrator saga.Orchestrator, searchIndexer *service.SearchIndexer, webhooks *service.WebhookDispatcher, changeFeed *service.ChangeFeed) *DocumentUsecase {
 	return &DocumentUsecase{
-		Ctx:                context.Background(),
+		Cfg:                cfg,
 		DocumentRepository: docRepo,
+		Presigner:          presigner,
 		Cache:              cache,
+		SearchIndexer:      searchIndexer,
+		Webhooks:           webhooks,
+		ChangeFeed:         changeFeed,
 		sagaOrchestrator:   sagaOrchestrator,
 	}
 }
 
-func (t *DocumentUsecase) SaveDocument(document *entity.Document) error {
-	uuidDoc := uuid.New().String()
+func (t *DocumentUsecase) SaveDocument(ctx context.Context, login string, document *entity.Document) error {
+	uuidDoc := cmp.Or(document.ReservedUUID, uuid.New().String())
 
 	document.Meta.UUID = uuidDoc
-
-	err := t.sagaOrchestrator.SaveDocument(t.Ctx, document)
-	if err != nil {
-		return err
-	}
+	document.Meta.Owner = login
 
 	if document.Meta.File {
-		go t.Cache.Set(t.Ctx, uuidDoc, document.Meta.Mime, document.File.Content, true)
+		// небольшие файлы попутно копируем в буфер, чтобы положить их в кэш,
+		// большие проходят в хранилище потоком без накопления в памяти.
+		// Файл, уже записанный в хранилище, через сервер не проходит и в кэш не попадает
+		var cached *cacheBuffer
+
+		if document.File.StagedKey == "" {
+			cached = newCacheBuffer(t.Cfg.MaxFileSize)
+			document.File.Content = io.TeeReader(document.File.Content, cached)
+		}
+
+		err := t.sagaOrchestrator.SaveDocument(sagaContext(ctx), document)
+		if err != nil {
+			return err
+		}
+
+		if cached != nil && !cached.overflow {
+			go t.Cache.Set(context.WithoutCancel(ctx), *document.Meta, cached.Bytes())
+		}
+
+		t.SearchIndexer.Index(uuidDoc)
+		t.notify(model.EventDocumentCreated, *document.Meta)
 
 		return nil
 	}
 
-	go t.Cache.Set(t.Ctx, uuidDoc, document.Meta.Mime, document.Json, false)
+	err := t.sagaOrchestrator.SaveDocument(sagaContext(ctx), document)
+	if err != nil {
+		return err
+	}
+
+	go t.Cache.Set(context.WithoutCancel(ctx), *document.Meta, document.Json)
+
+	t.SearchIndexer.Index(uuidDoc)
+	t.notify(model.EventDocumentCreated, *document.Meta)
 
 	return nil
 }
 
-func (t *DocumentUsecase) GetDocumentsList(req entity.DocumentListRequest) ([]model.MetaDocument, error) {
+func (t *DocumentUsecase) GetDocumentsList(req entity.DocumentListRequest) (entity.DocumentList, error) {
+	if err := req.Validate(); err != nil {
+		return entity.DocumentList{}, err
+	}
+
 	return t.DocumentRepository.GetList(req)
 }
 
-func (t *DocumentUsecase) GetDocumentById(uuid string) ([]byte, string, error) {
-	data, mime, ok := t.Cache.Get(t.Ctx, uuid)
+func (t *DocumentUsecase) CountDocuments(req entity.DocumentListRequest) (int64, error) {
+	if err := req.Validate(); err != nil {
+		return 0, err
+	}
+
+	return t.DocumentRepository.Count(req)
+}
+
+// GetDocumentById возвращает содержимое документа. Сжатое содержимое отдается без распаковки,
+// если клиент принимает его алгоритм сжатия по заголовку Accept-Encoding
+func (t *DocumentUsecase) GetDocumentById(ctx context.Context, login, uuid, acceptEncoding string) (entity.DocumentContent, error) {
+	data, cachedDoc, ok := t.Cache.Get(ctx, uuid)
 	if ok {
-		return data, mime, nil
+		if !cachedDoc.CanRead(login) {
+			return entity.DocumentContent{}, custom_error.ErrDocumentNotFound
+		}
+
+		return decodeContent(newDocumentContent(data, cachedDoc), cachedDoc.Size, acceptEncoding)
+	}
+
+	metaDoc, err := t.authorize(login, uuid, false)
+	if err != nil {
+		return entity.DocumentContent{}, err
+	}
+
+	content, err := t.readContent(ctx, metaDoc)
+	if err != nil {
+		return entity.DocumentContent{}, err
+	}
+
+	return decodeContent(content, metaDoc.Size, acceptEncoding)
+}
+
+func (t *DocumentUsecase) UpdateDocument(ctx context.Context, login, uuid string, document *entity.Document) error {
+	_, err := t.authorize(login, uuid, true)
+	if err != nil {
+		return err
+	}
+
+	document.Meta.UUID = uuid
+
+	err = t.sagaOrchestrator.UpdateDocument(sagaContext(ctx), document)
+	if err != nil {
+		return err
+	}
+
+	t.Cache.Delete(context.WithoutCancel(ctx), uuid)
+	t.SearchIndexer.Index(uuid)
+	t.notify(model.EventDocumentUpdated, *document.Meta)
+
+	return nil
+}
+
+func (t *DocumentUsecase) PatchDocument(ctx context.Context, login, uuid, patchType string, patch []byte) error {
+	metaDoc, err := t.authorize(login, uuid, true)
+	if err != nil {
+		return err
+	}
+
+	if metaDoc.File {
+		return custom_error.ErrDocumentNotJson
+	}
+
+	jsonDocMap, err := t.DocumentRepository.GetByDocumentId(ctx, metaDoc.StorageKey())
+	if err != nil {
+		return err
+	}
+
+	// служебный идентификатор MongoDB не является частью содержимого документа
+	delete(jsonDocMap, "_id")
+
+	original, err := json.Marshal(jsonDocMap)
+	if err != nil {
+		return err
+	}
+
+	modified, err := applyPatch(patchType, original, patch)
+	if err != nil {
+		return err
+	}
+
+	patchedDoc := make(map[string]interface{})
+	if err = json.Unmarshal(modified, &patchedDoc); err != nil {
+		return fmt.Errorf("%w: %w", custom_error.ErrPatchFailed, err)
+	}
+
+	document := &entity.Document{
+		Meta: &metaDoc,
+		Json: patchedDoc,
+	}
+
+	return t.UpdateDocument(ctx, login, uuid, document)
+}
+
+func (t *DocumentUsecase) DeleteDocumentById(ctx context.Context, login, uuid string) error {
+	metaDoc, err := t.authorize(login, uuid, true)
+	if err != nil {
+		return err
+	}
+
+	err = t.sagaOrchestrator.DeleteDocument(sagaContext(ctx), uuid)
+	if err != nil {
+		return err
+	}
+
+	go t.Cache.Delete(context.WithoutCancel(ctx), uuid)
+	t.SearchIndexer.Remove(uuid)
+	t.notify(model.EventDocumentDeleted, metaDoc)
+
+	return nil
+}
+
+// GetDownloadURL возвращает подписанную ссылку, по которой клиент скачивает файл документа из хранилища.
+// Зашифрованный файл отдается только через сервер, сжатый - если клиент принимает его алгоритм сжатия
+func (t *DocumentUsecase) GetDownloadURL(ctx context.Context, login, uuid, acceptEncoding string) (entity.PresignedURL, error) {
+	if t.Presigner == nil {
+		return entity.PresignedURL{}, custom_error.ErrDirectTransferUnsupported
+	}
+
+	metaDoc, err := t.authorize(login, uuid, false)
+	if err != nil {
+		return entity.PresignedURL{}, err
+	}
+
+	if !metaDoc.File || metaDoc.Encrypted() {
+		return entity.PresignedURL{}, custom_error.ErrDirectTransferUnavailable
+	}
+
+	params := url.Values{}
+	params.Set("response-content-type", metaDoc.Mime)
+	params.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": metaDoc.Name}))
+
+	if metaDoc.Encoding != "" {
+		if !entity.AcceptsEncoding(acceptEncoding, metaDoc.Encoding) {
+			return entity.PresignedURL{}, custom_error.ErrDirectTransferUnavailable
+		}
+
+		params.Set("response-content-encoding", metaDoc.Encoding)
+	}
+
+	expiresAt := time.Now().Add(t.Cfg.ConfigPresign.TTL)
+
+	link, err := t.Presigner.PresignGet(ctx, metaDoc.StorageKey(), t.Cfg.ConfigPresign.TTL, params)
+	if err != nil {
+		return entity.PresignedURL{}, err
+	}
+
+	presignedURL := entity.PresignedURL{
+		URL:       link,
+		Method:    http.MethodGet,
+		ExpiresAt: expiresAt,
 	}
 
+	return presignedURL, nil
+}
+
+// sagaContext отвязывает сагу от отмены запроса: начатая сага доводится до конца или компенсируется,
+// даже если клиент отключился
+func sagaContext(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
+
+// notify сообщает об изменении документа подписчикам webhook и ленты изменений
+func (t *DocumentUsecase) notify(eventType string, metaDoc model.MetaDocument) {
+	t.Webhooks.Notify(eventType, metaDoc)
+	t.ChangeFeed.Publish(eventType, metaDoc)
+}
+
+// authorize загружает метаданные документа и проверяет права пользователя на чтение
+// или изменение. Документ, недоступный для чтения, считается ненайденным,
+// чтобы не раскрывать факт его существования
+func (t *DocumentUsecase) authorize(login, uuid string, write bool) (model.MetaDocument, error) {
 	metaDoc, err := t.DocumentRepository.GetById(uuid)
 	if err != nil {
-		return nil, entity.DefaultMimeType, err
+		return metaDoc, err
 	}
 
+	if !metaDoc.CanRead(login) {
+		return metaDoc, custom_error.ErrDocumentNotFound
+	}
+
+	if write && !metaDoc.CanWrite(login) {
+		return metaDoc, custom_error.ErrAccessDenied
+	}
+
+	return metaDoc, nil
+}
+
+// readContent открывает содержимое документа в хранилище. Сжатый файл возвращается без распаковки
+func (t *DocumentUsecase) readContent(ctx context.Context, metaDoc model.MetaDocument) (entity.DocumentContent, error) {
 	if metaDoc.File {
-		file, err := t.DocumentRepository.Download(t.Ctx, metaDoc.UUID)
+		file, size, err := t.DocumentRepository.Download(ctx, metaDoc.StorageKey())
 		if err != nil {
-			return nil, entity.DefaultMimeType, err
+			return entity.DocumentContent{}, err
 		}
 
-		return file, metaDoc.Mime, nil
+		return entity.DocumentContent{
+			Mime:     metaDoc.Mime,
+			Size:     size,
+			Hash:     metaDoc.Hash,
+			ModTime:  metaDoc.UpdatedAt,
+			Body:     file,
+			Encoding: metaDoc.Encoding,
+		}, nil
 	}
 
-	jsonDocMap, err := t.DocumentRepository.GetByDocumentId(t.Ctx, uuid)
+	jsonDocMap, err := t.DocumentRepository.GetByDocumentId(ctx, metaDoc.StorageKey())
 	if err != nil {
-		return nil, entity.DefaultMimeType, err
+		return entity.DocumentContent{}, err
 	}
 
 	result := entity.ApiResponse{
@@ -87,19 +323,105 @@ func (t *DocumentUsecase) GetDocumentById(uuid string) ([]byte, string, error) {
 
 	jsonDoc, err := json.Marshal(result)
 	if err != nil {
-		return nil, entity.DefaultMimeType, err
+		return entity.DocumentContent{}, err
 	}
 
-	return jsonDoc, metaDoc.Mime, nil
+	return newDocumentContent(jsonDoc, metaDoc), nil
+}
+
+func applyPatch(patchType string, original, patch []byte) ([]byte, error) {
+	switch patchType {
+	case entity.JsonPatchMimeType:
+		decoded, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", custom_error.ErrInvalidPatch, err)
+		}
+
+		modified, err := decoded.Apply(original)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", custom_error.ErrPatchFailed, err)
+		}
+
+		return modified, nil
+	case entity.MergePatchMimeType:
+		if !json.Valid(patch) {
+			return nil, custom_error.ErrInvalidPatch
+		}
+
+		modified, err := jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", custom_error.ErrPatchFailed, err)
+		}
+
+		return modified, nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported patch type %s", custom_error.ErrInvalidPatch, patchType)
+}
+
+func newDocumentContent(data []byte, metaDoc model.MetaDocument) entity.DocumentContent {
+	return entity.DocumentContent{
+		Mime:     metaDoc.Mime,
+		Size:     int64(len(data)),
+		Hash:     metaDoc.Hash,
+		ModTime:  metaDoc.UpdatedAt,
+		Body:     nopSeekCloser{bytes.NewReader(data)},
+		Encoding: metaDoc.Encoding,
+	}
 }
 
-func (t *DocumentUsecase) DeleteDocumentById(uuid string) error {
-	err := t.sagaOrchestrator.DeleteDocument(t.Ctx, uuid)
+// decodeContent распаковывает сжатое содержимое размера size, если клиент не принимает его алгоритм сжатия
+func decodeContent(content entity.DocumentContent, size int64, acceptEncoding string) (entity.DocumentContent, error) {
+	if content.Encoding == "" || entity.AcceptsEncoding(acceptEncoding, content.Encoding) {
+		return content, nil
+	}
+
+	body, err := compression.NewReadSeekCloser(content.Body, content.Encoding, size)
 	if err != nil {
-		return err
+		_ = content.Body.Close()
+		return entity.DocumentContent{}, err
 	}
 
-	go t.Cache.Delete(t.Ctx, uuid)
+	content.Body = body
+	content.Size = size
+	content.Encoding = ""
+	content.Decoded = true
+
+	return content, nil
+}
 
+// nopSeekCloser добавляет пустой метод Close к io.ReadSeeker
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error {
 	return nil
 }
+
+// cacheBuffer накапливает записанные данные, пока их объем не превышает limit.
+// После превышения лимита буфер очищается и дальнейшие записи игнорируются
+type cacheBuffer struct {
+	bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+func newCacheBuffer(limit int64) *cacheBuffer {
+	return &cacheBuffer{limit: limit}
+}
+
+func (b *cacheBuffer) Write(p []byte) (int, error) {
+	if b.overflow {
+		return len(p), nil
+	}
+
+	if int64(b.Len()+len(p)) > b.limit {
+		b.overflow = true
+		b.Reset()
+
+		return len(p), nil
+	}
+
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,123 @@
+package usecases
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+func TestApplyPatch(t *testing.T) {
+	original := `{"name":"report","tags":["a","b"],"owner":{"login":"alice","team":"docs"},"draft":true}`
+
+	tests := []struct {
+		name      string
+		patchType string
+		patch     string
+		want      string
+		wantErr   error
+	}{
+		{
+			name:      "merge patch replaces and adds fields",
+			patchType: entity.MergePatchMimeType,
+			patch:     `{"name":"summary","pages":3}`,
+			want:      `{"name":"summary","pages":3,"tags":["a","b"],"owner":{"login":"alice","team":"docs"},"draft":true}`,
+		},
+		{
+			name:      "merge patch null removes field",
+			patchType: entity.MergePatchMimeType,
+			patch:     `{"draft":null}`,
+			want:      `{"name":"report","tags":["a","b"],"owner":{"login":"alice","team":"docs"}}`,
+		},
+		{
+			name:      "merge patch merges nested objects",
+			patchType: entity.MergePatchMimeType,
+			patch:     `{"owner":{"team":null,"role":"editor"}}`,
+			want:      `{"name":"report","tags":["a","b"],"owner":{"login":"alice","role":"editor"},"draft":true}`,
+		},
+		{
+			name:      "merge patch replaces arrays",
+			patchType: entity.MergePatchMimeType,
+			patch:     `{"tags":["c"]}`,
+			want:      `{"name":"report","tags":["c"],"owner":{"login":"alice","team":"docs"},"draft":true}`,
+		},
+		{
+			name:      "invalid merge patch",
+			patchType: entity.MergePatchMimeType,
+			patch:     `{"name":`,
+			wantErr:   custom_error.ErrInvalidPatch,
+		},
+		{
+			name:      "json patch operations",
+			patchType: entity.JsonPatchMimeType,
+			patch: `[{"op":"replace","path":"/name","value":"summary"},` +
+				`{"op":"add","path":"/tags/-","value":"c"},` +
+				`{"op":"remove","path":"/draft"}]`,
+			want: `{"name":"summary","tags":["a","b","c"],"owner":{"login":"alice","team":"docs"}}`,
+		},
+		{
+			name:      "json patch failed test",
+			patchType: entity.JsonPatchMimeType,
+			patch:     `[{"op":"test","path":"/name","value":"summary"},{"op":"remove","path":"/draft"}]`,
+			wantErr:   custom_error.ErrPatchFailed,
+		},
+		{
+			name:      "json patch missing path",
+			patchType: entity.JsonPatchMimeType,
+			patch:     `[{"op":"remove","path":"/pages"}]`,
+			wantErr:   custom_error.ErrPatchFailed,
+		},
+		{
+			name:      "invalid json patch",
+			patchType: entity.JsonPatchMimeType,
+			patch:     `{"op":"remove","path":"/draft"}`,
+			wantErr:   custom_error.ErrInvalidPatch,
+		},
+		{
+			name:      "unsupported patch type",
+			patchType: "application/json",
+			patch:     `{"name":"summary"}`,
+			wantErr:   custom_error.ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(tt.patchType, []byte(original), []byte(tt.patch))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertJSONEqual(t, got, []byte(tt.want))
+		})
+	}
+}
+
+func assertJSONEqual(t *testing.T, got, want []byte) {
+	t.Helper()
+
+	var gotValue, wantValue interface{}
+
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+
+	if err := json.Unmarshal(want, &wantValue); err != nil {
+		t.Fatalf("invalid JSON %s: %v", want, err)
+	}
+
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("JSON = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,28 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/service"
+)
+
+var _ Feed = (*FeedUsecase)(nil)
+
+type FeedUsecase struct {
+	ChangeFeed *service.ChangeFeed
+}
+
+func NewFeedUsecase(changeFeed *service.ChangeFeed) *FeedUsecase {
+	return &FeedUsecase{
+		ChangeFeed: changeFeed,
+	}
+}
+
+func (u *FeedUsecase) Subscribe(ctx context.Context, login, lastEventID string) (<-chan entity.ChangeEvent, error) {
+	if err := entity.ValidateLastEventID(lastEventID); err != nil {
+		return nil, err
+	}
+
+	return u.ChangeFeed.Subscribe(ctx, login, lastEventID)
+}
@@ -0,0 +1,73 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/cache"
+)
+
+var _ Idempotency = (*IdempotencyUsecase)(nil)
+
+type IdempotencyUsecase struct {
+	Ctx        context.Context
+	Cfg        *config.ConfigIdempotency
+	Repository cache.Idempotency
+}
+
+func NewIdempotencyUsecase(cfg *config.Config, repo cache.Idempotency) *IdempotencyUsecase {
+	return &IdempotencyUsecase{
+		Ctx:        context.Background(),
+		Cfg:        cfg.ConfigIdempotency,
+		Repository: repo,
+	}
+}
+
+// Begin занимает ключ идемпотентности для нового запроса.
+// Если по ключу уже сохранен результат, он возвращается вместе с true,
+// если первый запрос еще выполняется - возвращается ErrIdempotencyKeyInProgress
+func (u *IdempotencyUsecase) Begin(login, key string) (entity.IdempotencyRecord, bool, error) {
+	if err := entity.ValidateIdempotencyKey(key); err != nil {
+		return entity.IdempotencyRecord{}, false, err
+	}
+
+	// результат может истечь между попыткой занять ключ и его чтением, тогда ключ занимается повторно
+	for range 2 {
+		reserved, err := u.Repository.Reserve(u.Ctx, login, key, u.Cfg.LockTTL)
+		if err != nil {
+			return entity.IdempotencyRecord{}, false, err
+		}
+
+		if reserved {
+			return entity.IdempotencyRecord{}, false, nil
+		}
+
+		record, err := u.Repository.Get(u.Ctx, login, key)
+		if errors.Is(err, custom_error.ErrIdempotencyKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return entity.IdempotencyRecord{}, false, err
+		}
+
+		return record, true, nil
+	}
+
+	return entity.IdempotencyRecord{}, false, custom_error.ErrIdempotencyKeyInProgress
+}
+
+// Complete сохраняет результат запроса, занявшего ключ
+func (u *IdempotencyUsecase) Complete(login, key string, record entity.IdempotencyRecord) error {
+	record.CreatedAt = time.Now().UTC()
+
+	return u.Repository.Save(u.Ctx, login, key, record, u.Cfg.TTL)
+}
+
+// Abort освобождает ключ запроса, который завершился ошибкой, чтобы его можно было повторить
+func (u *IdempotencyUsecase) Abort(login, key string) error {
+	return u.Repository.Delete(u.Ctx, login, key)
+}
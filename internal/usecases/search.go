@@ -0,0 +1,14 @@
+package usecases
+
+import (
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+)
+
+// SearchDocuments ищет документы, доступные пользователю, по полнотекстовому индексу
+func (t *DocumentUsecase) SearchDocuments(req entity.SearchRequest) ([]entity.SearchHit, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	return t.DocumentRepository.Search(req)
+}
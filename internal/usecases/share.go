@@ -0,0 +1,281 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+	"github.com/AlexJudin/DocumentCacheServer/internal/service"
+)
+
+const (
+	// shareTokenSize размер генерируемого токена ссылки в байтах
+	shareTokenSize = 32
+
+	// shareGrantScope отделяет подпись разрешений скачивания от других подписей секретом токенов
+	shareGrantScope = "share-grant"
+)
+
+var _ Share = (*ShareUsecase)(nil)
+
+// ShareUsecase управляет ссылками на документы и отдает документы по токену ссылки.
+// Документ по ссылке читается от имени владельца ссылки, поэтому получателю не нужна учетная запись
+type ShareUsecase struct {
+	Cfg *config.ConfigShare
+	// GrantKey секрет подписи разрешений продолжить скачивание
+	GrantKey           []byte
+	Repository         postgres.Share
+	DocumentRepository repository.DocumentRepository
+	Documents          Document
+	ServiceAuth        service.AuthService
+}
+
+func NewShareUsecase(cfg *config.Config, repo postgres.Share, docRepo repository.DocumentRepository, documents Document, serviceAuth service.AuthService) *ShareUsecase {
+	return &ShareUsecase{
+		Cfg:                cfg.ConfigShare,
+		GrantKey:           cfg.TokenSalt,
+		Repository:         repo,
+		DocumentRepository: docRepo,
+		Documents:          documents,
+		ServiceAuth:        serviceAuth,
+	}
+}
+
+// CreateShare создает ссылку на документ. Создавать ссылки может только пользователь с правом изменения документа
+func (u *ShareUsecase) CreateShare(login, documentUUID string, req entity.ShareRequest) (entity.NewShare, error) {
+	if err := req.Validate(time.Now()); err != nil {
+		return entity.NewShare{}, err
+	}
+
+	metaDoc, err := u.DocumentRepository.GetById(documentUUID)
+	if err != nil {
+		return entity.NewShare{}, err
+	}
+
+	if !metaDoc.CanRead(login) {
+		return entity.NewShare{}, custom_error.ErrDocumentNotFound
+	}
+
+	if !metaDoc.CanWrite(login) {
+		return entity.NewShare{}, custom_error.ErrAccessDenied
+	}
+
+	buf := make([]byte, shareTokenSize)
+	if _, err = rand.Read(buf); err != nil {
+		return entity.NewShare{}, err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	link := model.ShareLink{
+		UUID:         uuid.NewString(),
+		TokenHash:    hashShareToken(token),
+		Owner:        login,
+		DocumentUUID: metaDoc.UUID,
+		ExpiresAt:    req.ExpiresAt,
+		MaxDownloads: req.MaxDownloads,
+	}
+
+	if req.Password != "" {
+		link.PasswordHash = u.ServiceAuth.GenerateHashPassword(req.Password)
+		link.Protected = true
+	}
+
+	if err = u.Repository.CreateShareLink(&link); err != nil {
+		return entity.NewShare{}, err
+	}
+
+	return entity.NewShare{Share: link, Token: token, URL: entity.SharePath + token}, nil
+}
+
+// GetShares возвращает ссылки пользователя. Непустой documentUUID оставляет только ссылки на этот документ
+func (u *ShareUsecase) GetShares(login, documentUUID string) ([]model.ShareLink, error) {
+	return u.Repository.GetShareLinks(login, documentUUID)
+}
+
+// RevokeShare отзывает ссылку. Журнал обращений по отозванной ссылке сохраняется
+func (u *ShareUsecase) RevokeShare(login, uuid string) error {
+	return u.Repository.RevokeShareLink(login, uuid, time.Now())
+}
+
+func (u *ShareUsecase) GetShareAccesses(login, uuid string, req entity.ShareAccessListRequest) (entity.ShareAccessList, error) {
+	if err := req.Validate(); err != nil {
+		return entity.ShareAccessList{}, err
+	}
+
+	if _, err := u.Repository.GetShareLink(login, uuid); err != nil {
+		return entity.ShareAccessList{}, err
+	}
+
+	accesses, total, err := u.Repository.GetShareAccesses(uuid, req.Limit, req.Offset)
+	if err != nil {
+		return entity.ShareAccessList{}, err
+	}
+
+	return entity.ShareAccessList{Accesses: accesses, Total: total}, nil
+}
+
+// OpenShare возвращает содержимое документа по токену ссылки и засчитывает скачивание.
+// Вместе с засчитанным скачиванием клиент получает подписанное разрешение на срок GrantTTL:
+// запросы диапазонов с этим разрешением продолжают скачивание и не расходуют скачивания ссылки,
+// в том числе после того, как скачивания исчерпаны. Остальные запросы засчитываются как новые скачивания.
+// Каждое обращение к существующей ссылке записывается в журнал обращений вместе с результатом
+func (u *ShareUsecase) OpenShare(ctx context.Context, req entity.OpenShareRequest) (entity.SharedDocument, error) {
+	link, err := u.Repository.GetShareLinkByToken(hashShareToken(req.Token))
+	if err != nil {
+		return entity.SharedDocument{}, err
+	}
+
+	now := time.Now()
+
+	repeat := req.Continuation && u.verifyGrant(link, req.Grant, now)
+
+	if result, err := shareState(link, now, repeat); err != nil {
+		u.audit(link, result, req.Client)
+		return entity.SharedDocument{}, err
+	}
+
+	if link.Protected {
+		passwordHash := u.ServiceAuth.GenerateHashPassword(req.Password)
+
+		if subtle.ConstantTimeCompare([]byte(passwordHash), []byte(link.PasswordHash)) != 1 {
+			u.audit(link, model.ShareAccessWrongPassword, req.Client)
+			return entity.SharedDocument{}, custom_error.ErrSharePasswordWrong
+		}
+	}
+
+	content, err := u.Documents.GetDocumentById(ctx, link.Owner, link.DocumentUUID, req.AcceptEncoding)
+	if errors.Is(err, custom_error.ErrDocumentNotFound) {
+		u.audit(link, model.ShareAccessNotFound, req.Client)
+		return entity.SharedDocument{}, err
+	}
+
+	if err != nil {
+		return entity.SharedDocument{}, err
+	}
+
+	if repeat {
+		u.audit(link, model.ShareAccessGranted, req.Client)
+		return entity.SharedDocument{Content: content}, nil
+	}
+
+	claimed, err := u.Repository.ClaimShareDownload(link.UUID, now)
+	if err != nil {
+		_ = content.Body.Close()
+		return entity.SharedDocument{}, err
+	}
+
+	// пока открывалось содержимое, ссылку могли отозвать или исчерпать другим скачиванием
+	if !claimed {
+		_ = content.Body.Close()
+
+		link, err = u.Repository.GetShareLinkByToken(link.TokenHash)
+		if err != nil {
+			return entity.SharedDocument{}, err
+		}
+
+		result, err := shareState(link, now, false)
+		if err == nil {
+			result, err = model.ShareAccessExhausted, custom_error.ErrShareExhausted
+		}
+
+		u.audit(link, result, req.Client)
+
+		return entity.SharedDocument{}, err
+	}
+
+	u.audit(link, model.ShareAccessGranted, req.Client)
+
+	grant, expiresAt := u.issueGrant(link, now)
+
+	return entity.SharedDocument{Content: content, Grant: grant, GrantExpiresAt: expiresAt}, nil
+}
+
+// issueGrant подписывает разрешение продолжить скачивание по ссылке. Разрешение действует GrantTTL,
+// но не дольше самой ссылки
+func (u *ShareUsecase) issueGrant(link model.ShareLink, now time.Time) (string, time.Time) {
+	expiresAt := now.Add(u.Cfg.GrantTTL).Truncate(time.Second)
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(expiresAt) {
+		expiresAt = link.ExpiresAt.Truncate(time.Second)
+	}
+
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	return expires + "." + u.signGrant(link.UUID, expires), expiresAt
+}
+
+// verifyGrant проверяет, что разрешение выдано для ссылки link и не истекло
+func (u *ShareUsecase) verifyGrant(link model.ShareLink, grant string, now time.Time) bool {
+	expires, signature, found := strings.Cut(grant, ".")
+	if !found {
+		return false
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() >= expiresAt {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(u.signGrant(link.UUID, expires)))
+}
+
+func (u *ShareUsecase) signGrant(shareUUID, expires string) string {
+	mac := hmac.New(sha256.New, u.GrantKey)
+	mac.Write([]byte(shareGrantScope + ":" + shareUUID + ":" + expires))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// audit записывает обращение по ссылке в журнал. Ошибка записи журнала не мешает ответу клиенту
+func (u *ShareUsecase) audit(link model.ShareLink, result string, client entity.ShareClient) {
+	access := &model.ShareAccess{
+		ShareUUID: link.UUID,
+		Result:    result,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	}
+
+	if err := u.Repository.CreateShareAccess(access); err != nil {
+		log.Errorf("failed to audit access of share link [%s]: %+v", link.UUID, err)
+	}
+}
+
+// shareState проверяет, что по ссылке можно получить документ в момент now.
+// Исчерпанные скачивания не мешают продолжить скачивание по разрешению (repeat).
+// Для недоступной ссылки возвращает результат обращения для журнала и ошибку
+func shareState(link model.ShareLink, now time.Time, repeat bool) (string, error) {
+	switch {
+	case link.Revoked():
+		return model.ShareAccessRevoked, custom_error.ErrShareRevoked
+	case link.Expired(now):
+		return model.ShareAccessExpired, custom_error.ErrShareExpired
+	case link.Exhausted() && !repeat:
+		return model.ShareAccessExhausted, custom_error.ErrShareExhausted
+	}
+
+	return "", nil
+}
+
+// hashShareToken возвращает SHA-256 токена ссылки в hex, под которым ссылка хранится в базе
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
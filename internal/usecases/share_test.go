@@ -0,0 +1,69 @@
+package usecases
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+func TestShareGrant(t *testing.T) {
+	u := &ShareUsecase{
+		Cfg:      &config.ConfigShare{GrantTTL: time.Hour},
+		GrantKey: []byte("token_secret"),
+	}
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	link := model.ShareLink{UUID: "link-1"}
+
+	grant, expiresAt := u.issueGrant(link, now)
+	if !expiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("grant expires at %s, want %s", expiresAt, now.Add(time.Hour))
+	}
+
+	_, signature, _ := strings.Cut(grant, ".")
+
+	other := &ShareUsecase{Cfg: u.Cfg, GrantKey: []byte("other_secret")}
+	otherGrant, _ := other.issueGrant(link, now)
+
+	tests := []struct {
+		name  string
+		link  model.ShareLink
+		grant string
+		now   time.Time
+		want  bool
+	}{
+		{name: "valid", link: link, grant: grant, now: now.Add(time.Minute), want: true},
+		{name: "expired", link: link, grant: grant, now: now.Add(time.Hour), want: false},
+		{name: "another link", link: model.ShareLink{UUID: "link-2"}, grant: grant, now: now, want: false},
+		{name: "another key", link: link, grant: otherGrant, now: now, want: false},
+		{name: "extended expiry", link: link, grant: "99999999999." + signature, now: now, want: false},
+		{name: "empty", link: link, grant: "", now: now, want: false},
+		{name: "malformed", link: link, grant: "grant", now: now, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := u.verifyGrant(tt.link, tt.grant, tt.now); got != tt.want {
+				t.Errorf("verifyGrant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShareGrantLimitedByLinkExpiry(t *testing.T) {
+	u := &ShareUsecase{
+		Cfg:      &config.ConfigShare{GrantTTL: time.Hour},
+		GrantKey: []byte("token_secret"),
+	}
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	linkExpiresAt := now.Add(10 * time.Minute)
+
+	_, expiresAt := u.issueGrant(model.ShareLink{UUID: "link-1", ExpiresAt: &linkExpiresAt}, now)
+	if !expiresAt.Equal(linkExpiresAt) {
+		t.Errorf("grant expires at %s, want %s", expiresAt, linkExpiresAt)
+	}
+}
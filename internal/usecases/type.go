@@ -1,15 +1,65 @@
 package usecases
 
 import (
+	"context"
+	"io"
+
 	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
 	"github.com/AlexJudin/DocumentCacheServer/internal/model"
 )
 
 type Document interface {
-	SaveDocument(document *entity.Document) error
-	GetDocumentsList(req entity.DocumentListRequest) ([]model.MetaDocument, error)
-	GetDocumentById(uuid string) ([]byte, string, error)
-	DeleteDocumentById(uuid string) error
+	SaveDocument(ctx context.Context, login string, document *entity.Document) error
+	GetDocumentsList(req entity.DocumentListRequest) (entity.DocumentList, error)
+	CountDocuments(req entity.DocumentListRequest) (int64, error)
+	SearchContent(ctx context.Context, query entity.ContentQuery) (entity.ContentSearchResult, error)
+	SearchDocuments(req entity.SearchRequest) ([]entity.SearchHit, error)
+	GetDocumentById(ctx context.Context, login, uuid, acceptEncoding string) (entity.DocumentContent, error)
+	UpdateDocument(ctx context.Context, login, uuid string, document *entity.Document) error
+	PatchDocument(ctx context.Context, login, uuid, patchType string, patch []byte) error
+	DeleteDocumentById(ctx context.Context, login, uuid string) error
+	GetDownloadURL(ctx context.Context, login, uuid, acceptEncoding string) (entity.PresignedURL, error)
+
+	GetDocumentVersions(login, uuid string) ([]model.DocumentVersion, error)
+	GetDocumentVersion(ctx context.Context, login, uuid string, version int, acceptEncoding string) (entity.DocumentContent, error)
+	DiffDocumentVersions(ctx context.Context, login, uuid string, from, to int) (map[string]interface{}, error)
+	RestoreDocumentVersion(ctx context.Context, login, uuid string, version int) error
+}
+
+type Webhook interface {
+	CreateWebhook(login string, req entity.WebhookRequest) (entity.NewWebhook, error)
+	GetWebhooks(login string) ([]model.WebhookSubscription, error)
+	DeleteWebhook(login, uuid string) error
+	GetDeliveries(login, uuid string, req entity.DeliveryListRequest) (entity.DeliveryList, error)
+	Redeliver(login, uuid, deliveryUUID string) error
+}
+
+type Upload interface {
+	MaxSize() int64
+	CreateUpload(login string, req entity.UploadRequest) (model.Upload, error)
+	GetUpload(login, uuid string) (model.Upload, error)
+	WriteUpload(login, uuid string, offset int64, content io.Reader) (model.Upload, error)
+	DeleteUpload(login, uuid string) error
+	CreateDirectUpload(login string, req entity.DirectUploadRequest) (entity.DirectUpload, error)
+	CompleteDirectUpload(login, uuid string, req entity.DirectUploadCompletion) (entity.DirectUploadResult, error)
+}
+
+type Share interface {
+	CreateShare(login, documentUUID string, req entity.ShareRequest) (entity.NewShare, error)
+	GetShares(login, documentUUID string) ([]model.ShareLink, error)
+	RevokeShare(login, uuid string) error
+	GetShareAccesses(login, uuid string, req entity.ShareAccessListRequest) (entity.ShareAccessList, error)
+	OpenShare(ctx context.Context, req entity.OpenShareRequest) (entity.SharedDocument, error)
+}
+
+type Idempotency interface {
+	Begin(login, key string) (entity.IdempotencyRecord, bool, error)
+	Complete(login, key string, record entity.IdempotencyRecord) error
+	Abort(login, key string) error
+}
+
+type Feed interface {
+	Subscribe(ctx context.Context, login, lastEventID string) (<-chan entity.ChangeEvent, error)
 }
 
 type Register interface {
@@ -21,3 +71,9 @@ type Authorization interface {
 	RefreshToken(refreshToken string) (entity.Tokens, error)
 	DeleteToken(token string) error
 }
+
+type Admin interface {
+	GetStuckSagas(req entity.SagaListRequest) (entity.SagaList, error)
+	GetSaga(uuid string) (entity.SagaDetails, error)
+	RetrySaga(uuid string) (model.Saga, error)
+}
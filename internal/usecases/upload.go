@@ -0,0 +1,667 @@
+package usecases
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
+	filestorage "github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/file_storage"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+	"github.com/AlexJudin/DocumentCacheServer/internal/service"
+)
+
+var _ Upload = (*UploadUsecase)(nil)
+
+// UploadUsecase принимает файл частями и создает из него документ, когда получен весь файл.
+// Файл разбивается на части одного размера PartSize, часть с номером n начинается со смещения (n-1)*PartSize,
+// поэтому повтор записи с того же смещения перезаписывает ту же часть.
+// При прямой загрузке клиент записывает файл в хранилище сам по подписанной ссылке и затем подтверждает загрузку
+type UploadUsecase struct {
+	Ctx                context.Context
+	Cfg                *config.ConfigUpload
+	PresignCfg         *config.ConfigPresign
+	Repository         postgres.Upload
+	Storage            filestorage.MultipartRepository
+	Presigner          filestorage.Presigner
+	DocumentRepository repository.DocumentRepository
+	Documents          Document
+	Cleaner            *service.UploadCleaner
+}
+
+func NewUploadUsecase(cfg *config.Config, repo postgres.Upload, storage filestorage.MultipartRepository, presigner filestorage.Presigner, docRepo repository.DocumentRepository, documents Document, cleaner *service.UploadCleaner) *UploadUsecase {
+	return &UploadUsecase{
+		Ctx:                context.Background(),
+		Cfg:                cfg.ConfigUpload,
+		PresignCfg:         cfg.ConfigPresign,
+		Repository:         repo,
+		Storage:            storage,
+		Presigner:          presigner,
+		DocumentRepository: docRepo,
+		Documents:          documents,
+		Cleaner:            cleaner,
+	}
+}
+
+// MaxSize возвращает максимальный размер загружаемого файла с учетом ограничения количества частей
+func (u *UploadUsecase) MaxSize() int64 {
+	return min(u.Cfg.MaxSize, u.partSize()*filestorage.MaxParts)
+}
+
+func (u *UploadUsecase) CreateUpload(login string, req entity.UploadRequest) (model.Upload, error) {
+	if u.Storage == nil {
+		return model.Upload{}, custom_error.ErrUploadsUnsupported
+	}
+
+	if req.Length > u.MaxSize() {
+		return model.Upload{}, custom_error.ErrUploadTooLarge
+	}
+
+	upload := model.Upload{
+		UUID:      uuid.NewString(),
+		Owner:     login,
+		Name:      req.Name,
+		Mime:      req.Mime,
+		Public:    req.Public,
+		Grant:     req.Grant,
+		Length:    req.Length,
+		ExpiresAt: time.Now().Add(u.Cfg.TTL),
+	}
+
+	if upload.Mime == "" {
+		upload.Mime = entity.UploadMimeType
+	}
+
+	if upload.Length > 0 {
+		multipartID, err := u.Storage.CreateMultipart(u.Ctx, upload.StorageKey())
+		if err != nil {
+			return model.Upload{}, err
+		}
+
+		upload.MultipartID = multipartID
+	}
+
+	if err := u.Repository.CreateUpload(&upload); err != nil {
+		if upload.MultipartID != "" {
+			_ = u.Storage.AbortMultipart(u.Ctx, upload.StorageKey(), upload.MultipartID)
+		}
+
+		return model.Upload{}, err
+	}
+
+	// пустой файл получен целиком уже при создании загрузки
+	if upload.Received() {
+		return u.WriteUpload(login, upload.UUID, 0, bytes.NewReader(nil))
+	}
+
+	return upload, nil
+}
+
+// GetUpload возвращает загрузку пользователя. Загрузки других пользователей, загрузки
+// с истекшим сроком и прямые загрузки считаются не найденными
+func (u *UploadUsecase) GetUpload(login, uuid string) (model.Upload, error) {
+	return u.getUpload(login, uuid, false)
+}
+
+// WriteUpload записывает content со смещения offset, которое должно совпадать с количеством уже полученных байт.
+// Когда получен весь файл, из него создается документ. Если создать документ не удалось,
+// его можно создать повторной записью пустого content со смещения, равного размеру файла
+func (u *UploadUsecase) WriteUpload(login, uuid string, offset int64, content io.Reader) (model.Upload, error) {
+	if _, err := u.GetUpload(login, uuid); err != nil {
+		return model.Upload{}, err
+	}
+
+	unlock, err := u.lock(uuid)
+	if err != nil {
+		return model.Upload{}, err
+	}
+	defer unlock()
+
+	// пока блокировку держал другой запрос, загрузка могла продвинуться
+	upload, err := u.Repository.GetUpload(uuid)
+	if err != nil {
+		return model.Upload{}, err
+	}
+
+	if offset != upload.Offset {
+		return upload, custom_error.ErrUploadOffsetMismatch
+	}
+
+	if !upload.Received() {
+		if err = u.receive(&upload, content); err != nil {
+			return upload, err
+		}
+	}
+
+	if upload.Received() && !upload.Completed() {
+		digest, hashed := uploadDigest(upload)
+
+		if _, err = u.complete(&upload, digest, hashed); err != nil {
+			return upload, err
+		}
+	}
+
+	return upload, nil
+}
+
+// CreateDirectUpload создает загрузку, файл которой клиент записывает в хранилище сам
+// по возвращаемой подписанной ссылке. Если указан документ, файл станет его новой версией,
+// поэтому пользователь должен иметь право на изменение документа
+func (u *UploadUsecase) CreateDirectUpload(login string, req entity.DirectUploadRequest) (entity.DirectUpload, error) {
+	if u.Storage == nil || u.Presigner == nil {
+		return entity.DirectUpload{}, custom_error.ErrDirectTransferUnsupported
+	}
+
+	if err := req.Validate(); err != nil {
+		return entity.DirectUpload{}, err
+	}
+
+	// файл загружается одним запросом PUT, размер которого ограничен хранилищем
+	if req.Size > min(u.Cfg.MaxSize, filestorage.MaxPutSize) {
+		return entity.DirectUpload{}, custom_error.ErrUploadTooLarge
+	}
+
+	upload := model.Upload{
+		UUID:      uuid.NewString(),
+		Owner:     login,
+		Name:      req.Name,
+		Mime:      req.Mime,
+		Public:    req.Public,
+		Grant:     req.Grant,
+		Length:    req.Size,
+		Direct:    true,
+		ExpiresAt: time.Now().Add(u.Cfg.TTL),
+	}
+
+	if req.DocumentID != "" {
+		target, err := u.getTarget(login, req.DocumentID)
+		if err != nil {
+			return entity.DirectUpload{}, err
+		}
+
+		upload.TargetUUID = target.UUID
+		upload.Name = cmp.Or(req.Name, target.Name)
+		upload.Mime = cmp.Or(req.Mime, target.Mime)
+		upload.Public = target.Public
+		upload.Grant = target.Grant
+	}
+
+	if upload.Mime == "" {
+		upload.Mime = entity.UploadMimeType
+	}
+
+	expiresAt := time.Now().Add(u.PresignCfg.TTL)
+
+	link, headers, err := u.Presigner.PresignPut(u.Ctx, upload.StorageKey(), u.PresignCfg.TTL, req.SHA256)
+	if err != nil {
+		return entity.DirectUpload{}, err
+	}
+
+	if err = u.Repository.CreateUpload(&upload); err != nil {
+		return entity.DirectUpload{}, err
+	}
+
+	directUpload := entity.DirectUpload{
+		Upload: upload,
+		Link: entity.PresignedURL{
+			URL:       link,
+			Method:    http.MethodPut,
+			Headers:   headers,
+			ExpiresAt: expiresAt,
+		},
+	}
+
+	return directUpload, nil
+}
+
+// CompleteDirectUpload проверяет, что клиент записал файл в хранилище, и создает из него документ
+// или новую версию документа через сагу. Файл должен совпадать с объявленным размером и хешем req.SHA256.
+// Если хранилище проверило хеш файла при записи, хеш сверяется по метаданным файла без его чтения.
+// Повторное подтверждение завершенной загрузки возвращает уже созданный документ
+func (u *UploadUsecase) CompleteDirectUpload(login, uuid string, req entity.DirectUploadCompletion) (entity.DirectUploadResult, error) {
+	if err := req.Validate(); err != nil {
+		return entity.DirectUploadResult{}, err
+	}
+
+	if _, err := u.getUpload(login, uuid, true); err != nil {
+		return entity.DirectUploadResult{}, err
+	}
+
+	unlock, err := u.lock(uuid)
+	if err != nil {
+		return entity.DirectUploadResult{}, err
+	}
+	defer unlock()
+
+	upload, err := u.Repository.GetUpload(uuid)
+	if err != nil {
+		return entity.DirectUploadResult{}, err
+	}
+
+	if upload.Completed() {
+		metaDoc, err := u.DocumentRepository.GetById(upload.DocumentUUID)
+		if err != nil {
+			return entity.DirectUploadResult{}, err
+		}
+
+		return newDirectUploadResult(metaDoc), nil
+	}
+
+	info, exists, err := u.Presigner.StatFile(u.Ctx, upload.StorageKey())
+	if err != nil {
+		return entity.DirectUploadResult{}, err
+	}
+
+	if !exists {
+		return entity.DirectUploadResult{}, custom_error.ErrUploadIncomplete
+	}
+
+	if info.Size != upload.Length {
+		return entity.DirectUploadResult{}, fmt.Errorf("%w: file size %d, expected %d", custom_error.ErrChecksumMismatch, info.Size, upload.Length)
+	}
+
+	if info.SHA256 != "" && info.SHA256 != req.SHA256 {
+		return entity.DirectUploadResult{}, fmt.Errorf("%w: file digest %s, expected %s", custom_error.ErrChecksumMismatch, info.SHA256, req.SHA256)
+	}
+
+	metaDoc, err := u.complete(&upload, req.SHA256, info.SHA256 != "")
+	if err != nil {
+		return entity.DirectUploadResult{}, err
+	}
+
+	return newDirectUploadResult(metaDoc), nil
+}
+
+// DeleteUpload отменяет загрузку. Документ, уже созданный из загрузки, не удаляется
+func (u *UploadUsecase) DeleteUpload(login, uuid string) error {
+	if _, err := u.GetUpload(login, uuid); err != nil {
+		return err
+	}
+
+	unlock, err := u.lock(uuid)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	upload, err := u.Repository.GetUpload(uuid)
+	if err != nil {
+		return err
+	}
+
+	return u.Cleaner.Discard(u.Ctx, upload)
+}
+
+// getUpload возвращает загрузку пользователя с признаком прямой загрузки direct.
+// Загрузки других пользователей и загрузки с истекшим сроком считаются не найденными
+func (u *UploadUsecase) getUpload(login, uuid string, direct bool) (model.Upload, error) {
+	upload, err := u.Repository.GetUpload(uuid)
+	if err != nil {
+		return model.Upload{}, err
+	}
+
+	if upload.Owner != login || upload.Direct != direct || !upload.ExpiresAt.After(time.Now()) {
+		return model.Upload{}, custom_error.ErrUploadNotFound
+	}
+
+	return upload, nil
+}
+
+// getTarget возвращает документ, новой версией которого станет файл загрузки.
+// Документ, недоступный пользователю для чтения, считается ненайденным
+func (u *UploadUsecase) getTarget(login, uuid string) (model.MetaDocument, error) {
+	metaDoc, err := u.DocumentRepository.GetById(uuid)
+	if err != nil {
+		return model.MetaDocument{}, err
+	}
+
+	if !metaDoc.CanRead(login) {
+		return model.MetaDocument{}, custom_error.ErrDocumentNotFound
+	}
+
+	if !metaDoc.CanWrite(login) {
+		return model.MetaDocument{}, custom_error.ErrAccessDenied
+	}
+
+	return metaDoc, nil
+}
+
+// receive читает content и записывает в хранилище каждую набранную часть.
+// Смещение сохраняется после каждой части, а остаток меньше части - при окончании content,
+// в том числе при обрыве соединения, чтобы клиент продолжил загрузку с последнего полученного байта.
+// Вместе со смещением сохраняется состояние хеша полученных байт
+func (u *UploadUsecase) receive(upload *model.Upload, content io.Reader) error {
+	pending, err := u.Repository.GetUploadPending(upload.UUID)
+	if err != nil {
+		return err
+	}
+
+	hasher, hashed := uploadHasher(*upload)
+
+	buf := make([]byte, len(pending), u.partSize())
+	copy(buf, pending)
+
+	reader := io.LimitReader(content, upload.Length-upload.Offset)
+
+	for {
+		n, readErr := io.ReadFull(reader, buf[len(buf):cap(buf)])
+
+		if hashed {
+			hasher.Write(buf[len(buf) : len(buf)+n])
+		}
+
+		buf = buf[:len(buf)+n]
+
+		offset := upload.Offset + int64(n)
+
+		var part *model.UploadPart
+
+		// последняя часть файла может быть меньше остальных
+		if len(buf) == cap(buf) || (offset == upload.Length && len(buf) > 0) {
+			part, err = u.uploadPart(upload, offset-int64(len(buf)), buf)
+			if err != nil {
+				return err
+			}
+
+			buf = buf[:0]
+		}
+
+		if n > 0 || part != nil {
+			var hashState []byte
+
+			if hashed {
+				hashState, err = hasher.(encoding.BinaryMarshaler).MarshalBinary()
+				if err != nil {
+					return err
+				}
+			}
+
+			if err = u.Repository.AdvanceUpload(upload.UUID, upload.Offset, offset, buf, hashState, part); err != nil {
+				return err
+			}
+
+			upload.Offset = offset
+			upload.HashState = hashState
+		}
+
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			return nil
+		}
+
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// uploadPart записывает в хранилище часть файла, которая начинается со смещения start
+func (u *UploadUsecase) uploadPart(upload *model.Upload, start int64, data []byte) (*model.UploadPart, error) {
+	part := &model.UploadPart{
+		UploadUUID: upload.UUID,
+		Number:     int(start/u.partSize()) + 1,
+		Size:       int64(len(data)),
+	}
+
+	etag, err := u.Storage.UploadPart(u.Ctx, upload.StorageKey(), upload.MultipartID, part.Number, bytes.NewReader(data), part.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	part.ETag = etag
+
+	return part, nil
+}
+
+// complete собирает файл из частей и создает из него документ или новую версию документа через сагу.
+// Непустой digest - ожидаемый хеш файла, с которым сага сверяет содержимое. Если хеш уже известен (verified):
+// посчитан при приеме частей или проверен хранилищем, - сага не читает файл, а копирует его под ключ хеша средствами хранилища.
+// Собранный файл после этого больше не нужен: документ хранит копию под хешем содержимого
+func (u *UploadUsecase) complete(upload *model.Upload, digest string, verified bool) (model.MetaDocument, error) {
+	// файл прямой загрузки записан в хранилище клиентом целиком
+	if !upload.Assembled && !upload.Direct {
+		if upload.Length > 0 {
+			parts, err := u.Repository.GetUploadParts(upload.UUID)
+			if err != nil {
+				return model.MetaDocument{}, err
+			}
+
+			err = u.Storage.CompleteMultipart(u.Ctx, upload.StorageKey(), upload.MultipartID, parts)
+			if err != nil {
+				return model.MetaDocument{}, err
+			}
+		}
+
+		if err := u.Repository.MarkUploadAssembled(upload.UUID); err != nil {
+			return model.MetaDocument{}, err
+		}
+
+		upload.Assembled = true
+	}
+
+	documentFile := &entity.DocumentFile{
+		Name:   upload.Name,
+		Size:   upload.Length,
+		Digest: digest,
+	}
+
+	switch {
+	case upload.Length == 0:
+		documentFile.Content = bytes.NewReader(nil)
+	case verified:
+		documentFile.StagedKey = upload.StorageKey()
+	default:
+		file, _, err := u.Storage.Download(u.Ctx, upload.StorageKey())
+		if err != nil {
+			return model.MetaDocument{}, err
+		}
+		defer file.Close()
+
+		documentFile.Content = file
+	}
+
+	document := &entity.Document{
+		Meta: &model.MetaDocument{
+			Name:   upload.Name,
+			File:   true,
+			Public: upload.Public,
+			Mime:   upload.Mime,
+			Grant:  upload.Grant,
+		},
+		File: documentFile,
+	}
+
+	var err error
+
+	if upload.TargetUUID == "" {
+		err = u.createDocument(upload, document)
+	} else {
+		err = u.createVersion(upload, document, digest)
+	}
+
+	if err != nil {
+		return model.MetaDocument{}, err
+	}
+
+	if err := u.Repository.CompleteUpload(upload.UUID, document.Meta.UUID); err != nil {
+		return model.MetaDocument{}, err
+	}
+
+	upload.DocumentUUID = document.Meta.UUID
+
+	// без ссылки загрузки оставшийся файл удалит проверка согласованности хранилищ
+	if upload.Length > 0 || upload.Direct {
+		if err := u.Storage.Delete(u.Ctx, upload.StorageKey()); err != nil {
+			log.Warnf("failed to delete assembled file of upload [%s]: %+v", upload.UUID, err)
+		}
+	}
+
+	return *document.Meta, nil
+}
+
+// createDocument создает документ из загрузки под идентификатором, записанным в загрузке до вызова саги.
+// Если документ уже создан предыдущей попыткой, завершение которой не удалось записать, он не создается повторно
+func (u *UploadUsecase) createDocument(upload *model.Upload, document *entity.Document) error {
+	if upload.PendingUUID == "" {
+		documentUUID := uuid.NewString()
+
+		if err := u.Repository.ReserveUploadDocument(upload.UUID, documentUUID, 0); err != nil {
+			return err
+		}
+
+		upload.PendingUUID = documentUUID
+	}
+
+	metaDoc, err := u.DocumentRepository.GetById(upload.PendingUUID)
+	switch {
+	case err == nil:
+		log.Infof("document [%s] of upload [%s] was created by previous attempt", metaDoc.UUID, upload.UUID)
+
+		document.Meta = &metaDoc
+
+		return nil
+	case !errors.Is(err, custom_error.ErrDocumentNotFound):
+		return err
+	}
+
+	document.ReservedUUID = upload.PendingUUID
+
+	return u.Documents.SaveDocument(u.Ctx, upload.Owner, document)
+}
+
+// createVersion создает из загрузки новую версию документа. Номер версии записывается в загрузке до вызова саги:
+// если версия с этим номером уже есть и ее хеш совпадает с хешем файла загрузки, она создана предыдущей попыткой.
+// Без известного заранее хеша версию предыдущей попытки не отличить от чужой, и она создается заново
+func (u *UploadUsecase) createVersion(upload *model.Upload, document *entity.Document, digest string) error {
+	// права на документ могли измениться после создания загрузки, поэтому доступ
+	// к нему берется из текущих метаданных, а не из загрузки
+	target, err := u.getTarget(upload.Owner, upload.TargetUUID)
+	if err != nil {
+		return err
+	}
+
+	if upload.PendingVersion > 0 && digest != "" {
+		version, err := u.DocumentRepository.GetVersion(target.UUID, upload.PendingVersion)
+		switch {
+		case err == nil && version.Hash == digest:
+			log.Infof("version %d of document [%s] was created by upload [%s] previous attempt", version.Version, target.UUID, upload.UUID)
+
+			document.Meta = &target
+
+			return nil
+		case err != nil && !errors.Is(err, custom_error.ErrVersionNotFound):
+			return err
+		}
+	}
+
+	if err = u.Repository.ReserveUploadDocument(upload.UUID, "", target.Version+1); err != nil {
+		return err
+	}
+
+	upload.PendingVersion = target.Version + 1
+
+	document.Meta.Public = target.Public
+	document.Meta.Grant = target.Grant
+
+	return u.Documents.UpdateDocument(u.Ctx, upload.Owner, upload.TargetUUID, document)
+}
+
+// lock занимает загрузку на время запроса. Пока запрос выполняется, блокировка продлевается,
+// поэтому запись большого файла не освобождает загрузку для других запросов
+func (u *UploadUsecase) lock(uploadUUID string) (func(), error) {
+	holder := uuid.NewString()
+
+	locked, err := u.Repository.LockUpload(uploadUUID, holder, time.Now().Add(model.UploadLockTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	if !locked {
+		return nil, custom_error.ErrUploadLocked
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(model.UploadLockTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			if _, err := u.Repository.LockUpload(uploadUUID, holder, time.Now().Add(model.UploadLockTTL)); err != nil {
+				log.Errorf("failed to extend lock of upload [%s]: %+v", uploadUUID, err)
+			}
+		}
+	}()
+
+	unlock := func() {
+		close(done)
+
+		if err := u.Repository.UnlockUpload(uploadUUID, holder); err != nil {
+			log.Errorf("failed to unlock upload [%s]: %+v", uploadUUID, err)
+		}
+	}
+
+	return unlock, nil
+}
+
+// uploadHasher восстанавливает SHA-256 полученных байт загрузки. Для загрузки, которая была начата
+// без сохранения состояния хеша, возвращает false: хеш ее файла считается чтением собранного файла
+func uploadHasher(upload model.Upload) (hash.Hash, bool) {
+	hasher := sha256.New()
+
+	if len(upload.HashState) == 0 {
+		return hasher, upload.Offset == 0
+	}
+
+	if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(upload.HashState); err != nil {
+		log.Warnf("failed to restore hash state of upload [%s]: %+v", upload.UUID, err)
+		return hasher, false
+	}
+
+	return hasher, true
+}
+
+// uploadDigest возвращает SHA-256 файла загрузки в hex, если он посчитан при приеме файла
+func uploadDigest(upload model.Upload) (string, bool) {
+	hasher, hashed := uploadHasher(upload)
+	if !hashed {
+		return "", false
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), true
+}
+
+// partSize возвращает размер части не меньше минимального размера части хранилища
+func (u *UploadUsecase) partSize() int64 {
+	return max(u.Cfg.PartSize, filestorage.MinPartSize)
+}
+
+func newDirectUploadResult(metaDoc model.MetaDocument) entity.DirectUploadResult {
+	return entity.DirectUploadResult{
+		DocumentID: metaDoc.UUID,
+		Version:    metaDoc.Version,
+		Size:       metaDoc.Size,
+		Digest:     metaDoc.Hash,
+	}
+}
@@ -0,0 +1,372 @@
+package usecases
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/AlexJudin/DocumentCacheServer/config"
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/encryption"
+	filestorage "github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/file_storage"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/memory"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+const testPartSize = filestorage.MinPartSize
+
+var errCompleteFailed = errors.New("complete upload failed")
+
+// failingUploads репозиторий загрузок, в котором не удается записать завершение загрузки
+type failingUploads struct {
+	postgres.Upload
+	failComplete bool
+}
+
+func (r *failingUploads) CompleteUpload(uuid, documentUUID string) error {
+	if r.failComplete {
+		return errCompleteFailed
+	}
+
+	return r.Upload.CompleteUpload(uuid, documentUUID)
+}
+
+// uploadDocuments сохраняет документы и версии из загрузок в репозиторий метаданных вместо саги
+type uploadDocuments struct {
+	Document
+	repo     repository.DocumentRepository
+	storage  *memory.FileRepo
+	contents [][]byte
+	saved    int
+	updated  int
+}
+
+func (d *uploadDocuments) SaveDocument(_ context.Context, login string, document *entity.Document) error {
+	d.saved++
+
+	document.Meta.UUID = cmp.Or(document.ReservedUUID, uuid.NewString())
+	document.Meta.Owner = login
+	document.Meta.Version = 1
+
+	return d.store(document, d.repo.Save)
+}
+
+func (d *uploadDocuments) UpdateDocument(_ context.Context, _, uuid string, document *entity.Document) error {
+	d.updated++
+
+	current, err := d.repo.GetById(uuid)
+	if err != nil {
+		return err
+	}
+
+	document.Meta.ID = current.ID
+	document.Meta.UUID = uuid
+	document.Meta.Owner = current.Owner
+	document.Meta.Version = current.Version + 1
+
+	return d.store(document, d.repo.Update)
+}
+
+func (d *uploadDocuments) store(document *entity.Document, save func(*model.MetaDocument, ...model.OutboxEvent) error) error {
+	content := document.File.Content
+
+	if document.File.StagedKey != "" {
+		file, _, err := d.storage.Download(context.Background(), document.File.StagedKey)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		content = file
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	d.contents = append(d.contents, data)
+
+	sum := sha256.Sum256(data)
+	document.Meta.Hash = hex.EncodeToString(sum[:])
+	document.Meta.Size = int64(len(data))
+
+	if err = save(document.Meta); err != nil {
+		return err
+	}
+
+	return d.repo.CreateVersion(model.NewDocumentVersion(*document.Meta))
+}
+
+type testUploads struct {
+	usecase   *UploadUsecase
+	uploads   *failingUploads
+	documents *uploadDocuments
+}
+
+func newTestUploads(t *testing.T) *testUploads {
+	t.Helper()
+
+	keyring, err := encryption.NewKeyring(&config.ConfigEncryption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := memory.NewDatabase()
+	storage := memory.NewFileRepository()
+	docRepo := repository.NewMemoryDocumentRepository(db, storage, keyring)
+
+	uploads := &failingUploads{Upload: memory.NewUploadRepo(db)}
+	documents := &uploadDocuments{repo: docRepo, storage: storage}
+
+	cfg := &config.Config{
+		ConfigUpload: &config.ConfigUpload{MaxSize: 1 << 30, PartSize: testPartSize, TTL: time.Hour},
+	}
+
+	return &testUploads{
+		usecase:   NewUploadUsecase(cfg, uploads, storage, nil, docRepo, documents, nil),
+		uploads:   uploads,
+		documents: documents,
+	}
+}
+
+func testUploadContent(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	return data
+}
+
+func TestUploadReceive(t *testing.T) {
+	s := newTestUploads(t)
+
+	data := testUploadContent(2*testPartSize + 100)
+
+	upload, err := s.usecase.CreateUpload("alice", entity.UploadRequest{Length: int64(len(data)), Name: "report.bin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name        string
+		from, to    int
+		wantParts   []int64
+		wantPending int
+	}{
+		{name: "less than part", from: 0, to: testPartSize - 10, wantPending: testPartSize - 10},
+		{name: "crossing part boundary", from: testPartSize - 10, to: testPartSize + 20, wantParts: []int64{testPartSize}, wantPending: 20},
+		{name: "exactly to part boundary", from: testPartSize + 20, to: 2 * testPartSize, wantParts: []int64{testPartSize, testPartSize}},
+		{name: "empty write", from: 2 * testPartSize, to: 2 * testPartSize, wantParts: []int64{testPartSize, testPartSize}},
+		{name: "last part smaller than others", from: 2 * testPartSize, to: 2*testPartSize + 50, wantParts: []int64{testPartSize, testPartSize}, wantPending: 50},
+	}
+
+	for _, step := range steps {
+		upload, err = s.usecase.WriteUpload("alice", upload.UUID, int64(step.from), bytes.NewReader(data[step.from:step.to]))
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+
+		if upload.Offset != int64(step.to) {
+			t.Fatalf("%s: offset = %d, want %d", step.name, upload.Offset, step.to)
+		}
+
+		parts, err := s.uploads.GetUploadParts(upload.UUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var sizes []int64
+		for i, part := range parts {
+			if part.Number != i+1 {
+				t.Errorf("%s: part %d has number %d", step.name, i, part.Number)
+			}
+
+			sizes = append(sizes, part.Size)
+		}
+
+		if !slices.Equal(sizes, step.wantParts) {
+			t.Errorf("%s: parts = %v, want %v", step.name, sizes, step.wantParts)
+		}
+
+		pending, err := s.uploads.GetUploadPending(upload.UUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(pending) != step.wantPending {
+			t.Errorf("%s: pending = %d bytes, want %d", step.name, len(pending), step.wantPending)
+		}
+	}
+
+	if _, err = s.usecase.WriteUpload("alice", upload.UUID, 10, bytes.NewReader(data[10:20])); !errors.Is(err, custom_error.ErrUploadOffsetMismatch) {
+		t.Fatalf("error = %v, want %v", err, custom_error.ErrUploadOffsetMismatch)
+	}
+
+	// байты сверх размера файла не принимаются
+	tail := append(bytes.Clone(data[2*testPartSize+50:]), "extra"...)
+
+	upload, err = s.usecase.WriteUpload("alice", upload.UUID, 2*testPartSize+50, bytes.NewReader(tail))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !upload.Completed() || upload.Offset != int64(len(data)) {
+		t.Fatalf("upload = offset %d, document [%s], want completed upload", upload.Offset, upload.DocumentUUID)
+	}
+
+	if len(s.documents.contents) != 1 || !bytes.Equal(s.documents.contents[0], data) {
+		t.Fatal("document content differs from uploaded file")
+	}
+
+	digest, _ := uploadDigest(upload)
+	sum := sha256.Sum256(data)
+
+	if digest != hex.EncodeToString(sum[:]) {
+		t.Errorf("upload digest = %s, want %s", digest, hex.EncodeToString(sum[:]))
+	}
+}
+
+func TestUploadReceiveInterrupted(t *testing.T) {
+	s := newTestUploads(t)
+
+	data := testUploadContent(testPartSize + 100)
+
+	upload, err := s.usecase.CreateUpload("alice", entity.UploadRequest{Length: int64(len(data)), Name: "report.bin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// соединение обрывается после части и еще 30 байт
+	content := io.MultiReader(bytes.NewReader(data[:testPartSize+30]), iotest.ErrReader(io.ErrClosedPipe))
+
+	if _, err = s.usecase.WriteUpload("alice", upload.UUID, 0, content); !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("error = %v, want %v", err, io.ErrClosedPipe)
+	}
+
+	upload, err = s.usecase.GetUpload("alice", upload.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if upload.Offset != testPartSize+30 {
+		t.Fatalf("offset after interrupted write = %d, want %d", upload.Offset, testPartSize+30)
+	}
+
+	upload, err = s.usecase.WriteUpload("alice", upload.UUID, upload.Offset, bytes.NewReader(data[upload.Offset:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !upload.Completed() || !bytes.Equal(s.documents.contents[0], data) {
+		t.Error("resumed upload does not produce uploaded file")
+	}
+}
+
+func TestUploadCompleteRetry(t *testing.T) {
+	s := newTestUploads(t)
+
+	data := testUploadContent(100)
+
+	upload, err := s.usecase.CreateUpload("alice", entity.UploadRequest{Length: int64(len(data)), Name: "report.bin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.uploads.failComplete = true
+
+	if _, err = s.usecase.WriteUpload("alice", upload.UUID, 0, bytes.NewReader(data)); !errors.Is(err, errCompleteFailed) {
+		t.Fatalf("error = %v, want %v", err, errCompleteFailed)
+	}
+
+	s.uploads.failComplete = false
+
+	// повтор последней записи пустым содержимым завершает загрузку уже созданным документом
+	upload, err = s.usecase.WriteUpload("alice", upload.UUID, int64(len(data)), bytes.NewReader(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.documents.saved != 1 {
+		t.Fatalf("documents saved %d times, want 1", s.documents.saved)
+	}
+
+	if !upload.Completed() || upload.DocumentUUID != upload.PendingUUID {
+		t.Errorf("upload document = [%s], want reserved [%s]", upload.DocumentUUID, upload.PendingUUID)
+	}
+}
+
+func TestUploadCompleteVersionRetry(t *testing.T) {
+	s := newTestUploads(t)
+
+	target := &entity.Document{
+		Meta: &model.MetaDocument{Name: "report.bin", File: true},
+		File: &entity.DocumentFile{Content: bytes.NewReader([]byte("first"))},
+	}
+	if err := s.documents.SaveDocument(context.Background(), "alice", target); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("second")
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	upload := model.Upload{
+		UUID:       uuid.NewString(),
+		Owner:      "alice",
+		Name:       "report.bin",
+		Length:     int64(len(data)),
+		Offset:     int64(len(data)),
+		Direct:     true,
+		TargetUUID: target.Meta.UUID,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	if err := s.uploads.CreateUpload(&upload); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := s.usecase.Storage.Upload(context.Background(), upload.StorageKey(), bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	complete := func() (model.MetaDocument, error) {
+		current, err := s.uploads.GetUpload(upload.UUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return s.usecase.complete(&current, digest, true)
+	}
+
+	s.uploads.failComplete = true
+
+	if _, err = complete(); !errors.Is(err, errCompleteFailed) {
+		t.Fatalf("error = %v, want %v", err, errCompleteFailed)
+	}
+
+	s.uploads.failComplete = false
+
+	metaDoc, err := complete()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.documents.updated != 1 || metaDoc.Version != 2 {
+		t.Errorf("document updated %d times, version = %d, want one update to version 2", s.documents.updated, metaDoc.Version)
+	}
+}
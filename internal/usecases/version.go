@@ -0,0 +1,132 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/custom_error"
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+)
+
+func (t *DocumentUsecase) GetDocumentVersions(login, uuid string) ([]model.DocumentVersion, error) {
+	_, err := t.authorize(login, uuid, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.DocumentRepository.GetVersions(uuid)
+}
+
+func (t *DocumentUsecase) GetDocumentVersion(ctx context.Context, login, uuid string, version int, acceptEncoding string) (entity.DocumentContent, error) {
+	_, err := t.authorize(login, uuid, false)
+	if err != nil {
+		return entity.DocumentContent{}, err
+	}
+
+	documentVersion, err := t.DocumentRepository.GetVersion(uuid, version)
+	if err != nil {
+		return entity.DocumentContent{}, err
+	}
+
+	metaDoc := versionMeta(documentVersion)
+
+	content, err := t.readContent(ctx, metaDoc)
+	if err != nil {
+		return entity.DocumentContent{}, err
+	}
+
+	return decodeContent(content, metaDoc.Size, acceptEncoding)
+}
+
+// DiffDocumentVersions возвращает JSON Merge Patch (RFC 7396),
+// который превращает версию from в версию to
+func (t *DocumentUsecase) DiffDocumentVersions(ctx context.Context, login, uuid string, from, to int) (map[string]interface{}, error) {
+	_, err := t.authorize(login, uuid, false)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := t.versionJson(ctx, uuid, from)
+	if err != nil {
+		return nil, err
+	}
+
+	modified, err := t.versionJson(ctx, uuid, to)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := jsonpatch.CreateMergePatch(original, modified)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make(map[string]interface{})
+	if err = json.Unmarshal(patch, &diff); err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+func (t *DocumentUsecase) RestoreDocumentVersion(ctx context.Context, login, uuid string, version int) error {
+	_, err := t.authorize(login, uuid, true)
+	if err != nil {
+		return err
+	}
+
+	err = t.sagaOrchestrator.RestoreVersion(sagaContext(ctx), uuid, version)
+	if err != nil {
+		return err
+	}
+
+	t.Cache.Delete(context.WithoutCancel(ctx), uuid)
+	t.SearchIndexer.Index(uuid)
+
+	// версия уже восстановлена, поэтому ошибка чтения метаданных отменяет только уведомление подписчиков
+	if metaDoc, err := t.DocumentRepository.GetById(uuid); err == nil {
+		t.notify(model.EventDocumentUpdated, metaDoc)
+	}
+
+	return nil
+}
+
+func (t *DocumentUsecase) versionJson(ctx context.Context, uuid string, version int) ([]byte, error) {
+	documentVersion, err := t.DocumentRepository.GetVersion(uuid, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if documentVersion.File {
+		return nil, fmt.Errorf("%w: version %d", custom_error.ErrDocumentNotJson, version)
+	}
+
+	jsonDocMap, err := t.DocumentRepository.GetByDocumentId(ctx, documentVersion.StorageKey())
+	if err != nil {
+		return nil, err
+	}
+
+	delete(jsonDocMap, "_id")
+
+	return json.Marshal(jsonDocMap)
+}
+
+// versionMeta представляет версию в виде метаданных документа для чтения ее содержимого
+func versionMeta(version model.DocumentVersion) model.MetaDocument {
+	return model.MetaDocument{
+		UUID:       version.DocumentUUID,
+		UpdatedAt:  version.CreatedAt,
+		Name:       version.Name,
+		File:       version.File,
+		Mime:       version.Mime,
+		Size:       version.Size,
+		Hash:       version.Hash,
+		ContentKey: version.StorageKey(),
+		Version:    version.Version,
+		Encoding:   version.Encoding,
+	}
+}
@@ -0,0 +1,100 @@
+package usecases
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/google/uuid"
+
+	"github.com/AlexJudin/DocumentCacheServer/internal/entity"
+	"github.com/AlexJudin/DocumentCacheServer/internal/infrastructure/repository/postgres"
+	"github.com/AlexJudin/DocumentCacheServer/internal/model"
+	"github.com/AlexJudin/DocumentCacheServer/internal/service"
+)
+
+// webhookSecretSize размер генерируемого секрета webhook в байтах
+const webhookSecretSize = 32
+
+var _ Webhook = (*WebhookUsecase)(nil)
+
+type WebhookUsecase struct {
+	Repository postgres.Webhook
+	Dispatcher *service.WebhookDispatcher
+}
+
+func NewWebhookUsecase(repo postgres.Webhook, dispatcher *service.WebhookDispatcher) *WebhookUsecase {
+	return &WebhookUsecase{
+		Repository: repo,
+		Dispatcher: dispatcher,
+	}
+}
+
+func (u *WebhookUsecase) CreateWebhook(login string, req entity.WebhookRequest) (entity.NewWebhook, error) {
+	if err := req.Validate(); err != nil {
+		return entity.NewWebhook{}, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, webhookSecretSize)
+		if _, err := rand.Read(buf); err != nil {
+			return entity.NewWebhook{}, err
+		}
+
+		secret = hex.EncodeToString(buf)
+	}
+
+	subscription := model.WebhookSubscription{
+		UUID:   uuid.NewString(),
+		Login:  login,
+		URL:    req.URL,
+		Events: req.Events,
+		Secret: secret,
+	}
+
+	if err := u.Repository.CreateSubscription(&subscription); err != nil {
+		return entity.NewWebhook{}, err
+	}
+
+	return entity.NewWebhook{Webhook: subscription, Secret: secret}, nil
+}
+
+func (u *WebhookUsecase) GetWebhooks(login string) ([]model.WebhookSubscription, error) {
+	return u.Repository.GetSubscriptions(login)
+}
+
+func (u *WebhookUsecase) DeleteWebhook(login, uuid string) error {
+	return u.Repository.DeleteSubscription(login, uuid)
+}
+
+func (u *WebhookUsecase) GetDeliveries(login, uuid string, req entity.DeliveryListRequest) (entity.DeliveryList, error) {
+	if err := req.Validate(); err != nil {
+		return entity.DeliveryList{}, err
+	}
+
+	if _, err := u.Repository.GetSubscription(login, uuid); err != nil {
+		return entity.DeliveryList{}, err
+	}
+
+	deliveries, total, err := u.Repository.GetDeliveries(uuid, req.Status, req.Limit, req.Offset)
+	if err != nil {
+		return entity.DeliveryList{}, err
+	}
+
+	return entity.DeliveryList{Deliveries: deliveries, Total: total}, nil
+}
+
+// Redeliver возвращает в очередь доставку, для которой исчерпаны все попытки
+func (u *WebhookUsecase) Redeliver(login, uuid, deliveryUUID string) error {
+	if _, err := u.Repository.GetSubscription(login, uuid); err != nil {
+		return err
+	}
+
+	if err := u.Repository.RedeliverDelivery(uuid, deliveryUUID); err != nil {
+		return err
+	}
+
+	u.Dispatcher.Wake()
+
+	return nil
+}